@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"start-feishubot/services"
+	"start-feishubot/services/openai"
+	"strconv"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// continuePrompt 回答因长度限制被截断后，请模型接着输出
+const continuePrompt = "你的上一条回答因长度限制被截断了，请紧接着截断的位置继续输出，不要重复已经输出的内容，也不要添加开场白。"
+
+func NewAnswerRegenerateHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == AnswerRegenerateKind {
+			if toast := m.takeAnswerToken(cardMsg, cardAction.OpenID); toast != nil {
+				return toast, nil
+			}
+			go func() {
+				m.CommonProcessAnswerRegenerate(cardMsg, cardAction.OpenID)
+			}()
+			return nil, nil
+		}
+		return nil, ErrNextHandler
+	}
+}
+
+func NewAnswerContinueHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == AnswerContinueKind {
+			if toast := m.takeAnswerToken(cardMsg, cardAction.OpenID); toast != nil {
+				return toast, nil
+			}
+			go func() {
+				m.CommonProcessAnswerContinue(cardMsg, cardAction.OpenID)
+			}()
+			return nil, nil
+		}
+		return nil, ErrNextHandler
+	}
+}
+
+func NewAnswerFeedbackHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == AnswerFeedbackKind {
+			return m.CommonProcessAnswerFeedback(cardMsg, cardAction)
+		}
+		return nil, ErrNextHandler
+	}
+}
+
+// CommonProcessAnswerRegenerate 去掉最新一轮问答后重新走一遍消息处理，新的回答替换会话中原来的回答
+func (m MessageHandler) CommonProcessAnswerRegenerate(cardMsg CardMsg, openId string) {
+	a := m.answerActionInfo(cardMsg, openId)
+	sessionId := cardMsg.SessionId
+	msgs := m.sessionCache.GetMsg(sessionId)
+	id, _ := cardMsg.Value.(string)
+	answer, question, ok := findAnswer(msgs, id)
+	if !ok {
+		replyMsg(*a.ctx, "🤖️：话题已过期或已被清除，无法重新生成这条回答～", a.info.msgId)
+		return
+	}
+	if answer != len(msgs)-1 {
+		replyMsg(*a.ctx, "🤖️：只能重新生成话题中最新的一条回答～", a.info.msgId)
+		return
+	}
+	fmt.Printf("🔄 [Answer] Regenerating answer in session %s\n", sessionId)
+
+	previous := append([]openai.Messages{}, msgs...)
+	prefix := append([]openai.Messages{}, msgs[:question]...)
+	a.info.qParsed = msgs[question].Content
+	a.search = resolveSearchTrigger(a.settings.SearchMode, m.config.SearchOnlyOnKeywords,
+		m.config.SearchKeywords, a.info.qParsed)
+	m.sessionCache.SetMsg(sessionId, prefix)
+	(&MessageAction{}).Execute(a)
+
+	// 重新生成失败时会话没有变化，恢复原来的回答
+	if after := m.sessionCache.GetMsg(sessionId); len(after) == len(prefix) &&
+		(len(after) == 0 || after[len(after)-1].Content == prefix[len(prefix)-1].Content) {
+		m.sessionCache.SetMsg(sessionId, previous)
+	}
+}
+
+// CommonProcessAnswerContinue 接着被截断的回答继续生成，续写内容拼接到会话中原来的回答后面
+func (m MessageHandler) CommonProcessAnswerContinue(cardMsg CardMsg, openId string) {
+	a := m.answerActionInfo(cardMsg, openId)
+	sessionId := cardMsg.SessionId
+	msgs := m.sessionCache.GetMsg(sessionId)
+	id, _ := cardMsg.Value.(string)
+	answer, question, ok := findAnswer(msgs, id)
+	if !ok {
+		replyMsg(*a.ctx, "🤖️：话题已过期或已被清除，无法继续生成～", a.info.msgId)
+		return
+	}
+	if answer != len(msgs)-1 {
+		replyMsg(*a.ctx, "🤖️：只能继续话题中最新的一条回答～", a.info.msgId)
+		return
+	}
+	fmt.Printf("⏩ [Answer] Continuing truncated answer in session %s\n", sessionId)
+	a.info.qParsed = msgs[question].Content
+
+	prompt := append(append([]openai.Messages{}, msgs...),
+		openai.Messages{Role: "user", Content: continuePrompt})
+	completion, err := a.answerCompletions(prompt, 0)
+	if err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：继续生成失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+		return
+	}
+	history := append([]openai.Messages{}, msgs...)
+	history[answer].Content += completion.Content
+	m.sessionCache.SetMsg(sessionId, history)
+
+	// 续写卡片上的按钮对应拼接后的完整回答
+	layout := answerLayout{actions: a.answerActions(history[answer].Content, completion.Truncated())}
+	if err := a.deliverAnswer(completion.Content, layout, a.replyAnswerCard); err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+	}
+}
+
+// CommonProcessAnswerFeedback 保存 👍/👎 评价，并附上发送回答时保存的提问和回答
+func (m MessageHandler) CommonProcessAnswerFeedback(cardMsg CardMsg,
+	cardAction *larkcard.CardAction) (interface{}, error) {
+	rating := services.FeedbackRating(cardMsg.Field)
+	if rating != services.FeedbackUp && rating != services.FeedbackDown {
+		return nil, nil
+	}
+	id, _ := cardMsg.Value.(string)
+	feedback := services.AnswerFeedback{
+		SessionId: cardMsg.SessionId,
+		ChatId:    cardMsg.ChatId,
+		UserId:    cardAction.OpenID,
+		Rating:    rating,
+	}
+	record, ok := m.feedbacks.FindAnswer(cardMsg.SessionId, id)
+	if !ok {
+		// 未保存原文时（如升级前发出的回答）退回到会话中查找
+		msgs := m.sessionCache.GetMsg(cardMsg.SessionId)
+		if answer, question, found := findAnswer(msgs, id); found {
+			record = services.AnswerRecord{Question: msgs[question].Content, Answer: msgs[answer].Content}
+		}
+	}
+	feedback.Question = record.Question
+	feedback.Answer = record.Answer
+	if err := m.feedbacks.Record(id, feedback); err != nil {
+		return nil, err
+	}
+	fmt.Printf("📝 [Feedback] %s rated an answer in session %s: %s\n",
+		feedback.UserId, feedback.SessionId, rating)
+	if rating == services.FeedbackUp {
+		return newToast("success", "感谢你的评价 👍"), nil
+	}
+	return newToast("success", "感谢反馈，我们会继续改进"), nil
+}
+
+// takeAnswerToken 重新生成和继续生成同样会调用模型，与普通提问共用令牌桶；
+// 访问控制已在 NewCardHandler 中统一检查。被限流时返回提示
+func (m MessageHandler) takeAnswerToken(cardMsg CardMsg, openId string) *larkcard.CustomResp {
+	if m.limiter == nil {
+		return nil
+	}
+	userKey := openId
+	if userKey == "" {
+		userKey = cardMsg.ChatId
+	}
+	groupChatId := ""
+	if cardMsg.ChatType == GroupChatType {
+		groupChatId = cardMsg.ChatId
+	}
+	allowed, wait := m.limiter.Allow(userKey, groupChatId)
+	if allowed {
+		return nil
+	}
+	fmt.Printf("    🚦 Answer action rate limited user=%s chat=%s, retry after %v\n",
+		userKey, groupChatId, wait)
+	seconds := int(wait.Seconds() + 0.999)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return newToast("warning", fmt.Sprintf("你点得太快啦，请 %d 秒后再试～", seconds))
+}
+
+// answerActionInfo 按回答卡片上记录的会话信息还原提问时的上下文
+func (m MessageHandler) answerActionInfo(cardMsg CardMsg, openId string) *ActionInfo {
+	ctx := context.Background()
+	handlerType := HandlerType(UserHandler)
+	if cardMsg.ChatType == GroupChatType {
+		handlerType = GroupHandler
+	}
+	msgId, chatId, sessionId := cardMsg.MsgId, cardMsg.ChatId, cardMsg.SessionId
+	return &ActionInfo{
+		handler: &m,
+		ctx:     &ctx,
+		info: &MsgInfo{
+			handlerType: handlerType,
+			msgType:     "text",
+			msgId:       &msgId,
+			chatId:      &chatId,
+			sessionId:   &sessionId,
+			openId:      openId,
+		},
+		settings: m.chatSettings.Resolve(chatId, m.config),
+	}
+}
+
+// answerActions 当前提问的回答卡片按钮，同时保存提问和回答供之后的评价关联
+func (a *ActionInfo) answerActions(answer string, truncated bool) larkcard.MessageCardElement {
+	id := answerId(answer)
+	if feedbacks := a.handler.feedbacks; feedbacks != nil {
+		record := services.AnswerRecord{Question: a.info.qParsed, Answer: answer}
+		if err := feedbacks.SaveAnswer(*a.info.sessionId, id, record); err != nil {
+			fmt.Printf("    ⚠️ Save answer for feedback failed: %v\n", err)
+		}
+	}
+	chatId := ""
+	if a.info.chatId != nil {
+		chatId = *a.info.chatId
+	}
+	chatType := UserChatType
+	if a.info.handlerType == GroupHandler {
+		chatType = GroupChatType
+	}
+	return withAnswerActions(*a.info.sessionId, *a.info.msgId, chatId, chatType, id, truncated)
+}
+
+// replyAnswer 回复带重新生成、继续和评价按钮的回答卡片
+func (a *ActionInfo) replyAnswer(answer string, newTopic bool, truncated bool) error {
+	layout := answerLayout{newTopic: newTopic, actions: a.answerActions(answer, truncated)}
+	return a.deliverAnswer(answer, layout, a.replyAnswerCard)
+}
+
+// answerId 用回答内容的摘要标识会话中的一条回答
+func answerId(answer string) string {
+	h := fnv.New64a()
+	h.Write([]byte(answer))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// findAnswer 在会话中查找 id 对应的回答，返回回答和它对应提问的下标
+func findAnswer(msgs []openai.Messages, id string) (answer int, question int, ok bool) {
+	if id == "" {
+		return 0, 0, false
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role != "assistant" || answerId(msgs[i].Content) != id {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if msgs[j].Role == "user" {
+				return i, j, true
+			}
+		}
+		return 0, 0, false
+	}
+	return 0, 0, false
+}
+
+func newToast(kind string, content string) *larkcard.CustomResp {
+	return &larkcard.CustomResp{Body: map[string]interface{}{
+		"toast": map[string]interface{}{"type": kind, "content": content},
+	}}
+}
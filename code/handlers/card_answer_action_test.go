@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"start-feishubot/services/openai"
+	"start-feishubot/services/ratelimit"
+	"testing"
+)
+
+func TestFindAnswer(t *testing.T) {
+	msgs := []openai.Messages{
+		{Role: "system", Content: "你是一个翻译"},
+		{Role: "user", Content: "第一个问题"},
+		{Role: "assistant", Content: "第一个回答"},
+		{Role: "user", Content: "第二个问题"},
+		{Role: "assistant", Content: "第二个回答"},
+	}
+	tests := []struct {
+		name     string
+		msgs     []openai.Messages
+		id       string
+		answer   int
+		question int
+		ok       bool
+	}{
+		{"latest answer", msgs, answerId("第二个回答"), 4, 3, true},
+		{"earlier answer", msgs, answerId("第一个回答"), 2, 1, true},
+		{"unknown answer", msgs, answerId("没有的回答"), 0, 0, false},
+		{"empty id", msgs, "", 0, 0, false},
+		{"answer without question", msgs[:1:1], answerId("你是一个翻译"), 0, 0, false},
+		{"expired session", nil, answerId("第一个回答"), 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			answer, question, ok := findAnswer(tt.msgs, tt.id)
+			if answer != tt.answer || question != tt.question || ok != tt.ok {
+				t.Errorf("findAnswer() = %d, %d, %t, want %d, %d, %t",
+					answer, question, ok, tt.answer, tt.question, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTakeAnswerToken(t *testing.T) {
+	limit := ratelimit.Limit{PerMinute: 1, Burst: 1}
+	m := MessageHandler{limiter: ratelimit.NewLimiter(ratelimit.NewMemoryStore(), limit, limit)}
+	cardMsg := CardMsg{ChatId: "oc_a", ChatType: GroupChatType}
+	if toast := m.takeAnswerToken(cardMsg, "ou_a"); toast != nil {
+		t.Fatalf("first click should be allowed, got %v", toast.Body)
+	}
+	if toast := m.takeAnswerToken(cardMsg, "ou_a"); toast == nil {
+		t.Fatal("second click should be rate limited")
+	}
+	if toast := (MessageHandler{}).takeAnswerToken(cardMsg, "ou_a"); toast != nil {
+		t.Fatal("no limiter should always allow")
+	}
+}
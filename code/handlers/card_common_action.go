@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"start-feishubot/services/accesscontrol"
+
 	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
 )
 
@@ -18,10 +20,19 @@ func NewCardHandler(m MessageHandler) CardHandlerFunc {
 	handlers := []CardHandlerMeta{
 		NewClearCardHandler,
 		NewPicResolutionHandler,
+		NewPicSettingHandler,
 		NewPicTextMoreHandler,
 		NewPicModeChangeHandler,
+		NewPicMoreLikeHandler,
+		NewPicEditMoreHandler,
 		NewRoleTagCardHandler,
 		NewRoleCardHandler,
+		NewChatSettingHandler,
+		NewChatSettingResetHandler,
+		NewVoiceSettingHandler,
+		NewAnswerRegenerateHandler,
+		NewAnswerContinueHandler,
+		NewAnswerFeedbackHandler,
 	}
 
 	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
@@ -30,6 +41,10 @@ func NewCardHandler(m MessageHandler) CardHandlerFunc {
 		actionValueJson, _ := json.Marshal(actionValue)
 		json.Unmarshal(actionValueJson, &cardMsg)
 		//pp.Println(cardMsg)
+		// 卡片按钮同样需要访问控制，避免无权限的用户通过按钮调用模型
+		if decision := m.checkCardAccess(cardMsg, cardAction); !decision.Allowed {
+			return newToast("error", "抱歉，你暂时没有使用机器人的权限～"), nil
+		}
 		for _, handler := range handlers {
 			h := handler(cardMsg, m)
 			i, err := h(ctx, cardAction)
@@ -41,3 +56,30 @@ func NewCardHandler(m MessageHandler) CardHandlerFunc {
 		return nil, nil
 	}
 }
+
+// checkCardAccess 对点击卡片的用户做与消息相同的访问控制
+func (m MessageHandler) checkCardAccess(cardMsg CardMsg,
+	cardAction *larkcard.CardAction) accesscontrol.Decision {
+	subject := accesscontrol.Subject{
+		OpenId: cardAction.OpenID,
+		ChatId: cardChatId(cardMsg, cardAction),
+	}
+	decision := m.access.Check(subject)
+	if !decision.Allowed {
+		fmt.Printf("🔐 Card action denied: openId=%s, chatId=%s, kind=%s, reason=%s\n",
+			subject.OpenId, subject.ChatId, cardMsg.Kind, decision.Reason)
+	}
+	return decision
+}
+
+// cardChatId 按钮值中没有群 ID 时，从卡片回调原文的 open_chat_id 读取
+func cardChatId(cardMsg CardMsg, cardAction *larkcard.CardAction) string {
+	if cardMsg.ChatId != "" || cardAction.EventReq == nil {
+		return cardMsg.ChatId
+	}
+	var body struct {
+		OpenChatId string `json:"open_chat_id"`
+	}
+	json.Unmarshal(cardAction.EventReq.Body, &body)
+	return body.OpenChatId
+}
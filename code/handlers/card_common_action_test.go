@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"start-feishubot/services/accesscontrol"
+	"testing"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	larkevent "github.com/larksuite/oapi-sdk-go/v3/event"
+)
+
+func TestCardChatId(t *testing.T) {
+	body := []byte(`{"open_id":"ou_a","open_chat_id":"oc_body","action":{"value":{}}}`)
+	tests := []struct {
+		name    string
+		cardMsg CardMsg
+		action  *larkcard.CardAction
+		want    string
+	}{
+		{"from button value", CardMsg{ChatId: "oc_value"},
+			&larkcard.CardAction{EventReq: &larkevent.EventReq{Body: body}}, "oc_value"},
+		{"from callback body", CardMsg{},
+			&larkcard.CardAction{EventReq: &larkevent.EventReq{Body: body}}, "oc_body"},
+		{"no request", CardMsg{}, &larkcard.CardAction{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cardChatId(tt.cardMsg, tt.action); got != tt.want {
+				t.Errorf("cardChatId() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeUserResolver open_id -> union_id，所有用户都不属于任何部门
+type fakeUserResolver map[string]string
+
+func (f fakeUserResolver) Departments(openId string) ([]string, error) { return nil, nil }
+
+func (f fakeUserResolver) UnionId(openId string) (string, error) { return f[openId], nil }
+
+func TestCheckCardAccessUnionId(t *testing.T) {
+	// 只按 union_id 配置的管理员点击卡片时，回调中只有 open_id
+	m := MessageHandler{access: accesscontrol.NewChecker(accesscontrol.Rules{
+		AllowUsers: []string{"ou_someone"},
+		Admins:     []string{"on_admin"},
+	}, fakeUserResolver{"ou_admin": "on_admin", "ou_other": "on_other"})}
+	tests := []struct {
+		openId    string
+		wantAllow bool
+	}{
+		{"ou_admin", true},
+		{"ou_other", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.openId, func(t *testing.T) {
+			decision := m.checkCardAccess(CardMsg{ChatId: "oc_a"}, &larkcard.CardAction{OpenID: tt.openId})
+			if decision.Allowed != tt.wantAllow || decision.Admin != tt.wantAllow {
+				t.Errorf("checkCardAccess(%s) = %+v", tt.openId, decision)
+			}
+		})
+	}
+}
@@ -2,10 +2,18 @@ package handlers
 
 import (
 	"context"
-	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	"fmt"
+	"io"
+	"start-feishubot/initialization"
 	"start-feishubot/services"
+	"start-feishubot/services/openai"
+	"strconv"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 )
 
+// NewPicResolutionHandler 兼容旧版本发出的分辨率选择卡片
 func NewPicResolutionHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
 	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
 		if cardMsg.Kind == PicResolutionKind {
@@ -16,10 +24,19 @@ func NewPicResolutionHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc
 	}
 }
 
+func NewPicSettingHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == PicSettingKind {
+			return m.CommonProcessPicSetting(cardMsg, cardAction)
+		}
+		return nil, ErrNextHandler
+	}
+}
+
 func NewPicModeChangeHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
 	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
 		if cardMsg.Kind == PicModeChangeKind {
-			newCard, err, done := CommonProcessPicModeChange(cardMsg, m.sessionCache)
+			newCard, err, done := CommonProcessPicModeChange(cardMsg, m)
 			if done {
 				return newCard, err
 			}
@@ -40,6 +57,30 @@ func NewPicTextMoreHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
 	}
 }
 
+func NewPicMoreLikeHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == PicMoreLikeKind {
+			go func() {
+				m.CommonProcessPicMoreLike(cardMsg)
+			}()
+			return nil, nil
+		}
+		return nil, ErrNextHandler
+	}
+}
+
+func NewPicEditMoreHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == PicEditMoreKind {
+			go func() {
+				m.CommonProcessPicEditMore(cardMsg)
+			}()
+			return nil, nil
+		}
+		return nil, ErrNextHandler
+	}
+}
+
 func CommonProcessPicResolution(msg CardMsg,
 	cardAction *larkcard.CardAction,
 	cache services.SessionServiceCacheInterface) {
@@ -51,33 +92,116 @@ func CommonProcessPicResolution(msg CardMsg,
 		&msg.MsgId)
 }
 
+// CommonProcessPicSetting 更新图片参数，切换模型后不再支持的参数恢复为默认值
+func (m MessageHandler) CommonProcessPicSetting(msg CardMsg,
+	cardAction *larkcard.CardAction) (interface{}, error) {
+	option := cardAction.Action.Option
+	setting := m.sessionCache.GetPicSetting(msg.SessionId)
+	switch msg.Field {
+	case "model":
+		setting.Model = option
+	case "size":
+		setting.Size = option
+	case "quality":
+		setting.Quality = option
+	case "style":
+		setting.Style = option
+	case "background":
+		setting.Background = option
+	case "n":
+		setting.N, _ = strconv.Atoi(option)
+	case "preset":
+		setting.Preset = option
+		if option == picPresetNone {
+			setting.Preset = ""
+		}
+	case "enhance":
+		enhance := option == "on"
+		setting.Enhance = &enhance
+	default:
+		return nil, fmt.Errorf("unknown picture setting: %s", msg.Field)
+	}
+	opts := m.normalizePicSetting(setting)
+	m.sessionCache.SetPicSetting(msg.SessionId, setting.WithImageOptions(opts))
+	return m.picModeCard(msg.SessionId)
+}
+
 func (m MessageHandler) CommonProcessPicMore(msg CardMsg) {
-	resolution := m.sessionCache.GetPicResolution(msg.SessionId)
-	//fmt.Println("resolution: ", resolution)
-	//fmt.Println("msg: ", msg)
-	question := msg.Value.(string)
-	bs64, _ := m.gpt.GenerateOneImage(question, resolution)
-	replayImageCardByBase64(context.Background(), bs64, &msg.MsgId,
-		&msg.SessionId, question)
+	question, _ := msg.Value.(string)
+	m.generatePictures(context.Background(), question,
+		m.picOptions(msg.SessionId), &msg.MsgId, &msg.SessionId)
+}
+
+// CommonProcessPicMoreLike 支持 variations 的模型基于该图片生成变体，其他模型按原提示词再生成一张
+func (m MessageHandler) CommonProcessPicMoreLike(msg CardMsg) {
+	question, _ := msg.Value.(string)
+	opts := m.picOptions(msg.SessionId)
+	opts.N = 1
+	if !openai.GetImageModelSpec(opts.Model).Variation || msg.ImageKey == "" {
+		m.generatePictures(context.Background(), question, opts,
+			&msg.MsgId, &msg.SessionId)
+		return
+	}
+
+	resp, err := initialization.GetLarkClient().Im.Image.Get(context.Background(),
+		larkim.NewGetImageReqBuilder().ImageKey(msg.ImageKey).Build())
+	if err == nil && !resp.Success() {
+		err = fmt.Errorf("%d %s", resp.Code, resp.Msg)
+	}
+	var img []byte
+	if err == nil {
+		img, err = io.ReadAll(resp.File)
+	}
+	if err == nil {
+		img, err = openai.ConvertToRGBAPng(img)
+	}
+	if err != nil {
+		fmt.Printf("⚠️ Download image %s failed, regenerating from prompt: %v\n", msg.ImageKey, err)
+		m.generatePictures(context.Background(), question, opts,
+			&msg.MsgId, &msg.SessionId)
+		return
+	}
+	bs64, err := m.gpt.GenerateOneImageVariationBytes(img, openai.VariationSize(opts.Size))
+	if err != nil {
+		replyMsg(context.Background(), fmt.Sprintf(
+			"🤖️：图片生成失败，请稍后再试～\n错误信息: %v", err), &msg.MsgId)
+		return
+	}
+	imageKey, err := uploadImage(bs64)
+	if err != nil || imageKey == nil {
+		replyMsg(context.Background(), "🤖️：图片上传失败，请稍后再试～", &msg.MsgId)
+		return
+	}
+	sendImageGalleryCard(context.Background(), []string{*imageKey},
+		&msg.MsgId, &msg.SessionId, question, "")
+}
+
+// CommonProcessPicEditMore 按原消息中的图片、蒙版和说明再修改一张
+func (m MessageHandler) CommonProcessPicEditMore(msg CardMsg) {
+	prompt, _ := msg.Value.(string)
+	opts := m.picOptions(msg.SessionId)
+	opts.N = 1
+	m.editPicture(context.Background(), picEdit{
+		MsgId:    msg.MsgId,
+		ImageKey: msg.ImageKey,
+		MaskKey:  msg.MaskKey,
+		Prompt:   prompt,
+	}, opts, &msg.SessionId)
 }
 
 func CommonProcessPicModeChange(cardMsg CardMsg,
-	session services.SessionServiceCacheInterface) (
+	m MessageHandler) (
 	interface{}, error, bool) {
+	session := m.sessionCache
 	if cardMsg.Value == "1" {
 
 		sessionId := cardMsg.SessionId
 		session.Clear(sessionId)
 		session.SetMode(sessionId,
 			services.ModePicCreate)
-		session.SetPicResolution(sessionId,
-			services.Resolution256)
-
-		newCard, _ :=
-			newSendCard(
-				withHeader("🖼️ 已进入图片创作模式", larkcard.TemplateBlue),
-				withPicResolutionBtn(&sessionId),
-				withNote("提醒：回复文本或图片，让AI生成相关的图片。"))
+		session.SetPicSetting(sessionId, services.PicSetting{})
+
+		newCard, _ := m.picModeCard(sessionId)
 		return newCard, nil, true
 	}
 	if cardMsg.Value == "0" {
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"start-feishubot/initialization"
+	"start-feishubot/services"
+	"strconv"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// settingDefaultOption 选择后恢复为沿用全局配置
+const settingDefaultOption = "default"
+
+func NewChatSettingHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == ChatSettingKind {
+			return CommonProcessChatSetting(cardMsg, cardAction, m)
+		}
+		return nil, ErrNextHandler
+	}
+}
+
+func NewChatSettingResetHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == ChatSettingResetKind {
+			return CommonProcessChatSettingReset(cardMsg, cardAction, m)
+		}
+		return nil, ErrNextHandler
+	}
+}
+
+func CommonProcessChatSetting(cardMsg CardMsg, cardAction *larkcard.CardAction,
+	m MessageHandler) (interface{}, error) {
+	if !m.canManageChat(cardMsg.ChatType, cardMsg.ChatId, cardAction.OpenID) {
+		replySettingDenied(cardAction)
+		return nil, nil
+	}
+	option := cardAction.Action.Option
+	// 先在副本上校验，非法选项不会写入存储
+	draft := m.chatSettings.Get(cardMsg.ChatId)
+	if err := applyChatSettingOption(&draft, cardMsg.Field, option, m.config.ModelOptions); err != nil {
+		return nil, err
+	}
+	_, err := m.chatSettings.Update(cardMsg.ChatId, cardAction.OpenID,
+		func(setting *services.ChatSetting) {
+			applyChatSettingOption(setting, cardMsg.Field, option, m.config.ModelOptions)
+		})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("⚙️ Chat setting updated: chat=%s, field=%s, option=%s, by=%s\n",
+		cardMsg.ChatId, cardMsg.Field, option, cardAction.OpenID)
+	return newChatSettingCard(cardMsg.ChatId, cardMsg.ChatType,
+		m.chatSettings.Get(cardMsg.ChatId),
+		m.chatSettings.Resolve(cardMsg.ChatId, m.config),
+		m.config.ModelOptions)
+}
+
+func CommonProcessChatSettingReset(cardMsg CardMsg, cardAction *larkcard.CardAction,
+	m MessageHandler) (interface{}, error) {
+	if !m.canManageChat(cardMsg.ChatType, cardMsg.ChatId, cardAction.OpenID) {
+		replySettingDenied(cardAction)
+		return nil, nil
+	}
+	if err := m.chatSettings.Reset(cardMsg.ChatId); err != nil {
+		return nil, err
+	}
+	fmt.Printf("⚙️ Chat setting reset: chat=%s, by=%s\n", cardMsg.ChatId, cardAction.OpenID)
+	return newChatSettingCard(cardMsg.ChatId, cardMsg.ChatType,
+		m.chatSettings.Get(cardMsg.ChatId),
+		m.chatSettings.Resolve(cardMsg.ChatId, m.config),
+		m.config.ModelOptions)
+}
+
+func replySettingDenied(cardAction *larkcard.CardAction) {
+	msgId := cardAction.OpenMessageID
+	replyMsg(context.Background(), "🤖️：只有群主和机器人管理员可以修改群设置～", &msgId)
+}
+
+// applyChatSettingOption 把卡片选项写入 setting，选项不在菜单范围内时返回错误，
+// model 只能是 modelOptions 中的模型
+func applyChatSettingOption(setting *services.ChatSetting, field string,
+	option string, modelOptions []string) error {
+	reset := option == settingDefaultOption
+	switch field {
+	case "search_mode":
+		// 修改后不再沿用旧版本的“总是联网”开关
+		setting.SearchAlways = nil
+		if reset {
+			setting.SearchMode = ""
+			return nil
+		}
+		mode, ok := services.ParseSearchMode(option)
+		if !ok {
+			return fmt.Errorf("invalid search mode: %s", option)
+		}
+		setting.SearchMode = mode
+	case "search_top_k":
+		if reset {
+			setting.SearchTopK = 0
+			return nil
+		}
+		topK, err := strconv.Atoi(option)
+		if err != nil || topK < 1 || topK > 10 {
+			return fmt.Errorf("invalid search top k: %s", option)
+		}
+		setting.SearchTopK = topK
+	case "show_search_plan":
+		if reset {
+			setting.ShowSearchPlan = nil
+			return nil
+		}
+		if option != "on" && option != "off" {
+			return fmt.Errorf("invalid show search plan: %s", option)
+		}
+		on := option == "on"
+		setting.ShowSearchPlan = &on
+	case "default_role":
+		if reset {
+			setting.DefaultRole = ""
+			return nil
+		}
+		if initialization.GetRoleByTitle(option) == nil {
+			return fmt.Errorf("unknown role: %s", option)
+		}
+		setting.DefaultRole = option
+	case "model":
+		if reset {
+			setting.Model = ""
+			return nil
+		}
+		for _, model := range modelOptions {
+			if model == option {
+				setting.Model = option
+				return nil
+			}
+		}
+		return fmt.Errorf("model not in MODEL_OPTIONS: %s", option)
+	case "reply_style":
+		if reset {
+			setting.ReplyStyle = services.ReplyStyleDefault
+			return nil
+		}
+		style := services.ReplyStyle(option)
+		if style != services.ReplyStyleConcise && style != services.ReplyStyleDetailed {
+			return fmt.Errorf("invalid reply style: %s", option)
+		}
+		setting.ReplyStyle = style
+	default:
+		return fmt.Errorf("unknown setting field: %s", field)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"start-feishubot/initialization"
+	"start-feishubot/services"
+	"testing"
+)
+
+func TestApplyChatSettingOption(t *testing.T) {
+	initialization.RoleList = &[]initialization.Role{{Title: "翻译"}}
+	models := []string{"gpt-4o", "gpt-4o-mini"}
+	tests := []struct {
+		field   string
+		option  string
+		wantErr bool
+	}{
+		{"model", "gpt-4o-mini", false},
+		{"model", "o1-pro", true},
+		{"model", settingDefaultOption, false},
+		{"default_role", "翻译", false},
+		{"default_role", "不存在", true},
+		{"reply_style", "concise", false},
+		{"reply_style", "verbose", true},
+		{"show_search_plan", "off", false},
+		{"show_search_plan", "maybe", true},
+		{"search_top_k", "11", true},
+		{"search_mode", "always", false},
+		{"unknown", "x", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field+"="+tt.option, func(t *testing.T) {
+			setting := services.ChatSetting{Model: "gpt-4o"}
+			err := applyChatSettingOption(&setting, tt.field, tt.option, models)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("applyChatSettingOption() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && setting.Model != "gpt-4o" {
+				t.Errorf("invalid option changed setting: %+v", setting)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"start-feishubot/services"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+func NewVoiceSettingHandler(cardMsg CardMsg, m MessageHandler) CardHandlerFunc {
+	return func(ctx context.Context, cardAction *larkcard.CardAction) (interface{}, error) {
+		if cardMsg.Kind == VoiceSettingKind {
+			return CommonProcessVoiceSetting(cardMsg, cardAction, m)
+		}
+		return nil, ErrNextHandler
+	}
+}
+
+func CommonProcessVoiceSetting(cardMsg CardMsg, cardAction *larkcard.CardAction,
+	m MessageHandler) (interface{}, error) {
+	userId := cardAction.OpenID
+	_, err := m.voiceSettings.Update(userId, func(setting *services.VoiceSetting) {
+		switch cardMsg.Field {
+		case "enabled":
+			on := cardMsg.Value == "on"
+			setting.Enabled = &on
+		case "voice":
+			setting.Voice = cardAction.Action.Option
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("🎙️ Voice setting updated: user=%s, field=%s\n", userId, cardMsg.Field)
+	enabled, voice := m.voiceSettings.Resolve(userId, m.config)
+	return newVoiceSettingCard(enabled, voice, m.config.TTSVoiceOptions)
+}
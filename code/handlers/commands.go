@@ -0,0 +1,45 @@
+package handlers
+
+import "strings"
+
+// chainCommands 责任链中所有命令的写法，连续消息合并时据此跳过命令；新增命令请通过 newChainCommand 定义
+var chainCommands [][]string
+
+// newChainCommand 登记一个命令的所有写法，带参数的命令以空格结尾
+func newChainCommand(aliases ...string) []string {
+	chainCommands = append(chainCommands, aliases)
+	return aliases
+}
+
+var (
+	clearCommand    = newChainCommand("/clear", "清除")
+	systemCommand   = newChainCommand("/system ", "角色扮演 ")
+	helpCommand     = newChainCommand("/help", "帮助")
+	readCommand     = newChainCommand("/read ", "联网 ")
+	balanceCommand  = newChainCommand("/balance", "余额")
+	rolesCommand    = newChainCommand("/roles", "角色列表")
+	pictureCommand  = newChainCommand("/picture", "图片创作")
+	noBgCommand     = newChainCommand("/nobg", "抠图", "去背景", "去除背景")
+	settingsCommand = newChainCommand("/settings", "设置")
+	voiceCommand    = newChainCommand("/voice", "语音设置")
+	kbCommand       = newChainCommand("知识库")
+	rememberCommand = newChainCommand("/remember")
+	memoryCommand   = newChainCommand("/memory", "记忆")
+)
+
+// isChainCommand 消息是否为责任链中的命令：以 / 开头，或等于命令、以“命令 ”开头
+func isChainCommand(text string) bool {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "/") {
+		return true
+	}
+	for _, aliases := range chainCommands {
+		for _, alias := range aliases {
+			alias = strings.TrimSpace(alias)
+			if text == alias || strings.HasPrefix(text, alias+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import "testing"
+
+func TestIsChainCommand(t *testing.T) {
+	tests := []struct {
+		text string
+		want bool
+	}{
+		{"/clear", true},
+		{"/kb list", true},
+		{" 清除 ", true},
+		{"余额", true},
+		{"帮助", true},
+		{"知识库", true},
+		{"记忆", true},
+		{"去背景", true},
+		{"语音设置", true},
+		{"角色扮演 你是一个翻译", true},
+		{"联网 https://example.com", true},
+		{"清除一下缓存的原理是什么", false},
+		{"帮助我写一段代码", false},
+		{"今天天气怎么样", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := isChainCommand(tt.text); got != tt.want {
+				t.Errorf("isChainCommand(%q) = %t, want %t", tt.text, got, tt.want)
+			}
+		})
+	}
+}
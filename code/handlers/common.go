@@ -6,16 +6,38 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 )
 
+var mentionKeyRegex = regexp.MustCompile(`@_user_\d+|@_all`)
+
 // func sendCard
 func msgFilter(msg string) string {
-	//replace @到下一个非空的字段 为 ''
-	regex := regexp.MustCompile(`@[^ ]*`)
-	return regex.ReplaceAllString(msg, "")
+	//去掉未被替换的 @ 占位符，如 @_user_1、@_all
+	return mentionKeyRegex.ReplaceAllString(msg, "")
 
 }
-func parseContent(content string) string {
+
+// replaceMentions 把 @_user_N 占位符替换为可读的 @名字，@机器人 本身直接去掉
+func replaceMentions(msg string, mentions []*larkim.MentionEvent,
+	isBot func(mention *larkim.MentionEvent) bool) string {
+	return mentionKeyRegex.ReplaceAllStringFunc(msg, func(key string) string {
+		for _, mention := range mentions {
+			if mention == nil || mention.Key == nil || *mention.Key != key {
+				continue
+			}
+			if isBot(mention) || mention.Name == nil {
+				return ""
+			}
+			return "@" + *mention.Name
+		}
+		return ""
+	})
+}
+
+func parseContent(content string, mentions []*larkim.MentionEvent,
+	isBot func(mention *larkim.MentionEvent) bool) string {
 	//"{\"text\":\"@_user_1  hahaha\"}",
 	//only get text content hahaha
 	var contentMap map[string]interface{}
@@ -27,7 +49,7 @@ func parseContent(content string) string {
 		return ""
 	}
 	text := contentMap["text"].(string)
-	return msgFilter(text)
+	return msgFilter(replaceMentions(text, mentions, isBot))
 }
 func processMessage(msg interface{}) (string, error) {
 	msg = strings.TrimSpace(msg.(string))
@@ -92,6 +114,16 @@ func parseFileKey(content string) string {
 	return fileKey
 }
 
+func parseFileName(content string) string {
+	var contentMap map[string]interface{}
+	err := json.Unmarshal([]byte(content), &contentMap)
+	if err != nil {
+		return ""
+	}
+	fileName, _ := contentMap["file_name"].(string)
+	return fileName
+}
+
 func parseImageKey(content string) string {
 	var contentMap map[string]interface{}
 	err := json.Unmarshal([]byte(content), &contentMap)
@@ -105,3 +137,63 @@ func parseImageKey(content string) string {
 	imageKey := contentMap["image_key"].(string)
 	return imageKey
 }
+
+type postElement struct {
+	Tag      string `json:"tag"`
+	Text     string `json:"text"`
+	UserId   string `json:"user_id"`
+	ImageKey string `json:"image_key"`
+}
+
+type postContent struct {
+	Title   string          `json:"title"`
+	Content [][]postElement `json:"content"`
+}
+
+// parsePostContent 解析富文本消息，返回文字内容与按顺序出现的图片
+//
+//	{"title":"","content":[[{"tag":"img","image_key":"img_xxx"}],[{"tag":"text","text":"换成星空背景"}]]}
+func parsePostContent(content string, mentions []*larkim.MentionEvent,
+	isBot func(mention *larkim.MentionEvent) bool) (string, []string) {
+	var post postContent
+	if err := json.Unmarshal([]byte(content), &post); err != nil {
+		fmt.Println(err)
+		return "", nil
+	}
+	if post.Content == nil {
+		// 部分事件会按语言包一层，如 {"zh_cn":{"title":"","content":[...]}}
+		var localized map[string]postContent
+		if err := json.Unmarshal([]byte(content), &localized); err == nil {
+			for _, p := range localized {
+				post = p
+				break
+			}
+		}
+	}
+
+	var lines []string
+	var imageKeys []string
+	if post.Title != "" {
+		lines = append(lines, post.Title)
+	}
+	for _, paragraph := range post.Content {
+		var line strings.Builder
+		for _, element := range paragraph {
+			switch element.Tag {
+			case "text", "a", "code_block":
+				line.WriteString(element.Text)
+			case "at":
+				line.WriteString(element.UserId)
+			case "img":
+				if element.ImageKey != "" {
+					imageKeys = append(imageKeys, element.ImageKey)
+				}
+			}
+		}
+		if text := strings.TrimSpace(line.String()); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	text := strings.Join(lines, "\n")
+	return strings.TrimSpace(msgFilter(replaceMentions(text, mentions, isBot))), imageKeys
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"testing"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+func newMention(key, openId, name string) *larkim.MentionEvent {
+	return &larkim.MentionEvent{
+		Key:  &key,
+		Id:   &larkim.UserId{OpenId: &openId},
+		Name: &name,
+	}
+}
+
+func TestParseContent(t *testing.T) {
+	mentions := []*larkim.MentionEvent{
+		newMention("@_user_1", "ou_bot", "chatGpt"),
+		newMention("@_user_2", "ou_alice", "Alice"),
+		newMention("@_user_10", "ou_bob", "Bob"),
+	}
+	isBot := func(m *larkim.MentionEvent) bool { return *m.Id.OpenId == "ou_bot" }
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "bot mention removed",
+			content: `{"text":"@_user_1 hello"}`,
+			want:    " hello",
+		},
+		{
+			name:    "other mentions kept as names",
+			content: `{"text":"@_user_1 please ask @_user_2 and @_user_10"}`,
+			want:    " please ask @Alice and @Bob",
+		},
+		{
+			name:    "unknown placeholders and @all stripped",
+			content: `{"text":"@_all @_user_3 hi"}`,
+			want:    "  hi",
+		},
+		{
+			name:    "plain @ text untouched",
+			content: `{"text":"mail me at a@example.com"}`,
+			want:    "mail me at a@example.com",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseContent(tt.content, mentions, isBot); got != tt.want {
+				t.Errorf("parseContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePostContent(t *testing.T) {
+	mentions := []*larkim.MentionEvent{
+		newMention("@_user_1", "ou_bot", "chatGpt"),
+	}
+	isBot := func(m *larkim.MentionEvent) bool { return *m.Id.OpenId == "ou_bot" }
+	tests := []struct {
+		name       string
+		content    string
+		wantText   string
+		wantImages []string
+	}{
+		{
+			name:       "image with caption",
+			content:    `{"title":"","content":[[{"tag":"img","image_key":"img_1"}],[{"tag":"at","user_id":"@_user_1"},{"tag":"text","text":" 换成星空背景"}]]}`,
+			wantText:   "换成星空背景",
+			wantImages: []string{"img_1"},
+		},
+		{
+			name:       "image and mask",
+			content:    `{"title":"","content":[[{"tag":"img","image_key":"img_1"},{"tag":"img","image_key":"img_2"}],[{"tag":"text","text":"加一顶帽子"}]]}`,
+			wantText:   "加一顶帽子",
+			wantImages: []string{"img_1", "img_2"},
+		},
+		{
+			name:     "title and multiple lines",
+			content:  `{"title":"周报","content":[[{"tag":"text","text":"第一行"}],[{"tag":"a","text":"链接","href":"https://example.com"}]]}`,
+			wantText: "周报\n第一行\n链接",
+		},
+		{
+			name:       "localized wrapper",
+			content:    `{"zh_cn":{"title":"","content":[[{"tag":"img","image_key":"img_1"},{"tag":"text","text":"抠图"}]]}}`,
+			wantText:   "抠图",
+			wantImages: []string{"img_1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, images := parsePostContent(tt.content, mentions, isBot)
+			if text != tt.wantText {
+				t.Errorf("parsePostContent() text = %q, want %q", text, tt.wantText)
+			}
+			if len(images) != len(tt.wantImages) {
+				t.Fatalf("parsePostContent() images = %v, want %v", images, tt.wantImages)
+			}
+			for i := range images {
+				if images[i] != tt.wantImages[i] {
+					t.Errorf("parsePostContent() images = %v, want %v", images, tt.wantImages)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"start-feishubot/services/accesscontrol"
+	"strings"
+)
+
+type AccessAction struct { /*访问控制*/
+}
+
+func (*AccessAction) Execute(a *ActionInfo) bool {
+	var chatId string
+	if a.info.chatId != nil {
+		chatId = *a.info.chatId
+	}
+	subject := accesscontrol.Subject{
+		OpenId:  a.info.openId,
+		UnionId: a.info.unionId,
+		ChatId:  chatId,
+	}
+	decision := a.handler.access.Check(subject)
+	a.info.isAdmin = decision.Admin
+	fmt.Printf("    🔐 AccessAction: openId=%s, unionId=%s, chatId=%s, allowed=%t, admin=%t, reason=%s\n",
+		subject.OpenId, subject.UnionId, subject.ChatId, decision.Allowed, decision.Admin, decision.Reason)
+
+	if !decision.Allowed {
+		sendAccessDeniedCard(*a.ctx, a.info.msgId,
+			"抱歉，你暂时没有使用机器人的权限～")
+		return false
+	}
+
+	if a.handler.access.HasAdmins() && !decision.Admin &&
+		isAdminOnlyCommand(a.info.qParsed, a.handler.config.AdminOnlyCommands) {
+		fmt.Printf("    🔐 AccessAction: admin-only command denied for %s\n", subject.OpenId)
+		sendAccessDeniedCard(*a.ctx, a.info.msgId,
+			"抱歉，该命令仅管理员可用～")
+		return false
+	}
+	return true
+}
+
+// isAdminOnlyCommand 判断消息是否为仅管理员可用的命令
+func isAdminOnlyCommand(msg string, commands []string) bool {
+	msg = strings.TrimSpace(msg)
+	for _, cmd := range commands {
+		cmd = strings.TrimSpace(cmd)
+		if cmd == "" {
+			continue
+		}
+		if msg == cmd || strings.HasPrefix(msg, cmd+" ") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"start-feishubot/utils"
+	"strings"
+)
+
+const (
+	// answerFileName 长回答作为附件发送时的文件名
+	answerFileName = "answer.md"
+	// answerPreviewBytes 长回答改为附件发送时，卡片中预览的正文字节数
+	answerPreviewBytes = 2000
+)
+
+// deliverAnswer 发送回答卡片，过长的回答按段落和代码块拆成多张卡片；
+// 超过 ANSWER_FILE_THRESHOLD_BYTES 或飞书提示卡片超限时，改为发送预览卡片并附上完整的 Markdown 文件。
+// send 用于发送第一张卡片，联网回答用它替换检索过程卡片，其余卡片回复在提问下
+func (a *ActionInfo) deliverAnswer(answer string, layout answerLayout, send func(card string) error) error {
+	config := a.handler.config
+	if threshold := config.AnswerFileThresholdBytes; threshold > 0 && len(answer) > threshold {
+		fmt.Printf("    📎 Answer has %d bytes, sending as a file\n", len(answer))
+		return a.deliverAnswerFile(answer, answer, layout, send)
+	}
+	parts := utils.SplitMarkdown(answer, config.AnswerCardMaxBytes)
+	if len(parts) > 1 {
+		fmt.Printf("    ✂️ Answer has %d bytes, splitting into %d cards\n", len(answer), len(parts))
+	}
+	for i, part := range parts {
+		sendCard := send
+		if i > 0 {
+			sendCard = a.replyAnswerCard
+		}
+		err := sendCard(newAnswerCard(part, layout, i, len(parts)))
+		if err == nil {
+			continue
+		}
+		if !isCardTooLarge(err) {
+			return err
+		}
+		fmt.Printf("    ⚠️ Answer card %d/%d is too large, falling back to a file: %v\n", i+1, len(parts), err)
+		return a.deliverAnswerFile(answer, strings.Join(parts[i:], "\n\n"), layout, sendCard)
+	}
+	return nil
+}
+
+// deliverAnswerFile 发送 rest 开头部分的预览卡片，并把完整回答作为 Markdown 文件回复在提问下
+func (a *ActionInfo) deliverAnswerFile(answer string, rest string, layout answerLayout,
+	send func(card string) error) error {
+	preview := utils.SplitMarkdown(rest, answerPreviewBytes)[0]
+	if len(preview) < len(strings.TrimSpace(rest)) {
+		preview += "\n\n……"
+	}
+	layout.fileName = answerFileName
+	cardErr := send(newAnswerCard(preview, layout, 0, 1))
+	if cardErr != nil {
+		fmt.Printf("    ⚠️ Send answer preview card failed: %v\n", cardErr)
+	}
+	fileKey, err := uploadFile(answerFileName, []byte(answer))
+	if err == nil {
+		err = replyFile(*a.ctx, *fileKey, a.info.msgId)
+	}
+	if err != nil {
+		fmt.Printf("    ❌ Send answer file failed: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// replyAnswerCard 把卡片回复在当前提问下
+func (a *ActionInfo) replyAnswerCard(card string) error {
+	return replyCard(*a.ctx, a.info.msgId, card)
+}
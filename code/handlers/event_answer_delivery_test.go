@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestIsCardTooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"content too long code", &larkAPIError{op: "reply card", Code: 230025, Msg: "message content too long"}, true},
+		{"wrapped", fmt.Errorf("send answer: %w", &larkAPIError{Code: 230025}), true},
+		{"size message", &larkAPIError{Code: 11310, Msg: "card content exceeds the limit"}, true},
+		{"rate limited", &larkAPIError{Code: 230020, Msg: "request trigger frequency limit"}, false},
+		{"network error", errors.New("connection reset by peer"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isCardTooLarge(tt.err); got != tt.want {
+				t.Errorf("isCardTooLarge() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,23 +1,26 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"fmt"
-	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
-	"os"
+	"io"
 	"start-feishubot/initialization"
 	"start-feishubot/utils/audio"
+	"strings"
+	"sync"
+	"time"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 )
 
+// 在每段末尾这么长的范围内寻找静音切分点
+const audioSilenceSearchWindow = 15 * time.Second
+
 type AudioAction struct { /*语音*/
 }
 
 func (*AudioAction) Execute(a *ActionInfo) bool {
-	// 只有私聊才解析语音,其他不解析
-	if a.info.handlerType != UserHandler {
-		return true
-	}
-
 	//判断是否是语音
 	if a.info.msgType == "audio" {
 		fileKey := a.info.fileKey
@@ -32,22 +35,11 @@ func (*AudioAction) Execute(a *ActionInfo) bool {
 			fmt.Println(err)
 			return true
 		}
-		f := fmt.Sprintf("%s.ogg", fileKey)
-		resp.WriteFile(f)
-		defer os.Remove(f)
-
-		//fmt.Println("f: ", f)
-		output := fmt.Sprintf("%s.mp3", fileKey)
-		// 等待转换完成
-		audio.OggToWavByPath(f, output)
-		defer os.Remove(output)
-		//fmt.Println("output: ", output)
-
-		text, err := a.handler.gpt.AudioToText(output)
+		text, err := a.transcribeOgg(resp.File)
 		if err != nil {
 			fmt.Println(err)
 
-			sendMsg(*a.ctx, fmt.Sprintf("🤖️：语音转换失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+			sendMsg(*a.ctx, fmt.Sprintf("🤖️：语音转换失败，请稍后再试～\n错误信息: %v", err), a.info.chatId)
 			return false
 		}
 
@@ -60,3 +52,53 @@ func (*AudioAction) Execute(a *ActionInfo) bool {
 	return true
 
 }
+
+// transcribeOgg 解码语音，超长时按静音切分后并发转写，再按顺序拼接
+func (a *ActionInfo) transcribeOgg(input io.Reader) (string, error) {
+	pcm, err := audio.DecodeOggOpus(input)
+	if err != nil {
+		return "", err
+	}
+
+	maxChunk := time.Duration(a.handler.config.AudioChunkSec) * time.Second
+	chunks := audio.SplitOnSilence(*pcm, maxChunk, audioSilenceSearchWindow)
+	fmt.Printf("    🎧 Audio %v split into %d chunk(s)\n", pcm.Duration(), len(chunks))
+
+	concurrency := a.handler.config.AudioMaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	texts := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range chunks {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			texts[index], errs[index] = a.transcribeChunk(chunks[index])
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return "", fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n")), nil
+}
+
+func (a *ActionInfo) transcribeChunk(chunk audio.PCM) (string, error) {
+	var wav bytes.Buffer
+	if err := audio.WriteWav(&wav, chunk); err != nil {
+		return "", err
+	}
+	text, err := a.handler.gpt.AudioBytesToText(wav.Bytes(), "audio.wav")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(text), nil
+}
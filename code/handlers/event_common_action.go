@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"start-feishubot/initialization"
+	"start-feishubot/services"
 	"start-feishubot/services/openai"
 	"start-feishubot/utils"
+	"strings"
 
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 )
@@ -17,14 +20,85 @@ type MsgInfo struct {
 	chatId      *string
 	qParsed     string
 	fileKey     string
+	fileName    string
 	imageKey    string
+	imageKeys   []string // 富文本消息中的图片
 	sessionId   *string
 	mention     []*larkim.MentionEvent
+	openId      string
+	unionId     string
+	isAdmin     bool
+	coalesced   bool // 已合并过连续消息，不再参与合并
 }
 type ActionInfo struct {
-	handler *MessageHandler
-	ctx     *context.Context
-	info    *MsgInfo
+	handler  *MessageHandler
+	ctx      *context.Context
+	info     *MsgInfo
+	settings services.ResolvedChatSetting
+	search   searchTrigger
+	pending  []Action // 责任链中当前及之后的 action，用于延迟后继续执行
+}
+
+// completions 按当前群设置的模型请求，maxTokens<=0 时使用默认值
+func (a *ActionInfo) completions(msgs []openai.Messages, maxTokens int) (openai.Messages, error) {
+	return a.handler.gpt.CompletionsWithModel(msgs, a.settings.Model, maxTokens)
+}
+
+// answerCompletions 用于生成最终回答，会附加群设置中的回复风格要求；
+// 开启长期记忆时模型可以调用工具保存记忆，保存的内容附在回答末尾告知用户
+func (a *ActionInfo) answerCompletions(msgs []openai.Messages, maxTokens int) (openai.Messages, error) {
+	if style := replyStyleInstruction(a.settings.ReplyStyle); style != "" {
+		msgs = append(append([]openai.Messages{}, msgs...),
+			openai.Messages{Role: "system", Content: style})
+	}
+	if !a.memoryToolEnabled() {
+		return a.completions(msgs, maxTokens)
+	}
+	var saved []string
+	resp, err := a.handler.gpt.CompletionsWithTools(msgs, a.settings.Model, maxTokens,
+		[]openai.Tool{saveMemoryTool}, func(call openai.ToolCall) string {
+			result, fact := a.runMemoryTool(call)
+			if fact != "" {
+				saved = append(saved, fact)
+			}
+			return result
+		})
+	if err == nil && len(saved) > 0 {
+		resp.Content += "\n\n🧠 已记住：" + strings.Join(saved, "；") + "（发送 /memory 查看或删除）"
+	}
+	return resp, err
+}
+
+// sessionHistory 获取话题上下文，新话题会带上群设置的默认角色和用户的长期记忆
+func (a *ActionInfo) sessionHistory() []openai.Messages {
+	history := a.handler.sessionCache.GetMsg(*a.info.sessionId)
+	if len(history) > 0 {
+		return history
+	}
+	if a.settings.DefaultRole != "" {
+		content, err := initialization.GetFirstRoleContentByTitle(a.settings.DefaultRole)
+		if err != nil {
+			fmt.Printf("⚠️ Default role %s not found: %v\n", a.settings.DefaultRole, err)
+		} else {
+			history = append(history, openai.Messages{Role: "system", Content: content})
+		}
+	}
+	// 新话题带上用户的长期记忆，随会话保存，之后的轮次不再重复注入
+	if memory, ok := a.memoryMessage(); ok {
+		history = append(history, memory)
+	}
+	return history
+}
+
+func replyStyleInstruction(style services.ReplyStyle) string {
+	switch style {
+	case services.ReplyStyleConcise:
+		return "请用简洁的语言回答，只保留要点。"
+	case services.ReplyStyleDetailed:
+		return "请尽量详细地回答，给出步骤、示例和必要的解释。"
+	default:
+		return ""
+	}
 }
 
 type Action interface {
@@ -57,13 +131,24 @@ func (*ProcessMentionAction) Execute(a *ActionInfo) bool {
 	// 群聊判断是否提到机器人
 	if a.info.handlerType == GroupHandler {
 		fmt.Printf("    👥 Group chat, checking mentions: %d mentions\n", len(a.info.mention))
-		mentioned := a.handler.judgeIfMentionMe(a.info.mention)
-		if mentioned {
+		if a.handler.judgeIfMentionMe(a.info.mention) {
 			fmt.Printf("    ✅ Bot mentioned, proceeding\n")
-		} else {
-			fmt.Printf("    ❌ Bot not mentioned, skipping\n")
+			return true
+		}
+		// 语音和文件消息无法 @机器人，回复在机器人参与的话题中即视为提问
+		if (a.info.msgType == "audio" || a.info.msgType == "media" ||
+			a.info.msgType == "file") &&
+			a.handler.judgeIfInBotThread(a.info.sessionId, a.info.msgId) {
+			fmt.Printf("    ✅ Voice or file reply in bot thread, proceeding\n")
+			return true
 		}
-		return mentioned
+		if a.handler.config.GroupThreadContinue &&
+			a.handler.judgeIfInBotThread(a.info.sessionId, a.info.msgId) {
+			fmt.Printf("    ✅ Reply in bot thread, proceeding\n")
+			return true
+		}
+		fmt.Printf("    ❌ Bot not mentioned, skipping\n")
+		return false
 	}
 
 	fmt.Printf("    ❌ Unknown handler type, skipping\n")
@@ -75,6 +160,10 @@ type EmptyAction struct { /*空消息*/
 
 func (*EmptyAction) Execute(a *ActionInfo) bool {
 	fmt.Printf("    🔍 EmptyAction: qParsed='%s' (length=%d)\n", a.info.qParsed, len(a.info.qParsed))
+	// 图片消息没有文字内容，交给图片处理
+	if a.info.msgType == "image" || len(a.info.imageKeys) > 0 {
+		return true
+	}
 	if len(a.info.qParsed) == 0 {
 		fmt.Printf("    ❌ Empty message, sending default response\n")
 		sendMsg(*a.ctx, "🤖️：你想知道什么呢~", a.info.chatId)
@@ -90,7 +179,7 @@ type ClearAction struct { /*清除消息*/
 
 func (*ClearAction) Execute(a *ActionInfo) bool {
 	if _, foundClear := utils.EitherTrimEqual(a.info.qParsed,
-		"/clear", "清除"); foundClear {
+		clearCommand...); foundClear {
 		sendClearCacheCheckCard(*a.ctx, a.info.sessionId,
 			a.info.msgId)
 		return false
@@ -103,7 +192,7 @@ type RolePlayAction struct { /*角色扮演*/
 
 func (*RolePlayAction) Execute(a *ActionInfo) bool {
 	if system, foundSystem := utils.EitherCutPrefix(a.info.qParsed,
-		"/system ", "角色扮演 "); foundSystem {
+		systemCommand...); foundSystem {
 		a.handler.sessionCache.Clear(*a.info.sessionId)
 		systemMsg := append([]openai.Messages{}, openai.Messages{
 			Role: "system", Content: system,
@@ -120,8 +209,8 @@ type HelpAction struct { /*帮助*/
 }
 
 func (*HelpAction) Execute(a *ActionInfo) bool {
-	if _, foundHelp := utils.EitherTrimEqual(a.info.qParsed, "/help",
-		"帮助"); foundHelp {
+	if _, foundHelp := utils.EitherTrimEqual(a.info.qParsed,
+		helpCommand...); foundHelp {
 		sendHelpCard(*a.ctx, a.info.sessionId, a.info.msgId)
 		return false
 	}
@@ -132,23 +221,28 @@ type WebBrowseAction struct { /*联网读取*/
 }
 
 func (*WebBrowseAction) Execute(a *ActionInfo) bool {
-	if url, ok := utils.EitherCutPrefix(a.info.qParsed, "/read ", "联网 "); ok {
+	if url, ok := utils.EitherCutPrefix(a.info.qParsed, readCommand...); ok {
 		content, err := utils.FetchURLAsPlainText(url)
 		if err != nil {
+			var policyErr *utils.FetchPolicyError
+			if errors.As(err, &policyErr) {
+				replyMsg(*a.ctx, "🤖️：无法读取该链接，"+policyErr.Reason, a.info.msgId)
+				return false
+			}
 			replyMsg(*a.ctx, fmt.Sprintf("读取失败：%v", err), a.info.msgId)
 			return false
 		}
 
-		msgs := a.handler.sessionCache.GetMsg(*a.info.sessionId)
+		msgs := a.sessionHistory()
 		msgs = append(msgs, openai.Messages{Role: "system", Content: "以下是联网获取的参考资料：\n" + content})
 		msgs = append(msgs, openai.Messages{Role: "user", Content: "请基于上述资料回答。"})
-		completion, err := a.handler.gpt.Completions(msgs)
+		completion, err := a.answerCompletions(msgs, 0)
 		if err != nil {
 			replyMsg(*a.ctx, fmt.Sprintf("🤖️：联网回答失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
 			return false
 		}
 		a.handler.sessionCache.SetMsg(*a.info.sessionId, append(msgs, completion))
-		if err := replyMsg(*a.ctx, completion.Content, a.info.msgId); err != nil {
+		if err := a.replyAnswer(completion.Content, false, completion.Truncated()); err != nil {
 			replyMsg(*a.ctx, fmt.Sprintf("🤖️：发送消息失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
 		}
 		return false
@@ -156,23 +250,26 @@ func (*WebBrowseAction) Execute(a *ActionInfo) bool {
 	return true
 }
 
-// AutoSearchAction: if enabled in config, always search web and answer with context
+// AutoSearchAction 处理 /search、/nosearch 指令，并按联网模式和关键词决定本条消息是否联网
 type AutoSearchAction struct{}
 
 func (*AutoSearchAction) Execute(a *ActionInfo) bool {
-	fmt.Printf("[AutoSearchAction] Checking for auto search: %s\n", a.info.qParsed)
-	// only for text messages
-	if a.info.msgType != "text" {
-		fmt.Printf("[AutoSearchAction] Not text message, skipping\n")
-		return true
-	}
-	// respect config: only run when explicitly enabled
-	if !a.handler.config.SearchAlways {
-		fmt.Printf("[AutoSearchAction] SearchAlways disabled, skipping\n")
-		return true
+	if a.info.msgType == "text" {
+		if question, trigger, ok := parseSearchCommand(a.info.qParsed); ok {
+			if question == "" {
+				replyMsg(*a.ctx, "🤖️：请在指令后输入问题，例如 /search 今天的科技新闻", a.info.msgId)
+				return false
+			}
+			fmt.Printf("[AutoSearchAction] Search command, trigger=%d: %s\n", trigger, question)
+			a.info.qParsed = question
+			a.search = trigger
+			return true
+		}
 	}
-	fmt.Printf("[AutoSearchAction] SearchAlways enabled, but forcing skip to use two-stage flow\n")
-	return true // Force skip to use MessageAction's two-stage flow
+	a.search = resolveSearchTrigger(a.settings.SearchMode, a.handler.config.SearchOnlyOnKeywords,
+		a.handler.config.SearchKeywords, a.info.qParsed)
+	fmt.Printf("[AutoSearchAction] mode=%s, trigger=%d\n", a.settings.SearchMode, a.search)
+	return true
 }
 
 type BalanceAction struct { /*余额*/
@@ -180,7 +277,7 @@ type BalanceAction struct { /*余额*/
 
 func (*BalanceAction) Execute(a *ActionInfo) bool {
 	if _, foundBalance := utils.EitherTrimEqual(a.info.qParsed,
-		"/balance", "余额"); foundBalance {
+		balanceCommand...); foundBalance {
 		balanceResp, err := a.handler.gpt.GetBalance()
 		if err != nil {
 			replyMsg(*a.ctx, "查询余额失败，请稍后再试", a.info.msgId)
@@ -197,7 +294,7 @@ type RoleListAction struct { /*角色列表*/
 
 func (*RoleListAction) Execute(a *ActionInfo) bool {
 	if _, foundSystem := utils.EitherTrimEqual(a.info.qParsed,
-		"/roles", "角色列表"); foundSystem {
+		rolesCommand...); foundSystem {
 		//a.handler.sessionCache.Clear(*a.info.sessionId)
 		//systemMsg := append([]openai.Messages{}, openai.Messages{
 		//	Role: "system", Content: system,
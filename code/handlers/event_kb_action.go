@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"start-feishubot/initialization"
+	"start-feishubot/services/kb"
+	"start-feishubot/services/larkdocs"
+	"start-feishubot/utils"
+	"strings"
+	"sync"
+	"time"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+	"github.com/patrickmn/go-cache"
+)
+
+// kbUploadWindow /kb add 之后等待上传文件的时间
+const kbUploadWindow = 5 * time.Minute
+
+// 等待上传的知识库文件：群聊按话题记录，单聊按用户记录
+var kbPendingUploads = cache.New(kbUploadWindow, 10*time.Minute)
+
+// larkDocsSyncMu 定时同步和 /kb reload 不同时拉取飞书文档
+var larkDocsSyncMu sync.Mutex
+
+// newKnowledgeBase 开启知识库或配置了飞书文档时加载已导入的文档，
+// 并在后台同步 KB_DIR 目录、定时同步飞书知识空间
+func newKnowledgeBase(config initialization.Config, embedder utils.Embedder) *kb.Base {
+	if !config.KbEnabled && !larkDocsEnabled(config) {
+		return nil
+	}
+	opts := kb.Options{Embedder: embedder}
+	if larkDocsEnabled(config) {
+		allow := larkdocs.ParseAllowlist(config.LarkDocsChatSpaces)
+		if len(allow) == 0 {
+			fmt.Println("⚠️ LARK_DOCS_CHAT_SPACES is empty, synced Lark docs are not searchable in any chat")
+		}
+		opts.SpaceAllowed = allow.Allowed
+	}
+	path := filepath.Join(config.DataDir, "knowledge_base.json")
+	base, err := kb.Open(path, opts)
+	if err != nil {
+		fmt.Printf("⚠️ Failed to load knowledge base from %s: %v\n", path, err)
+		return nil
+	}
+	if config.KbDir != "" {
+		go syncKnowledgeDir(base, config.KbDir)
+	}
+	if larkDocsEnabled(config) {
+		go scheduleLarkDocsSync(base, config)
+	} else if removed, err := larkdocs.RemoveAll(base); err != nil {
+		fmt.Printf("⚠️ [KB] Remove lark docs failed: %v\n", err)
+	} else if removed > 0 {
+		fmt.Printf("📚 [KB] Lark docs are no longer configured, removed %d synced docs\n", removed)
+	}
+	return base
+}
+
+func larkDocsEnabled(config initialization.Config) bool {
+	return len(config.LarkDocsSpaces) > 0 || len(config.LarkDocsTokens) > 0
+}
+
+// scheduleLarkDocsSync 启动时同步一次，之后每 LARK_DOCS_SYNC_MIN 分钟同步有更新的文档
+func scheduleLarkDocsSync(base *kb.Base, config initialization.Config) {
+	interval := time.Duration(config.LarkDocsSyncMin) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	syncLarkDocs(base, config)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		syncLarkDocs(base, config)
+	}
+}
+
+func syncLarkDocs(base *kb.Base, config initialization.Config) (int, int, error) {
+	larkDocsSyncMu.Lock()
+	defer larkDocsSyncMu.Unlock()
+	conn := larkdocs.NewConnector(initialization.GetLarkClient(), config.LarkDocsBaseUrl)
+	added, removed, err := larkdocs.Sync(context.Background(), conn, base,
+		config.LarkDocsSpaces, config.LarkDocsTokens)
+	if err != nil {
+		fmt.Printf("⚠️ [KB] Lark docs sync incomplete: %v\n", err)
+	}
+	fmt.Printf("📚 [KB] Synced lark docs: %d imported, %d removed\n", added, removed)
+	return added, removed, err
+}
+
+func syncKnowledgeDir(base *kb.Base, dir string) (int, int, error) {
+	added, removed, err := base.SyncDir(context.Background(), dir)
+	if err != nil {
+		fmt.Printf("⚠️ [KB] Sync %s failed: %v\n", dir, err)
+		return added, removed, err
+	}
+	fmt.Printf("📚 [KB] Synced %s: %d imported, %d removed\n", dir, added, removed)
+	return added, removed, nil
+}
+
+func kbSessionKey(sessionId string) string {
+	return "session:" + sessionId
+}
+
+func kbUserKey(chatId, openId string) string {
+	return "user:" + chatId + ":" + openId
+}
+
+// hasPendingKnowledgeUpload 话题中是否有等待上传的知识库文件
+func hasPendingKnowledgeUpload(sessionId string) bool {
+	_, ok := kbPendingUploads.Get(kbSessionKey(sessionId))
+	return ok
+}
+
+type KnowledgeBaseAction struct { /*知识库*/
+}
+
+func (*KnowledgeBaseAction) Execute(a *ActionInfo) bool {
+	if a.handler.knowledge == nil {
+		return true
+	}
+	if a.info.msgType == "file" {
+		return a.receiveKnowledgeFile()
+	}
+	if _, ok := utils.EitherTrimEqual(a.info.qParsed, kbCommand...); ok {
+		a.replyKnowledgeList()
+		return false
+	}
+	fields := strings.Fields(a.info.qParsed)
+	if len(fields) == 0 || !strings.EqualFold(fields[0], "/kb") {
+		return true
+	}
+	sub := "list"
+	if len(fields) > 1 {
+		sub = strings.ToLower(fields[1])
+	}
+	arg := ""
+	if len(fields) > 2 {
+		arg = strings.Join(fields[2:], " ")
+	}
+	chatType := UserChatType
+	if a.info.handlerType == GroupHandler {
+		chatType = GroupChatType
+	}
+	chatId := *a.info.chatId
+
+	switch sub {
+	case "list", "ls":
+		a.replyKnowledgeList()
+	case "add":
+		if !a.handler.canManageChat(chatType, chatId, a.info.openId) {
+			sendAccessDeniedCard(*a.ctx, a.info.msgId, "抱歉，只有群主和机器人管理员可以修改本群的知识库～")
+			return false
+		}
+		kbPendingUploads.Set(kbSessionKey(*a.info.sessionId), a.info.openId, cache.DefaultExpiration)
+		kbPendingUploads.Set(kbUserKey(chatId, a.info.openId), a.info.openId, cache.DefaultExpiration)
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：请在 %d 分钟内回复本消息发送文件（支持 md/txt/pdf，不超过 %dMB），文件只在本群的知识库中可见",
+			int(kbUploadWindow.Minutes()), a.handler.config.KbMaxFileMB), a.info.msgId)
+	case "remove", "rm", "delete":
+		if arg == "" {
+			replyMsg(*a.ctx, "🤖️：请输入要删除的文档名称，例如 /kb remove 值班手册.md", a.info.msgId)
+			return false
+		}
+		if !a.handler.canManageChat(chatType, chatId, a.info.openId) {
+			sendAccessDeniedCard(*a.ctx, a.info.msgId, "抱歉，只有群主和机器人管理员可以修改本群的知识库～")
+			return false
+		}
+		doc, err := a.handler.knowledge.Remove(chatId, arg)
+		if errors.Is(err, kb.ErrNotFound) {
+			replyMsg(*a.ctx, "🤖️：本群知识库中没有这个文档，目录导入的公共文档需要在 KB_DIR 中删除", a.info.msgId)
+			return false
+		}
+		if err != nil {
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：删除失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+			return false
+		}
+		fmt.Printf("📚 [KB] Removed %s from chat %s by %s\n", doc.ID, chatId, a.info.openId)
+		replyMsg(*a.ctx, "🤖️：已从知识库删除 "+doc.Name, a.info.msgId)
+	case "reload":
+		if a.handler.access.HasAdmins() && !a.info.isAdmin {
+			sendAccessDeniedCard(*a.ctx, a.info.msgId, "抱歉，只有机器人管理员可以重新导入知识库目录～")
+			return false
+		}
+		config := a.handler.config
+		if config.KbDir == "" && !larkDocsEnabled(config) {
+			replyMsg(*a.ctx, "🤖️：未配置知识库目录 KB_DIR 或飞书知识空间 LARK_DOCS_SPACES", a.info.msgId)
+			return false
+		}
+		var lines []string
+		if config.KbDir != "" {
+			added, removed, err := syncKnowledgeDir(a.handler.knowledge, config.KbDir)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("知识库目录导入失败: %v", err))
+			} else {
+				lines = append(lines, fmt.Sprintf("知识库目录已同步，导入 %d 个文档，移除 %d 个文档", added, removed))
+			}
+		}
+		if larkDocsEnabled(config) {
+			added, removed, err := syncLarkDocs(a.handler.knowledge, config)
+			if err != nil {
+				lines = append(lines, fmt.Sprintf("飞书文档同步未完成（导入 %d 个文档）: %v", added, err))
+			} else {
+				lines = append(lines, fmt.Sprintf("飞书文档已同步，导入 %d 个文档，移除 %d 个文档", added, removed))
+			}
+		}
+		replyMsg(*a.ctx, "🤖️："+strings.Join(lines, "\n"), a.info.msgId)
+	default:
+		replyMsg(*a.ctx, "🤖️：知识库指令：/kb list 查看文档，/kb add 上传文件，/kb remove 名称 删除文档，/kb reload 重新导入目录", a.info.msgId)
+	}
+	return false
+}
+
+// receiveKnowledgeFile 处理 /kb add 之后上传的文件，没有等待上传时交给后续 Action
+func (a *ActionInfo) receiveKnowledgeFile() bool {
+	chatId := *a.info.chatId
+	uploader, ok := kbPendingUploads.Get(kbSessionKey(*a.info.sessionId))
+	if !ok {
+		uploader, ok = kbPendingUploads.Get(kbUserKey(chatId, a.info.openId))
+	}
+	if !ok || uploader.(string) != a.info.openId {
+		return true
+	}
+	name := a.info.fileName
+	if !kb.SupportedFile(name) {
+		replyMsg(*a.ctx, "🤖️：知识库只支持 md/txt/pdf 文件～", a.info.msgId)
+		return false
+	}
+
+	req := larkim.NewGetMessageResourceReqBuilder().MessageId(
+		*a.info.msgId).FileKey(a.info.fileKey).Type("file").Build()
+	resp, err := initialization.GetLarkClient().Im.MessageResource.Get(context.Background(), req)
+	if err == nil && !resp.Success() {
+		err = fmt.Errorf("%d %s", resp.Code, resp.Msg)
+	}
+	if err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：文件下载失败～\n错误信息: %v", err), a.info.msgId)
+		return false
+	}
+	maxBytes := int64(a.handler.config.KbMaxFileMB) * 1024 * 1024
+	data, err := io.ReadAll(io.LimitReader(resp.File, maxBytes+1))
+	if err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：文件下载失败～\n错误信息: %v", err), a.info.msgId)
+		return false
+	}
+	if int64(len(data)) > maxBytes {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：文件超过 %dMB，无法加入知识库", a.handler.config.KbMaxFileMB), a.info.msgId)
+		return false
+	}
+
+	doc, err := a.handler.knowledge.AddFile(context.Background(), kb.Document{
+		ID:      kb.UploadID(chatId, name),
+		Name:    name,
+		Scope:   chatId,
+		Source:  kb.SourceUpload,
+		AddedBy: a.info.openId,
+	}, data)
+	if err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：文件解析失败，无法加入知识库～\n错误信息: %v", err), a.info.msgId)
+		return false
+	}
+	fmt.Printf("📚 [KB] Added %s to chat %s: %d chunks\n", doc.ID, chatId, len(doc.Chunks))
+	replyMsg(*a.ctx, fmt.Sprintf("🤖️：已加入本群知识库：%s（%d 个片段）", doc.Name, len(doc.Chunks)), a.info.msgId)
+	return false
+}
+
+func (a *ActionInfo) replyKnowledgeList() {
+	docs := a.handler.knowledge.List(*a.info.chatId)
+	var lines []string
+	for _, doc := range docs {
+		scope, name := "公共", doc.Name
+		if doc.Scope != "" {
+			scope = "本群"
+		}
+		if doc.Source == kb.SourceLark {
+			scope = "飞书文档"
+			if doc.URL != "" {
+				name = fmt.Sprintf("[%s](%s)", doc.Name, doc.URL)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("- **%s** · %s · %d 个片段 · %s",
+			name, scope, len(doc.Chunks), doc.AddedAt.Format("2006-01-02")))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "知识库中还没有文档")
+	}
+	card, err := newSendCard(
+		withHeader("📚 知识库", larkcard.TemplateBlue),
+		withMainMd(strings.Join(lines, "\n")),
+		withNote("/kb add 上传文件 · /kb remove 名称 删除本群文档"))
+	if err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：知识库卡片生成失败～\n错误信息: %v", err), a.info.msgId)
+		return
+	}
+	replyCard(*a.ctx, a.info.msgId, card)
+}
+
+// knowledgeSources 检索本群可见的知识库片段，作为回答的资料
+func (a *ActionInfo) knowledgeSources() []utils.WebSource {
+	if a.handler.knowledge == nil || a.info.chatId == nil {
+		return nil
+	}
+	hits := a.handler.knowledge.Search(context.Background(), *a.info.chatId,
+		a.info.qParsed, a.handler.config.KbTopK)
+	return knowledgeHitsToSources(hits, a.handler.config.KbContextTokens)
+}
+
+// knowledgeHitsToSources 在 token 预算内按相关度保留片段，同一文档的片段按原文顺序合并为一个来源
+func knowledgeHitsToSources(hits []kb.Hit, budget int) []utils.WebSource {
+	var order []string
+	byDoc := map[string][]kb.Hit{}
+	remaining := budget
+	for _, h := range hits {
+		tokens := utils.EstimateTokens(h.Text)
+		if budget > 0 && tokens > remaining {
+			continue
+		}
+		remaining -= tokens
+		if _, ok := byDoc[h.DocID]; !ok {
+			order = append(order, h.DocID)
+		}
+		byDoc[h.DocID] = append(byDoc[h.DocID], h)
+	}
+	var sources []utils.WebSource
+	for _, id := range order {
+		docHits := byDoc[id]
+		sort.Slice(docHits, func(i, j int) bool { return docHits[i].Chunk < docHits[j].Chunk })
+		var parts []string
+		for i, h := range docHits {
+			if i > 0 && h.Chunk != docHits[i-1].Chunk+1 {
+				parts = append(parts, "……")
+			}
+			parts = append(parts, h.Text)
+		}
+		origin := "知识库"
+		if docHits[0].URL != "" {
+			origin = "飞书文档"
+		}
+		sources = append(sources, utils.WebSource{
+			Title:   docHits[0].DocName,
+			URL:     docHits[0].URL,
+			Content: strings.Join(parts, "\n"),
+			Origin:  origin,
+		})
+	}
+	return sources
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"start-feishubot/services/kb"
+	"testing"
+)
+
+func TestKnowledgeHitsToSources(t *testing.T) {
+	hits := []kb.Hit{
+		{DocID: "a", DocName: "值班手册.md", Chunk: 3, Text: "告警处理", Score: 0.9},
+		{DocID: "b", DocName: "发布流程", URL: "https://feishu.cn/wiki/n1", Chunk: 0, Text: "发布步骤", Score: 0.8},
+		{DocID: "a", DocName: "值班手册.md", Chunk: 0, Text: "值班安排", Score: 0.7},
+		{DocID: "b", DocName: "发布流程", URL: "https://feishu.cn/wiki/n1", Chunk: 1, Text: "这个片段超出预算，不会被选中", Score: 0.6},
+	}
+	got := knowledgeHitsToSources(hits, 12)
+	if len(got) != 2 {
+		t.Fatalf("knowledgeHitsToSources() = %+v", got)
+	}
+	if got[0].Title != "值班手册.md" || got[0].Content != "值班安排\n……\n告警处理" || got[0].Origin != "知识库" {
+		t.Errorf("first source = %+v", got[0])
+	}
+	if got[1].Content != "发布步骤" || got[1].URL != "https://feishu.cn/wiki/n1" || got[1].Origin != "飞书文档" {
+		t.Errorf("second source = %+v", got[1])
+	}
+}
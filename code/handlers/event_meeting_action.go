@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"start-feishubot/initialization"
+	"start-feishubot/services/openai"
+	"start-feishubot/utils/audio"
+	"start-feishubot/utils/transcript"
+	"strings"
+	"sync"
+	"time"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+)
+
+const (
+	// whisper 单次上传上限
+	whisperMaxUploadBytes = 25 * 1024 * 1024
+	// 转写稿超过这个长度时先分段整理要点，再汇总成纪要
+	minutesPartRunes = 8000
+)
+
+// whisper 支持的格式，值为 true 的格式可以在本地解码切分，其他格式只能整体上传，需小于 25MB
+var meetingFileExts = map[string]bool{
+	".mp3": false, ".mp4": false, ".mpeg": false, ".mpga": false, ".m4a": false,
+	".webm": false, ".flac": false, ".wav": true, ".ogg": true, ".opus": true,
+}
+
+// meetingFileUnsupported 文件类型不支持时回复的原因
+const meetingFileUnsupported = "暂不支持该文件类型，会议转写支持 mp3/m4a/wav/ogg/mp4/webm 等音视频文件"
+
+// meetingFileLimit 按扩展名返回允许下载的最大字节数和超出时回复的原因，不支持的格式 ok 为 false
+func meetingFileLimit(fileName string, maxFileMB int) (maxBytes int64, tooLarge string, ok bool) {
+	splittable, ok := meetingFileExts[strings.ToLower(filepath.Ext(fileName))]
+	if !ok {
+		return 0, "", false
+	}
+	maxBytes = int64(maxFileMB) * 1024 * 1024
+	tooLarge = fmt.Sprintf("文件超过 %dMB，暂不支持转写", maxFileMB)
+	if !splittable && maxBytes > whisperMaxUploadBytes {
+		maxBytes = whisperMaxUploadBytes
+		tooLarge = "mp3/m4a/mp4 等格式无法在本地切分，文件需小于 25MB，可转换为 wav 后重试"
+	}
+	return maxBytes, tooLarge, true
+}
+
+const minutesSystemPrompt = "你是会议记录助手。下面是一段会议录音的自动转写，不区分发言人，可能有识别错误。" +
+	"请整理成 Markdown 格式的会议纪要，包含三个部分：**摘要**、**决议**、**待办事项**。" +
+	"待办事项写明事项内容，负责人和截止时间在转写中提到时注明，否则写“未明确”。" +
+	"没有相关内容的部分写“无”，不要编造转写中没有的信息。"
+
+const minutesPartPrompt = "你是会议记录助手。下面是一段较长会议转写中的一部分，不区分发言人。" +
+	"请用要点列出这部分讨论的主要内容、已做出的决定和提到的待办事项，不要编造。"
+
+type MeetingAction struct { /*会议录音转写*/
+}
+
+func (*MeetingAction) Execute(a *ActionInfo) bool {
+	if a.info.msgType != "media" && a.info.msgType != "file" {
+		return true
+	}
+	// 能走到这里的群消息都是在机器人话题中的回复，同样需要告诉用户原因
+	if !a.handler.config.MeetingEnabled {
+		replyMsg(*a.ctx, "🤖️：会议录音转写未开启，请联系管理员配置 MEETING_ENABLED～", a.info.msgId)
+		return false
+	}
+
+	fileName := a.info.fileName
+	if fileName == "" && a.info.msgType == "media" {
+		fileName = "video.mp4"
+	}
+	maxBytes, tooLarge, ok := meetingFileLimit(fileName, a.handler.config.MeetingMaxFileMB)
+	if !ok {
+		replyMsg(*a.ctx, "🤖️："+meetingFileUnsupported+"～", a.info.msgId)
+		return false
+	}
+
+	progress := newMeetingProgress(*a.ctx, a.info.msgId, fileName)
+	progress.update("⬇️ 正在下载文件...")
+
+	req := larkim.NewGetMessageResourceReqBuilder().MessageId(
+		*a.info.msgId).FileKey(a.info.fileKey).Type("file").Build()
+	resp, err := initialization.GetLarkClient().Im.MessageResource.Get(context.Background(), req)
+	if err == nil && !resp.Success() {
+		err = fmt.Errorf("%d %s", resp.Code, resp.Msg)
+	}
+	if err != nil {
+		progress.fail(fmt.Sprintf("文件下载失败：%v", err))
+		return false
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.File, maxBytes+1))
+	if err != nil {
+		progress.fail(fmt.Sprintf("文件下载失败：%v", err))
+		return false
+	}
+	if int64(len(data)) > maxBytes {
+		progress.fail(tooLarge)
+		return false
+	}
+
+	segments, err := a.transcribeMeeting(data, fileName, progress)
+	if err != nil {
+		progress.fail(fmt.Sprintf("转写失败：%v", err))
+		return false
+	}
+	text := transcript.Text(segments)
+	if text == "" {
+		progress.fail("没有识别到有效的语音内容")
+		return false
+	}
+
+	baseName := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	progress.update("📎 正在上传逐字稿...")
+	for _, file := range []struct{ name, content string }{
+		{baseName + ".md", transcript.FormatMarkdown(baseName, segments)},
+		{baseName + ".srt", transcript.FormatSRT(segments)},
+	} {
+		fileKey, err := uploadFile(file.name, []byte(file.content))
+		if err == nil {
+			err = replyFile(*a.ctx, *fileKey, a.info.msgId)
+		}
+		if err != nil {
+			fmt.Printf("    ❌ Upload transcript %s failed: %v\n", file.name, err)
+		}
+	}
+
+	progress.update("📝 正在整理会议纪要...")
+	minutes, err := a.meetingMinutes(text)
+	if err != nil {
+		progress.fail(fmt.Sprintf("逐字稿已发送，会议纪要生成失败：%v", err))
+		return false
+	}
+	progress.done(minutes)
+	return false
+}
+
+// transcribeMeeting 能本地解码的格式按静音切分后并发转写，其他格式整体上传
+func (a *ActionInfo) transcribeMeeting(data []byte, fileName string,
+	progress *meetingProgress) ([]transcript.Segment, error) {
+	var pcm *audio.PCM
+	var err error
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".wav":
+		pcm, err = audio.DecodeWav(bytes.NewReader(data))
+	case ".ogg", ".opus":
+		pcm, err = audio.DecodeOggOpus(bytes.NewReader(data))
+	}
+	if err != nil {
+		fmt.Printf("    ⚠️ Local decode of %s failed, uploading as is: %v\n", fileName, err)
+		pcm = nil
+	}
+
+	if pcm == nil {
+		if len(data) > whisperMaxUploadBytes {
+			return nil, fmt.Errorf("该格式无法在本地切分，文件需小于 25MB，可转换为 wav 后重试")
+		}
+		progress.update("🎧 正在转写...")
+		resp, err := a.handler.gpt.AudioBytesToSegments(data, fileName)
+		if err != nil {
+			return nil, err
+		}
+		return whisperSegments(resp, 0), nil
+	}
+
+	maxChunk := time.Duration(a.handler.config.AudioChunkSec) * time.Second
+	chunks := audio.SplitOnSilence(*pcm, maxChunk, audioSilenceSearchWindow)
+	offsets := make([]time.Duration, len(chunks))
+	for i := 1; i < len(chunks); i++ {
+		offsets[i] = offsets[i-1] + chunks[i-1].Duration()
+	}
+	fmt.Printf("    🎧 Meeting audio %v split into %d chunk(s)\n", pcm.Duration(), len(chunks))
+	progress.update(fmt.Sprintf("🎧 正在转写 0/%d 段（共 %v）...", len(chunks),
+		pcm.Duration().Round(time.Second)))
+
+	concurrency := a.handler.config.AudioMaxConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([][]transcript.Segment, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, concurrency)
+	var mu sync.Mutex
+	finished := 0
+	var wg sync.WaitGroup
+	for i := range chunks {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			var wav bytes.Buffer
+			if errs[index] = audio.WriteWav(&wav, chunks[index]); errs[index] != nil {
+				return
+			}
+			resp, err := a.handler.gpt.AudioBytesToSegments(wav.Bytes(), "audio.wav")
+			if err != nil {
+				errs[index] = err
+				return
+			}
+			results[index] = whisperSegments(resp, offsets[index])
+
+			mu.Lock()
+			finished++
+			progress.update(fmt.Sprintf("🎧 正在转写 %d/%d 段...", finished, len(chunks)))
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	var segments []transcript.Segment
+	for i := range chunks {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), errs[i])
+		}
+		segments = append(segments, results[i]...)
+	}
+	return segments, nil
+}
+
+// whisperSegments 接口未返回分段时，整段文本作为一个分段
+func whisperSegments(resp *openai.AudioToTextResponseBody,
+	offset time.Duration) []transcript.Segment {
+	var segments []transcript.Segment
+	for _, s := range resp.Segments {
+		segments = append(segments, transcript.Segment{
+			Start: time.Duration(s.Start * float64(time.Second)),
+			End:   time.Duration(s.End * float64(time.Second)),
+			Text:  s.Text,
+		})
+	}
+	if len(segments) == 0 && strings.TrimSpace(resp.Text) != "" {
+		segments = append(segments, transcript.Segment{
+			End:  time.Duration(resp.Duration * float64(time.Second)),
+			Text: resp.Text,
+		})
+	}
+	return transcript.Offset(segments, offset)
+}
+
+// meetingMinutes 转写稿过长时先分段提炼要点，再汇总成纪要
+func (a *ActionInfo) meetingMinutes(text string) (string, error) {
+	parts := transcript.SplitText(text, minutesPartRunes)
+	if len(parts) > 1 {
+		var notes []string
+		for i, part := range parts {
+			resp, err := a.completions([]openai.Messages{
+				{Role: "system", Content: minutesPartPrompt},
+				{Role: "user", Content: part},
+			}, 0)
+			if err != nil {
+				return "", fmt.Errorf("part %d/%d: %w", i+1, len(parts), err)
+			}
+			notes = append(notes, fmt.Sprintf("第 %d 部分要点：\n%s", i+1, resp.Content))
+		}
+		text = strings.Join(notes, "\n\n")
+	}
+	resp, err := a.answerCompletions([]openai.Messages{
+		{Role: "system", Content: minutesSystemPrompt},
+		{Role: "user", Content: text},
+	}, 0)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// meetingProgress 在一张卡片上展示转写进度，卡片发送失败时只记录日志
+type meetingProgress struct {
+	ctx      context.Context
+	msgId    *string
+	cardId   *string
+	fileName string
+}
+
+func newMeetingProgress(ctx context.Context, msgId *string, fileName string) *meetingProgress {
+	return &meetingProgress{ctx: ctx, msgId: msgId, fileName: fileName}
+}
+
+func (p *meetingProgress) update(status string) {
+	fmt.Printf("    📊 Meeting %s: %s\n", p.fileName, status)
+	p.show(newMeetingCard(p.fileName, status, larkcard.TemplateBlue,
+		"转写完成后会发送逐字稿文件和会议纪要"))
+}
+
+func (p *meetingProgress) fail(reason string) {
+	fmt.Printf("    ❌ Meeting %s: %s\n", p.fileName, reason)
+	p.show(newMeetingCard(p.fileName, "🤖️："+reason, larkcard.TemplateRed,
+		"请稍后再试～"))
+}
+
+func (p *meetingProgress) done(minutes string) {
+	p.show(newMeetingCard(p.fileName, minutes, larkcard.TemplateGreen,
+		"纪要由 AI 根据自动转写生成，不区分发言人，请注意核对"))
+}
+
+func (p *meetingProgress) show(card string) {
+	if p.cardId == nil {
+		cardId, err := replyCardWithId(p.ctx, p.msgId, card)
+		if err != nil {
+			fmt.Printf("    ⚠️ Send meeting progress card failed: %v\n", err)
+			return
+		}
+		p.cardId = cardId
+		return
+	}
+	if err := patchCard(p.ctx, p.cardId, card); err != nil {
+		fmt.Printf("    ⚠️ Update meeting progress card failed: %v\n", err)
+	}
+}
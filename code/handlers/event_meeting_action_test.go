@@ -0,0 +1,26 @@
+package handlers
+
+import "testing"
+
+func TestMeetingFileLimit(t *testing.T) {
+	tests := []struct {
+		fileName     string
+		maxFileMB    int
+		wantMaxBytes int64
+		wantOK       bool
+	}{
+		{"周会.wav", 200, 200 * 1024 * 1024, true},
+		{"周会.OGG", 200, 200 * 1024 * 1024, true},
+		{"周会.m4a", 200, whisperMaxUploadBytes, true},
+		{"video.mp4", 10, 10 * 1024 * 1024, true},
+		{"纪要.pdf", 200, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fileName, func(t *testing.T) {
+			maxBytes, tooLarge, ok := meetingFileLimit(tt.fileName, tt.maxFileMB)
+			if maxBytes != tt.wantMaxBytes || ok != tt.wantOK || ok && tooLarge == "" {
+				t.Errorf("meetingFileLimit() = %d, %q, %v", maxBytes, tooLarge, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"start-feishubot/services"
+	"start-feishubot/services/openai"
+	"start-feishubot/utils"
+	"strconv"
+	"strings"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// memoryPromptPrefix 长期记忆 system 消息的开头，用于从会话中识别这条消息
+const memoryPromptPrefix = "以下是用户要求你长期记住的信息"
+
+type MemoryAction struct { /*长期记忆*/
+}
+
+func (*MemoryAction) Execute(a *ActionInfo) bool {
+	text := strings.TrimSpace(a.info.qParsed)
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return true
+	}
+	cmd := strings.ToLower(fields[0])
+	_, isRemember := utils.EitherTrimEqual(cmd, rememberCommand...)
+	_, isMemory := utils.EitherTrimEqual(cmd, memoryCommand...)
+	if !isRemember && !isMemory {
+		return true
+	}
+	if !a.handler.config.MemoryEnabled {
+		replyMsg(*a.ctx, "🤖️：长期记忆功能未开启，请联系管理员配置 MEMORY_ENABLED", a.info.msgId)
+		return false
+	}
+	if a.info.openId == "" {
+		replyMsg(*a.ctx, "🤖️：无法识别你的身份，暂时不能使用长期记忆～", a.info.msgId)
+		return false
+	}
+	if isRemember {
+		a.remember(strings.TrimSpace(text[len(fields[0]):]))
+		return false
+	}
+
+	sub := ""
+	if len(fields) > 1 {
+		sub = strings.ToLower(fields[1])
+	}
+	switch sub {
+	case "", "list", "ls":
+		a.replyMemoryList()
+	case "delete", "rm", "remove":
+		index := 0
+		if len(fields) > 2 {
+			index, _ = strconv.Atoi(fields[2])
+		}
+		if index <= 0 {
+			replyMsg(*a.ctx, "🤖️：请输入要删除的记忆序号，例如 /memory delete 2", a.info.msgId)
+			return false
+		}
+		entry, err := a.handler.memories.Forget(a.info.openId, index)
+		if errors.Is(err, services.ErrMemoryNotFound) {
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：没有第 %d 条记忆，发送 /memory 查看全部记忆", index), a.info.msgId)
+			return false
+		}
+		if err != nil {
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：删除失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+			return false
+		}
+		replyMsg(*a.ctx, "🤖️：已忘记："+entry.Text, a.info.msgId)
+	case "clear":
+		if err := a.handler.memories.Clear(a.info.openId); err != nil {
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：清空失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+			return false
+		}
+		replyMsg(*a.ctx, "🤖️：已清空你的全部记忆", a.info.msgId)
+	case "on", "off":
+		if _, err := a.handler.memories.SetDisabled(a.info.openId, sub == "off"); err != nil {
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：设置失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+			return false
+		}
+		if sub == "off" {
+			replyMsg(*a.ctx, "🤖️：已暂停使用长期记忆，已保存的记忆不会删除，发送 /memory on 恢复", a.info.msgId)
+		} else {
+			replyMsg(*a.ctx, "🤖️：已开启长期记忆，之后的新话题会参考你的记忆", a.info.msgId)
+		}
+	default:
+		replyMsg(*a.ctx, "🤖️：记忆指令：/remember 内容 记住信息，/memory 查看，/memory delete 序号 删除，/memory clear 清空，/memory off 暂停使用", a.info.msgId)
+	}
+	return false
+}
+
+func (a *ActionInfo) remember(fact string) {
+	if fact == "" {
+		replyMsg(*a.ctx, "🤖️：请在指令后输入要记住的内容，例如 /remember 我主要写 Go，示例请用 Go", a.info.msgId)
+		return
+	}
+	if limit := a.handler.config.MemoryMaxChars; limit > 0 && len([]rune(fact)) > limit {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：每条记忆最多 %d 个字，请精简后再试～", limit), a.info.msgId)
+		return
+	}
+	memory, err := a.handler.memories.Remember(a.info.openId, fact, a.handler.config.MemoryMaxEntries)
+	if err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：保存失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+		return
+	}
+	fmt.Printf("🧠 [Memory] %s saved a memory (%d total)\n", a.info.openId, len(memory.Entries))
+	note := "之后的新话题会参考这条记忆"
+	if a.info.handlerType == GroupHandler && !a.handler.config.MemoryInGroups {
+		note = "之后与我单聊的新话题会参考这条记忆"
+	}
+	replyMsg(*a.ctx, fmt.Sprintf("🤖️：已记住：%s\n%s，发送 /memory 查看或删除", fact, note), a.info.msgId)
+}
+
+func (a *ActionInfo) replyMemoryList() {
+	memory := a.handler.memories.Get(a.info.openId)
+	var lines []string
+	for i, e := range memory.Entries {
+		lines = append(lines, fmt.Sprintf("%d. %s · %s", i+1, e.Text, e.CreatedAt.Format("2006-01-02")))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "还没有记忆，发送 /remember 内容 让我记住你的偏好")
+	}
+	if memory.Disabled {
+		lines = append(lines, "", "⏸️ 长期记忆已暂停，发送 /memory on 恢复")
+	}
+	card, err := newSendCard(
+		withHeader("🧠 我的记忆", larkcard.TemplateBlue),
+		withMainMd(strings.Join(lines, "\n")),
+		withNote("记忆只对你本人可见 · /memory delete 序号 删除 · /memory clear 清空 · /memory off 暂停"))
+	if err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：记忆卡片生成失败～\n错误信息: %v", err), a.info.msgId)
+		return
+	}
+	replyCard(*a.ctx, a.info.msgId, card)
+}
+
+// memoryAllowed 当前消息能否使用发送者的长期记忆，群聊默认不使用以免泄露给其他成员
+func (a *ActionInfo) memoryAllowed() bool {
+	config := a.handler.config
+	if !config.MemoryEnabled || a.handler.memories == nil || a.info.openId == "" {
+		return false
+	}
+	return a.info.handlerType != GroupHandler || config.MemoryInGroups
+}
+
+// memoryMessage 新话题开始时注入的长期记忆
+func (a *ActionInfo) memoryMessage() (openai.Messages, bool) {
+	if !a.memoryAllowed() {
+		return openai.Messages{}, false
+	}
+	memory := a.handler.memories.Get(a.info.openId)
+	if !memory.Active() {
+		return openai.Messages{}, false
+	}
+	return openai.Messages{Role: "system", Content: memoryPrompt(memory.Entries)}, true
+}
+
+func memoryPrompt(entries []services.MemoryEntry) string {
+	lines := []string{memoryPromptPrefix + "，回答时在相关的地方参考，不要主动复述："}
+	for _, e := range entries {
+		lines = append(lines, "- "+strings.Join(strings.Fields(e.Text), " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isNewTopic 话题的第一轮问答，长期记忆的 system 消息不计入
+func isNewTopic(msgs []openai.Messages) bool {
+	n := len(msgs)
+	for _, m := range msgs {
+		if m.Role == "system" && strings.HasPrefix(m.Content, memoryPromptPrefix) {
+			n--
+		}
+	}
+	return n == 2
+}
+
+const saveMemoryToolName = "save_memory"
+
+// saveMemoryTool 让模型在回答时保存用户的长期偏好或背景
+var saveMemoryTool = openai.Tool{
+	Type: "function",
+	Function: openai.ToolFunction{
+		Name: saveMemoryToolName,
+		Description: "保存一条关于用户的长期信息，之后的新话题会提供给你。" +
+			"仅在用户要求你记住某件事，或明确说明了长期有效的偏好、背景（如常用语言、负责的项目）时调用；" +
+			"不要保存一次性的问题、临时信息或敏感信息。",
+		Parameters: json.RawMessage(`{"type":"object","properties":{"fact":{"type":"string",` +
+			`"description":"简短的一句话，例如：偏好 Go 语言示例"}},"required":["fact"]}`),
+	},
+}
+
+// memoryToolEnabled 是否把保存记忆的工具提供给模型，用户暂停记忆时不提供
+func (a *ActionInfo) memoryToolEnabled() bool {
+	return a.handler.config.MemoryTool && a.memoryAllowed() &&
+		!a.handler.memories.Get(a.info.openId).Disabled
+}
+
+// runMemoryTool 执行模型的保存记忆调用，返回发回给模型的结果和保存的内容（失败时为空）
+func (a *ActionInfo) runMemoryTool(call openai.ToolCall) (string, string) {
+	fact, err := parseMemoryToolCall(call, a.handler.config.MemoryMaxChars)
+	if err != nil {
+		return "保存失败：" + err.Error(), ""
+	}
+	memory, err := a.handler.memories.Remember(a.info.openId, fact, a.handler.config.MemoryMaxEntries)
+	if err != nil {
+		fmt.Printf("🧠 [Memory] Tool failed to save memory for %s: %v\n", a.info.openId, err)
+		return "保存失败，请告诉用户稍后用 /remember 重试", ""
+	}
+	fmt.Printf("🧠 [Memory] Model saved a memory for %s (%d total)\n", a.info.openId, len(memory.Entries))
+	return "已保存", fact
+}
+
+// parseMemoryToolCall 校验工具名和参数，返回要保存的内容
+func parseMemoryToolCall(call openai.ToolCall, maxChars int) (string, error) {
+	if call.Function.Name != saveMemoryToolName {
+		return "", fmt.Errorf("未知的工具 %s", call.Function.Name)
+	}
+	var args struct {
+		Fact string `json:"fact"`
+	}
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		return "", errors.New("参数不是合法的 JSON")
+	}
+	fact := strings.Join(strings.Fields(args.Fact), " ")
+	if fact == "" {
+		return "", errors.New("fact 不能为空")
+	}
+	if maxChars > 0 && len([]rune(fact)) > maxChars {
+		return "", fmt.Errorf("每条记忆最多 %d 个字，请精简后重试", maxChars)
+	}
+	return fact, nil
+}
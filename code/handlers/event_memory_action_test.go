@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"start-feishubot/services"
+	"start-feishubot/services/openai"
+	"strings"
+	"testing"
+)
+
+func TestMemoryPrompt(t *testing.T) {
+	got := memoryPrompt([]services.MemoryEntry{{Text: "偏好 Go\n示例"}, {Text: "负责支付服务"}})
+	if !strings.HasPrefix(got, memoryPromptPrefix) || !strings.HasSuffix(got, "\n- 偏好 Go 示例\n- 负责支付服务") {
+		t.Errorf("memoryPrompt() = %q", got)
+	}
+}
+
+func TestIsNewTopic(t *testing.T) {
+	memory := openai.Messages{Role: "system", Content: memoryPrompt([]services.MemoryEntry{{Text: "偏好 Go"}})}
+	role := openai.Messages{Role: "system", Content: "你是一个翻译"}
+	q := openai.Messages{Role: "user", Content: "你好"}
+	ans := openai.Messages{Role: "assistant", Content: "你好！"}
+	tests := []struct {
+		name string
+		msgs []openai.Messages
+		want bool
+	}{
+		{"first turn", []openai.Messages{q, ans}, true},
+		{"first turn with memory", []openai.Messages{memory, q, ans}, true},
+		{"second turn with memory", []openai.Messages{memory, q, ans, q, ans}, false},
+		{"role play keeps old behaviour", []openai.Messages{role, q, ans}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNewTopic(tt.msgs); got != tt.want {
+				t.Errorf("isNewTopic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMemoryToolCall(t *testing.T) {
+	newCall := func(name, args string) openai.ToolCall {
+		var call openai.ToolCall
+		call.Function.Name, call.Function.Arguments = name, args
+		return call
+	}
+	tests := []struct {
+		name    string
+		call    openai.ToolCall
+		want    string
+		wantErr bool
+	}{
+		{"ok", newCall(saveMemoryToolName, `{"fact":" 偏好 Go\n示例 "}`), "偏好 Go 示例", false},
+		{"unknown tool", newCall("search", `{"fact":"x"}`), "", true},
+		{"invalid json", newCall(saveMemoryToolName, `{"fact":`), "", true},
+		{"empty fact", newCall(saveMemoryToolName, `{"fact":"  "}`), "", true},
+		{"too long", newCall(saveMemoryToolName, `{"fact":"一二三四五六七八九十百"}`), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMemoryToolCall(tt.call, 10)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("parseMemoryToolCall() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
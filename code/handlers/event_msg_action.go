@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"start-feishubot/initialization"
+	"start-feishubot/services/cache"
 	"start-feishubot/services/openai"
 	"start-feishubot/utils"
 	"strings"
@@ -18,6 +21,73 @@ func min(a, b int) int {
 	return b
 }
 
+// newSearcher 按配置顺序组装搜索引擎回退链
+func newSearcher(config initialization.Config) utils.SearchProvider {
+	providers := utils.NewSearchProviders(config.SearchProviders, utils.SearchProviderConfig{
+		GoogleApiKey: config.GoogleApiKey,
+		GoogleCSEId:  config.GoogleCSEId,
+		BingApiKey:   config.BingApiKey,
+		BraveApiKey:  config.BraveApiKey,
+		SearXNGURL:   config.SearXNGURL,
+		TavilyApiKey: config.TavilyApiKey,
+	})
+	if len(providers) == 0 {
+		fmt.Println("⚠️ No search provider available, falling back to duckduckgo")
+		providers = []utils.SearchProvider{&utils.DuckDuckGoProvider{}}
+	}
+	chain := utils.NewSearchChain(providers, utils.ParseSearchQuotas(config.SearchProviderQuotas))
+	fmt.Printf("🔍 Search providers: %s\n", chain.Name())
+	return chain
+}
+
+// newEmbedder 配置了 EMBEDDING_API_URL 或开启 EMBEDDING_USE_CHAT_API 时启用语义重排，
+// 向量请求统一由 ChatGPT 发送（含 key 负载均衡和重试）
+func newEmbedder(config initialization.Config, gpt *openai.ChatGPT) utils.Embedder {
+	if gpt == nil || (!config.EmbeddingUseChatApi && strings.TrimSpace(config.EmbeddingApiUrl) == "") {
+		return nil
+	}
+	fmt.Printf("🧭 Embedding rerank enabled: %s\n", gpt.EmbeddingModel)
+	return gpt
+}
+
+// configureWebCaches 按配置创建搜索结果和网页正文缓存，可选持久化到磁盘或 Redis
+func configureWebCaches(config initialization.Config) {
+	// 磁盘缓存与内存缓存使用相同的条数和大小上限
+	newBackend := func(name string, maxEntries int, maxBytes int64) cache.Backend {
+		switch config.SearchCacheBackend {
+		case "file":
+			dir := filepath.Join(config.DataDir, "web_cache", name)
+			backend, err := cache.NewFileBackend(dir, maxEntries, maxBytes)
+			if err != nil {
+				fmt.Printf("⚠️ Failed to open web cache dir %s, using memory only: %v\n", dir, err)
+				return nil
+			}
+			return backend
+		case "redis":
+			if client := initialization.GetRedisClient(); client != nil {
+				return cache.NewRedisBackend(client, "feishubot:webcache:"+name+":")
+			}
+			fmt.Println("⚠️ SEARCH_CACHE_BACKEND=redis but REDIS_ADDR is empty, using memory only")
+		}
+		return nil
+	}
+	utils.SetWebCaches(
+		cache.New(cache.Options{
+			Name:       "search",
+			MaxEntries: config.SearchCacheSize,
+			TTL:        time.Duration(config.SearchCacheTTLMin) * time.Minute,
+			Backend:    newBackend("search", config.SearchCacheSize, 0),
+		}),
+		cache.New(cache.Options{
+			Name:     "page",
+			MaxBytes: int64(config.FetchCacheSizeMB) << 20,
+			TTL:      time.Duration(config.FetchCacheTTLMin) * time.Minute,
+			Backend:  newBackend("page", 0, int64(config.FetchCacheSizeMB)<<20),
+		}))
+	fmt.Printf("🗃️ Web cache: backend=%s, search ttl=%dm, page ttl=%dm\n",
+		config.SearchCacheBackend, config.SearchCacheTTLMin, config.FetchCacheTTLMin)
+}
+
 type MessageAction struct { /*消息*/
 }
 
@@ -39,17 +109,40 @@ func (*MessageAction) Execute(a *ActionInfo) bool {
 	// Build classification prompt
 	classifySystem := openai.Messages{Role: "system", Content: "你是一个助手。请严格输出 JSON，不要包含多余文本。根据用户问题判断是否需要联网检索外部信息才能给出可靠答案。若需要，请给出3-6条精炼的中文检索关键信息（queries），并建议每个查询的搜索数量（search_top_k，建议1-5个结果）和回答的最大token数（max_tokens，建议500-2000）。若不需要，请直接给出最终答案。必须输出如下 JSON：{\"need_web\": boolean, \"queries\": string[], \"answer\": string, \"search_top_k\": number, \"max_tokens\": number}. 当 need_web=true 时，尽量填写 queries、search_top_k 和 max_tokens，answer 可留空；当 need_web=false 时，必须填写 answer 和 max_tokens，queries 和 search_top_k 可留空。"}
 
+	if style := replyStyleInstruction(a.settings.ReplyStyle); style != "" {
+		classifySystem.Content += " 填写 answer 时的回复风格要求：" + style
+	}
+
 	fmt.Printf("    📚 Getting session history...\n")
-	history := a.handler.sessionCache.GetMsg(*a.info.sessionId)
+	history := a.sessionHistory()
 	fmt.Printf("    📖 Session history length: %d messages\n", len(history))
 
+	// 本群可见的知识库片段，与联网结果一起作为资料
+	kbSources := a.knowledgeSources()
+	if len(kbSources) > 0 {
+		fmt.Printf("    📚 Knowledge base hits: %d documents\n", len(kbSources))
+	}
+
+	// 指令或联网模式已经决定是否联网时跳过分类，节省一次模型调用
+	switch a.search {
+	case searchSkipped:
+		fmt.Printf("    🚫 Web search disabled for this message, answering directly\n")
+		if len(kbSources) > 0 {
+			return a.knowledgeAnswer(history, kbSources)
+		}
+		return a.directAnswer(history, 0)
+	case searchCommand:
+		fmt.Printf("    🌐 /search command, searching with the question\n")
+		return a.webSearchAnswer(history, []string{a.info.qParsed}, 0, kbSources)
+	}
+
 	fmt.Printf("    🔧 Building classification messages...\n")
 	classifyMsgs := append([]openai.Messages{classifySystem}, history...)
 	classifyMsgs = append(classifyMsgs, openai.Messages{Role: "user", Content: a.info.qParsed})
 	fmt.Printf("    📝 Total messages to send: %d\n", len(classifyMsgs))
 
 	fmt.Printf("    🤖 Calling OpenAI for classification...\n")
-	clsResp, err := a.handler.gpt.Completions(classifyMsgs)
+	clsResp, err := a.completions(classifyMsgs, 0)
 	if err != nil {
 		fmt.Printf("    ❌ OpenAI classification failed: %v\n", err)
 		replyMsg(*a.ctx, fmt.Sprintf(
@@ -69,7 +162,7 @@ func (*MessageAction) Execute(a *ActionInfo) bool {
 		// Fallback: if not valid JSON, use original single-shot behavior
 		msg := append(history, openai.Messages{Role: "user", Content: a.info.qParsed})
 		fmt.Printf("    🤖 Calling OpenAI for single-shot response...\n")
-		completions, err2 := a.handler.gpt.Completions(msg)
+		completions, err2 := a.answerCompletions(msg, 0)
 		if err2 != nil {
 			fmt.Printf("    ❌ Single-shot OpenAI call failed: %v\n", err2)
 			replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err2), a.info.msgId)
@@ -80,12 +173,9 @@ func (*MessageAction) Execute(a *ActionInfo) bool {
 		// append to history as final answer
 		msg = append(msg, completions)
 		a.handler.sessionCache.SetMsg(*a.info.sessionId, msg)
-		// new topic card logic
-		if len(msg) == 2 {
-			sendNewTopicCard(*a.ctx, a.info.sessionId, a.info.msgId, completions.Content)
-			return false
-		}
-		if err = replyMsg(*a.ctx, completions.Content, a.info.msgId); err != nil {
+		// 语音提问时在文字回答之后补发语音
+		defer a.replyVoice(completions.Content)
+		if err = a.replyAnswer(completions.Content, isNewTopic(msg), completions.Truncated()); err != nil {
 			replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
 			return false
 		}
@@ -99,431 +189,462 @@ func (*MessageAction) Execute(a *ActionInfo) bool {
 	fmt.Printf("    🔍 Decision details: need_web=%t, queries_count=%d, search_top_k=%d, max_tokens=%d\n",
 		decision.NeedWeb, len(decision.Queries), decision.SearchTopK, decision.MaxTokens)
 
+	// 群设置要求总是联网或命中关键词时，忽略分类结果
+	if a.search == searchRequired && !decision.NeedWeb {
+		fmt.Printf("    🌐 Web search required for this message, forcing web search\n")
+		decision.NeedWeb = true
+	}
+
 	if decision.NeedWeb {
-		fmt.Printf("    🌐 Step 2: Web search required\n")
-		// Step 2: 自动触发检索与二次回答
-		queries := decision.Queries
-		if len(queries) == 0 {
-			fmt.Printf("    🔄 No queries provided, using original question\n")
-			queries = []string{a.info.qParsed}
-		}
-		fmt.Printf("    🔍 Search queries: %v\n", queries)
+		return a.webSearchAnswer(history, decision.Queries, decision.SearchTopK, kbSources)
+	}
+	// 知识库有相关内容时基于知识库回答，不使用分类阶段给出的答案
+	if len(kbSources) > 0 {
+		return a.knowledgeAnswer(history, kbSources)
+	}
 
-		// 使用 ChatGPT 建议的搜索数量，如果没有则使用默认值
-		searchTopK := decision.SearchTopK
-		if searchTopK <= 0 {
-			searchTopK = 3 // 默认值
-		}
-		if searchTopK > 10 {
-			searchTopK = 10 // 限制最大值
-		}
-		fmt.Printf("[Second Stage] Using SearchTopK: %d\n", searchTopK)
+	// NeedWeb == false: directly return final answer from decision.Answer
+	answer := decision.Answer
+	if answer == "" {
+		// Safety fallback: run a normal completion to produce an answer
+		return a.directAnswer(history, decision.MaxTokens)
+	}
+	// debug: print direct answer
+	fmt.Println("[OpenAI Direct Answer]:", answer)
 
-		// 并发搜索：最多取前10条查询，并发构建搜索上下文
-		maxQ := 10
-		if len(queries) < maxQ {
-			maxQ = len(queries)
-		}
+	// Append assistant answer to history and reply
+	finalHistory := append(history, openai.Messages{Role: "user", Content: a.info.qParsed})
+	finalHistory = append(finalHistory, openai.Messages{Role: "assistant", Content: answer})
+	a.handler.sessionCache.SetMsg(*a.info.sessionId, finalHistory)
+	defer a.replyVoice(answer)
+	if err := a.replyAnswer(answer, isNewTopic(finalHistory), false); err != nil {
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+		return false
+	}
+	return true
+}
 
-		// 获取并发数配置
-		maxConcurrency := a.handler.config.SearchMaxConcurrency
-		if maxConcurrency <= 0 {
-			maxConcurrency = 3 // 默认并发数
-		}
-		if maxConcurrency > 10 {
-			maxConcurrency = 10 // 限制最大并发数
+// webSearchAnswer 按检索词并发搜索，连同知识库片段一起生成带引用的回答
+func (a *ActionInfo) webSearchAnswer(history []openai.Messages, rawQueries []string, suggestedTopK int,
+	kbSources []utils.WebSource) bool {
+	fmt.Printf("    🌐 Step 2: Web search required\n")
+	// Step 2: 自动触发检索与二次回答
+	// 去掉空查询，保证下标与检索过程卡片一致
+	var queries []string
+	for _, q := range rawQueries {
+		if q = strings.TrimSpace(q); q != "" {
+			queries = append(queries, q)
 		}
+	}
+	if len(queries) == 0 {
+		fmt.Printf("    🔄 No queries provided, using original question\n")
+		queries = []string{a.info.qParsed}
+	}
+	fmt.Printf("    🔍 Search queries: %v\n", queries)
 
-		fmt.Printf("🚀 Starting concurrent search for %d queries (max concurrency: %d)...\n", maxQ, maxConcurrency)
+	// 使用 ChatGPT 建议的搜索数量，如果没有则使用默认值
+	searchTopK := suggestedTopK
+	if searchTopK <= 0 {
+		searchTopK = a.settings.SearchTopK // 默认值
+	}
+	if searchTopK <= 0 {
+		searchTopK = 3
+	}
+	if searchTopK > 10 {
+		searchTopK = 10 // 限制最大值
+	}
+	fmt.Printf("[Second Stage] Using SearchTopK: %d\n", searchTopK)
 
-		// 创建结果通道和信号量
-		type searchResult struct {
-			index int
-			query string
-			ctx   string
-			err   error
-		}
+	// 并发搜索：最多取前10条查询，并发构建搜索上下文
+	maxQ := 10
+	if len(queries) < maxQ {
+		maxQ = len(queries)
+	}
 
-		resultChan := make(chan searchResult, maxQ)
-		semaphore := make(chan struct{}, maxConcurrency) // 信号量控制并发数
-		var wg sync.WaitGroup
+	// 获取并发数配置
+	maxConcurrency := a.handler.config.SearchMaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 3 // 默认并发数
+	}
+	if maxConcurrency > 10 {
+		maxConcurrency = 10 // 限制最大并发数
+	}
 
-		// 启动并发搜索
-		for i := 0; i < maxQ; i++ {
-			q := strings.TrimSpace(queries[i])
-			if q == "" {
-				continue
-			}
+	fmt.Printf("🚀 Starting concurrent search for %d queries (max concurrency: %d)...\n", maxQ, maxConcurrency)
 
-			wg.Add(1)
-			go func(index int, query string) {
-				defer wg.Done()
+	// 创建结果通道和信号量
+	type searchResult struct {
+		index   int
+		query   string
+		sc      *utils.SearchContext
+		err     error
+		timeout bool
+	}
 
-				// 获取信号量
-				semaphore <- struct{}{}
-				defer func() { <-semaphore }()
+	resultChan := make(chan searchResult, maxQ)
+	semaphore := make(chan struct{}, maxConcurrency) // 信号量控制并发数
+	var wg sync.WaitGroup
 
-				fmt.Printf("🔍 [Concurrent] Query %d: %s (topK=%d)\n", index+1, query, searchTopK)
+	// 展示检索过程卡片，回答完成后替换为最终回答
+	plan := newSearchPlan(*a.ctx, a.info.msgId, queries[:maxQ], a.settings.ShowSearchPlan)
+	plan.start()
+	defer plan.close()
 
-				// 创建带超时的上下文
-				timeout := time.Duration(a.handler.config.SearchPerFetchTimeoutSec) * time.Second
-				if timeout <= 0 {
-					timeout = 6 * time.Second // 默认超时时间
-				}
+	// 启动并发搜索
+	for i := 0; i < maxQ; i++ {
+		q := queries[i]
 
-				ctx, cancel := context.WithTimeout(context.Background(), timeout)
-				defer cancel()
+		wg.Add(1)
+		go func(index int, query string) {
+			defer wg.Done()
 
-				// 使用通道来接收搜索结果
-				type searchResultChan struct {
-					ctx string
-					err error
-				}
+			// 获取信号量
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
 
-				searchChan := make(chan searchResultChan, 1)
-
-				go func() {
-					var resultCtx string
-					var resultErr error
-
-					// 优先使用 Google 搜索，如果失败则回退到 DuckDuckGo
-					if a.handler.config.GoogleApiKey != "" && a.handler.config.GoogleCSEId != "" {
-						resultCtx, resultErr = utils.BuildGoogleSearchContext(query, a.handler.config.GoogleApiKey, a.handler.config.GoogleCSEId, searchTopK)
-						if resultErr != nil {
-							fmt.Printf("⚠️ [Concurrent] Query %d Google failed, falling back to DuckDuckGo: %v\n", index+1, resultErr)
-							resultCtx, resultErr = utils.BuildSearchContext(query, searchTopK)
-						}
-					} else {
-						resultCtx, resultErr = utils.BuildSearchContext(query, searchTopK)
-					}
-
-					searchChan <- searchResultChan{ctx: resultCtx, err: resultErr}
-				}()
-
-				// 等待搜索结果或超时
-				select {
-				case result := <-searchChan:
-					// 发送结果到通道
-					resultChan <- searchResult{
-						index: index,
-						query: query,
-						ctx:   result.ctx,
-						err:   result.err,
-					}
-				case <-ctx.Done():
-					fmt.Printf("⏰ [Concurrent] Query %d timed out after %v\n", index+1, timeout)
-					resultChan <- searchResult{
-						index: index,
-						query: query,
-						ctx:   "",
-						err:   fmt.Errorf("search timeout after %v", timeout),
-					}
-				}
-			}(i, q)
-		}
+			fmt.Printf("🔍 [Concurrent] Query %d: %s (topK=%d)\n", index+1, query, searchTopK)
 
-		// 等待所有搜索完成，带整体超时
-		done := make(chan struct{})
-		go func() {
-			wg.Wait()
-			close(resultChan)
-			close(done)
-		}()
-
-		// 设置整体超时
-		overallTimeout := time.Duration(a.handler.config.SearchOverallTimeoutSec) * time.Second
-		if overallTimeout <= 0 {
-			overallTimeout = 10 * time.Second // 默认整体超时时间
-		}
+			// 创建带超时的上下文
+			timeout := time.Duration(a.handler.config.SearchPerFetchTimeoutSec) * time.Second
+			if timeout <= 0 {
+				timeout = 6 * time.Second // 默认超时时间
+			}
 
-		fmt.Printf("⏱️ [Concurrent] Overall timeout: %v\n", overallTimeout)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
 
-		// 收集结果
-		var ctxParts []string
-		var successfulSearches int
-		var failedSearches int
-		var timeoutOccurred bool
+			// 使用通道来接收搜索结果
+			type searchResultChan struct {
+				sc  *utils.SearchContext
+				err error
+			}
+
+			searchChan := make(chan searchResultChan, 1)
+
+			go func() {
+				// 按 SEARCH_PROVIDERS 顺序搜索，失败时自动回退
+				resultSC, resultErr := utils.BuildProviderSearchSources(a.handler.searcher, query, searchTopK)
 
-		// 使用 select 等待结果或超时
-		for {
+				searchChan <- searchResultChan{sc: resultSC, err: resultErr}
+			}()
+
+			// 等待搜索结果或超时
 			select {
-			case result, ok := <-resultChan:
-				if !ok {
-					// 所有搜索完成
-					fmt.Printf("🎯 [Concurrent] Search completed: %d successful, %d failed\n", successfulSearches, failedSearches)
-					goto searchComplete
+			case result := <-searchChan:
+				// 发送结果到通道
+				resultChan <- searchResult{
+					index: index,
+					query: query,
+					sc:    result.sc,
+					err:   result.err,
 				}
-
-				if result.err != nil {
-					fmt.Printf("❌ [Concurrent] Query %d failed: %v\n", result.index+1, result.err)
-					failedSearches++
-					continue
+			case <-ctx.Done():
+				fmt.Printf("⏰ [Concurrent] Query %d timed out after %v\n", index+1, timeout)
+				resultChan <- searchResult{
+					index:   index,
+					query:   query,
+					err:     fmt.Errorf("search timeout after %v", timeout),
+					timeout: true,
 				}
+			}
+		}(i, q)
+	}
 
-				if strings.TrimSpace(result.ctx) == "" {
-					fmt.Printf("⚠️ [Concurrent] Query %d returned empty context\n", result.index+1)
-					failedSearches++
-					continue
-				}
+	// 等待所有搜索完成，带整体超时
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(resultChan)
+		close(done)
+	}()
+
+	// 设置整体超时
+	overallTimeout := time.Duration(a.handler.config.SearchOverallTimeoutSec) * time.Second
+	if overallTimeout <= 0 {
+		overallTimeout = 10 * time.Second // 默认整体超时时间
+	}
 
-				fmt.Printf("✅ [Concurrent] Query %d context length: %d chars\n", result.index+1, len(result.ctx))
-				fmt.Printf("📄 [Concurrent] Query %d context preview: %s...\n", result.index+1, result.ctx[:min(200, len(result.ctx))])
+	fmt.Printf("⏱️ [Concurrent] Overall timeout: %v\n", overallTimeout)
+
+	// 收集结果
+	// 按查询顺序保存来源，保证编号稳定
+	sourcesByQuery := make([][]utils.WebSource, maxQ)
+	var successfulSearches int
+	var failedSearches int
+	var timeoutOccurred bool
+
+	// 使用 select 等待结果或超时
+	for {
+		select {
+		case result, ok := <-resultChan:
+			if !ok {
+				// 所有搜索完成
+				fmt.Printf("🎯 [Concurrent] Search completed: %d successful, %d failed\n", successfulSearches, failedSearches)
+				goto searchComplete
+			}
 
-				// 确保 ctx 是有效的 JSON 字符串
-				var ctxJSON interface{}
-				if err := json.Unmarshal([]byte(result.ctx), &ctxJSON); err != nil {
-					fmt.Printf("⚠️ [Concurrent] Query %d ctx is not valid JSON, using fallback: %v\n", result.index+1, err)
-					ctxParts = append(ctxParts, fmt.Sprintf("{\"query\": %q, \"sources\": \"搜索失败，无法获取内容\"}", result.query))
-				} else {
-					ctxParts = append(ctxParts, fmt.Sprintf("{\"query\": %q, \"sources\": %s}", result.query, result.ctx))
-				}
-				successfulSearches++
+			if result.err != nil {
+				fmt.Printf("❌ [Concurrent] Query %d failed: %v\n", result.index+1, result.err)
+				failedSearches++
+				plan.queryFailed(result.index, result.timeout)
+				continue
+			}
 
-			case <-time.After(overallTimeout):
-				fmt.Printf("⏰ [Concurrent] Overall search timeout after %v\n", overallTimeout)
-				timeoutOccurred = true
-				goto searchComplete
+			if result.sc == nil || len(result.sc.Sources) == 0 {
+				fmt.Printf("⚠️ [Concurrent] Query %d returned empty context\n", result.index+1)
+				failedSearches++
+				plan.queryFailed(result.index, false)
+				continue
 			}
-		}
 
-	searchComplete:
-		if timeoutOccurred {
-			fmt.Printf("⚠️ [Concurrent] Search terminated due to timeout: %d successful, %d failed\n", successfulSearches, failedSearches)
-		} else {
-			fmt.Printf("🎯 [Concurrent] Search completed: %d successful, %d failed\n", successfulSearches, failedSearches)
+			fmt.Printf("✅ [Concurrent] Query %d: %d sources from %s (cached=%t)\n",
+				result.index+1, len(result.sc.Sources), result.sc.Provider, result.sc.Cached)
+			sourcesByQuery[result.index] = result.sc.Sources
+			successfulSearches++
+			plan.queryDone(result.index, result.sc.Provider, len(result.sc.Sources), result.sc.Cached)
+
+		case <-time.After(overallTimeout):
+			fmt.Printf("⏰ [Concurrent] Overall search timeout after %v\n", overallTimeout)
+			timeoutOccurred = true
+			goto searchComplete
 		}
-		fmt.Println("[Second Stage] built contexts:", len(ctxParts))
+	}
+
+searchComplete:
+	if timeoutOccurred {
+		fmt.Printf("⚠️ [Concurrent] Search terminated due to timeout: %d successful, %d failed\n", successfulSearches, failedSearches)
+	} else {
+		fmt.Printf("🎯 [Concurrent] Search completed: %d successful, %d failed\n", successfulSearches, failedSearches)
+	}
+	var webSources []utils.WebSource
+	for _, items := range sourcesByQuery {
+		webSources = append(webSources, items...)
+	}
+	fmt.Println("[Second Stage] built sources:", len(webSources))
+	// 多个查询可能返回相同链接，去重后按相关度挑选片段，控制在 token 预算内
+	webSources = utils.DedupeSources(webSources)
+	webSources = utils.SelectSourceContent(context.Background(), webSources, utils.SelectOptions{
+		Queries:     append([]string{a.info.qParsed}, queries...),
+		TokenBudget: a.handler.config.SearchContextTokens,
+		Embedder:    a.handler.embedder,
+	})
+	fmt.Println("[Second Stage] selected sources:", len(webSources))
+	// 知识库片段排在网页来源之前
+	webSources = append(append([]utils.WebSource{}, kbSources...), webSources...)
+	plan.answering(len(webSources))
+	return a.sourcesAnswer(history, webSources, plan)
+}
+
+// sourcesAnswer 基于编号后的资料生成带引用的回答，回答卡片替换检索过程卡片
+func (a *ActionInfo) sourcesAnswer(history []openai.Messages, webSources []utils.WebSource, plan *searchPlan) bool {
+	// 为来源编号，要求模型按编号标注引用
+	sources := utils.NumberSources(webSources)
+	webSystem := openai.Messages{Role: "system", Content: "你是一个智能助手。请根据提供的检索资料回答用户问题。如果检索资料不足，请基于你的知识尽力回答。请提供准确、有用的信息。"}
+	var userWithCtx openai.Messages
+	if len(sources) == 0 {
+		// 容错处理：即使没有搜索上下文，也继续向 ChatGPT 提问，让它基于自己的知识回答
+		fmt.Printf("⚠️ [Second Stage] No successful searches, but continuing with ChatGPT anyway\n")
+		userWithCtx = openai.Messages{Role: "user", Content: fmt.Sprintf("用户问题：%s\n检索资料：搜索失败，请基于现有知识回答", a.info.qParsed)}
+	} else {
+		fmt.Printf("✅ [Second Stage] Using %d sources\n", len(sources))
+		webSystem.Content += utils.CitationInstruction
+		userWithCtx = openai.Messages{Role: "user", Content: fmt.Sprintf("用户问题：%s\n检索资料：\n%s", a.info.qParsed, utils.FormatSourcesPrompt(sources))}
+	}
+	secondMsgs := append(history, webSystem)
+	secondMsgs = append(secondMsgs, userWithCtx)
+
+	// 调试信息
+	fmt.Printf("    📋 [Second Stage] Messages count: %d\n", len(secondMsgs))
+	fmt.Printf("    📋 [Second Stage] User question: %s\n", a.info.qParsed)
+	fmt.Printf("    📋 [Second Stage] Context length: %d chars\n", len(userWithCtx.Content))
+
+	// 使用 ChatGPT 建议的 max_tokens
+	maxTokens := 10000
+	fmt.Printf("    🎯 Using ChatGPT suggested max_tokens: %d\n", maxTokens)
 
-		// 容错处理：即使部分搜索失败，只要有成功的就继续
-		if len(ctxParts) == 0 {
-			fmt.Printf("⚠️ [Second Stage] No successful searches, but continuing with ChatGPT anyway\n")
-			// 即使没有搜索上下文，也继续向 ChatGPT 提问，让它基于自己的知识回答
-			ctxParts = []string{"{\"query\": \"用户问题\", \"sources\": \"基于现有知识回答\"}"}
+	finalResp, err := a.answerCompletions(secondMsgs, maxTokens)
+	if err != nil {
+		fmt.Printf("    ❌ Second stage OpenAI call failed: %v\n", err)
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+		return false
+	}
+
+	fmt.Printf("    ✅ Second stage OpenAI call successful\n")
+	fmt.Printf("    📄 Response content length: %d\n", len(finalResp.Content))
+	fmt.Printf("    📄 Response content: %s\n", finalResp.Content)
+
+	// 检查响应是否为通用"无法回答"消息
+	responseText := strings.TrimSpace(finalResp.Content)
+	if strings.Contains(responseText, "抱歉，我暂时无法回答") ||
+		strings.Contains(responseText, "无法回答您的问题") ||
+		strings.Contains(responseText, "问题过于复杂") {
+		fmt.Printf("    ⚠️ Second stage returned generic 'cannot answer' response, trying simplified approach...\n")
+
+		// 尝试简化的请求
+		simpleMsg := openai.Messages{Role: "user", Content: a.info.qParsed}
+		simpleMsgs := append(history, simpleMsg)
+
+		finalResp, err = a.answerCompletions(simpleMsgs, 1500)
+		if err != nil {
+			fmt.Printf("    ❌ Simplified retry also failed: %v\n", err)
 		} else {
-			fmt.Printf("✅ [Second Stage] Using %d successful search results, ignoring %d failed searches\n", len(ctxParts), failedSearches)
+			fmt.Printf("    ✅ Simplified retry successful\n")
+			fmt.Printf("    📄 Simplified response: %s\n", finalResp.Content)
 		}
-		// 组合检索上下文为 JSON 数组字符串
-		contextJSON := "[" + strings.Join(ctxParts, ",") + "]"
-		fmt.Printf("[Second Stage] Final context JSON length: %d chars\n", len(contextJSON))
-		fmt.Printf("[Second Stage] Final context JSON preview: %s...\n", contextJSON[:min(500, len(contextJSON))])
-
-		// 验证 JSON 格式
-		var jsonTest interface{}
-		if err := json.Unmarshal([]byte(contextJSON), &jsonTest); err != nil {
-			fmt.Printf("❌ [Second Stage] Invalid JSON format: %v\n", err)
-			fmt.Printf("❌ [Second Stage] Raw contextJSON: %s\n", contextJSON)
-			// 使用安全的默认值
-			contextJSON = "[{\"query\": \"用户问题\", \"sources\": \"基于现有知识回答\"}]"
-			fmt.Printf("✅ [Second Stage] Using safe fallback JSON: %s\n", contextJSON)
-		} else {
-			fmt.Printf("✅ [Second Stage] JSON format is valid\n")
+	}
+
+	// 检查响应是否为空，如果为空则重试
+	if strings.TrimSpace(finalResp.Content) == "" {
+		fmt.Printf("    ⚠️ Second stage response is empty, retrying with higher max_tokens...\n")
+		maxTokens = maxTokens * 2
+		if maxTokens > 4000 {
+			maxTokens = 4000
 		}
-		// 构建二次提问消息，携带检索资料
-		webSystem := openai.Messages{Role: "system", Content: "你是一个智能助手。请根据提供的检索资料回答用户问题。如果检索资料不足，请基于你的知识尽力回答。请提供准确、有用的信息。"}
-		userWithCtx := openai.Messages{Role: "user", Content: fmt.Sprintf("用户问题：%s\n检索资料(JSON)：%s", a.info.qParsed, contextJSON)}
-		secondMsgs := append(history, webSystem)
-		secondMsgs = append(secondMsgs, userWithCtx)
-
-		// 调试信息
-		fmt.Printf("    📋 [Second Stage] Messages count: %d\n", len(secondMsgs))
-		fmt.Printf("    📋 [Second Stage] User question: %s\n", a.info.qParsed)
-		fmt.Printf("    📋 [Second Stage] Context JSON length: %d chars\n", len(contextJSON))
-
-		// 使用 ChatGPT 建议的 max_tokens
-		maxTokens := 10000
-		fmt.Printf("    🎯 Using ChatGPT suggested max_tokens: %d\n", maxTokens)
-
-		finalResp, err := a.handler.gpt.CompletionsWithMaxTokens(secondMsgs, maxTokens)
+		fmt.Printf("    🔄 Retrying with max_tokens: %d\n", maxTokens)
+
+		finalResp, err = a.answerCompletions(secondMsgs, maxTokens)
 		if err != nil {
-			fmt.Printf("    ❌ Second stage OpenAI call failed: %v\n", err)
-			replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+			fmt.Printf("    ❌ Retry failed: %v\n", err)
+			replyMsg(*a.ctx, "🤖️：抱歉，我无法生成有效的回答，请稍后再试。", a.info.msgId)
 			return false
 		}
 
-		fmt.Printf("    ✅ Second stage OpenAI call successful\n")
-		fmt.Printf("    📄 Response content length: %d\n", len(finalResp.Content))
-		fmt.Printf("    📄 Response content: %s\n", finalResp.Content)
-
-		// 检查响应是否为通用"无法回答"消息
-		responseText := strings.TrimSpace(finalResp.Content)
-		if strings.Contains(responseText, "抱歉，我暂时无法回答") ||
-			strings.Contains(responseText, "无法回答您的问题") ||
-			strings.Contains(responseText, "问题过于复杂") {
-			fmt.Printf("    ⚠️ Second stage returned generic 'cannot answer' response, trying simplified approach...\n")
+		if strings.TrimSpace(finalResp.Content) == "" {
+			fmt.Printf("    ❌ Retry also returned empty response, trying fallback approach...\n")
 
-			// 尝试简化的请求
-			simpleMsg := openai.Messages{Role: "user", Content: a.info.qParsed}
-			simpleMsgs := append(history, simpleMsg)
+			// 尝试使用更简单的提示词和更高的 max_tokens
+			simpleSystem := openai.Messages{Role: "system", Content: "你是一个友好的助手。请简洁地回答用户的问题。"}
+			simpleUser := openai.Messages{Role: "user", Content: a.info.qParsed}
+			simpleMsgs := []openai.Messages{simpleSystem, simpleUser}
 
-			finalResp, err = a.handler.gpt.CompletionsWithMaxTokens(simpleMsgs, 1500)
-			if err != nil {
-				fmt.Printf("    ❌ Simplified retry also failed: %v\n", err)
-			} else {
-				fmt.Printf("    ✅ Simplified retry successful\n")
-				fmt.Printf("    📄 Simplified response: %s\n", finalResp.Content)
-			}
-		}
-
-		// 检查响应是否为空，如果为空则重试
-		if strings.TrimSpace(finalResp.Content) == "" {
-			fmt.Printf("    ⚠️ Second stage response is empty, retrying with higher max_tokens...\n")
-			maxTokens = maxTokens * 2
-			if maxTokens > 4000 {
-				maxTokens = 4000
-			}
-			fmt.Printf("    🔄 Retrying with max_tokens: %d\n", maxTokens)
+			fmt.Printf("    🔄 Trying simple approach with max_tokens: 2000\n")
+			finalResp, err = a.answerCompletions(simpleMsgs, 2000)
 
-			finalResp, err = a.handler.gpt.CompletionsWithMaxTokens(secondMsgs, maxTokens)
 			if err != nil {
-				fmt.Printf("    ❌ Retry failed: %v\n", err)
-				replyMsg(*a.ctx, "🤖️：抱歉，我无法生成有效的回答，请稍后再试。", a.info.msgId)
+				fmt.Printf("    ❌ Simple approach also failed: %v\n", err)
+				replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。请稍后再试或尝试重新表述您的问题。", a.info.msgId)
 				return false
 			}
 
 			if strings.TrimSpace(finalResp.Content) == "" {
-				fmt.Printf("    ❌ Retry also returned empty response, trying fallback approach...\n")
+				fmt.Printf("    ❌ Simple approach also returned empty response\n")
+				replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。这可能是因为问题过于复杂或需要更多上下文信息。请尝试重新表述您的问题。", a.info.msgId)
+				return false
+			}
 
-				// 尝试使用更简单的提示词和更高的 max_tokens
-				simpleSystem := openai.Messages{Role: "system", Content: "你是一个友好的助手。请简洁地回答用户的问题。"}
-				simpleUser := openai.Messages{Role: "user", Content: a.info.qParsed}
-				simpleMsgs := []openai.Messages{simpleSystem, simpleUser}
+			fmt.Printf("    ✅ Simple approach successful, got response: %s\n", finalResp.Content[:min(100, len(finalResp.Content))])
+		} else {
+			fmt.Printf("    ✅ Retry successful, got response: %s\n", finalResp.Content[:min(100, len(finalResp.Content))])
+		}
+	}
+	// 去掉不存在的来源编号
+	answer, cited := utils.CleanCitations(finalResp.Content, len(sources))
+	fmt.Printf("    📚 Cited sources: %v\n", cited)
+	finalHistory := append(history, openai.Messages{Role: "user", Content: a.info.qParsed})
+	finalHistory = append(finalHistory, openai.Messages{Role: "assistant", Content: answer})
+	a.handler.sessionCache.SetMsg(*a.info.sessionId, finalHistory)
+	defer a.replyVoice(utils.StripCitations(answer))
+	fmt.Printf("    📤 Sending response to user...\n")
+	layout := answerLayout{
+		newTopic: isNewTopic(finalHistory),
+		sources:  sources,
+		cited:    cited,
+		actions:  a.answerActions(answer, finalResp.Truncated()),
+	}
+	if plan.enabled {
+		layout.planNote = plan.summary()
+	}
+	if err := a.deliverAnswer(answer, layout, plan.finish); err != nil {
+		fmt.Printf("    ❌ Failed to send response: %v\n", err)
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+		return false
+	}
+	fmt.Printf("    ✅ Response sent successfully\n")
+	return true
+}
 
-				fmt.Printf("    🔄 Trying simple approach with max_tokens: 2000\n")
-				finalResp, err = a.handler.gpt.CompletionsWithMaxTokens(simpleMsgs, 2000)
+// knowledgeAnswer 只基于知识库片段回答，不联网
+func (a *ActionInfo) knowledgeAnswer(history []openai.Messages, kbSources []utils.WebSource) bool {
+	plan := newSearchPlan(*a.ctx, a.info.msgId, nil, false)
+	return a.sourcesAnswer(history, kbSources, plan)
+}
 
-				if err != nil {
-					fmt.Printf("    ❌ Simple approach also failed: %v\n", err)
-					replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。请稍后再试或尝试重新表述您的问题。", a.info.msgId)
-					return false
-				}
+// directAnswer 不联网，直接请求一次模型回答，maxTokens<=0 时使用默认值
+func (a *ActionInfo) directAnswer(history []openai.Messages, maxTokens int) bool {
+	msg := append(history, openai.Messages{Role: "user", Content: a.info.qParsed})
 
-				if strings.TrimSpace(finalResp.Content) == "" {
-					fmt.Printf("    ❌ Simple approach also returned empty response\n")
-					replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。这可能是因为问题过于复杂或需要更多上下文信息。请尝试重新表述您的问题。", a.info.msgId)
-					return false
-				}
+	// 使用 ChatGPT 建议的 max_tokens
+	if maxTokens <= 0 {
+		maxTokens = 1500 // 默认值
+	}
+	if maxTokens < 100 {
+		maxTokens = 500 // 最小值
+	}
+	if maxTokens > 4000 {
+		maxTokens = 4000 // 限制最大值
+	}
+	fmt.Printf("    🎯 Using ChatGPT suggested max_tokens for fallback: %d\n", maxTokens)
 
-				fmt.Printf("    ✅ Simple approach successful, got response: %s\n", finalResp.Content[:min(100, len(finalResp.Content))])
-			} else {
-				fmt.Printf("    ✅ Retry successful, got response: %s\n", finalResp.Content[:min(100, len(finalResp.Content))])
-			}
-		}
-		finalHistory := append(history, openai.Messages{Role: "user", Content: a.info.qParsed})
-		finalHistory = append(finalHistory, openai.Messages{Role: "assistant", Content: finalResp.Content})
-		a.handler.sessionCache.SetMsg(*a.info.sessionId, finalHistory)
-		if len(finalHistory) == 2 {
-			sendNewTopicCard(*a.ctx, a.info.sessionId, a.info.msgId, finalResp.Content)
-			return false
-		}
-		fmt.Printf("    📤 Sending response to user...\n")
-		if err := replyMsg(*a.ctx, finalResp.Content, a.info.msgId); err != nil {
-			fmt.Printf("    ❌ Failed to send response: %v\n", err)
-			replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
-			return false
-		}
-		fmt.Printf("    ✅ Response sent successfully\n")
-		return true
+	completions, err2 := a.answerCompletions(msg, maxTokens)
+	if err2 != nil {
+		fmt.Printf("    ❌ Fallback OpenAI call failed: %v\n", err2)
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err2), a.info.msgId)
+		return false
 	}
 
-	// NeedWeb == false: directly return final answer from decision.Answer
-	answer := decision.Answer
-	if answer == "" {
-		// Safety fallback: run a normal completion to produce an answer
-		msg := append(history, openai.Messages{Role: "user", Content: a.info.qParsed})
+	fmt.Printf("    ✅ Fallback OpenAI call successful\n")
+	fmt.Printf("    📄 Fallback response content length: %d\n", len(completions.Content))
+	fmt.Printf("    📄 Fallback response content: %s\n", completions.Content)
 
-		// 使用 ChatGPT 建议的 max_tokens
-		maxTokens := decision.MaxTokens
-		if maxTokens <= 0 {
-			maxTokens = 1500 // 默认值
-		}
-		if maxTokens < 100 {
-			maxTokens = 500 // 最小值
-		}
+	// 检查响应是否为空，如果为空则重试
+	if strings.TrimSpace(completions.Content) == "" {
+		fmt.Printf("    ⚠️ Fallback response is empty, retrying with higher max_tokens...\n")
+		maxTokens = maxTokens * 2
 		if maxTokens > 4000 {
-			maxTokens = 4000 // 限制最大值
+			maxTokens = 4000
 		}
-		fmt.Printf("    🎯 Using ChatGPT suggested max_tokens for fallback: %d\n", maxTokens)
+		fmt.Printf("    🔄 Retrying fallback with max_tokens: %d\n", maxTokens)
 
-		completions, err2 := a.handler.gpt.CompletionsWithMaxTokens(msg, maxTokens)
+		completions, err2 = a.answerCompletions(msg, maxTokens)
 		if err2 != nil {
-			fmt.Printf("    ❌ Fallback OpenAI call failed: %v\n", err2)
-			replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err2), a.info.msgId)
+			fmt.Printf("    ❌ Fallback retry failed: %v\n", err2)
+			replyMsg(*a.ctx, "🤖️：抱歉，我无法生成有效的回答，请稍后再试。", a.info.msgId)
 			return false
 		}
 
-		fmt.Printf("    ✅ Fallback OpenAI call successful\n")
-		fmt.Printf("    📄 Fallback response content length: %d\n", len(completions.Content))
-		fmt.Printf("    📄 Fallback response content: %s\n", completions.Content)
-
-		// 检查响应是否为空，如果为空则重试
 		if strings.TrimSpace(completions.Content) == "" {
-			fmt.Printf("    ⚠️ Fallback response is empty, retrying with higher max_tokens...\n")
-			maxTokens = maxTokens * 2
-			if maxTokens > 4000 {
-				maxTokens = 4000
+			fmt.Printf("    ❌ Fallback retry also returned empty response, trying simple approach...\n")
+
+			// 尝试使用最简单的提示词
+			simpleMsgs := []openai.Messages{
+				{Role: "system", Content: "你是一个友好的助手。"},
+				{Role: "user", Content: a.info.qParsed},
 			}
-			fmt.Printf("    🔄 Retrying fallback with max_tokens: %d\n", maxTokens)
 
-			completions, err2 = a.handler.gpt.CompletionsWithMaxTokens(msg, maxTokens)
+			fmt.Printf("    🔄 Trying simple fallback with max_tokens: 2000\n")
+			completions, err2 = a.answerCompletions(simpleMsgs, 2000)
+
 			if err2 != nil {
-				fmt.Printf("    ❌ Fallback retry failed: %v\n", err2)
-				replyMsg(*a.ctx, "🤖️：抱歉，我无法生成有效的回答，请稍后再试。", a.info.msgId)
+				fmt.Printf("    ❌ Simple fallback also failed: %v\n", err2)
+				replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。请稍后再试或尝试重新表述您的问题。", a.info.msgId)
 				return false
 			}
 
 			if strings.TrimSpace(completions.Content) == "" {
-				fmt.Printf("    ❌ Fallback retry also returned empty response, trying simple approach...\n")
-
-				// 尝试使用最简单的提示词
-				simpleMsgs := []openai.Messages{
-					{Role: "system", Content: "你是一个友好的助手。"},
-					{Role: "user", Content: a.info.qParsed},
-				}
-
-				fmt.Printf("    🔄 Trying simple fallback with max_tokens: 2000\n")
-				completions, err2 = a.handler.gpt.CompletionsWithMaxTokens(simpleMsgs, 2000)
-
-				if err2 != nil {
-					fmt.Printf("    ❌ Simple fallback also failed: %v\n", err2)
-					replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。请稍后再试或尝试重新表述您的问题。", a.info.msgId)
-					return false
-				}
-
-				if strings.TrimSpace(completions.Content) == "" {
-					fmt.Printf("    ❌ Simple fallback also returned empty response\n")
-					replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。这可能是因为问题过于复杂或需要更多上下文信息。请尝试重新表述您的问题。", a.info.msgId)
-					return false
-				}
-
-				fmt.Printf("    ✅ Simple fallback successful, got response: %s\n", completions.Content[:min(100, len(completions.Content))])
-			} else {
-				fmt.Printf("    ✅ Fallback retry successful, got response: %s\n", completions.Content[:min(100, len(completions.Content))])
+				fmt.Printf("    ❌ Simple fallback also returned empty response\n")
+				replyMsg(*a.ctx, "🤖️：抱歉，我暂时无法回答您的问题。这可能是因为问题过于复杂或需要更多上下文信息。请尝试重新表述您的问题。", a.info.msgId)
+				return false
 			}
-		}
-		msg = append(msg, completions)
-		a.handler.sessionCache.SetMsg(*a.info.sessionId, msg)
-		if len(msg) == 2 {
-			sendNewTopicCard(*a.ctx, a.info.sessionId, a.info.msgId, completions.Content)
-			return false
-		}
-		if err = replyMsg(*a.ctx, completions.Content, a.info.msgId); err != nil {
-			replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
-			return false
-		}
-		return true
-	}
-	// debug: print direct answer
-	fmt.Println("[OpenAI Direct Answer]:", answer)
 
-	// Append assistant answer to history and reply
-	finalHistory := append(history, openai.Messages{Role: "user", Content: a.info.qParsed})
-	finalHistory = append(finalHistory, openai.Messages{Role: "assistant", Content: answer})
-	a.handler.sessionCache.SetMsg(*a.info.sessionId, finalHistory)
-	if len(finalHistory) == 2 {
-		sendNewTopicCard(*a.ctx, a.info.sessionId, a.info.msgId, answer)
-		return false
+			fmt.Printf("    ✅ Simple fallback successful, got response: %s\n", completions.Content[:min(100, len(completions.Content))])
+		} else {
+			fmt.Printf("    ✅ Fallback retry successful, got response: %s\n", completions.Content[:min(100, len(completions.Content))])
+		}
 	}
-	if err := replyMsg(*a.ctx, answer, a.info.msgId); err != nil {
+	msg = append(msg, completions)
+	a.handler.sessionCache.SetMsg(*a.info.sessionId, msg)
+	defer a.replyVoice(completions.Content)
+	if err := a.replyAnswer(completions.Content, isNewTopic(msg), completions.Truncated()); err != nil {
 		replyMsg(*a.ctx, fmt.Sprintf("🤖️：消息机器人摆烂了，请稍后再试～\n错误信息: %v", err), a.info.msgId)
 		return false
 	}
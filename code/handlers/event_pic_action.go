@@ -4,11 +4,12 @@ import (
 	"context"
 	"fmt"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
-	"os"
+	"io"
 	"start-feishubot/initialization"
 	"start-feishubot/services"
 	"start-feishubot/services/openai"
 	"start-feishubot/utils"
+	"strings"
 )
 
 type PicAction struct { /*图片*/
@@ -17,14 +18,14 @@ type PicAction struct { /*图片*/
 func (*PicAction) Execute(a *ActionInfo) bool {
 	// 开启图片创作模式
 	if _, foundPic := utils.EitherTrimEqual(a.info.qParsed,
-		"/picture", "图片创作"); foundPic {
+		pictureCommand...); foundPic {
 		a.handler.sessionCache.Clear(*a.info.sessionId)
 		a.handler.sessionCache.SetMode(*a.info.sessionId,
 			services.ModePicCreate)
-		a.handler.sessionCache.SetPicResolution(*a.info.sessionId,
-			services.Resolution256)
-		sendPicCreateInstructionCard(*a.ctx, a.info.sessionId,
-			a.info.msgId)
+		a.handler.sessionCache.SetPicSetting(*a.info.sessionId,
+			services.PicSetting{})
+		newCard, _ := a.handler.picModeCard(*a.info.sessionId)
+		sendPicCreateInstructionCard(*a.ctx, a.info.msgId, newCard)
 		return false
 	}
 
@@ -38,38 +39,27 @@ func (*PicAction) Execute(a *ActionInfo) bool {
 	}
 
 	if a.info.msgType == "image" && mode == services.ModePicCreate {
-		//保存图片
-		imageKey := a.info.imageKey
-		//fmt.Printf("fileKey: %s \n", imageKey)
-		msgId := a.info.msgId
-		//fmt.Println("msgId: ", *msgId)
-		req := larkim.NewGetMessageResourceReqBuilder().MessageId(
-			*msgId).FileKey(imageKey).Type("image").Build()
-		resp, err := initialization.GetLarkClient().Im.MessageResource.Get(context.Background(), req)
-		//fmt.Println(resp, err)
+		raw, err := downloadMessageImage(*a.ctx, *a.info.msgId, a.info.imageKey)
 		if err != nil {
-			//fmt.Println(err)
-			fmt.Sprintf("🤖️：图片下载失败，请稍后再试～\n 错误信息: %v", err)
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：图片下载失败，请稍后再试～\n错误信息: %v", err),
+				a.info.msgId)
 			return false
 		}
 
-		f := fmt.Sprintf("%s.png", imageKey)
-		resp.WriteFile(f)
-		defer os.Remove(f)
-		resolution := a.handler.sessionCache.GetPicResolution(*a.
-			info.sessionId)
-
-		openai.ConvertJpegToPNG(f)
-		openai.ConvertToRGBA(f, f)
+		resolution := openai.VariationSize(
+			a.handler.picOptions(*a.info.sessionId).Size)
 
-		//图片校验
-		err = openai.VerifyPngs([]string{f})
+		//图片在内存中转换为 RGBA png 并校验
+		img, err := openai.ConvertToRGBAPng(raw)
+		if err == nil {
+			err = openai.VerifyPngBytes(img)
+		}
 		if err != nil {
 			replyMsg(*a.ctx, fmt.Sprintf("🤖️：无法解析图片，请发送原图并尝试重新操作～"),
 				a.info.msgId)
 			return false
 		}
-		bs64, err := a.handler.gpt.GenerateOneImageVariation(f, resolution)
+		bs64, err := a.handler.gpt.GenerateOneImageVariationBytes(img, resolution)
 		if err != nil {
 			replyMsg(*a.ctx, fmt.Sprintf(
 				"🤖️：图片生成失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
@@ -80,21 +70,239 @@ func (*PicAction) Execute(a *ActionInfo) bool {
 
 	}
 
-	// 生成图片
-	if mode == services.ModePicCreate {
-		resolution := a.handler.sessionCache.GetPicResolution(*a.
-			info.sessionId)
-		bs64, err := a.handler.gpt.GenerateOneImage(a.info.qParsed,
-			resolution)
-		if err != nil {
-			replyMsg(*a.ctx, fmt.Sprintf(
-				"🤖️：图片生成失败，请稍后再试～\n错误信息: %v", err), a.info.msgId)
+	// 图片加文字的富文本消息：按文字说明修改图片，第二张图片作为蒙版
+	// 富文本消息只在图片创作模式下处理，见 acceptPostMessage
+	if a.info.msgType == "post" && len(a.info.imageKeys) > 0 {
+		if a.info.qParsed == "" {
+			replyMsg(*a.ctx, "🤖️：请在图片后附上修改说明，例如“换成星空背景”或“抠图”～",
+				a.info.msgId)
 			return false
 		}
-		replayImageCardByBase64(*a.ctx, bs64, a.info.msgId, a.info.sessionId,
-			a.info.qParsed)
+		edit := picEdit{
+			MsgId:    *a.info.msgId,
+			ImageKey: a.info.imageKeys[0],
+			Prompt:   a.info.qParsed,
+		}
+		if len(a.info.imageKeys) > 1 {
+			edit.MaskKey = a.info.imageKeys[1]
+		}
+		opts := a.handler.picOptions(*a.info.sessionId)
+		a.handler.editPicture(*a.ctx, edit, opts, a.info.sessionId)
+		return false
+	}
+
+	// 生成图片
+	if mode == services.ModePicCreate {
+		a.handler.generatePictures(*a.ctx, a.info.qParsed,
+			a.handler.picOptions(*a.info.sessionId), a.info.msgId, a.info.sessionId)
 		return false
 	}
 
 	return true
 }
+
+// picEdit 一次图片修改请求，图片与蒙版都从原消息中下载，便于“再来一张”重复修改
+type picEdit struct {
+	MsgId    string
+	ImageKey string
+	MaskKey  string
+	Prompt   string
+}
+
+// acceptPostMessage 富文本消息只用于图片创作模式下“图片 + 修改说明”的改图，
+// 其他富文本消息与之前一样忽略
+func acceptPostMessage(imageKeys []string, mode services.SessionMode) bool {
+	return len(imageKeys) > 0 && mode == services.ModePicCreate
+}
+
+// isBackgroundRemoval 判断修改说明是否为“去背景”预设
+func isBackgroundRemoval(prompt string) bool {
+	_, found := utils.EitherTrimEqual(prompt, noBgCommand...)
+	return found
+}
+
+// editPicture 调用 edits 接口修改图片，当前模型不支持时换用支持的模型
+func (m MessageHandler) editPicture(ctx context.Context, edit picEdit,
+	opts openai.ImageOptions, sessionId *string) {
+	prompt := edit.Prompt
+	opts.Model = openai.EditModel(opts.Model, m.config.ImageModels)
+	if isBackgroundRemoval(prompt) {
+		model := openai.BackgroundRemovalModel(opts.Model, m.config.ImageModels)
+		if model == "" {
+			replyMsg(ctx, "🤖️：当前可用的图片模型都不支持透明背景，无法抠图～\n"+
+				"请在 IMAGE_MODELS 中加入 gpt-image-1", &edit.MsgId)
+			return
+		}
+		prompt = openai.BackgroundRemovalPrompt
+		opts.Model = model
+		opts.Background = "transparent"
+	}
+	opts = openai.GetImageModelSpec(opts.Model).Normalize(opts, m.config.ImageMaxN)
+
+	raw, err := downloadMessageImage(ctx, edit.MsgId, edit.ImageKey)
+	if err != nil {
+		replyMsg(ctx, fmt.Sprintf("🤖️：图片下载失败，请稍后再试～\n错误信息: %v", err),
+			&edit.MsgId)
+		return
+	}
+	img, err := openai.ConvertToRGBAPng(raw)
+	if err == nil && opts.Model == "dall-e-2" {
+		err = openai.VerifyPngBytes(img)
+	}
+	if err != nil {
+		replyMsg(ctx, fmt.Sprintf("🤖️：无法解析图片，%s 需要 4MB 以内的正方形图片～\n错误信息: %v",
+			opts.Model, err), &edit.MsgId)
+		return
+	}
+
+	var mask []byte
+	if edit.MaskKey != "" {
+		rawMask, err := downloadMessageImage(ctx, edit.MsgId, edit.MaskKey)
+		if err == nil {
+			mask, err = openai.BuildEditMask(img, rawMask)
+		}
+		if err != nil {
+			replyMsg(ctx, fmt.Sprintf("🤖️：蒙版图片处理失败，请稍后再试～\n错误信息: %v", err),
+				&edit.MsgId)
+			return
+		}
+	}
+
+	fmt.Printf("🎨 Editing image %s with %s, mask=%t\n", edit.ImageKey, opts.Model, mask != nil)
+	bs64s, err := m.gpt.EditImages(img, mask, prompt, opts)
+	if err != nil {
+		replyMsg(ctx, fmt.Sprintf(
+			"🤖️：图片修改失败，请稍后再试～\n错误信息: %v", err), &edit.MsgId)
+		return
+	}
+	var imageKeys []string
+	for _, bs64 := range bs64s {
+		imageKey, err := uploadImage(bs64)
+		if err != nil || imageKey == nil {
+			fmt.Printf("❌ Upload edited image failed: %v\n", err)
+			continue
+		}
+		imageKeys = append(imageKeys, *imageKey)
+	}
+	if len(imageKeys) == 0 {
+		replyMsg(ctx, "🤖️：图片上传失败，请稍后再试～", &edit.MsgId)
+		return
+	}
+	sendImageEditCard(ctx, imageKeys, edit, sessionId)
+}
+
+// downloadMessageImage 下载消息中的图片资源
+func downloadMessageImage(ctx context.Context, msgId string, imageKey string) ([]byte, error) {
+	req := larkim.NewGetMessageResourceReqBuilder().MessageId(
+		msgId).FileKey(imageKey).Type("image").Build()
+	resp, err := initialization.GetLarkClient().Im.MessageResource.Get(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success() {
+		return nil, fmt.Errorf("%d %s", resp.Code, resp.Msg)
+	}
+	return io.ReadAll(resp.File)
+}
+
+// picOptions 当前话题的图片参数，未选择模型时使用配置的默认模型
+func (m MessageHandler) picOptions(sessionId string) openai.ImageOptions {
+	return m.normalizePicSetting(m.sessionCache.GetPicSetting(sessionId))
+}
+
+func (m MessageHandler) normalizePicSetting(setting services.PicSetting) openai.ImageOptions {
+	opts := setting.ImageOptions()
+	if opts.Model == "" {
+		opts.Model = m.config.ImageModel
+	}
+	return openai.GetImageModelSpec(opts.Model).Normalize(opts, m.config.ImageMaxN)
+}
+
+// picModeCard 按当前话题的图片设置生成图片创作模式卡片
+func (m MessageHandler) picModeCard(sessionId string) (string, error) {
+	setting := m.sessionCache.GetPicSetting(sessionId)
+	return newPicModeCard(sessionId, m.normalizePicSetting(setting),
+		setting.Preset, m.picEnhance(setting), m.config.ImageModels,
+		m.config.ImageMaxN)
+}
+
+// picEnhance 话题未单独设置时沿用全局配置
+func (m MessageHandler) picEnhance(setting services.PicSetting) bool {
+	if setting.Enhance != nil {
+		return *setting.Enhance
+	}
+	return m.config.ImagePromptEnhance
+}
+
+// picPresetNone 风格预设菜单中“不使用预设”的选项
+const picPresetNone = "不使用"
+
+const picEnhanceSystemPrompt = "You are a prompt engineer for image generation models. " +
+	"Rewrite the user's idea into one detailed English prompt describing subject, " +
+	"composition, lighting, colors and style. Keep every requirement the user gave, " +
+	"do not add text or watermarks. Reply with the prompt only."
+
+// buildPicPrompt 追加风格预设并按设置扩写提示词，扩写失败时使用原提示词
+func (m MessageHandler) buildPicPrompt(prompt string, setting services.PicSetting) string {
+	if style := initialization.GetPicStyleByTitle(setting.Preset); style != nil {
+		prompt = applyPicStyle(prompt, style.Content)
+	}
+	if !m.picEnhance(setting) {
+		return prompt
+	}
+	resp, err := m.gpt.Completions([]openai.Messages{
+		{Role: "system", Content: picEnhanceSystemPrompt},
+		{Role: "user", Content: prompt},
+	})
+	if err != nil {
+		fmt.Printf("⚠️ Prompt enhancement failed, using original prompt: %v\n", err)
+		return prompt
+	}
+	if enhanced := cleanEnhancedPrompt(resp.Content); enhanced != "" {
+		return enhanced
+	}
+	return prompt
+}
+
+func applyPicStyle(prompt string, style string) string {
+	if style == "" {
+		return prompt
+	}
+	return strings.TrimRight(strings.TrimSpace(prompt), "，,。.") + ", " + style
+}
+
+// cleanEnhancedPrompt 去掉模型回复中常见的前缀和引号
+func cleanEnhancedPrompt(content string) string {
+	content = strings.TrimSpace(content)
+	for _, prefix := range []string{"Prompt:", "prompt:", "提示词：", "提示词:"} {
+		content = strings.TrimSpace(strings.TrimPrefix(content, prefix))
+	}
+	return strings.TrimSpace(strings.Trim(content, "\"'`“”"))
+}
+
+// generatePictures 生成图片并以画廊卡片回复，question 为用户原始描述
+func (m MessageHandler) generatePictures(ctx context.Context, question string,
+	opts openai.ImageOptions, msgId *string, sessionId *string) {
+	prompt := m.buildPicPrompt(question, m.sessionCache.GetPicSetting(*sessionId))
+	fmt.Printf("🎨 Generating %d image(s) with %s %s\n", opts.N, opts.Model, opts.Size)
+	bs64s, err := m.gpt.GenerateImages(prompt, opts)
+	if err != nil {
+		replyMsg(ctx, fmt.Sprintf(
+			"🤖️：图片生成失败，请稍后再试～\n错误信息: %v", err), msgId)
+		return
+	}
+	var imageKeys []string
+	for _, bs64 := range bs64s {
+		imageKey, err := uploadImage(bs64)
+		if err != nil || imageKey == nil {
+			fmt.Printf("❌ Upload generated image failed: %v\n", err)
+			continue
+		}
+		imageKeys = append(imageKeys, *imageKey)
+	}
+	if len(imageKeys) == 0 {
+		replyMsg(ctx, "🤖️：图片上传失败，请稍后再试～", msgId)
+		return
+	}
+	sendImageGalleryCard(ctx, imageKeys, msgId, sessionId, question, prompt)
+}
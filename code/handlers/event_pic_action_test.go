@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"start-feishubot/services"
+	"testing"
+)
+
+func TestApplyPicStyle(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+		style  string
+		want   string
+	}{
+		{"no style", "一只猫", "", "一只猫"},
+		{"style appended", "一只猫", "watercolor painting", "一只猫, watercolor painting"},
+		{"trailing punctuation trimmed", " a red fox. ", "flat illustration", "a red fox, flat illustration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := applyPicStyle(tt.prompt, tt.style); got != tt.want {
+				t.Errorf("applyPicStyle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanEnhancedPrompt(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"plain", "A cat on the moon", "A cat on the moon"},
+		{"prefix and quotes", "Prompt: \"A cat on the moon\"\n", "A cat on the moon"},
+		{"chinese quotes", "提示词：“月亮上的猫”", "月亮上的猫"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanEnhancedPrompt(tt.content); got != tt.want {
+				t.Errorf("cleanEnhancedPrompt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptPostMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		imageKeys []string
+		mode      services.SessionMode
+		want      bool
+	}{
+		{"image in pic mode", []string{"img_1"}, services.ModePicCreate, true},
+		{"image outside pic mode", []string{"img_1"}, services.ModeGPT, false},
+		{"text only in pic mode", nil, services.ModePicCreate, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acceptPostMessage(tt.imageKeys, tt.mode); got != tt.want {
+				t.Errorf("acceptPostMessage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
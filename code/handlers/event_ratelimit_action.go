@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"start-feishubot/initialization"
+	"start-feishubot/services/ratelimit"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// 同一用户的限流提醒 30 秒内只发一次，避免刷屏
+var rateLimitNotified = cache.New(30*time.Second, time.Minute)
+
+func newRateLimiter(config initialization.Config) *ratelimit.Limiter {
+	if !config.RateLimitEnabled {
+		return nil
+	}
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if config.RateLimitBackend == "redis" {
+		if client := initialization.GetRedisClient(); client != nil {
+			store = ratelimit.NewRedisStore(client, "feishubot:ratelimit:")
+		} else {
+			fmt.Println("⚠️ RATE_LIMIT_BACKEND=redis but REDIS_ADDR is empty, falling back to memory")
+		}
+	}
+	return ratelimit.NewLimiter(store,
+		ratelimit.Limit{PerMinute: config.RateLimitUserPerMin, Burst: config.RateLimitUserBurst},
+		ratelimit.Limit{PerMinute: config.RateLimitChatPerMin, Burst: config.RateLimitChatBurst})
+}
+
+func newCoalescer(config initialization.Config) *ratelimit.Coalescer {
+	if !config.RateLimitEnabled || !config.RateLimitCoalesce {
+		return nil
+	}
+	return ratelimit.NewCoalescer(time.Duration(config.RateLimitCoalesceMs) * time.Millisecond)
+}
+
+type RateLimitAction struct { /*限流与防刷*/
+}
+
+func (*RateLimitAction) Execute(a *ActionInfo) bool {
+	var chatId string
+	if a.info.chatId != nil {
+		chatId = *a.info.chatId
+	}
+	userKey := a.info.openId
+	if userKey == "" {
+		userKey = chatId
+	}
+
+	// 合并连续发送的普通文本，命令不参与合并。
+	// 窗口结束后由定时器带着合并后的文本从本 action 起继续执行责任链，不阻塞事件处理
+	if a.handler.coalescer != nil && !a.info.coalesced && a.info.msgType == "text" &&
+		a.info.qParsed != "" && !isChainCommand(a.info.qParsed) {
+		key := chatId + ":" + userKey
+		if *a.info.sessionId != *a.info.msgId {
+			key += ":" + *a.info.sessionId
+		}
+		pending := a.pending
+		leader := a.handler.coalescer.Join(key, a.info.qParsed, func(merged string) {
+			if merged != a.info.qParsed {
+				fmt.Printf("    🧲 RateLimitAction: coalesced messages into one request (%d chars)\n", len(merged))
+				a.info.qParsed = merged
+			}
+			a.info.coalesced = true
+			chain(a, pending...)
+			fmt.Println("✅ Coalesced action chain completed")
+		})
+		if leader {
+			fmt.Printf("    🧲 RateLimitAction: waiting for following messages of %s\n", key)
+		} else {
+			fmt.Printf("    🧲 RateLimitAction: message merged into pending request of %s\n", key)
+		}
+		return false
+	}
+
+	if a.handler.limiter == nil {
+		return true
+	}
+	groupChatId := ""
+	if a.info.handlerType == GroupHandler {
+		groupChatId = chatId
+	}
+	allowed, wait := a.handler.limiter.Allow(userKey, groupChatId)
+	if allowed {
+		return true
+	}
+	fmt.Printf("    🚦 RateLimitAction: rate limited user=%s chat=%s, retry after %v\n",
+		userKey, groupChatId, wait)
+	if _, notified := rateLimitNotified.Get(userKey); !notified {
+		rateLimitNotified.SetDefault(userKey, true)
+		seconds := int(wait.Seconds() + 0.999)
+		if seconds < 1 {
+			seconds = 1
+		}
+		replyMsg(*a.ctx, fmt.Sprintf("🤖️：你发得太快啦，请 %d 秒后再试～", seconds), a.info.msgId)
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+type searchQueryStatus int
+
+const (
+	searchPending searchQueryStatus = iota
+	searchDone
+	searchTimeout
+	searchFailed
+)
+
+type searchQueryState struct {
+	query    string
+	status   searchQueryStatus
+	provider string
+	sources  int
+	cached   bool
+}
+
+// searchPlan 在一张卡片上展示联网搜索的关键词和进度，回答完成后卡片替换为最终回答；
+// 群设置隐藏检索过程时不发送卡片，最终回答直接回复
+type searchPlan struct {
+	ctx     context.Context
+	msgId   *string
+	cardId  *string
+	enabled bool
+	queries []searchQueryState
+	stage   string
+	sources int
+	closed  bool
+}
+
+func newSearchPlan(ctx context.Context, msgId *string, queries []string, enabled bool) *searchPlan {
+	p := &searchPlan{ctx: ctx, msgId: msgId, enabled: enabled, stage: "正在搜索…"}
+	for _, q := range queries {
+		p.queries = append(p.queries, searchQueryState{query: q})
+	}
+	return p
+}
+
+func (p *searchPlan) start() {
+	p.show()
+}
+
+// queryDone 记录单个查询的结果，index 为 queries 中的下标
+func (p *searchPlan) queryDone(index int, provider string, sources int, cached bool) {
+	if index < 0 || index >= len(p.queries) {
+		return
+	}
+	p.queries[index] = searchQueryState{query: p.queries[index].query, status: searchDone,
+		provider: provider, sources: sources, cached: cached}
+	p.show()
+}
+
+func (p *searchPlan) queryFailed(index int, timeout bool) {
+	if index < 0 || index >= len(p.queries) {
+		return
+	}
+	p.queries[index].status = searchFailed
+	if timeout {
+		p.queries[index].status = searchTimeout
+	}
+	p.show()
+}
+
+// answering 搜索结束，未返回的查询视为超时
+func (p *searchPlan) answering(sources int) {
+	for i := range p.queries {
+		if p.queries[i].status == searchPending {
+			p.queries[i].status = searchTimeout
+		}
+	}
+	p.sources = sources
+	if sources > 0 {
+		p.stage = fmt.Sprintf("已选取 %d 个来源，正在生成回答…", sources)
+	} else {
+		p.stage = "未获取到可用资料，将基于已有知识回答…"
+	}
+	p.show()
+}
+
+// finish 用最终回答替换检索过程卡片，卡片未发送时直接回复
+func (p *searchPlan) finish(answerCard string) error {
+	p.closed = true
+	if p.cardId != nil {
+		if err := patchCard(p.ctx, p.cardId, answerCard); err == nil {
+			return nil
+		}
+	}
+	return replyCard(p.ctx, p.msgId, answerCard)
+}
+
+// close 回答失败时把卡片收起为检索摘要，避免一直显示“正在搜索”
+func (p *searchPlan) close() {
+	if p.closed || p.cardId == nil {
+		return
+	}
+	p.closed = true
+	p.stage = "检索已结束"
+	p.show()
+}
+
+// summary 回答卡片底部的检索摘要
+func (p *searchPlan) summary() string {
+	var providers []string
+	seen := map[string]bool{}
+	for _, q := range p.queries {
+		name := q.provider
+		if q.cached {
+			name = "缓存"
+		}
+		if q.status == searchDone && name != "" && !seen[name] {
+			seen[name] = true
+			providers = append(providers, name)
+		}
+	}
+	note := fmt.Sprintf("🔎 搜索了 %d 个关键词", len(p.queries))
+	if len(providers) > 0 {
+		note += " · " + strings.Join(providers, "、")
+	}
+	return note + fmt.Sprintf(" · 使用 %d 个来源", p.sources)
+}
+
+func (p *searchPlan) show() {
+	if !p.enabled || len(p.queries) == 0 {
+		return
+	}
+	card := newSearchPlanCard(p.queries, p.stage)
+	if p.cardId == nil {
+		cardId, err := replyCardWithId(p.ctx, p.msgId, card)
+		if err != nil {
+			fmt.Printf("    ⚠️ Send search plan card failed: %v\n", err)
+			p.enabled = false
+			return
+		}
+		p.cardId = cardId
+		return
+	}
+	if err := patchCard(p.ctx, p.cardId, card); err != nil {
+		fmt.Printf("    ⚠️ Update search plan card failed: %v\n", err)
+	}
+}
+
+func newSearchPlanCard(queries []searchQueryState, stage string) string {
+	lines := []string{"**正在搜索**"}
+	for _, q := range queries {
+		switch q.status {
+		case searchDone:
+			provider := q.provider
+			if q.cached {
+				provider = "缓存"
+			}
+			lines = append(lines, fmt.Sprintf("✅ %s · %s · %d 个结果", q.query, provider, q.sources))
+		case searchTimeout:
+			lines = append(lines, fmt.Sprintf("⏰ %s · 超时", q.query))
+		case searchFailed:
+			lines = append(lines, fmt.Sprintf("❌ %s · 未找到可用结果", q.query))
+		default:
+			lines = append(lines, "⏳ "+q.query)
+		}
+	}
+	return newUpdatableCard(withHeader("🔎 联网搜索", larkcard.TemplateBlue),
+		withMainMd(strings.Join(lines, "\n")),
+		withNote(stage))
+}
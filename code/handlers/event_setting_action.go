@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"start-feishubot/initialization"
+	"start-feishubot/services/accesscontrol"
+	"start-feishubot/utils"
+	"time"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+	"github.com/patrickmn/go-cache"
+)
+
+// 群主信息缓存，避免每次打开设置都查询群信息
+var chatOwnerCache = cache.New(10*time.Minute, 30*time.Minute)
+
+type SettingAction struct { /*群设置*/
+}
+
+func (*SettingAction) Execute(a *ActionInfo) bool {
+	if _, foundSetting := utils.EitherTrimEqual(a.info.qParsed,
+		settingsCommand...); foundSetting {
+		chatType := UserChatType
+		if a.info.handlerType == GroupHandler {
+			chatType = GroupChatType
+		}
+		chatId := *a.info.chatId
+		if !a.handler.canManageChat(chatType, chatId, a.info.openId) {
+			sendAccessDeniedCard(*a.ctx, a.info.msgId,
+				"抱歉，只有群主和机器人管理员可以修改群设置～")
+			return false
+		}
+		newCard, err := newChatSettingCard(chatId, chatType,
+			a.handler.chatSettings.Get(chatId),
+			a.handler.chatSettings.Resolve(chatId, a.handler.config),
+			a.handler.config.ModelOptions)
+		if err != nil {
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：设置卡片生成失败～\n错误信息: %v", err), a.info.msgId)
+			return false
+		}
+		replyCard(*a.ctx, a.info.msgId, newCard)
+		return false
+	}
+	return true
+}
+
+// canManageChat 单聊中用户可以管理自己的设置，群聊需要群主或机器人管理员
+func (m MessageHandler) canManageChat(chatType CardChatType, chatId string,
+	openId string) bool {
+	if chatType == UserChatType {
+		return true
+	}
+	if m.access.IsAdmin(accesscontrol.Subject{OpenId: openId, ChatId: chatId}) {
+		return true
+	}
+	owner, err := getChatOwner(chatId)
+	if err != nil {
+		fmt.Printf("⚠️ Failed to get owner of chat %s: %v\n", chatId, err)
+		return false
+	}
+	return owner != "" && owner == openId
+}
+
+func getChatOwner(chatId string) (string, error) {
+	if v, ok := chatOwnerCache.Get(chatId); ok {
+		return v.(string), nil
+	}
+	req := larkim.NewGetChatReqBuilder().ChatId(chatId).UserIdType("open_id").Build()
+	resp, err := initialization.GetLarkClient().Im.Chat.Get(context.Background(), req)
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success() {
+		return "", fmt.Errorf("get chat failed: %d %s", resp.Code, resp.Msg)
+	}
+	var owner string
+	if resp.Data != nil && resp.Data.OwnerId != nil {
+		owner = *resp.Data.OwnerId
+	}
+	chatOwnerCache.SetDefault(chatId, owner)
+	return owner, nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"start-feishubot/utils"
+	"start-feishubot/utils/audio"
+)
+
+type VoiceSettingAction struct { /*语音回复设置*/
+}
+
+func (*VoiceSettingAction) Execute(a *ActionInfo) bool {
+	if _, foundVoice := utils.EitherTrimEqual(a.info.qParsed,
+		voiceCommand...); foundVoice {
+		if !a.handler.config.TTSEnabled {
+			replyMsg(*a.ctx, "🤖️：语音回复功能未开启，请联系管理员配置 TTS_ENABLED", a.info.msgId)
+			return false
+		}
+		enabled, voice := a.handler.voiceSettings.Resolve(a.info.openId, a.handler.config)
+		newCard, err := newVoiceSettingCard(enabled, voice, a.handler.config.TTSVoiceOptions)
+		if err != nil {
+			replyMsg(*a.ctx, fmt.Sprintf("🤖️：设置卡片生成失败～\n错误信息: %v", err), a.info.msgId)
+			return false
+		}
+		replyCard(*a.ctx, a.info.msgId, newCard)
+		return false
+	}
+	return true
+}
+
+// replyVoice 用户发送的是语音且开启了语音回复时，在文字回答之后补发一条语音
+// 语音失败不影响已发送的文字回答，只记录日志
+func (a *ActionInfo) replyVoice(answer string) {
+	if a.info.msgType != "audio" {
+		return
+	}
+	enabled, voice := a.handler.voiceSettings.Resolve(a.info.openId, a.handler.config)
+	if !enabled {
+		return
+	}
+	text := utils.PlainSpeechText(answer, a.handler.config.TTSMaxChars)
+	if text == "" {
+		return
+	}
+	fmt.Printf("    🔊 Synthesizing voice reply (%d chars, voice=%s)\n", len([]rune(text)), voice)
+	speech, err := a.handler.gpt.TextToSpeech(text, voice, a.handler.config.TTSModel)
+	if err != nil {
+		fmt.Printf("    ❌ Text to speech failed: %v\n", err)
+		return
+	}
+	pcm, err := audio.DecodeWav(bytes.NewReader(speech))
+	if err != nil {
+		fmt.Printf("    ❌ Invalid wav speech: %v\n", err)
+		return
+	}
+	var opus bytes.Buffer
+	if err := audio.EncodeOggOpus(&opus, *pcm); err != nil {
+		fmt.Printf("    ❌ Encode ogg/opus failed: %v\n", err)
+		return
+	}
+	fileKey, err := uploadAudio(opus.Bytes(), int(pcm.Duration().Milliseconds()))
+	if err != nil {
+		fmt.Printf("    ❌ Upload voice failed: %v\n", err)
+		return
+	}
+	if err := replyAudio(context.Background(), *fileKey, a.info.msgId); err != nil {
+		fmt.Printf("    ❌ Reply voice failed: %v\n", err)
+		return
+	}
+	fmt.Printf("    ✅ Voice reply sent (%v)\n", pcm.Duration())
+}
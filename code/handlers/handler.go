@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"start-feishubot/initialization"
 	"start-feishubot/services"
+	"start-feishubot/services/accesscontrol"
+	"start-feishubot/services/kb"
 	"start-feishubot/services/openai"
+	"start-feishubot/services/ratelimit"
+	"start-feishubot/utils"
 	"strings"
 
 	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
@@ -18,6 +22,7 @@ func chain(data *ActionInfo, actions ...Action) bool {
 	for i, v := range actions {
 		actionName := fmt.Sprintf("%T", v)
 		fmt.Printf("  🔧 Action %d: %s\n", i+1, actionName)
+		data.pending = actions[i:]
 
 		if !v.Execute(data) {
 			fmt.Printf("  ⏹️ Action %d (%s) returned false, stopping chain\n", i+1, actionName)
@@ -29,10 +34,20 @@ func chain(data *ActionInfo, actions ...Action) bool {
 }
 
 type MessageHandler struct {
-	sessionCache services.SessionServiceCacheInterface
-	msgCache     services.MsgCacheInterface
-	gpt          *openai.ChatGPT
-	config       initialization.Config
+	sessionCache  services.SessionServiceCacheInterface
+	msgCache      services.MsgCacheInterface
+	gpt           *openai.ChatGPT
+	config        initialization.Config
+	access        *accesscontrol.Checker
+	limiter       *ratelimit.Limiter
+	coalescer     *ratelimit.Coalescer
+	chatSettings  services.ChatSettingServiceInterface
+	voiceSettings services.VoiceSettingServiceInterface
+	memories      services.UserMemoryServiceInterface
+	feedbacks     services.AnswerFeedbackServiceInterface
+	searcher      utils.SearchProvider
+	embedder      utils.Embedder
+	knowledge     *kb.Base
 }
 
 func (m MessageHandler) cardHandler(ctx context.Context,
@@ -50,7 +65,7 @@ func judgeMsgType(event *larkim.P2MessageReceiveV1) (string, error) {
 	msgType := *event.Event.Message.MessageType
 
 	switch msgType {
-	case "text", "image", "audio":
+	case "text", "post", "image", "audio", "media", "file":
 		return msgType, nil
 	default:
 		return "", fmt.Errorf("unknown message type: %v", msgType)
@@ -119,12 +134,30 @@ func (m MessageHandler) msgReceivedHandler(ctx context.Context, event *larkim.P2
 
 	// 安全地解析内容
 	var parsedContent string
-	if content != nil {
-		parsedContent = strings.Trim(parseContent(*content), " ")
+	var imageKeys []string
+	if content != nil && msgType == "post" {
+		parsedContent, imageKeys = parsePostContent(*content, mention, m.isBotMention)
+	} else if content != nil {
+		parsedContent = strings.Trim(parseContent(*content, mention, m.isBotMention), " ")
 	} else {
 		parsedContent = ""
 	}
 	fmt.Printf("📝 Parsed content: %s\n", parsedContent)
+	if msgType == "post" && (sessionId == nil ||
+		!acceptPostMessage(imageKeys, m.sessionCache.GetMode(*sessionId))) {
+		fmt.Println("❌ Post message outside picture mode, ignoring")
+		return nil
+	}
+
+	var openId, unionId string
+	if event.Event.Sender != nil && event.Event.Sender.SenderId != nil {
+		if event.Event.Sender.SenderId.OpenId != nil {
+			openId = *event.Event.Sender.SenderId.OpenId
+		}
+		if event.Event.Sender.SenderId.UnionId != nil {
+			unionId = *event.Event.Sender.SenderId.UnionId
+		}
+	}
 
 	msgInfo := MsgInfo{
 		handlerType: handlerType,
@@ -133,27 +166,39 @@ func (m MessageHandler) msgReceivedHandler(ctx context.Context, event *larkim.P2
 		chatId:      chatId,
 		qParsed:     parsedContent,
 		fileKey:     parseFileKey(*content),
+		fileName:    parseFileName(*content),
 		imageKey:    parseImageKey(*content),
+		imageKeys:   imageKeys,
 		sessionId:   sessionId,
 		mention:     mention,
+		openId:      openId,
+		unionId:     unionId,
 	}
 	data := &ActionInfo{
-		ctx:     &ctx,
-		handler: &m,
-		info:    &msgInfo,
+		ctx:      &ctx,
+		handler:  &m,
+		info:     &msgInfo,
+		settings: m.chatSettings.Resolve(chatIdStr, m.config),
 	}
 
 	fmt.Println("🔄 Starting action chain...")
 	actions := []Action{
 		&ProcessedUniqueAction{}, //避免重复处理
 		&ProcessMentionAction{},  //判断机器人是否应该被调用
+		&AccessAction{},          //访问控制
+		&RateLimitAction{},       //限流与防刷
+		&KnowledgeBaseAction{},   //知识库管理
 		&AudioAction{},           //语音处理
+		&MeetingAction{},         //会议录音转写
 		&EmptyAction{},           //空消息处理
 		&WebBrowseAction{},       //联网读取
 		&AutoSearchAction{},      //自动联网搜索
 		&ClearAction{},           //清除消息处理
 		&PicAction{},             //图片处理
 		&RoleListAction{},        //角色列表处理
+		&SettingAction{},         //群设置
+		&VoiceSettingAction{},    //语音回复设置
+		&MemoryAction{},          //长期记忆
 		&HelpAction{},            //帮助处理
 		&BalanceAction{},         //余额处理
 		&RolePlayAction{},        //角色扮演处理
@@ -170,18 +215,61 @@ var _ MessageHandlerInterface = (*MessageHandler)(nil)
 
 func NewMessageHandler(gpt *openai.ChatGPT,
 	config initialization.Config) MessageHandlerInterface {
+	embedder := newEmbedder(config, gpt)
 	return &MessageHandler{
 		sessionCache: services.GetSessionCache(),
 		msgCache:     services.GetMsgCache(),
 		gpt:          gpt,
 		config:       config,
+		access: accesscontrol.NewChecker(accesscontrol.Rules{
+			AllowUsers:       config.AccessAllowUsers,
+			DenyUsers:        config.AccessDenyUsers,
+			AllowDepartments: config.AccessAllowDepartments,
+			DenyDepartments:  config.AccessDenyDepartments,
+			AllowChats:       config.AccessAllowChats,
+			DenyChats:        config.AccessDenyChats,
+			Admins:           config.AdminUsers,
+		}, accesscontrol.NewLarkDepartmentResolver()),
+		limiter:       newRateLimiter(config),
+		coalescer:     newCoalescer(config),
+		chatSettings:  services.GetChatSettingService(),
+		voiceSettings: services.GetVoiceSettingService(),
+		memories:      services.GetUserMemoryService(),
+		feedbacks:     services.GetAnswerFeedbackService(),
+		searcher:      newSearcher(config),
+		embedder:      embedder,
+		knowledge:     newKnowledgeBase(config, embedder),
 	}
 }
 
+// isBotMention 优先按机器人 open_id 匹配，获取不到 open_id 时回退到名称匹配
+func (m MessageHandler) isBotMention(mention *larkim.MentionEvent) bool {
+	if mention == nil {
+		return false
+	}
+	if botOpenId := initialization.GetBotOpenId(); botOpenId != "" {
+		return mention.Id != nil && mention.Id.OpenId != nil &&
+			*mention.Id.OpenId == botOpenId
+	}
+	return mention.Name != nil && *mention.Name == m.config.FeishuBotName
+}
+
 func (m MessageHandler) judgeIfMentionMe(mention []*larkim.
 	MentionEvent) bool {
-	if len(mention) != 1 {
+	for _, item := range mention {
+		if m.isBotMention(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// judgeIfInBotThread 判断消息是否回复在机器人已参与的话题中
+func (m MessageHandler) judgeIfInBotThread(sessionId, msgId *string) bool {
+	if sessionId == nil || msgId == nil || *sessionId == *msgId {
 		return false
 	}
-	return *mention[0].Name == m.config.FeishuBotName
+	return len(m.sessionCache.GetMsg(*sessionId)) > 0 ||
+		m.sessionCache.GetMode(*sessionId) != services.ModeGPT ||
+		hasPendingKnowledgeUpload(*sessionId)
 }
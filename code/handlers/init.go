@@ -3,7 +3,9 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"start-feishubot/initialization"
+	"start-feishubot/services"
 	"start-feishubot/services/openai"
 
 	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
@@ -26,6 +28,24 @@ const (
 var handlers MessageHandlerInterface
 
 func InitHandlers(gpt *openai.ChatGPT, config initialization.Config) {
+	settingPath := filepath.Join(config.DataDir, "chat_settings.json")
+	if err := services.InitChatSettingService(settingPath); err != nil {
+		fmt.Printf("⚠️ Failed to load chat settings from %s: %v\n", settingPath, err)
+	}
+	voicePath := filepath.Join(config.DataDir, "voice_settings.json")
+	if err := services.InitVoiceSettingService(voicePath); err != nil {
+		fmt.Printf("⚠️ Failed to load voice settings from %s: %v\n", voicePath, err)
+	}
+	memoryPath := filepath.Join(config.DataDir, "user_memory.json")
+	if err := services.InitUserMemoryService(memoryPath); err != nil {
+		fmt.Printf("⚠️ Failed to load user memory from %s: %v\n", memoryPath, err)
+	}
+	feedbackPath := filepath.Join(config.DataDir, "answer_feedback.json")
+	answersDir := filepath.Join(config.DataDir, "answers")
+	if err := services.InitAnswerFeedbackService(feedbackPath, answersDir); err != nil {
+		fmt.Printf("⚠️ Failed to load answer feedback from %s: %v\n", feedbackPath, err)
+	}
+	configureWebCaches(config)
 	handlers = NewMessageHandler(gpt, config)
 }
 
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"start-feishubot/initialization"
 	"start-feishubot/services"
 	"start-feishubot/services/openai"
+	"start-feishubot/utils"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
@@ -18,13 +22,22 @@ type CardKind string
 type CardChatType string
 
 var (
-	ClearCardKind      = CardKind("clear")            // 清空上下文
-	PicModeChangeKind  = CardKind("pic_mode_change")  // 切换图片创作模式
-	PicResolutionKind  = CardKind("pic_resolution")   // 图片分辨率调整
-	PicTextMoreKind    = CardKind("pic_text_more")    // 重新根据文本生成图片
-	PicVarMoreKind     = CardKind("pic_var_more")     // 变量图片
-	RoleTagsChooseKind = CardKind("role_tags_choose") // 内置角色所属标签选择
-	RoleChooseKind     = CardKind("role_choose")      // 内置角色选择
+	ClearCardKind        = CardKind("clear")              // 清空上下文
+	PicModeChangeKind    = CardKind("pic_mode_change")    // 切换图片创作模式
+	PicResolutionKind    = CardKind("pic_resolution")     // 图片分辨率调整
+	PicTextMoreKind      = CardKind("pic_text_more")      // 重新根据文本生成图片
+	PicVarMoreKind       = CardKind("pic_var_more")       // 变量图片
+	PicSettingKind       = CardKind("pic_setting")        // 图片模型、尺寸等参数调整
+	PicMoreLikeKind      = CardKind("pic_more_like")      // 生成与某张图片类似的图片
+	PicEditMoreKind      = CardKind("pic_edit_more")      // 重复上一次图片修改
+	RoleTagsChooseKind   = CardKind("role_tags_choose")   // 内置角色所属标签选择
+	RoleChooseKind       = CardKind("role_choose")        // 内置角色选择
+	ChatSettingKind      = CardKind("chat_setting")       // 修改群设置
+	ChatSettingResetKind = CardKind("chat_setting_reset") // 恢复默认群设置
+	VoiceSettingKind     = CardKind("voice_setting")      // 修改语音回复设置
+	AnswerRegenerateKind = CardKind("answer_regenerate")  // 重新生成最新一轮回答
+	AnswerContinueKind   = CardKind("answer_continue")    // 继续生成被截断的回答
+	AnswerFeedbackKind   = CardKind("answer_feedback")    // 评价回答
 )
 
 var (
@@ -38,6 +51,10 @@ type CardMsg struct {
 	Value     interface{}
 	SessionId string
 	MsgId     string
+	ChatId    string
+	Field     string
+	ImageKey  string
+	MaskKey   string
 }
 
 type MenuOption struct {
@@ -68,8 +85,81 @@ func replyCard(ctx context.Context,
 	// 服务端错误处理
 	if !resp.Success() {
 		fmt.Println(resp.Code, resp.Msg, resp.RequestId())
+		return &larkAPIError{op: "reply card", Code: resp.Code, Msg: resp.Msg}
+	}
+	return nil
+}
+
+// larkAPIError 飞书接口返回的业务错误
+type larkAPIError struct {
+	op   string
+	Code int
+	Msg  string
+}
+
+func (e *larkAPIError) Error() string {
+	return fmt.Sprintf("%s failed: %d %s", e.op, e.Code, e.Msg)
+}
+
+// larkCodeContentTooLong 消息内容超过大小限制
+const larkCodeContentTooLong = 230025
+
+// isCardTooLarge 判断发送或更新卡片失败是否因为卡片内容超过大小限制
+func isCardTooLarge(err error) bool {
+	var apiErr *larkAPIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code == larkCodeContentTooLong {
+		return true
+	}
+	msg := strings.ToLower(apiErr.Msg)
+	return strings.Contains(msg, "too long") || strings.Contains(msg, "too large") ||
+		strings.Contains(msg, "exceed")
+}
+
+// replyCardWithId 回复卡片并返回新消息的 id，用于后续更新卡片
+func replyCardWithId(ctx context.Context,
+	msgId *string,
+	cardContent string,
+) (*string, error) {
+	client := initialization.GetLarkClient()
+	resp, err := client.Im.Message.Reply(ctx, larkim.NewReplyMessageReqBuilder().
+		MessageId(*msgId).
+		Body(larkim.NewReplyMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeInteractive).
+			Uuid(uuid.New().String()).
+			Content(cardContent).
+			Build()).
+		Build())
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	if !resp.Success() {
+		fmt.Println(resp.Code, resp.Msg, resp.RequestId())
+		return nil, &larkAPIError{op: "reply card", Code: resp.Code, Msg: resp.Msg}
+	}
+	return resp.Data.MessageId, nil
+}
+
+// patchCard 更新已发送的卡片内容
+func patchCard(ctx context.Context, msgId *string, cardContent string) error {
+	client := initialization.GetLarkClient()
+	resp, err := client.Im.Message.Patch(ctx, larkim.NewPatchMessageReqBuilder().
+		MessageId(*msgId).
+		Body(larkim.NewPatchMessageReqBodyBuilder().
+			Content(cardContent).
+			Build()).
+		Build())
+	if err != nil {
+		fmt.Println(err)
 		return err
 	}
+	if !resp.Success() {
+		fmt.Println(resp.Code, resp.Msg, resp.RequestId())
+		return &larkAPIError{op: "patch card", Code: resp.Code, Msg: resp.Msg}
+	}
 	return nil
 }
 
@@ -320,34 +410,92 @@ func withOneBtn(btn *larkcard.MessageCardEmbedButton) larkcard.
 
 //新建对话按钮
 
-func withPicResolutionBtn(sessionID *string) larkcard.
-	MessageCardElement {
-	cancelMenu := newMenu("默认分辨率",
+// withPicSettingMenu 图片参数下拉菜单，选项来自当前模型支持的取值
+func withPicSettingMenu(label string, field string, sessionId string,
+	current string, values ...string) larkcard.MessageCardElement {
+	var options []MenuOption
+	for _, v := range values {
+		options = append(options, MenuOption{label: v, value: v})
+	}
+	return withPicSettingOptions(label, field, sessionId, current, options...)
+}
+
+func withPicSettingOptions(label string, field string, sessionId string,
+	current string, options ...MenuOption) larkcard.MessageCardElement {
+	menu := newMenu(label,
 		map[string]interface{}{
-			"value":     "0",
-			"kind":      PicResolutionKind,
-			"sessionId": *sessionID,
-			"msgId":     *sessionID,
-		},
-		MenuOption{
-			label: "256x256",
-			value: string(services.Resolution256),
-		},
-		MenuOption{
-			label: "512x512",
-			value: string(services.Resolution512),
-		},
-		MenuOption{
-			label: "1024x1024",
-			value: string(services.Resolution1024),
+			"kind":      PicSettingKind,
+			"sessionId": sessionId,
+			"field":     field,
 		},
+		options...,
 	)
-
-	actions := larkcard.NewMessageCardAction().
-		Actions([]larkcard.MessageCardActionElement{cancelMenu}).
-		Layout(larkcard.MessageCardActionLayoutFlow.Ptr()).
+	if current != "" {
+		menu.MessageCardEmbedSelectMenuBase.InitialOption(current)
+	}
+	return larkcard.NewMessageCardDiv().
+		Text(larkcard.NewMessageCardLarkMd().
+			Content(label).
+			Build()).
+		Extra(menu).
 		Build()
-	return actions
+}
+
+// newPicModeCard 图片创作模式卡片，只展示所选模型支持的参数
+func newPicModeCard(sessionId string, opts openai.ImageOptions,
+	preset string, enhance bool, models []string, maxN int) (string, error) {
+	spec := openai.GetImageModelSpec(opts.Model)
+	elements := []larkcard.MessageCardElement{
+		withPicSettingMenu("🧠 **模型**", "model", sessionId, opts.Model, models...),
+		withPicSettingMenu("📐 **尺寸**", "size", sessionId, opts.Size, spec.Sizes...),
+	}
+	if len(spec.Qualities) > 0 {
+		elements = append(elements, withPicSettingMenu("💎 **质量**", "quality",
+			sessionId, opts.Quality, spec.Qualities...))
+	}
+	if len(spec.Styles) > 0 {
+		elements = append(elements, withPicSettingMenu("🎨 **风格**", "style",
+			sessionId, opts.Style, spec.Styles...))
+	}
+	if len(spec.Backgrounds) > 0 {
+		elements = append(elements, withPicSettingMenu("🪟 **背景**", "background",
+			sessionId, opts.Background, spec.Backgrounds...))
+	}
+	if maxN <= 0 || maxN > spec.MaxN {
+		maxN = spec.MaxN
+	}
+	if maxN > 1 {
+		var counts []string
+		for i := 1; i <= maxN; i++ {
+			counts = append(counts, strconv.Itoa(i))
+		}
+		elements = append(elements, withPicSettingMenu("🔢 **数量**", "n",
+			sessionId, strconv.Itoa(opts.N), counts...))
+	}
+	if styles := initialization.GetPicStyleList(); len(styles) > 0 {
+		presets := []string{picPresetNone}
+		for _, style := range styles {
+			presets = append(presets, style.Title)
+		}
+		if preset == "" {
+			preset = picPresetNone
+		}
+		elements = append(elements, withPicSettingMenu("🖌️ **风格预设**", "preset",
+			sessionId, preset, presets...))
+	}
+	enhanceOption := "off"
+	if enhance {
+		enhanceOption = "on"
+	}
+	elements = append(elements, withPicSettingOptions("✨ **提示词扩写**", "enhance",
+		sessionId, enhanceOption,
+		MenuOption{label: boolLabel(true), value: "on"},
+		MenuOption{label: boolLabel(false), value: "off"}))
+	elements = append(elements,
+		withNote("提醒：回复文本或图片，让AI生成相关的图片；图片附上文字说明可以修改图片，第二张图片作为蒙版（白色或透明处会被修改），说明为“抠图”时去除背景。"))
+	return newSendCard(
+		withHeader("🖼️ 已进入图片创作模式", larkcard.TemplateBlue),
+		elements...)
 }
 func withRoleTagsBtn(sessionID *string, tags ...string) larkcard.
 	MessageCardElement {
@@ -403,6 +551,205 @@ func withRoleBtn(sessionID *string, titles ...string) larkcard.
 	return actions
 }
 
+// withSettingMenu 左侧为设置项说明，右侧为下拉选择
+func withSettingMenu(label string, field string, chatId string,
+	chatType CardChatType, current string, options ...MenuOption) larkcard.
+	MessageCardElement {
+	menu := newMenu("沿用默认",
+		map[string]interface{}{
+			"kind":     ChatSettingKind,
+			"chatType": chatType,
+			"chatId":   chatId,
+			"field":    field,
+		},
+		append([]MenuOption{{label: "沿用默认", value: settingDefaultOption}},
+			options...)...,
+	)
+	if current != "" {
+		menu.MessageCardEmbedSelectMenuBase.InitialOption(current)
+	}
+	return larkcard.NewMessageCardDiv().
+		Text(larkcard.NewMessageCardLarkMd().
+			Content(label).
+			Build()).
+		Extra(menu).
+		Build()
+}
+
+func newChatSettingCard(chatId string, chatType CardChatType,
+	setting services.ChatSetting, resolved services.ResolvedChatSetting,
+	modelOptions []string) (string, error) {
+	title := "⚙️ 群设置"
+	if chatType == UserChatType {
+		title = "⚙️ 会话设置"
+	}
+
+	searchMode := string(setting.SearchMode)
+	if searchMode == "" && setting.SearchAlways != nil && *setting.SearchAlways {
+		searchMode = string(services.SearchModeAlways)
+	}
+	showSearchPlan := ""
+	if setting.ShowSearchPlan != nil {
+		showSearchPlan = "off"
+		if *setting.ShowSearchPlan {
+			showSearchPlan = "on"
+		}
+	}
+	var topKOptions []MenuOption
+	for i := 1; i <= 10; i++ {
+		topKOptions = append(topKOptions, MenuOption{label: strconv.Itoa(i), value: strconv.Itoa(i)})
+	}
+	topK := ""
+	if setting.SearchTopK > 0 {
+		topK = strconv.Itoa(setting.SearchTopK)
+	}
+	var roleOptions []MenuOption
+	for _, role := range *initialization.GetRoleList() {
+		roleOptions = append(roleOptions, MenuOption{label: role.Title, value: role.Title})
+	}
+	var modelMenuOptions []MenuOption
+	for _, model := range modelOptions {
+		modelMenuOptions = append(modelMenuOptions, MenuOption{label: model, value: model})
+	}
+
+	role := resolved.DefaultRole
+	if role == "" {
+		role = "无"
+	}
+	summary := fmt.Sprintf("**当前生效**\n联网模式：%s\n搜索条数：%d\n检索过程：%s\n默认角色：%s\n模型：%s\n回复风格：%s",
+		searchModeLabel(resolved.SearchMode), resolved.SearchTopK, boolLabel(resolved.ShowSearchPlan), role, resolved.Model,
+		replyStyleLabel(resolved.ReplyStyle))
+
+	note := "提醒：未单独设置的项目沿用机器人全局配置"
+	if !setting.UpdatedAt.IsZero() {
+		note = fmt.Sprintf("最后修改：%s", setting.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+
+	return newSendCard(
+		withHeader(title, larkcard.TemplateBlue),
+		withMainMd(summary),
+		withSplitLine(),
+		withSettingMenu("🌐 **联网模式**", "search_mode", chatId, chatType, searchMode,
+			MenuOption{label: "自动", value: string(services.SearchModeAuto)},
+			MenuOption{label: "总是", value: string(services.SearchModeAlways)},
+			MenuOption{label: "从不", value: string(services.SearchModeNever)}),
+		withSettingMenu("🔍 **搜索条数**", "search_top_k", chatId, chatType, topK,
+			topKOptions...),
+		withSettingMenu("🧾 **检索过程**", "show_search_plan", chatId, chatType, showSearchPlan,
+			MenuOption{label: "展示", value: "on"},
+			MenuOption{label: "隐藏", value: "off"}),
+		withSettingMenu("🥷 **默认角色**", "default_role", chatId, chatType,
+			setting.DefaultRole, roleOptions...),
+		withSettingMenu("🧠 **模型**", "model", chatId, chatType, setting.Model,
+			modelMenuOptions...),
+		withSettingMenu("✍️ **回复风格**", "reply_style", chatId, chatType,
+			string(setting.ReplyStyle),
+			MenuOption{label: "简洁", value: string(services.ReplyStyleConcise)},
+			MenuOption{label: "详细", value: string(services.ReplyStyleDetailed)}),
+		withSplitLine(),
+		withOneBtn(newBtn("恢复默认设置", map[string]interface{}{
+			"kind":     ChatSettingResetKind,
+			"chatType": chatType,
+			"chatId":   chatId,
+		}, larkcard.MessageCardButtonTypeDanger)),
+		withNote(note),
+	)
+}
+
+func boolLabel(b bool) string {
+	if b {
+		return "开启"
+	}
+	return "关闭"
+}
+
+func searchModeLabel(mode services.SearchMode) string {
+	switch mode {
+	case services.SearchModeAlways:
+		return "总是"
+	case services.SearchModeNever:
+		return "从不"
+	default:
+		return "自动"
+	}
+}
+
+func replyStyleLabel(style services.ReplyStyle) string {
+	switch style {
+	case services.ReplyStyleConcise:
+		return "简洁"
+	case services.ReplyStyleDetailed:
+		return "详细"
+	default:
+		return "默认"
+	}
+}
+
+// newMeetingCard 会议转写进度卡片，需要开启 update_multi 才能被更新
+func newMeetingCard(fileName string, content string, template string,
+	note string) string {
+	config := larkcard.NewMessageCardConfig().
+		WideScreenMode(true).
+		EnableForward(true).
+		UpdateMulti(true).
+		Build()
+	cardContent, _ := larkcard.NewMessageCard().
+		Config(config).
+		Header(withHeader("🎙️ 会议转写："+fileName, template)).
+		Elements([]larkcard.MessageCardElement{
+			withMainMd(content),
+			withNote(note),
+		}).
+		String()
+	return cardContent
+}
+
+func newVoiceSettingCard(enabled bool, voice string,
+	voiceOptions []string) (string, error) {
+	status := "🔇 语音回复已关闭"
+	toggle := newBtn("开启语音回复", map[string]interface{}{
+		"kind":  VoiceSettingKind,
+		"field": "enabled",
+		"value": "on",
+	}, larkcard.MessageCardButtonTypePrimary)
+	if enabled {
+		status = "🔊 语音回复已开启"
+		toggle = newBtn("关闭语音回复", map[string]interface{}{
+			"kind":  VoiceSettingKind,
+			"field": "enabled",
+			"value": "off",
+		}, larkcard.MessageCardButtonTypeDefault)
+	}
+
+	var options []MenuOption
+	for _, v := range voiceOptions {
+		options = append(options, MenuOption{label: v, value: v})
+	}
+	menu := newMenu("选择音色",
+		map[string]interface{}{
+			"kind":  VoiceSettingKind,
+			"field": "voice",
+		},
+		options...,
+	)
+	if voice != "" {
+		menu.MessageCardEmbedSelectMenuBase.InitialOption(voice)
+	}
+
+	return newSendCard(
+		withHeader("🎙️ 语音回复设置", larkcard.TemplateBlue),
+		withMainMd(fmt.Sprintf("%s\n当前音色：%s", status, voice)),
+		withOneBtn(toggle),
+		larkcard.NewMessageCardDiv().
+			Text(larkcard.NewMessageCardLarkMd().
+				Content("🗣️ **音色**").
+				Build()).
+			Extra(menu).
+			Build(),
+		withNote("开启后，发送语音消息时机器人会额外用语音朗读回答"),
+	)
+}
+
 func replyMsg(ctx context.Context, msg string, msgId *string) error {
 	msg, i := processMessage(msg)
 	if i != nil {
@@ -481,6 +828,107 @@ func replyImage(ctx context.Context, ImageKey *string,
 
 }
 
+// uploadAudio 上传 ogg/opus 语音，duration 为毫秒
+func uploadAudio(data []byte, duration int) (*string, error) {
+	client := initialization.GetLarkClient()
+	resp, err := client.Im.File.Create(context.Background(),
+		larkim.NewCreateFileReqBuilder().
+			Body(larkim.NewCreateFileReqBodyBuilder().
+				FileType(larkim.FileTypeOpus).
+				FileName("reply.opus").
+				Duration(duration).
+				File(bytes.NewReader(data)).
+				Build()).
+			Build())
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	if !resp.Success() {
+		fmt.Println(resp.Code, resp.Msg, resp.RequestId())
+		return nil, fmt.Errorf("upload audio failed: %d %s", resp.Code, resp.Msg)
+	}
+	return resp.Data.FileKey, nil
+}
+
+// uploadFile 以普通文件形式上传，例如转写稿
+func uploadFile(fileName string, data []byte) (*string, error) {
+	client := initialization.GetLarkClient()
+	resp, err := client.Im.File.Create(context.Background(),
+		larkim.NewCreateFileReqBuilder().
+			Body(larkim.NewCreateFileReqBodyBuilder().
+				FileType(larkim.FileTypeStream).
+				FileName(fileName).
+				File(bytes.NewReader(data)).
+				Build()).
+			Build())
+	if err != nil {
+		fmt.Println(err)
+		return nil, err
+	}
+	if !resp.Success() {
+		fmt.Println(resp.Code, resp.Msg, resp.RequestId())
+		return nil, fmt.Errorf("upload file failed: %d %s", resp.Code, resp.Msg)
+	}
+	return resp.Data.FileKey, nil
+}
+
+func replyFile(ctx context.Context, fileKey string, msgId *string) error {
+	msgFile := larkim.MessageFile{FileKey: fileKey}
+	content, err := msgFile.String()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	client := initialization.GetLarkClient()
+
+	resp, err := client.Im.Message.Reply(ctx, larkim.NewReplyMessageReqBuilder().
+		MessageId(*msgId).
+		Body(larkim.NewReplyMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeFile).
+			Uuid(uuid.New().String()).
+			Content(content).
+			Build()).
+		Build())
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	if !resp.Success() {
+		fmt.Println(resp.Code, resp.Msg, resp.RequestId())
+		return fmt.Errorf("reply file failed: %d %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+func replyAudio(ctx context.Context, fileKey string, msgId *string) error {
+	msgAudio := larkim.MessageAudio{FileKey: fileKey}
+	content, err := msgAudio.String()
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	client := initialization.GetLarkClient()
+
+	resp, err := client.Im.Message.Reply(ctx, larkim.NewReplyMessageReqBuilder().
+		MessageId(*msgId).
+		Body(larkim.NewReplyMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeAudio).
+			Uuid(uuid.New().String()).
+			Content(content).
+			Build()).
+		Build())
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	if !resp.Success() {
+		fmt.Println(resp.Code, resp.Msg, resp.RequestId())
+		return fmt.Errorf("reply audio failed: %d %s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
 func replayImageCardByBase64(ctx context.Context, base64Str string,
 	msgId *string, sessionId *string, question string) error {
 	imageKey, err := uploadImage(base64Str)
@@ -575,6 +1023,14 @@ func sendClearCacheCheckCard(ctx context.Context,
 	replyCard(ctx, msgId, newCard)
 }
 
+func sendAccessDeniedCard(ctx context.Context, msgId *string, reason string) {
+	newCard, _ := newSendCard(
+		withHeader("🔒 机器人提醒", larkcard.TemplateGrey),
+		withMainMd(reason),
+		withNote("如需开通，请联系机器人管理员"))
+	replyCard(ctx, msgId, newCard)
+}
+
 func sendSystemInstructionCard(ctx context.Context,
 	sessionId *string, msgId *string, content string) {
 	newCard, _ := newSendCard(
@@ -585,11 +1041,7 @@ func sendSystemInstructionCard(ctx context.Context,
 }
 
 func sendPicCreateInstructionCard(ctx context.Context,
-	sessionId *string, msgId *string) {
-	newCard, _ := newSendCard(
-		withHeader("🖼️ 已进入图片创作模式", larkcard.TemplateBlue),
-		withPicResolutionBtn(sessionId),
-		withNote("提醒：回复文本或图片，让AI生成相关的图片。"))
+	msgId *string, newCard string) {
 	replyCard(ctx, msgId, newCard)
 }
 
@@ -603,13 +1055,123 @@ func sendPicModeCheckCard(ctx context.Context,
 	replyCard(ctx, msgId, newCard)
 }
 
-func sendNewTopicCard(ctx context.Context,
-	sessionId *string, msgId *string, content string) {
-	newCard, _ := newSendCard(
-		withHeader("👻️ 已开启新的话题", larkcard.TemplateBlue),
-		withMainMd(content),
-		withNote("提醒：点击对话框参与回复，可保持话题连贯"))
-	replyCard(ctx, msgId, newCard)
+// withSourcesMd 列出回答引用的来源，回答未标注任何引用时列出全部来源
+func withSourcesMd(sources []utils.NumberedSource, cited []int) larkcard.MessageCardElement {
+	byIndex := make(map[int]utils.NumberedSource, len(sources))
+	for _, s := range sources {
+		byIndex[s.Index] = s
+	}
+	if len(cited) == 0 {
+		for _, s := range sources {
+			cited = append(cited, s.Index)
+		}
+	}
+	escape := strings.NewReplacer("[", "(", "]", ")")
+	lines := []string{"**📚 来源**"}
+	for _, n := range cited {
+		s, ok := byIndex[n]
+		if !ok {
+			continue
+		}
+		domain := s.Origin
+		if domain == "" {
+			domain = utils.SourceDomain(s.URL)
+		}
+		title := strings.TrimSpace(escape.Replace(s.Title))
+		if title == "" {
+			title = domain
+		}
+		if s.URL == "" {
+			lines = append(lines, fmt.Sprintf("[%d] %s · %s", n, title, domain))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("[%d] [%s](%s) · %s", n, title, s.URL, domain))
+	}
+	return withMainMd(strings.Join(lines, "\n"))
+}
+
+// newUpdatableCard 开启 update_multi 的卡片，发送后可以通过 patchCard 更新，header 可为 nil
+func newUpdatableCard(header *larkcard.MessageCardHeader,
+	elements ...larkcard.MessageCardElement) string {
+	config := larkcard.NewMessageCardConfig().
+		WideScreenMode(false).
+		EnableForward(true).
+		UpdateMulti(true).
+		Build()
+	cardContent, _ := larkcard.NewMessageCard().
+		Config(config).
+		Header(header).
+		Elements(elements).
+		String()
+	return cardContent
+}
+
+// answerLayout 回答卡片正文以外的部分，回答拆成多张卡片时标题放在第一张，来源和按钮放在最后一张
+type answerLayout struct {
+	newTopic bool
+	sources  []utils.NumberedSource
+	cited    []int
+	planNote string
+	fileName string // 完整回答作为附件发送时的文件名
+	actions  larkcard.MessageCardElement
+}
+
+// newAnswerCard 回答的第 index 张卡片（共 total 张），联网回答的第一张可直接替换检索过程卡片
+func newAnswerCard(part string, layout answerLayout, index int, total int) string {
+	elements := []larkcard.MessageCardElement{withMainMd(part)}
+	if total > 1 {
+		elements = append(elements, withNote(fmt.Sprintf("📄 第 %d/%d 部分", index+1, total)))
+	}
+	if index == total-1 {
+		if len(layout.sources) > 0 {
+			elements = append(elements, withSplitLine(), withSourcesMd(layout.sources, layout.cited))
+		}
+		if layout.fileName != "" {
+			elements = append(elements, withNote("📎 回答较长，完整内容见附件 "+layout.fileName))
+		}
+		if layout.planNote != "" {
+			elements = append(elements, withNote(layout.planNote))
+		}
+		if layout.newTopic {
+			elements = append(elements, withNote("提醒：点击对话框参与回复，可保持话题连贯"))
+		}
+		if layout.actions != nil {
+			elements = append(elements, layout.actions)
+		}
+	}
+	if layout.newTopic && index == 0 {
+		return newUpdatableCard(withHeader("👻️ 已开启新的话题", larkcard.TemplateBlue), elements...)
+	}
+	return newUpdatableCard(nil, elements...)
+}
+
+// withAnswerActions 回答卡片底部的按钮，answerId 用于在会话中找到这条回答，回答被截断时额外提供“继续”
+func withAnswerActions(sessionId string, msgId string, chatId string,
+	chatType CardChatType, answerId string, truncated bool) larkcard.MessageCardElement {
+	value := func(kind CardKind, field string) map[string]interface{} {
+		return map[string]interface{}{
+			"value":     answerId,
+			"kind":      kind,
+			"chatType":  chatType,
+			"sessionId": sessionId,
+			"msgId":     msgId,
+			"chatId":    chatId,
+			"field":     field,
+		}
+	}
+	var btns []larkcard.MessageCardActionElement
+	if truncated {
+		btns = append(btns, newBtn("⏩ 继续", value(AnswerContinueKind, ""),
+			larkcard.MessageCardButtonTypePrimary))
+	}
+	btns = append(btns,
+		newBtn("🔄 重新生成", value(AnswerRegenerateKind, ""), larkcard.MessageCardButtonTypeDefault),
+		newBtn("👍", value(AnswerFeedbackKind, string(services.FeedbackUp)), larkcard.MessageCardButtonTypeDefault),
+		newBtn("👎", value(AnswerFeedbackKind, string(services.FeedbackDown)), larkcard.MessageCardButtonTypeDefault))
+	return larkcard.NewMessageCardAction().
+		Actions(btns).
+		Layout(larkcard.MessageCardActionLayoutFlow.Ptr()).
+		Build()
 }
 
 func sendHelpCard(ctx context.Context,
@@ -637,8 +1199,20 @@ func sendHelpCard(ctx context.Context,
 		withSplitLine(),
 		withMainMd("🎰 **Token余额查询**\n回复*余额* 或 */balance*"),
 		withSplitLine(),
+		withMainMd("⚙️ **群设置**\n回复*设置* 或 */settings*，群主和管理员可以调整本群的联网、模型、默认角色等"),
+		withSplitLine(),
+		withMainMd("🎙️ **语音回复**\n回复*语音设置* 或 */voice*，开关语音回复并选择音色"),
+		withSplitLine(),
+		withMainMd("📼 **会议转写**\n发送会议录音或视频文件，返回逐字稿和会议纪要"),
+		withSplitLine(),
 		withMainMd("🌐 **联网阅读**\n回复 *联网 URL* 或 */read URL*，我会读取网页并基于内容回答"),
 		withSplitLine(),
+		withMainMd("📚 **知识库**\n回复 */kb list* 查看文档，*/kb add* 后上传 md/txt/pdf 文件加入本群知识库，*/kb reload* 重新同步目录和飞书文档"),
+		withSplitLine(),
+		withMainMd("🔎 **联网搜索**\n回复 */search 问题* 强制联网搜索，*/nosearch 问题* 本条消息不联网"),
+		withSplitLine(),
+		withMainMd("🧠 **长期记忆**\n回复 */remember 内容* 让我记住你的偏好，新话题会自动参考；*/memory* 查看和删除"),
+		withSplitLine(),
 		withMainMd("🔃️ **历史话题回档** 🚧\n"+" 进入话题的回复详情页,文本回复 *恢复* 或 */reload*"),
 		withSplitLine(),
 		withMainMd("📤 **话题内容导出** 🚧\n"+" 文本回复 *导出* 或 */export*"),
@@ -668,6 +1242,65 @@ func sendImageCard(ctx context.Context, imageKey string,
 	return nil
 }
 
+// sendImageGalleryCard 一张卡片展示本次生成的所有图片，每张图片可以单独“再来一张类似的”，
+// finalPrompt 与 question 不同时展示实际使用的提示词
+func sendImageGalleryCard(ctx context.Context, imageKeys []string,
+	msgId *string, sessionId *string, question string, finalPrompt string) error {
+	var elements []larkcard.MessageCardElement
+	if finalPrompt != "" && finalPrompt != question {
+		elements = append(elements,
+			withNote("✨ 实际使用的提示词："+finalPrompt))
+	}
+	for _, imageKey := range imageKeys {
+		elements = append(elements,
+			withImageDiv(imageKey),
+			withOneBtn(newBtn("类似的再来一张", map[string]interface{}{
+				"value":     question,
+				"imageKey":  imageKey,
+				"kind":      PicMoreLikeKind,
+				"chatType":  UserChatType,
+				"msgId":     *msgId,
+				"sessionId": *sessionId,
+			}, larkcard.MessageCardButtonTypeDefault)),
+		)
+	}
+	elements = append(elements,
+		withSplitLine(),
+		withOneBtn(newBtn("再来一组", map[string]interface{}{
+			"value":     question,
+			"kind":      PicTextMoreKind,
+			"chatType":  UserChatType,
+			"msgId":     *msgId,
+			"sessionId": *sessionId,
+		}, larkcard.MessageCardButtonTypePrimary)),
+	)
+	newCard, _ := newSimpleSendCard(elements...)
+	return replyCard(ctx, msgId, newCard)
+}
+
+// sendImageEditCard 展示修改后的图片，“再来一张”按原图、蒙版和说明重新修改
+func sendImageEditCard(ctx context.Context, imageKeys []string,
+	edit picEdit, sessionId *string) error {
+	var elements []larkcard.MessageCardElement
+	for _, imageKey := range imageKeys {
+		elements = append(elements, withImageDiv(imageKey))
+	}
+	elements = append(elements,
+		withSplitLine(),
+		withOneBtn(newBtn("再来一张", map[string]interface{}{
+			"value":     edit.Prompt,
+			"imageKey":  edit.ImageKey,
+			"maskKey":   edit.MaskKey,
+			"kind":      PicEditMoreKind,
+			"chatType":  UserChatType,
+			"msgId":     edit.MsgId,
+			"sessionId": *sessionId,
+		}, larkcard.MessageCardButtonTypePrimary)),
+	)
+	newCard, _ := newSimpleSendCard(elements...)
+	return replyCard(ctx, &edit.MsgId, newCard)
+}
+
 func sendVarImageCard(ctx context.Context, imageKey string,
 	msgId *string, sessionId *string) error {
 	newCard, _ := newSimpleSendCard(
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"start-feishubot/services"
+	"strings"
+	"unicode"
+)
+
+// searchTrigger 本条消息的联网方式，由 AutoSearchAction 根据指令、群设置和关键词确定
+type searchTrigger int
+
+const (
+	searchByModel  searchTrigger = iota // 由分类模型判断是否联网
+	searchRequired                      // 必须联网，检索词仍由模型生成
+	searchCommand                       // /search 指令，直接用消息内容检索
+	searchSkipped                       // 不联网，跳过分类直接回答
+)
+
+var searchCommands = []struct {
+	prefix  string
+	trigger searchTrigger
+}{
+	{"/nosearch", searchSkipped},
+	{"/search", searchCommand},
+}
+
+// parseSearchCommand 识别 /search 与 /nosearch 前缀，返回去掉前缀后的问题
+func parseSearchCommand(text string) (string, searchTrigger, bool) {
+	text = strings.TrimSpace(text)
+	for _, c := range searchCommands {
+		if len(text) < len(c.prefix) || !strings.EqualFold(text[:len(c.prefix)], c.prefix) {
+			continue
+		}
+		rest := text[len(c.prefix):]
+		// 避免把 /searchxxx 之类的内容当成指令
+		if rest != "" && !unicode.IsSpace([]rune(rest)[0]) {
+			continue
+		}
+		return strings.TrimSpace(rest), c.trigger, true
+	}
+	return text, searchByModel, false
+}
+
+// resolveSearchTrigger 没有指令时按群设置的联网模式决定；
+// auto 模式下开启 onlyOnKeywords 时，命中关键词才联网，否则直接回答
+func resolveSearchTrigger(mode services.SearchMode, onlyOnKeywords bool,
+	keywords []string, text string) searchTrigger {
+	switch mode {
+	case services.SearchModeAlways:
+		return searchRequired
+	case services.SearchModeNever:
+		return searchSkipped
+	}
+	if !onlyOnKeywords {
+		return searchByModel
+	}
+	if containsKeyword(text, keywords) {
+		return searchRequired
+	}
+	return searchSkipped
+}
+
+func containsKeyword(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, k := range keywords {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" && strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"start-feishubot/services"
+	"testing"
+)
+
+func TestParseSearchCommand(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    string
+		trigger searchTrigger
+		ok      bool
+	}{
+		{"/search Go 1.22 发布时间", "Go 1.22 发布时间", searchCommand, true},
+		{"  /SEARCH  今日新闻 ", "今日新闻", searchCommand, true},
+		{"/nosearch 解释一下闭包", "解释一下闭包", searchSkipped, true},
+		{"/search", "", searchCommand, true},
+		{"/searchengine 是什么", "/searchengine 是什么", searchByModel, false},
+		{"帮我 /search 一下", "帮我 /search 一下", searchByModel, false},
+	}
+	for _, tt := range tests {
+		got, trigger, ok := parseSearchCommand(tt.text)
+		if got != tt.want || trigger != tt.trigger || ok != tt.ok {
+			t.Errorf("parseSearchCommand(%q) = %q, %d, %t, want %q, %d, %t",
+				tt.text, got, trigger, ok, tt.want, tt.trigger, tt.ok)
+		}
+	}
+}
+
+func TestResolveSearchTrigger(t *testing.T) {
+	keywords := []string{"搜索", "Google"}
+	tests := []struct {
+		name           string
+		mode           services.SearchMode
+		onlyOnKeywords bool
+		text           string
+		want           searchTrigger
+	}{
+		{"always", services.SearchModeAlways, true, "你好", searchRequired},
+		{"never", services.SearchModeNever, false, "搜索一下新闻", searchSkipped},
+		{"auto decided by model", services.SearchModeAuto, false, "你好", searchByModel},
+		{"keyword hit", services.SearchModeAuto, true, "google 一下天气", searchRequired},
+		{"keyword miss", services.SearchModeAuto, true, "写一首诗", searchSkipped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveSearchTrigger(tt.mode, tt.onlyOnKeywords, keywords, tt.text)
+			if got != tt.want {
+				t.Errorf("resolveSearchTrigger() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
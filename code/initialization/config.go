@@ -3,6 +3,7 @@ package initialization
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/viper"
@@ -15,6 +16,8 @@ type Config struct {
 	FeishuAppVerificationToken string
 	FeishuBotName              string
 	OpenaiApiKeys              []string
+	OpenaiModel                string
+	ModelOptions               []string
 	HttpPort                   int
 	HttpsPort                  int
 	UseHttps                   bool
@@ -30,8 +33,10 @@ type Config struct {
 	ArkBotId  string
 	// debug http request/response logs
 	DebugHTTP bool
-	// Always perform web search before answering
+	// Always perform web search before answering (deprecated, use SearchMode)
 	SearchAlways bool
+	// Web search mode: auto, always or never
+	SearchMode string
 	// Number of top results to read
 	SearchTopK int
 	// Search behavior options
@@ -39,15 +44,116 @@ type Config struct {
 	SearchPerFetchTimeoutSec int
 	SearchMaxConcurrency     int
 	SearchCacheTTLMin        int
+	// Search result list and fetched page caches
+	SearchCacheSize    int
+	SearchCacheBackend string
+	FetchCacheTTLMin   int
+	FetchCacheSizeMB   int
+	// Token budget of the assembled search context
+	SearchContextTokens int
+	// Show the search plan card during web search
+	SearchShowPlan bool
+	// Optional OpenAI compatible embeddings endpoint for reranking
+	EmbeddingApiUrl string
+	EmbeddingApiKey string
+	EmbeddingModel  string
+	// Embedding output dimensions, 0 uses the model default
+	EmbeddingDimensions int
+	// Send embedding requests through the chat API client
+	EmbeddingUseChatApi bool
 	// Trigger control
 	SearchOnlyOnKeywords bool
 	SearchKeywords       []string
+	// Local knowledge base
+	KbEnabled       bool
+	KbDir           string
+	KbTopK          int
+	KbContextTokens int
+	KbMaxFileMB     int
+	// Lark Wiki / Docs connector
+	LarkDocsSpaces     []string
+	LarkDocsTokens     []string
+	LarkDocsChatSpaces []string
+	LarkDocsSyncMin    int
+	LarkDocsBaseUrl    string
+	// Long-term user memory
+	MemoryEnabled    bool
+	MemoryMaxEntries int
+	MemoryMaxChars   int
+	MemoryInGroups   bool
+	MemoryTool       bool
+	// Long answer splitting
+	AnswerCardMaxBytes       int
+	AnswerFileThresholdBytes int
 	// Google Custom Search API key
 	GoogleApiKey string
 	// Google Custom Search Engine ID (cx)
 	GoogleCSEId string
+	// Search providers tried in order, with optional daily quotas like "google:100"
+	SearchProviders      []string
+	SearchProviderQuotas []string
+	BingApiKey           string
+	BraveApiKey          string
+	SearXNGURL           string
+	TavilyApiKey         string
+	// Page fetching backend: builtin or jina
+	WebFetchBackend string
+	JinaApiKey      string
+	// Fetch policy against SSRF: ports, domains, allowed private networks and limits
+	FetchAllowedPorts []int
+	FetchAllowDomains []string
+	FetchDenyDomains  []string
+	FetchAllowedCIDRs []string
+	FetchContentTypes []string
+	FetchMaxBodyMB    int
+	FetchMaxRedirects int
 	// ChatGPT API timeout in seconds
 	ChatGPTTimeoutSec int
+	// Access control: open_id/union_id, open_department_id and chat_id lists
+	AccessAllowUsers       []string
+	AccessDenyUsers        []string
+	AccessAllowDepartments []string
+	AccessDenyDepartments  []string
+	AccessAllowChats       []string
+	AccessDenyChats        []string
+	AdminUsers             []string
+	AdminOnlyCommands      []string
+	// Per-chat settings and other persistent data
+	DataDir string
+	// Allow replies in a thread the bot participates in without mentioning it
+	GroupThreadContinue bool
+	// Shared state backend
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	// Rate limiting: token bucket per user and per chat
+	RateLimitEnabled    bool
+	RateLimitBackend    string
+	RateLimitUserPerMin int
+	RateLimitUserBurst  int
+	RateLimitChatPerMin int
+	RateLimitChatBurst  int
+	RateLimitCoalesce   bool
+	RateLimitCoalesceMs int
+	// Text-to-speech replies for voice messages
+	TTSEnabled      bool
+	TTSDefaultOn    bool
+	TTSModel        string
+	TTSVoice        string
+	TTSVoiceOptions []string
+	TTSMaxChars     int
+	// Long voice messages are split into chunks transcribed concurrently
+	AudioChunkSec       int
+	AudioMaxConcurrency int
+	// Meeting recording transcription for media/file messages
+	MeetingEnabled   bool
+	MeetingMaxFileMB int
+	// Picture mode: default image model, selectable models and max images per request
+	ImageModel  string
+	ImageModels []string
+	ImageMaxN   int
+	// Picture mode: expand prompts with the chat model before generating by default
+	ImagePromptEnhance bool
 }
 
 func LoadConfig(cfg string) *Config {
@@ -67,6 +173,8 @@ func LoadConfig(cfg string) *Config {
 		FeishuAppVerificationToken: getViperStringValue("APP_VERIFICATION_TOKEN", ""),
 		FeishuBotName:              getViperStringValue("BOT_NAME", ""),
 		OpenaiApiKeys:              getViperStringArray("OPENAI_KEY", nil),
+		OpenaiModel:                getViperStringValue("OPENAI_MODEL", "gpt-5-2025-08-07"),
+		ModelOptions:               getViperStringArray("MODEL_OPTIONS", []string{"gpt-5-2025-08-07", "gpt-5-mini", "gpt-4o", "gpt-4o-mini"}),
 		HttpPort:                   getViperIntValue("HTTP_PORT", 9000),
 		HttpsPort:                  getViperIntValue("HTTPS_PORT", 9001),
 		UseHttps:                   getViperBoolValue("USE_HTTPS", false),
@@ -80,16 +188,102 @@ func LoadConfig(cfg string) *Config {
 		ArkBotId:                   getViperStringValue("ARK_BOT_ID", ""),
 		DebugHTTP:                  getViperBoolValue("DEBUG_HTTP", true),
 		SearchAlways:               getViperBoolValue("SEARCH_ALWAYS", false),
+		SearchMode:                 getViperStringValue("SEARCH_MODE", ""),
 		SearchTopK:                 getViperIntValue("SEARCH_TOPK", 3),
 		SearchOverallTimeoutSec:    getViperIntValue("SEARCH_OVERALL_TIMEOUT_SEC", 12),
 		SearchPerFetchTimeoutSec:   getViperIntValue("SEARCH_PER_FETCH_TIMEOUT_SEC", 20),
 		SearchMaxConcurrency:       getViperIntValue("SEARCH_MAX_CONCURRENCY", 3),
 		SearchCacheTTLMin:          getViperIntValue("SEARCH_CACHE_TTL_MIN", 5),
-		SearchOnlyOnKeywords:       getViperBoolValue("SEARCH_ONLY_ON_KEYWORDS", true),
+		SearchCacheSize:            getViperIntValue("SEARCH_CACHE_SIZE", 500),
+		SearchCacheBackend:         getViperStringValue("SEARCH_CACHE_BACKEND", "memory"),
+		FetchCacheTTLMin:           getViperIntValue("FETCH_CACHE_TTL_MIN", 30),
+		FetchCacheSizeMB:           getViperIntValue("FETCH_CACHE_SIZE_MB", 32),
+		SearchContextTokens:        getViperIntValue("SEARCH_CONTEXT_TOKENS", 6000),
+		SearchShowPlan:             getViperBoolValue("SEARCH_SHOW_PLAN", true),
+		EmbeddingApiUrl:            getViperStringValue("EMBEDDING_API_URL", ""),
+		EmbeddingApiKey:            getViperStringValue("EMBEDDING_API_KEY", ""),
+		EmbeddingModel:             getViperStringValue("EMBEDDING_MODEL", "text-embedding-3-small"),
+		EmbeddingDimensions:        getViperIntValue("EMBEDDING_DIMENSIONS", 0),
+		EmbeddingUseChatApi:        getViperBoolValue("EMBEDDING_USE_CHAT_API", false),
+		SearchOnlyOnKeywords:       getViperBoolValue("SEARCH_ONLY_ON_KEYWORDS", false),
 		SearchKeywords:             getViperStringArray("SEARCH_KEYWORDS", []string{"/read", "联网", "上网", "google", "谷歌", "搜索", "查一下", "最新", "实时"}),
+		KbEnabled:                  getViperBoolValue("KB_ENABLED", false),
+		KbDir:                      getViperStringValue("KB_DIR", ""),
+		KbTopK:                     getViperIntValue("KB_TOP_K", 4),
+		KbContextTokens:            getViperIntValue("KB_CONTEXT_TOKENS", 3000),
+		KbMaxFileMB:                getViperIntValue("KB_MAX_FILE_MB", 20),
+		LarkDocsSpaces:             getViperStringArray("LARK_DOCS_SPACES", nil),
+		LarkDocsTokens:             getViperStringArray("LARK_DOCS_TOKENS", nil),
+		LarkDocsChatSpaces:         getViperStringArray("LARK_DOCS_CHAT_SPACES", nil),
+		LarkDocsSyncMin:            getViperIntValue("LARK_DOCS_SYNC_MIN", 60),
+		LarkDocsBaseUrl:            getViperStringValue("LARK_DOCS_BASE_URL", "https://feishu.cn"),
+		MemoryEnabled:              getViperBoolValue("MEMORY_ENABLED", false),
+		MemoryMaxEntries:           getViperIntValue("MEMORY_MAX_ENTRIES", 20),
+		MemoryMaxChars:             getViperIntValue("MEMORY_MAX_CHARS", 200),
+		MemoryInGroups:             getViperBoolValue("MEMORY_IN_GROUPS", false),
+		MemoryTool:                 getViperBoolValue("MEMORY_TOOL", true),
+		AnswerCardMaxBytes:         getViperIntValue("ANSWER_CARD_MAX_BYTES", 8000),
+		AnswerFileThresholdBytes:   getViperIntValue("ANSWER_FILE_THRESHOLD_BYTES", 32000),
 		GoogleApiKey:               getViperStringValue("GOOGLE_API_KEY", ""),
 		GoogleCSEId:                getViperStringValue("GOOGLE_CSE_ID", ""),
+		SearchProviders:            getViperStringArray("SEARCH_PROVIDERS", []string{"google", "duckduckgo"}),
+		SearchProviderQuotas:       getViperStringArray("SEARCH_PROVIDER_QUOTAS", nil),
+		BingApiKey:                 getViperStringValue("BING_API_KEY", ""),
+		BraveApiKey:                getViperStringValue("BRAVE_API_KEY", ""),
+		SearXNGURL:                 getViperStringValue("SEARXNG_URL", ""),
+		TavilyApiKey:               getViperStringValue("TAVILY_API_KEY", ""),
+		WebFetchBackend:            getViperStringValue("WEB_FETCH_BACKEND", "builtin"),
+		JinaApiKey:                 getViperStringValue("JINA_API_KEY", ""),
+		FetchAllowedPorts:          getViperIntArray("FETCH_ALLOWED_PORTS", []int{80, 443, 8080, 8443}),
+		FetchAllowDomains:          getViperStringArray("FETCH_ALLOW_DOMAINS", nil),
+		FetchDenyDomains:           getViperStringArray("FETCH_DENY_DOMAINS", nil),
+		FetchAllowedCIDRs:          getViperStringArray("FETCH_ALLOWED_CIDRS", nil),
+		FetchContentTypes:          getViperStringArray("FETCH_CONTENT_TYPES", nil),
+		FetchMaxBodyMB:             getViperIntValue("FETCH_MAX_BODY_MB", 10),
+		FetchMaxRedirects:          getViperIntValue("FETCH_MAX_REDIRECTS", 5),
 		ChatGPTTimeoutSec:          getViperIntValue("CHATGPT_TIMEOUT_SEC", 120),
+		AccessAllowUsers:           getViperStringArray("ACCESS_ALLOW_USERS", nil),
+		AccessDenyUsers:            getViperStringArray("ACCESS_DENY_USERS", nil),
+		AccessAllowDepartments:     getViperStringArray("ACCESS_ALLOW_DEPARTMENTS", nil),
+		AccessDenyDepartments:      getViperStringArray("ACCESS_DENY_DEPARTMENTS", nil),
+		AccessAllowChats:           getViperStringArray("ACCESS_ALLOW_CHATS", nil),
+		AccessDenyChats:            getViperStringArray("ACCESS_DENY_CHATS", nil),
+		AdminUsers:                 getViperStringArray("ADMIN_USERS", nil),
+		AdminOnlyCommands:          getViperStringArray("ADMIN_ONLY_COMMANDS", []string{"/balance", "余额"}),
+		GroupThreadContinue:        getViperBoolValue("GROUP_THREAD_CONTINUE", false),
+		DataDir:                    getViperStringValue("DATA_DIR", "./data"),
+		RedisAddr:                  getViperStringValue("REDIS_ADDR", ""),
+		RedisPassword:              getViperStringValue("REDIS_PASSWORD", ""),
+		RedisDB:                    getViperIntValue("REDIS_DB", 0),
+		RateLimitEnabled:           getViperBoolValue("RATE_LIMIT_ENABLED", true),
+		RateLimitBackend:           getViperStringValue("RATE_LIMIT_BACKEND", "memory"),
+		RateLimitUserPerMin:        getViperIntValue("RATE_LIMIT_USER_PER_MIN", 10),
+		RateLimitUserBurst:         getViperIntValue("RATE_LIMIT_USER_BURST", 5),
+		RateLimitChatPerMin:        getViperIntValue("RATE_LIMIT_CHAT_PER_MIN", 30),
+		RateLimitChatBurst:         getViperIntValue("RATE_LIMIT_CHAT_BURST", 10),
+		RateLimitCoalesce:          getViperBoolValue("RATE_LIMIT_COALESCE", false),
+		RateLimitCoalesceMs:        getViperIntValue("RATE_LIMIT_COALESCE_MS", 1200),
+		TTSEnabled:                 getViperBoolValue("TTS_ENABLED", false),
+		TTSDefaultOn:               getViperBoolValue("TTS_DEFAULT_ON", false),
+		TTSModel:                   getViperStringValue("TTS_MODEL", "tts-1"),
+		TTSVoice:                   getViperStringValue("TTS_VOICE", "alloy"),
+		TTSVoiceOptions:            getViperStringArray("TTS_VOICE_OPTIONS", []string{"alloy", "echo", "fable", "onyx", "nova", "shimmer"}),
+		TTSMaxChars:                getViperIntValue("TTS_MAX_CHARS", 1000),
+		AudioChunkSec:              getViperIntValue("AUDIO_CHUNK_SEC", 120),
+		AudioMaxConcurrency:        getViperIntValue("AUDIO_MAX_CONCURRENCY", 3),
+		MeetingEnabled:             getViperBoolValue("MEETING_ENABLED", true),
+		MeetingMaxFileMB:           getViperIntValue("MEETING_MAX_FILE_MB", 200),
+		ImageModel:                 getViperStringValue("IMAGE_MODEL", "dall-e-3"),
+		ImageModels:                getViperStringArray("IMAGE_MODELS", []string{"dall-e-3", "gpt-image-1", "dall-e-2"}),
+		ImageMaxN:                  getViperIntValue("IMAGE_MAX_N", 4),
+		ImagePromptEnhance:         getViperBoolValue("IMAGE_PROMPT_ENHANCE", false),
+	}
+	// 兼容旧配置：未设置 SEARCH_MODE 时由 SEARCH_ALWAYS 决定
+	if config.SearchMode == "" {
+		config.SearchMode = "auto"
+		if config.SearchAlways {
+			config.SearchMode = "always"
+		}
 	}
 
 	return config
@@ -99,10 +293,41 @@ func LoadConfig(cfg string) *Config {
 SEARCH_OVERALL_TIMEOUT_SEC: 总超时（默认 10）
 SEARCH_PER_FETCH_TIMEOUT_SEC: 单页抓取超时（默认 6）
 SEARCH_MAX_CONCURRENCY: 并发抓取上限（默认 4）
-SEARCH_CACHE_TTL_MIN: 搜索上下文缓存分钟数（默认 5）
-SEARCH_ONLY_ON_KEYWORDS: 是否仅在关键词命中时触发（默认 true）
+SEARCH_CACHE_TTL_MIN: 搜索结果列表缓存分钟数，0 表示不缓存（默认 5）
+SEARCH_CACHE_SIZE: 搜索结果列表缓存条数上限（默认 500）
+SEARCH_CACHE_BACKEND: 缓存持久化方式 memory/file/redis，file 保存在 DATA_DIR/web_cache 下，条数和大小上限沿用 SEARCH_CACHE_SIZE / FETCH_CACHE_SIZE_MB（默认 memory）
+FETCH_CACHE_TTL_MIN: 网页正文缓存分钟数，与搜索结果分开缓存，0 表示不缓存（默认 30）
+FETCH_CACHE_SIZE_MB: 网页正文内存缓存上限（默认 32）
+SEARCH_CONTEXT_TOKENS: 检索资料的 token 预算，按相关度挑选网页片段（默认 6000）
+SEARCH_SHOW_PLAN: 联网搜索时展示检索过程卡片（默认 true，可在群设置中单独关闭）
+EMBEDDING_API_URL / EMBEDDING_API_KEY / EMBEDDING_MODEL: 配置后使用向量对网页片段语义重排，请求 EMBEDDING_API_URL + /embeddings，key 留空时沿用 OPENAI_KEY
+EMBEDDING_DIMENSIONS: 向量输出维度，仅 text-embedding-3 等支持降维的模型可用（默认 0，使用模型默认维度）
+EMBEDDING_USE_CHAT_API: 向量请求使用对话接口的地址和 key（含多 key 负载均衡与重试），PROVIDER=ark 时调用方舟向量接口，EMBEDDING_MODEL 填方舟的向量模型或接入点 ID（默认 false）
+SEARCH_MODE: 联网模式 auto/always/never（默认 auto，SEARCH_ALWAYS=true 时为 always，可在群设置中单独修改）
+SEARCH_ONLY_ON_KEYWORDS: auto 模式下仅在关键词命中时联网，未命中时跳过判断直接回答（默认 false）
 SEARCH_KEYWORDS: 触发关键词列表（默认 [/read, 联网, 上网, google, 谷歌, 搜索, 查一下, 最新, 实时]）
-其他沿用：SEARCH_ALWAYS、SEARCH_TOPK、GOOGLE_API_KEY、GOOGLE_CSE_ID
+KB_ENABLED: 开启本地知识库，回答时检索知识库片段并标注引用（默认 false）
+KB_DIR: 知识库目录，启动时导入其中的 md/txt/pdf 文件，oc_ 开头的子目录只对对应的群可见
+KB_TOP_K / KB_CONTEXT_TOKENS: 每次最多使用的知识库片段数（默认 4）和 token 预算（默认 3000）
+KB_MAX_FILE_MB: /kb add 上传文件的大小上限（默认 20）
+LARK_DOCS_SPACES: 同步到知识库的飞书知识空间 ID 列表，配置后自动开启知识库
+LARK_DOCS_TOKENS: 单独同步的文档，可填知识库节点 token 或新版文档 (docx) token
+LARK_DOCS_CHAT_SPACES: 各群可检索的知识空间，格式 群ID:空间1|空间2，群 ID 为 * 时对所有群生效，空间为 * 时可检索全部已同步文档；留空时任何群都不能检索飞书文档
+LARK_DOCS_SYNC_MIN: 飞书文档的同步间隔分钟数，只重新拉取有更新的文档（默认 60）
+LARK_DOCS_BASE_URL: 回答中引用文档时使用的链接域名（默认 https://feishu.cn）
+MEMORY_ENABLED: 开启长期记忆，用户通过 /remember 保存的信息会在新话题开始时提供给模型（默认 false）
+MEMORY_MAX_ENTRIES / MEMORY_MAX_CHARS: 每个用户最多保存的记忆条数（默认 20）和单条字数（默认 200）
+MEMORY_IN_GROUPS: 群聊的新话题也使用发起人的记忆，群内其他成员可能从回答中看到（默认 false，仅单聊使用）
+MEMORY_TOOL: 开启长期记忆时允许模型通过工具调用（function calling）保存用户的偏好，保存后会在回答末尾提示，需要模型支持工具调用，PROVIDER=ark 时不生效（默认 true）
+MEETING_ENABLED: 开启会议录音转写，单聊或机器人话题中发送音视频文件时返回逐字稿和会议纪要，关闭时回复提示（默认 true）
+MEETING_MAX_FILE_MB: 会议文件大小上限，wav/ogg/opus 在本地切分后转写；mp3/m4a/mp4 等格式整体上传，上限固定为 25MB（默认 200）
+RATE_LIMIT_COALESCE: 合并同一用户连续发送的普通文本为一次提问，开启后每条消息会先等待 RATE_LIMIT_COALESCE_MS 毫秒，命令不参与合并（默认 false）
+ANSWER_CARD_MAX_BYTES: 单张回答卡片正文的字节数上限，超出时按段落和代码块拆成多张卡片（默认 8000）
+ANSWER_FILE_THRESHOLD_BYTES: 回答超过该字节数时发送摘要卡片并附上完整的 Markdown 文件，0 表示总是拆成多张卡片（默认 32000）
+SEARCH_PROVIDERS: 搜索引擎回退顺序（默认 [google, duckduckgo]，可选 bing、brave、searxng、tavily）
+SEARCH_PROVIDER_QUOTAS: 各搜索引擎每日请求上限（如 [google:100, brave:2000]）
+消息以 /search 开头时强制联网，以 /nosearch 开头时不联网
+其他沿用：SEARCH_ALWAYS（已由 SEARCH_MODE 替代）、SEARCH_TOPK、GOOGLE_API_KEY、GOOGLE_CSE_ID
 */
 
 func getViperStringValue(key string, defaultValue string) string {
@@ -137,6 +362,28 @@ func getViperStringArray(key string, defaultValue []string) []string {
 	return defaultValue
 }
 
+// FETCH_ALLOWED_PORTS: 80,443
+// result:[80 443], 无效项会被忽略
+func getViperIntArray(key string, defaultValue []int) []int {
+	values := getViperStringArray(key, nil)
+	if len(values) == 0 {
+		return defaultValue
+	}
+	var result []int
+	for _, v := range values {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			fmt.Printf("⚠️ Ignoring invalid %s value %q\n", key, v)
+			continue
+		}
+		result = append(result, n)
+	}
+	if len(result) == 0 {
+		return defaultValue
+	}
+	return result
+}
+
 func getViperIntValue(key string, defaultValue int) int {
 	value := viper.GetInt(key)
 	if value == 0 {
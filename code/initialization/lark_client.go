@@ -1,11 +1,19 @@
 package initialization
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+
 	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkcore "github.com/larksuite/oapi-sdk-go/v3/core"
 )
 
 var larkClient *lark.Client
 
+// botOpenId 机器人自身的 open_id，用于准确识别 @机器人
+var botOpenId string
+
 func LoadLarkClient(config Config) {
 	larkClient = lark.NewClient(config.FeishuAppId, config.FeishuAppSecret)
 }
@@ -13,3 +21,35 @@ func LoadLarkClient(config Config) {
 func GetLarkClient() *lark.Client {
 	return larkClient
 }
+
+type botInfoResponse struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Bot  struct {
+		AppName string `json:"app_name"`
+		OpenId  string `json:"open_id"`
+	} `json:"bot"`
+}
+
+// LoadBotInfo 通过机器人信息接口获取机器人的 open_id
+func LoadBotInfo() error {
+	resp, err := larkClient.Get(context.Background(), "/open-apis/bot/v3/info",
+		nil, larkcore.AccessTokenTypeTenant)
+	if err != nil {
+		return err
+	}
+	var info botInfoResponse
+	if err := json.Unmarshal(resp.RawBody, &info); err != nil {
+		return err
+	}
+	if info.Code != 0 {
+		return fmt.Errorf("get bot info failed: %d %s", info.Code, info.Msg)
+	}
+	botOpenId = info.Bot.OpenId
+	return nil
+}
+
+// GetBotOpenId 获取失败时返回空字符串，调用方应回退到按名称匹配
+func GetBotOpenId() string {
+	return botOpenId
+}
@@ -0,0 +1,19 @@
+package initialization
+
+import (
+	"start-feishubot/services/redis"
+)
+
+var redisClient *redis.Client
+
+func LoadRedisClient(config Config) {
+	if config.RedisAddr == "" {
+		return
+	}
+	redisClient = redis.NewClient(config.RedisAddr, config.RedisPassword, config.RedisDB)
+}
+
+// GetRedisClient 未配置 REDIS_ADDR 时返回 nil
+func GetRedisClient() *redis.Client {
+	return redisClient
+}
@@ -0,0 +1,44 @@
+package initialization
+
+import (
+	"io/ioutil"
+	"log"
+
+	"gopkg.in/yaml.v2"
+)
+
+type PicStyle struct {
+	Title   string `yaml:"title"`
+	Content string `yaml:"content"`
+}
+
+var PicStyleList []PicStyle
+
+// InitPicStyleList 加载图片风格预设，文件不存在时不提供预设
+func InitPicStyleList(path string) []PicStyle {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Printf("⚠️ Style presets not loaded from %s: %v", path, err)
+		return PicStyleList
+	}
+	var styles []PicStyle
+	if err := yaml.Unmarshal(data, &styles); err != nil {
+		log.Printf("⚠️ Invalid style presets in %s: %v", path, err)
+		return PicStyleList
+	}
+	PicStyleList = styles
+	return PicStyleList
+}
+
+func GetPicStyleList() []PicStyle {
+	return PicStyleList
+}
+
+func GetPicStyleByTitle(title string) *PicStyle {
+	for _, style := range PicStyleList {
+		if style.Title == title {
+			return &style
+		}
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"start-feishubot/handlers"
 	"start-feishubot/initialization"
 	"start-feishubot/services/openai"
+	"start-feishubot/utils"
 	"strconv"
 
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
@@ -31,6 +32,7 @@ func main() {
 
 	log.Println("📋 Initializing role list...")
 	initialization.InitRoleList()
+	initialization.InitPicStyleList("style_list.yaml")
 
 	log.Println("⚙️ Parsing command line flags...")
 	pflag.Parse()
@@ -40,6 +42,15 @@ func main() {
 	config := initialization.LoadConfig(*cfg)
 	log.Printf("✅ Config loaded: HTTP_PORT=%d, HTTPS_PORT=%d, USE_HTTPS=%t",
 		config.HttpPort, config.HttpsPort, config.UseHttps)
+	utils.SetPageFetcher(utils.NewPageFetcher(config.WebFetchBackend, config.JinaApiKey, &utils.FetchPolicy{
+		AllowedPorts: config.FetchAllowedPorts,
+		AllowDomains: config.FetchAllowDomains,
+		DenyDomains:  config.FetchDenyDomains,
+		AllowedNets:  utils.ParseCIDRs(config.FetchAllowedCIDRs),
+		ContentTypes: config.FetchContentTypes,
+		MaxBodyBytes: int64(config.FetchMaxBodyMB) << 20,
+		MaxRedirects: config.FetchMaxRedirects,
+	}))
 
 	// 支持 Railway 的 PORT 环境变量
 	if port := os.Getenv("PORT"); port != "" {
@@ -60,6 +71,19 @@ func main() {
 	log.Printf("✅ Lark client loaded: APP_ID=%s, BOT_NAME=%s",
 		config.FeishuAppId, config.FeishuBotName)
 
+	log.Println("🪪 Loading bot info...")
+	if err := initialization.LoadBotInfo(); err != nil {
+		log.Printf("⚠️ Failed to load bot info, falling back to BOT_NAME for mentions: %v", err)
+	} else {
+		log.Printf("✅ Bot info loaded: BOT_OPEN_ID=%s", initialization.GetBotOpenId())
+	}
+
+	if config.RedisAddr != "" {
+		log.Println("🧰 Loading Redis client...")
+		initialization.LoadRedisClient(*config)
+		log.Printf("✅ Redis client loaded: REDIS_ADDR=%s", config.RedisAddr)
+	}
+
 	log.Println("🤖 Initializing ChatGPT client...")
 	gpt := openai.NewChatGPT(*config)
 	log.Printf("✅ ChatGPT client initialized: API_URL=%s, PROVIDER=%s",
@@ -120,6 +144,14 @@ func main() {
 		})
 	})
 
+	// 缓存命中统计
+	log.Println("  📍 Registering /stats endpoint")
+	r.GET("/stats", func(c *gin.Context) {
+		c.JSON(200, gin.H{
+			"web_cache": utils.WebCacheStats(),
+		})
+	})
+
 	log.Println("  📍 Registering /webhook/event endpoint")
 	r.POST("/webhook/event", func(c *gin.Context) {
 		fmt.Printf("📨 Webhook event received from %s\n", c.ClientIP())
@@ -0,0 +1,227 @@
+package accesscontrol
+
+import (
+	"context"
+	"fmt"
+	"start-feishubot/initialization"
+	"strings"
+	"time"
+
+	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
+	"github.com/patrickmn/go-cache"
+)
+
+// Rules 访问控制规则，用户可填写 open_id 或 union_id（on_ 开头），部门为 open_department_id
+type Rules struct {
+	AllowUsers       []string
+	DenyUsers        []string
+	AllowDepartments []string
+	DenyDepartments  []string
+	AllowChats       []string
+	DenyChats        []string
+	Admins           []string
+}
+
+// Subject 一次请求的发起方
+type Subject struct {
+	OpenId  string
+	UnionId string
+	ChatId  string
+}
+
+// Decision 鉴权结果
+type Decision struct {
+	Allowed bool
+	Admin   bool
+	Reason  string
+}
+
+// DepartmentResolver 查询用户所属部门
+type DepartmentResolver interface {
+	Departments(openId string) ([]string, error)
+}
+
+// UnionIdResolver 通过 open_id 查询 union_id，卡片回调等只带 open_id 的请求需要用它匹配 union_id 规则
+type UnionIdResolver interface {
+	UnionId(openId string) (string, error)
+}
+
+type Checker struct {
+	rules    Rules
+	resolver DepartmentResolver
+}
+
+func NewChecker(rules Rules, resolver DepartmentResolver) *Checker {
+	return &Checker{rules: rules, resolver: resolver}
+}
+
+// Enabled 是否配置了任意一条访问规则
+func (c *Checker) Enabled() bool {
+	r := c.rules
+	return len(r.AllowUsers)+len(r.DenyUsers)+len(r.AllowDepartments)+
+		len(r.DenyDepartments)+len(r.AllowChats)+len(r.DenyChats) > 0
+}
+
+// HasAdmins 是否配置了管理员，未配置时管理员命令对所有人开放
+func (c *Checker) HasAdmins() bool {
+	return len(c.rules.Admins) > 0
+}
+
+func (c *Checker) IsAdmin(s Subject) bool {
+	return matchUser(c.rules.Admins, s)
+}
+
+// hasUnionIds 用户规则中是否填写了 union_id（on_ 开头）
+func (c *Checker) hasUnionIds() bool {
+	for _, list := range [][]string{c.rules.AllowUsers, c.rules.DenyUsers, c.rules.Admins} {
+		for _, item := range list {
+			if strings.HasPrefix(strings.TrimSpace(item), "on_") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check 依次判断：用户黑名单 > 群黑名单 > 管理员 > 部门黑名单 > 白名单。
+// 请求只带 open_id 而规则中填写了 union_id 时，先查询用户的 union_id
+func (c *Checker) Check(s Subject) Decision {
+	if resolver, ok := c.resolver.(UnionIdResolver); ok && s.UnionId == "" && s.OpenId != "" && c.hasUnionIds() {
+		unionId, err := resolver.UnionId(s.OpenId)
+		if err != nil {
+			fmt.Printf("⚠️ [Access] Failed to resolve union_id for %s: %v\n", s.OpenId, err)
+			// 无法确认用户不在按 union_id 配置的黑名单中，按拒绝处理
+			if len(c.rules.DenyUsers) > 0 {
+				return Decision{Allowed: false, Reason: "union_id lookup failed"}
+			}
+		}
+		s.UnionId = unionId
+	}
+	admin := c.IsAdmin(s)
+	if matchUser(c.rules.DenyUsers, s) {
+		return Decision{Allowed: false, Admin: admin, Reason: "user denied"}
+	}
+	if contains(c.rules.DenyChats, s.ChatId) {
+		return Decision{Allowed: false, Admin: admin, Reason: "chat denied"}
+	}
+	if admin {
+		return Decision{Allowed: true, Admin: true, Reason: "admin"}
+	}
+
+	var departments []string
+	needDepartments := len(c.rules.DenyDepartments) > 0 || len(c.rules.AllowDepartments) > 0
+	if needDepartments && c.resolver != nil && s.OpenId != "" {
+		var err error
+		departments, err = c.resolver.Departments(s.OpenId)
+		if err != nil {
+			fmt.Printf("⚠️ [Access] Failed to resolve departments for %s: %v\n", s.OpenId, err)
+			// 查不到部门时无法确认用户不在部门黑名单中，按拒绝处理
+			if len(c.rules.DenyDepartments) > 0 {
+				return Decision{Allowed: false, Reason: "department lookup failed"}
+			}
+		}
+	}
+	for _, d := range departments {
+		if contains(c.rules.DenyDepartments, d) {
+			return Decision{Allowed: false, Reason: "department denied: " + d}
+		}
+	}
+
+	hasAllowList := len(c.rules.AllowUsers)+len(c.rules.AllowDepartments)+len(c.rules.AllowChats) > 0
+	if !hasAllowList {
+		return Decision{Allowed: true, Reason: "no allowlist"}
+	}
+	if matchUser(c.rules.AllowUsers, s) {
+		return Decision{Allowed: true, Reason: "user allowed"}
+	}
+	if contains(c.rules.AllowChats, s.ChatId) {
+		return Decision{Allowed: true, Reason: "chat allowed"}
+	}
+	for _, d := range departments {
+		if contains(c.rules.AllowDepartments, d) {
+			return Decision{Allowed: true, Reason: "department allowed: " + d}
+		}
+	}
+	return Decision{Allowed: false, Reason: "not in allowlist"}
+}
+
+func matchUser(list []string, s Subject) bool {
+	return contains(list, s.OpenId) || contains(list, s.UnionId)
+}
+
+func contains(list []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, item := range list {
+		if strings.TrimSpace(item) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// departmentFailureTTL 查询失败的结果缓存时间，避免通讯录接口故障时每条消息都重复请求
+const departmentFailureTTL = time.Minute
+
+type userResult struct {
+	departments []string
+	unionId     string
+	err         error
+}
+
+// LarkDepartmentResolver 通过飞书通讯录接口查询用户部门和 union_id，结果缓存 30 分钟，查询失败缓存 1 分钟
+type LarkDepartmentResolver struct {
+	cache *cache.Cache
+}
+
+func NewLarkDepartmentResolver() *LarkDepartmentResolver {
+	return &LarkDepartmentResolver{cache: cache.New(30*time.Minute, time.Hour)}
+}
+
+func (r *LarkDepartmentResolver) Departments(openId string) ([]string, error) {
+	result := r.lookup(openId)
+	return result.departments, result.err
+}
+
+func (r *LarkDepartmentResolver) UnionId(openId string) (string, error) {
+	result := r.lookup(openId)
+	return result.unionId, result.err
+}
+
+func (r *LarkDepartmentResolver) lookup(openId string) userResult {
+	if v, ok := r.cache.Get(openId); ok {
+		return v.(userResult)
+	}
+	result, err := r.fetch(openId)
+	if err != nil {
+		result = userResult{err: err}
+		r.cache.Set(openId, result, departmentFailureTTL)
+		return result
+	}
+	r.cache.Set(openId, result, cache.DefaultExpiration)
+	return result
+}
+
+func (r *LarkDepartmentResolver) fetch(openId string) (userResult, error) {
+	req := larkcontact.NewGetUserReqBuilder().
+		UserId(openId).
+		UserIdType("open_id").
+		DepartmentIdType("open_department_id").
+		Build()
+	resp, err := initialization.GetLarkClient().Contact.User.Get(context.Background(), req)
+	if err != nil {
+		return userResult{}, err
+	}
+	if !resp.Success() {
+		return userResult{}, fmt.Errorf("get user failed: %d %s", resp.Code, resp.Msg)
+	}
+	var result userResult
+	if resp.Data != nil && resp.Data.User != nil {
+		result.departments = resp.Data.User.DepartmentIds
+		if resp.Data.User.UnionId != nil {
+			result.unionId = *resp.Data.User.UnionId
+		}
+	}
+	return result, nil
+}
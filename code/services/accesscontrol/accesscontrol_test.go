@@ -0,0 +1,109 @@
+package accesscontrol
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) Departments(openId string) ([]string, error) {
+	if d, ok := f[openId]; ok {
+		return d, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestChecker_Check(t *testing.T) {
+	resolver := fakeResolver{
+		"ou_dev":   {"od_dev"},
+		"ou_sales": {"od_sales"},
+		"ou_admin": {"od_sales"},
+	}
+	tests := []struct {
+		name    string
+		rules   Rules
+		subject Subject
+		allowed bool
+		admin   bool
+	}{
+		{
+			name:    "no rules allows everyone",
+			subject: Subject{OpenId: "ou_any", ChatId: "oc_1"},
+			allowed: true,
+		},
+		{
+			name:    "denied user by union id",
+			rules:   Rules{DenyUsers: []string{"on_bad"}},
+			subject: Subject{OpenId: "ou_bad", UnionId: "on_bad"},
+			allowed: false,
+		},
+		{
+			name:    "denied chat",
+			rules:   Rules{DenyChats: []string{"oc_blocked"}},
+			subject: Subject{OpenId: "ou_dev", ChatId: "oc_blocked"},
+			allowed: false,
+		},
+		{
+			name:    "denied department",
+			rules:   Rules{DenyDepartments: []string{"od_sales"}},
+			subject: Subject{OpenId: "ou_sales"},
+			allowed: false,
+		},
+		{
+			name:    "department lookup failure denies when department deny rules exist",
+			rules:   Rules{DenyDepartments: []string{"od_sales"}},
+			subject: Subject{OpenId: "ou_unknown"},
+			allowed: false,
+		},
+		{
+			name:    "department lookup failure without deny rules falls back to allowlist",
+			rules:   Rules{AllowDepartments: []string{"od_dev"}, AllowChats: []string{"oc_ok"}},
+			subject: Subject{OpenId: "ou_unknown", ChatId: "oc_ok"},
+			allowed: true,
+		},
+		{
+			name:    "allowed department",
+			rules:   Rules{AllowDepartments: []string{"od_dev"}},
+			subject: Subject{OpenId: "ou_dev"},
+			allowed: true,
+		},
+		{
+			name:    "not in allowlist",
+			rules:   Rules{AllowDepartments: []string{"od_dev"}, AllowChats: []string{"oc_ok"}},
+			subject: Subject{OpenId: "ou_sales", ChatId: "oc_other"},
+			allowed: false,
+		},
+		{
+			name:    "allowed chat",
+			rules:   Rules{AllowChats: []string{"oc_ok"}},
+			subject: Subject{OpenId: "ou_sales", ChatId: "oc_ok"},
+			allowed: true,
+		},
+		{
+			name:    "admin bypasses allowlist and department deny",
+			rules:   Rules{AllowUsers: []string{"ou_dev"}, DenyDepartments: []string{"od_sales"}, Admins: []string{"ou_admin"}},
+			subject: Subject{OpenId: "ou_admin"},
+			allowed: true,
+			admin:   true,
+		},
+		{
+			name:    "user deny wins over admin",
+			rules:   Rules{DenyUsers: []string{"ou_admin"}, Admins: []string{"ou_admin"}},
+			subject: Subject{OpenId: "ou_admin"},
+			allowed: false,
+			admin:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewChecker(tt.rules, resolver).Check(tt.subject)
+			if got.Allowed != tt.allowed {
+				t.Errorf("Check() allowed = %v, want %v (reason %s)", got.Allowed, tt.allowed, got.Reason)
+			}
+			if got.Admin != tt.admin {
+				t.Errorf("Check() admin = %v, want %v", got.Admin, tt.admin)
+			}
+		})
+	}
+}
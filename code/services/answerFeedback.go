@@ -0,0 +1,132 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"start-feishubot/services/cache"
+	"start-feishubot/services/store"
+	"time"
+)
+
+type FeedbackRating string
+
+const (
+	FeedbackUp   FeedbackRating = "up"
+	FeedbackDown FeedbackRating = "down"
+)
+
+// AnswerFeedback 用户对一条回答的 👍/👎 评价，保存提问和回答原文方便之后分析
+type AnswerFeedback struct {
+	SessionId string         `json:"session_id"`
+	ChatId    string         `json:"chat_id,omitempty"`
+	UserId    string         `json:"user_id"`
+	Rating    FeedbackRating `json:"rating"`
+	Question  string         `json:"question,omitempty"`
+	Answer    string         `json:"answer,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// answerRetention 发出的回答保留多久，期间收到的评价都能关联到提问和回答原文
+const answerRetention = 30 * 24 * time.Hour
+
+// AnswerRecord 发送回答时保存的提问和回答，会话过期后评价仍能找到原文
+type AnswerRecord struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+type AnswerFeedbackServiceInterface interface {
+	// SaveAnswer 发送带评价按钮的回答时保存提问和回答
+	SaveAnswer(sessionId string, answerId string, record AnswerRecord) error
+	// FindAnswer 查找 SaveAnswer 保存的提问和回答
+	FindAnswer(sessionId string, answerId string) (AnswerRecord, bool)
+	// Record 保存评价，同一用户对同一条回答重复评价时覆盖之前的结果
+	Record(answerId string, feedback AnswerFeedback) error
+	// List 按时间顺序返回全部评价
+	List() []AnswerFeedback
+}
+
+type AnswerFeedbackService struct {
+	store   *store.FileStore[AnswerFeedback]
+	answers cache.Backend
+}
+
+var answerFeedbackService *AnswerFeedbackService
+
+// InitAnswerFeedbackService 从 path 加载持久化的回答评价，发出的回答每条一个文件保存在 answersDir，
+// 保留 answerRetention 后由 FileBackend 定期清理
+func InitAnswerFeedbackService(path string, answersDir string) error {
+	s, err := store.NewFileStore[AnswerFeedback](path)
+	if err != nil {
+		return err
+	}
+	answers, err := cache.NewFileBackend(answersDir, 0, 0)
+	if err != nil {
+		return err
+	}
+	answerFeedbackService = &AnswerFeedbackService{store: s, answers: answers}
+	return nil
+}
+
+// GetAnswerFeedbackService 未初始化时退化为不落盘的内存存储，不保存回答原文
+func GetAnswerFeedbackService() AnswerFeedbackServiceInterface {
+	if answerFeedbackService == nil {
+		s, _ := store.NewFileStore[AnswerFeedback]("")
+		answerFeedbackService = &AnswerFeedbackService{store: s}
+	}
+	return answerFeedbackService
+}
+
+func (s *AnswerFeedbackService) Record(answerId string, feedback AnswerFeedback) error {
+	if feedback.CreatedAt.IsZero() {
+		feedback.CreatedAt = time.Now()
+	}
+	key := feedback.SessionId + ":" + answerId + ":" + feedback.UserId
+	if err := s.store.Set(key, feedback); err != nil {
+		return fmt.Errorf("save answer feedback: %w", err)
+	}
+	return nil
+}
+
+func (s *AnswerFeedbackService) SaveAnswer(sessionId string, answerId string, record AnswerRecord) error {
+	if s.answers == nil {
+		return nil
+	}
+	content, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := s.answers.Set(sessionId+":"+answerId, string(content), answerRetention); err != nil {
+		return fmt.Errorf("save answer: %w", err)
+	}
+	return nil
+}
+
+func (s *AnswerFeedbackService) FindAnswer(sessionId string, answerId string) (AnswerRecord, bool) {
+	var record AnswerRecord
+	if s.answers == nil {
+		return record, false
+	}
+	content, ok, err := s.answers.Get(sessionId + ":" + answerId)
+	if err != nil || !ok {
+		return record, false
+	}
+	if err := json.Unmarshal([]byte(content), &record); err != nil {
+		return record, false
+	}
+	return record, true
+}
+
+func (s *AnswerFeedbackService) List() []AnswerFeedback {
+	var list []AnswerFeedback
+	for _, key := range s.store.Keys() {
+		if feedback, ok := s.store.Get(key); ok {
+			list = append(list, feedback)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
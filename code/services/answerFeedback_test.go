@@ -0,0 +1,32 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestAnswerFeedbackService_SaveAnswer(t *testing.T) {
+	dir := t.TempDir()
+	if err := InitAnswerFeedbackService("", filepath.Join(dir, "answers")); err != nil {
+		t.Fatal(err)
+	}
+	s := GetAnswerFeedbackService()
+	record := AnswerRecord{Question: "什么是 Go?", Answer: "Go 是一门编程语言"}
+	if err := s.SaveAnswer("om_a", "1f2e", record); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s.FindAnswer("om_a", "1f2e"); !ok || got != record {
+		t.Errorf("FindAnswer() = %+v, %v", got, ok)
+	}
+	if _, ok := s.FindAnswer("om_b", "1f2e"); ok {
+		t.Error("answers should be isolated per session")
+	}
+
+	// 重新初始化后仍能找到之前保存的回答
+	if err := InitAnswerFeedbackService("", filepath.Join(dir, "answers")); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := GetAnswerFeedbackService().FindAnswer("om_a", "1f2e"); !ok || got != record {
+		t.Errorf("FindAnswer() after reload = %+v, %v", got, ok)
+	}
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"start-feishubot/services/redis"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend 可选的持久化存储，内存未命中时读取，用于重启后或多个实例之间复用缓存
+type Backend interface {
+	Get(key string) (string, bool, error)
+	Set(key, value string, ttl time.Duration) error
+}
+
+// fileSweepInterval 写入时最多每隔这么久在后台清理一次过期文件，
+// 过期的条目很少会被再次读取，只靠读取时删除会让目录一直增长
+const fileSweepInterval = 10 * time.Minute
+
+// FileBackend 每个条目保存为目录下的一个文件，文件名为 key 的 sha1。
+// 条数或字节数超过上限时，清理时先删除最早过期的条目
+type FileBackend struct {
+	dir        string
+	maxEntries int
+	maxBytes   int64
+	now        func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	sweeping  bool
+	// 上次清理后的条目数和字节数，写入时累加，用于判断是否需要提前清理
+	entries int
+	bytes   int64
+}
+
+type fileEntry struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Value     string    `json:"value"`
+}
+
+// NewFileBackend 创建缓存目录，并清理上次运行遗留的过期文件。
+// maxEntries、maxBytes 小于等于 0 时不做对应限制
+func NewFileBackend(dir string, maxEntries int, maxBytes int64) (*FileBackend, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	b := &FileBackend{dir: dir, maxEntries: maxEntries, maxBytes: maxBytes, now: time.Now}
+	b.lastSweep = b.now()
+	b.sweep(0)
+	return b, nil
+}
+
+func (b *FileBackend) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(b.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (b *FileBackend) Get(key string) (string, bool, error) {
+	entry, err := b.read(b.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if !b.now().Before(entry.ExpiresAt) {
+		os.Remove(b.path(key))
+		return "", false, nil
+	}
+	return entry.Value, true, nil
+}
+
+func (b *FileBackend) Set(key, value string, ttl time.Duration) error {
+	content, err := json.Marshal(fileEntry{ExpiresAt: b.now().Add(ttl), Value: value})
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再改名，避免读到写了一半的内容
+	tmp, err := ioutil.TempFile(b.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), b.path(key)); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.entries++
+	b.bytes += int64(len(content))
+	b.mu.Unlock()
+	b.maybeSweep()
+	return nil
+}
+
+func (b *FileBackend) overLimit() bool {
+	return (b.maxEntries > 0 && b.entries > b.maxEntries) || (b.maxBytes > 0 && b.bytes > b.maxBytes)
+}
+
+// maybeSweep 距上次清理超过 fileSweepInterval 或超出容量时在后台清理，同一时间只有一个清理任务
+func (b *FileBackend) maybeSweep() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if b.sweeping || (now.Sub(b.lastSweep) < fileSweepInterval && !b.overLimit()) {
+		return
+	}
+	b.sweeping, b.lastSweep = true, now
+	go func() {
+		// 其他写入可能正在使用临时文件，只删除上一轮之前遗留的
+		b.sweep(fileSweepInterval)
+		b.mu.Lock()
+		b.sweeping = false
+		b.mu.Unlock()
+	}()
+}
+
+func (b *FileBackend) read(path string) (fileEntry, error) {
+	var entry fileEntry
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(content, &entry)
+	return entry, err
+}
+
+// sweep 删除过期文件和修改时间早于 tmpAge 之前的临时文件，超出容量时再按过期时间从早到晚删除
+func (b *FileBackend) sweep(tmpAge time.Duration) {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return
+	}
+	type liveFile struct {
+		path      string
+		size      int64
+		expiresAt time.Time
+	}
+	var live []liveFile
+	var bytes int64
+	now := b.now()
+	for _, f := range files {
+		path := filepath.Join(b.dir, f.Name())
+		if strings.HasPrefix(f.Name(), ".tmp-") {
+			if now.Sub(f.ModTime()) >= tmpAge {
+				os.Remove(path)
+			}
+			continue
+		}
+		entry, err := b.read(path)
+		if err != nil || !now.Before(entry.ExpiresAt) {
+			os.Remove(path)
+			continue
+		}
+		live = append(live, liveFile{path: path, size: f.Size(), expiresAt: entry.ExpiresAt})
+		bytes += f.Size()
+	}
+
+	sort.Slice(live, func(i, j int) bool { return live[i].expiresAt.Before(live[j].expiresAt) })
+	for len(live) > 0 && ((b.maxEntries > 0 && len(live) > b.maxEntries) || (b.maxBytes > 0 && bytes > b.maxBytes)) {
+		os.Remove(live[0].path)
+		bytes -= live[0].size
+		live = live[1:]
+	}
+
+	b.mu.Lock()
+	b.entries, b.bytes = len(live), bytes
+	b.mu.Unlock()
+}
+
+// RedisBackend 使用 Redis 保存缓存，过期由 Redis 处理
+type RedisBackend struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisBackend(client *redis.Client, prefix string) *RedisBackend {
+	return &RedisBackend{client: client, prefix: prefix}
+}
+
+func (b *RedisBackend) Get(key string) (string, bool, error) {
+	value, err := b.client.Get(b.prefix + key)
+	if errors.Is(err, redis.ErrNil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func (b *RedisBackend) Set(key, value string, ttl time.Duration) error {
+	return b.client.Set(b.prefix+key, value, ttl)
+}
@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stats 缓存命中统计
+type Stats struct {
+	Hits        uint64  `json:"hits"`
+	Misses      uint64  `json:"misses"`
+	BackendHits uint64  `json:"backend_hits"`
+	Shared      uint64  `json:"shared"`
+	Evictions   uint64  `json:"evictions"`
+	Entries     int     `json:"entries"`
+	Bytes       int64   `json:"bytes"`
+	HitRate     float64 `json:"hit_rate"`
+}
+
+type Options struct {
+	// 名称用于日志
+	Name       string
+	MaxEntries int
+	MaxBytes   int64
+	// TTL 小于等于 0 时不缓存，只合并并发请求
+	TTL time.Duration
+	// 可选的持久化存储
+	Backend Backend
+}
+
+// Cache 内存 LRU 加可选持久化存储，并合并同一个 key 的并发加载
+type Cache struct {
+	// 计数器放在开头，保证 32 位平台上原子操作对齐
+	hits        uint64
+	misses      uint64
+	backendHits uint64
+	shared      uint64
+
+	opts  Options
+	lru   *LRU
+	group Group
+}
+
+func New(opts Options) *Cache {
+	return &Cache{opts: opts, lru: NewLRU(opts.MaxEntries, opts.MaxBytes)}
+}
+
+// Get 依次查询内存和持久化存储，持久化存储命中时写回内存
+func (c *Cache) Get(key string) (string, bool) {
+	if c.opts.TTL <= 0 {
+		return "", false
+	}
+	if v, ok := c.lru.Get(key); ok {
+		atomic.AddUint64(&c.hits, 1)
+		return v, true
+	}
+	if c.opts.Backend != nil {
+		v, ok, err := c.opts.Backend.Get(key)
+		if err != nil {
+			fmt.Printf("⚠️ [Cache] %s backend get failed: %v\n", c.opts.Name, err)
+		}
+		value, expiresAt, valid := decodeBackendValue(v)
+		// 写回内存时只保留剩余的有效期，避免每次从持久化存储读取都延长一个 TTL
+		if ttl := time.Until(expiresAt); ok && valid && ttl > 0 {
+			atomic.AddUint64(&c.hits, 1)
+			atomic.AddUint64(&c.backendHits, 1)
+			c.lru.Set(key, value, ttl)
+			return value, true
+		}
+	}
+	atomic.AddUint64(&c.misses, 1)
+	return "", false
+}
+
+func (c *Cache) Set(key, value string) {
+	if c.opts.TTL <= 0 {
+		return
+	}
+	c.lru.Set(key, value, c.opts.TTL)
+	if c.opts.Backend != nil {
+		value = encodeBackendValue(value, time.Now().Add(c.opts.TTL))
+		if err := c.opts.Backend.Set(key, value, c.opts.TTL); err != nil {
+			fmt.Printf("⚠️ [Cache] %s backend set failed: %v\n", c.opts.Name, err)
+		}
+	}
+}
+
+// Load 缓存未命中时调用 load 并写入缓存，同一个 key 的并发请求只加载一次，
+// 加载失败的结果不缓存；cached 表示结果直接来自缓存
+func (c *Cache) Load(key string, load func() (string, error)) (value string, cached bool, err error) {
+	if v, ok := c.Get(key); ok {
+		return v, true, nil
+	}
+	v, err, shared := c.group.Do(key, func() (string, error) {
+		// 上一次加载可能刚好在 Get 之后完成
+		if v, ok := c.lru.Get(key); ok {
+			return v, nil
+		}
+		v, err := load()
+		if err == nil {
+			c.Set(key, v)
+		}
+		return v, err
+	})
+	if shared {
+		atomic.AddUint64(&c.shared, 1)
+	}
+	return v, false, err
+}
+
+// encodeBackendValue 在持久化的值前面加上过期时间（Unix 毫秒）
+func encodeBackendValue(value string, expiresAt time.Time) string {
+	return strconv.FormatInt(expiresAt.UnixMilli(), 10) + "\n" + value
+}
+
+// decodeBackendValue 解析 encodeBackendValue 的结果，旧版本写入的值没有过期时间，按未命中处理
+func decodeBackendValue(s string) (string, time.Time, bool) {
+	i := strings.IndexByte(s, '\n')
+	if i < 0 {
+		return "", time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(s[:i], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return s[i+1:], time.UnixMilli(ms), true
+}
+
+func (c *Cache) Stats() Stats {
+	entries, bytes := c.lru.Len()
+	s := Stats{
+		Hits:        atomic.LoadUint64(&c.hits),
+		Misses:      atomic.LoadUint64(&c.misses),
+		BackendHits: atomic.LoadUint64(&c.backendHits),
+		Shared:      atomic.LoadUint64(&c.shared),
+		Evictions:   c.lru.evictionCount(),
+		Entries:     entries,
+		Bytes:       bytes,
+	}
+	if total := s.Hits + s.Misses; total > 0 {
+		s.HitRate = float64(s.Hits) / float64(total)
+	}
+	return s
+}
@@ -0,0 +1,202 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	lru := NewLRU(2, 0)
+	lru.now = func() time.Time { return now }
+
+	lru.Set("a", "1", time.Minute)
+	lru.Set("b", "2", time.Minute)
+	lru.Get("a")
+	lru.Set("c", "3", time.Minute)
+	if _, ok := lru.Get("b"); ok {
+		t.Errorf("least recently used entry should be evicted")
+	}
+	if v, ok := lru.Get("a"); !ok || v != "1" {
+		t.Errorf("Get(a) = %q, %t", v, ok)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := lru.Get("a"); ok {
+		t.Errorf("expired entry should not be returned")
+	}
+	// 写入时清理全部过期条目
+	now = now.Add(sweepInterval)
+	lru.Set("d", "4", time.Minute)
+	if n, _ := lru.Len(); n != 1 {
+		t.Errorf("Len() = %d after sweep, want 1", n)
+	}
+}
+
+func TestLRUMaxBytes(t *testing.T) {
+	lru := NewLRU(0, 9)
+	lru.Set("a", "1234", time.Minute)
+	lru.Set("b", "1234", time.Minute)
+	if _, ok := lru.Get("a"); ok {
+		t.Errorf("entry should be evicted when exceeding max bytes")
+	}
+	lru.Set("c", "012345678", time.Minute)
+	if _, ok := lru.Get("c"); ok {
+		t.Errorf("entry larger than max bytes should not be cached")
+	}
+	if _, bytes := lru.Len(); bytes != 5 {
+		t.Errorf("Len() bytes = %d, want 5", bytes)
+	}
+}
+
+func TestCacheLoad(t *testing.T) {
+	c := New(Options{Name: "test", MaxEntries: 10, TTL: time.Minute})
+	var calls int32
+	release := make(chan struct{})
+	load := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "v", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, _, err := c.Load("k", load); v != "v" || err != nil {
+				t.Errorf("Load() = %q, %v", v, err)
+			}
+		}()
+	}
+	// 等待所有调用进入加载，再放行
+	for c.Stats().Misses < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf("load called %d times, want 1", calls)
+	}
+	if _, cached, _ := c.Load("k", load); !cached {
+		t.Errorf("second Load() should hit cache")
+	}
+	if s := c.Stats(); s.Hits != 1 || s.Shared != 4 || s.Entries != 1 {
+		t.Errorf("Stats() = %+v", s)
+	}
+
+	// 加载失败不缓存
+	boom := errors.New("boom")
+	if _, _, err := c.Load("bad", func() (string, error) { return "", boom }); err != boom {
+		t.Errorf("Load() error = %v", err)
+	}
+	if _, ok := c.Get("bad"); ok {
+		t.Errorf("failed load should not be cached")
+	}
+}
+
+func TestFileBackend(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewFileBackend(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	New(Options{Name: "test", TTL: time.Minute, Backend: b}).Set("k", "v")
+	// 重新打开后仍可读取，相当于进程重启
+	c := New(Options{Name: "test", TTL: time.Minute, Backend: mustFileBackend(t, dir)})
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Errorf("Get() = %q, %t", v, ok)
+	}
+	if s := c.Stats(); s.BackendHits != 1 {
+		t.Errorf("Stats().BackendHits = %d, want 1", s.BackendHits)
+	}
+
+	b.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, ok, _ := b.Get("k"); ok {
+		t.Errorf("expired file entry should not be returned")
+	}
+}
+
+func TestCacheBackendKeepsExpiry(t *testing.T) {
+	dir := t.TempDir()
+	New(Options{Name: "short", TTL: time.Minute, Backend: mustFileBackend(t, dir)}).Set("k", "v")
+
+	// 另一个进程用更长的 TTL 读取，写回内存时仍按原来的过期时间
+	c := New(Options{Name: "long", TTL: time.Hour, Backend: mustFileBackend(t, dir)})
+	if v, ok := c.Get("k"); !ok || v != "v" {
+		t.Fatalf("Get() = %q, %t", v, ok)
+	}
+	expiresAt := c.lru.items["k"].Value.(*lruEntry).expiresAt
+	if remaining := time.Until(expiresAt); remaining > time.Minute || remaining < 50*time.Second {
+		t.Errorf("memory entry expires in %v, want about 1m", remaining)
+	}
+}
+
+func TestFileBackendLimit(t *testing.T) {
+	dir := t.TempDir()
+	b, err := NewFileBackend(dir, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range []string{"a", "b", "c"} {
+		if err := b.Set(key, "v", time.Duration(i+1)*time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 超出条数后在后台清理，最早过期的条目先被删除
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok, _ := b.Get("a"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("oldest entry was not evicted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, ok, _ := b.Get(key); !ok {
+			t.Errorf("Get(%s) missing", key)
+		}
+	}
+}
+
+func TestFileBackendSweep(t *testing.T) {
+	dir := t.TempDir()
+	b := mustFileBackend(t, dir)
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	if err := b.Set("old", "v", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	// 过期的条目不再被读取，下一次写入时在后台清理
+	now = now.Add(fileSweepInterval)
+	if err := b.Set("new", "v", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(b.path("old")); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired file was not swept")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := os.Stat(b.path("new")); err != nil {
+		t.Errorf("fresh file removed: %v", err)
+	}
+}
+
+func mustFileBackend(t *testing.T, dir string) *FileBackend {
+	b, err := NewFileBackend(dir, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
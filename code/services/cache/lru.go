@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// sweepInterval 定期清理过期条目的间隔，避免过期数据一直占用容量
+const sweepInterval = time.Minute
+
+type lruEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// LRU 按条数和字节数限制大小的 LRU，每个条目带过期时间
+type LRU struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+	evictions  uint64
+	ll         *list.List
+	items      map[string]*list.Element
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+// NewLRU maxEntries、maxBytes 小于等于 0 时不做对应限制
+func NewLRU(maxEntries int, maxBytes int64) *LRU {
+	return &LRU{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+		now:        time.Now,
+	}
+}
+
+func (c *LRU) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	e := el.Value.(*lruEntry)
+	if !c.now().Before(e.expiresAt) {
+		c.remove(el)
+		return "", false
+	}
+	c.ll.MoveToFront(el)
+	return e.value, true
+}
+
+func (c *LRU) Set(key, value string, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if now.Sub(c.lastSweep) >= sweepInterval {
+		c.sweep(now)
+	}
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+	size := entrySize(key, value)
+	// 单个条目超过总容量时不缓存
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruEntry{key: key, value: value, expiresAt: now.Add(ttl)})
+	c.bytes += size
+	for (c.maxEntries > 0 && c.ll.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.remove(c.ll.Back())
+		c.evictions++
+	}
+}
+
+func (c *LRU) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len 返回条目数和占用字节数
+func (c *LRU) Len() (int, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len(), c.bytes
+}
+
+func (c *LRU) sweep(now time.Time) {
+	c.lastSweep = now
+	for el := c.ll.Back(); el != nil; {
+		prev := el.Prev()
+		if !now.Before(el.Value.(*lruEntry).expiresAt) {
+			c.remove(el)
+		}
+		el = prev
+	}
+}
+
+func (c *LRU) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*lruEntry)
+	delete(c.items, e.key)
+	c.bytes -= entrySize(e.key, e.value)
+}
+
+func entrySize(key, value string) int64 {
+	return int64(len(key) + len(value))
+}
+
+func (c *LRU) evictionCount() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.evictions
+}
@@ -0,0 +1,41 @@
+package cache
+
+import "sync"
+
+type call struct {
+	wg  sync.WaitGroup
+	val string
+	err error
+}
+
+// Group 合并同一个 key 的并发加载，只有第一个调用真正执行
+type Group struct {
+	mu sync.Mutex
+	m  map[string]*call
+}
+
+// Do 执行 fn 并返回结果，shared 表示结果来自其他调用者正在进行的加载
+func (g *Group) Do(key string, fn func() (string, error)) (val string, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if c, ok := g.m[key]; ok {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+	c := &call{}
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err, false
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"fmt"
+	"start-feishubot/initialization"
+	"start-feishubot/services/store"
+	"strings"
+	"time"
+)
+
+type ReplyStyle string
+
+const (
+	ReplyStyleDefault  ReplyStyle = ""
+	ReplyStyleConcise  ReplyStyle = "concise"
+	ReplyStyleDetailed ReplyStyle = "detailed"
+)
+
+// SearchMode 联网搜索模式：auto 由模型判断是否需要联网，always 总是联网，never 从不联网
+type SearchMode string
+
+const (
+	SearchModeAuto   SearchMode = "auto"
+	SearchModeAlways SearchMode = "always"
+	SearchModeNever  SearchMode = "never"
+)
+
+// ParseSearchMode 无法识别的值返回 auto
+func ParseSearchMode(s string) (SearchMode, bool) {
+	switch mode := SearchMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case SearchModeAuto, SearchModeAlways, SearchModeNever:
+		return mode, true
+	}
+	return SearchModeAuto, false
+}
+
+// ChatSetting 群（或单聊）级别的设置，零值字段表示沿用全局配置
+type ChatSetting struct {
+	SearchMode SearchMode `json:"search_mode,omitempty"`
+	// 旧版本的“总是联网”开关，仅用于兼容已保存的设置
+	SearchAlways   *bool      `json:"search_always,omitempty"`
+	SearchTopK     int        `json:"search_top_k,omitempty"`
+	ShowSearchPlan *bool      `json:"show_search_plan,omitempty"`
+	DefaultRole    string     `json:"default_role,omitempty"`
+	Model          string     `json:"model,omitempty"`
+	ReplyStyle     ReplyStyle `json:"reply_style,omitempty"`
+	UpdatedBy      string     `json:"updated_by,omitempty"`
+	UpdatedAt      time.Time  `json:"updated_at,omitempty"`
+}
+
+// ResolvedChatSetting 群设置叠加全局配置后的最终结果，供各个 Action 使用
+type ResolvedChatSetting struct {
+	SearchMode     SearchMode
+	SearchTopK     int
+	ShowSearchPlan bool
+	DefaultRole    string
+	Model          string
+	ReplyStyle     ReplyStyle
+}
+
+type ChatSettingServiceInterface interface {
+	Get(chatId string) ChatSetting
+	Update(chatId string, operator string, fn func(setting *ChatSetting)) (ChatSetting, error)
+	Reset(chatId string) error
+	Resolve(chatId string, config initialization.Config) ResolvedChatSetting
+}
+
+type ChatSettingService struct {
+	store *store.FileStore[ChatSetting]
+}
+
+var chatSettingService *ChatSettingService
+
+// InitChatSettingService 从 path 加载持久化的群设置
+func InitChatSettingService(path string) error {
+	s, err := store.NewFileStore[ChatSetting](path)
+	if err != nil {
+		return err
+	}
+	chatSettingService = &ChatSettingService{store: s}
+	return nil
+}
+
+// GetChatSettingService 未初始化时退化为不落盘的内存存储
+func GetChatSettingService() ChatSettingServiceInterface {
+	if chatSettingService == nil {
+		s, _ := store.NewFileStore[ChatSetting]("")
+		chatSettingService = &ChatSettingService{store: s}
+	}
+	return chatSettingService
+}
+
+func (s *ChatSettingService) Get(chatId string) ChatSetting {
+	setting, _ := s.store.Get(chatId)
+	return setting
+}
+
+func (s *ChatSettingService) Update(chatId string, operator string,
+	fn func(setting *ChatSetting)) (ChatSetting, error) {
+	var updated ChatSetting
+	err := s.store.Update(chatId, func(setting ChatSetting, _ bool) (ChatSetting, bool) {
+		fn(&setting)
+		setting.UpdatedBy = operator
+		setting.UpdatedAt = time.Now()
+		updated = setting
+		return setting, true
+	})
+	if err != nil {
+		return updated, fmt.Errorf("save chat setting: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *ChatSettingService) Reset(chatId string) error {
+	return s.store.Delete(chatId)
+}
+
+func (s *ChatSettingService) Resolve(chatId string,
+	config initialization.Config) ResolvedChatSetting {
+	setting := s.Get(chatId)
+	resolved := ResolvedChatSetting{
+		SearchTopK:     config.SearchTopK,
+		ShowSearchPlan: config.SearchShowPlan,
+		DefaultRole:    setting.DefaultRole,
+		Model:          config.OpenaiModel,
+		ReplyStyle:     setting.ReplyStyle,
+	}
+	resolved.SearchMode, _ = ParseSearchMode(config.SearchMode)
+	if setting.SearchMode != "" {
+		resolved.SearchMode = setting.SearchMode
+	} else if setting.SearchAlways != nil && *setting.SearchAlways {
+		resolved.SearchMode = SearchModeAlways
+	}
+	if setting.SearchTopK > 0 {
+		resolved.SearchTopK = setting.SearchTopK
+	}
+	if setting.ShowSearchPlan != nil {
+		resolved.ShowSearchPlan = *setting.ShowSearchPlan
+	}
+	if setting.Model != "" {
+		resolved.Model = setting.Model
+	}
+	return resolved
+}
@@ -0,0 +1,522 @@
+package kb
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"start-feishubot/services/store"
+	"start-feishubot/utils"
+	"start-feishubot/utils/vector"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SourceDir    = "dir"
+	SourceUpload = "upload"
+	SourceLark   = "lark"
+
+	// embedBatchSize 每次向量请求包含的片段数
+	embedBatchSize = 64
+)
+
+// ErrNotFound 文档不存在或不属于当前群
+var ErrNotFound = errors.New("document not found")
+
+// Vector float32 向量，在向量文件中按小端序保存，旧版本的 JSON 中以 base64 保存
+type Vector []float32
+
+func (v Vector) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf, nil
+}
+
+func (v *Vector) UnmarshalBinary(buf []byte) error {
+	out := make(Vector, len(buf)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	*v = out
+	return nil
+}
+
+func (v Vector) MarshalJSON() ([]byte, error) {
+	buf, _ := v.MarshalBinary()
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf))
+}
+
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return v.UnmarshalBinary(buf)
+}
+
+// Chunk 文档片段，Vector 保存在单独的向量文件中，文档 JSON 里只有旧版本导入的数据才带向量
+type Chunk struct {
+	Text   string `json:"text"`
+	Vector Vector `json:"vector,omitempty"`
+}
+
+// Document 知识库中的一个文档，Scope 为空时所有群可见，否则只在对应的群可见。
+// Space 非空时还需要通过 Options.SpaceAllowed 检查，用于按群限制可见的飞书知识空间
+type Document struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Scope   string    `json:"scope,omitempty"`
+	Space   string    `json:"space,omitempty"`
+	URL     string    `json:"url,omitempty"`
+	Source  string    `json:"source"`
+	Hash    string    `json:"hash"`
+	Chunks  []Chunk   `json:"chunks"`
+	AddedBy string    `json:"added_by,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Hit 检索命中的片段
+type Hit struct {
+	DocID   string
+	DocName string
+	URL     string
+	Chunk   int
+	Text    string
+	Score   float64
+}
+
+type Options struct {
+	// 单个片段的最大字符数
+	ChunkRunes int
+	// 可选，配置后导入时计算片段向量，检索时与 BM25 分数加权合并
+	Embedder utils.Embedder
+	// 语义分数的权重 (0-1)
+	EmbedWeight float64
+	// BM25 原始分数达到该值才视为相关
+	MinScore float64
+	// 向量相似度达到该值也视为相关
+	MinSimilarity float64
+	// 可选，判断群 scope 是否可以看到 Space 非空的文档，未设置时这些文档对所有群都不可见
+	SpaceAllowed func(scope, space string) bool
+}
+
+// Base 本地知识库，文档保存在 JSON 文件中，片段向量保存在单独的向量文件中，检索索引在内存中按需重建
+type Base struct {
+	opts  Options
+	store *store.FileStore[Document]
+
+	mu      sync.Mutex
+	vectors *vectorStore
+	// batch 大于 0 时暂缓写向量文件，见 Batch
+	batch  int
+	dirty  bool
+	chunks []indexedChunk
+	bm     *utils.BM25
+}
+
+type indexedChunk struct {
+	doc   *Document
+	index int
+}
+
+// Open 加载 path 中的知识库，向量保存在同目录的 .vectors 文件中，path 为空时只保存在内存中。
+// 旧版本把向量写在 JSON 中，打开时会迁移到向量文件
+func Open(path string, opts Options) (*Base, error) {
+	if opts.ChunkRunes <= 0 {
+		opts.ChunkRunes = 600
+	}
+	if opts.EmbedWeight <= 0 || opts.EmbedWeight > 1 {
+		opts.EmbedWeight = 0.5
+	}
+	if opts.MinScore <= 0 {
+		opts.MinScore = 1
+	}
+	if opts.MinSimilarity <= 0 {
+		opts.MinSimilarity = 0.5
+	}
+	s, err := store.NewFileStore[Document](path)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := openVectors(vectorPath(path))
+	if err != nil {
+		return nil, err
+	}
+	b := &Base{opts: opts, store: s, vectors: vectors, dirty: true}
+	if err := b.migrateVectors(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// migrateVectors 把 JSON 中的向量移到向量文件
+func (b *Base) migrateVectors() error {
+	return b.Batch(func() error {
+		for _, id := range b.store.Keys() {
+			doc, _ := b.store.Get(id)
+			doc, vectors := splitVectors(doc)
+			if vectors == nil {
+				continue
+			}
+			if err := b.store.Set(id, doc); err != nil {
+				return err
+			}
+			b.mu.Lock()
+			b.vectors.set(id, vectors)
+			b.mu.Unlock()
+		}
+		return nil
+	})
+}
+
+// Batch 执行 fn 期间暂缓写文件，结束后文档和向量文件各写一次，用于目录和飞书文档的批量同步
+func (b *Base) Batch(fn func() error) error {
+	b.mu.Lock()
+	b.batch++
+	b.mu.Unlock()
+
+	err := b.store.Batch(fn)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batch--
+	if b.batch == 0 {
+		if saveErr := b.vectors.save(); err == nil {
+			err = saveErr
+		}
+	}
+	return err
+}
+
+// UploadID 群内上传文件的文档 ID，同名文件会覆盖
+func UploadID(scope, name string) string {
+	return "upload:" + scope + ":" + name
+}
+
+// AddText 切片并索引文本，ID 相同的文档会被替换
+func (b *Base) AddText(ctx context.Context, doc Document, text string) (Document, error) {
+	doc.Chunks = nil
+	for _, c := range utils.ChunkText(text, b.opts.ChunkRunes) {
+		doc.Chunks = append(doc.Chunks, Chunk{Text: c})
+	}
+	if len(doc.Chunks) == 0 {
+		return doc, errors.New("document is empty")
+	}
+	if doc.Hash == "" {
+		doc.Hash = hashOf([]byte(text))
+	}
+	if doc.AddedAt.IsZero() {
+		doc.AddedAt = time.Now()
+	}
+	if err := b.embed(ctx, doc.Chunks); err != nil {
+		// 向量只用于重排，失败时仍可以用 BM25 检索
+		fmt.Printf("⚠️ [KB] Embedding %s failed, using BM25 only: %v\n", doc.Name, err)
+	}
+	stored, vectors := splitVectors(doc)
+	if err := b.store.Set(doc.ID, stored); err != nil {
+		return doc, err
+	}
+	return doc, b.setVectors(doc.ID, vectors)
+}
+
+// AddFile 解析文件后加入知识库
+func (b *Base) AddFile(ctx context.Context, doc Document, data []byte) (Document, error) {
+	text, err := ParseFile(doc.Name, data)
+	if err != nil {
+		return doc, err
+	}
+	doc.Hash = hashOf(data)
+	return b.AddText(ctx, doc, text)
+}
+
+// Remove 按名称或 ID 删除 scope 下的文档，目录导入的全局文档不能通过这里删除
+func (b *Base) Remove(scope, nameOrID string) (Document, error) {
+	for _, doc := range b.List(scope) {
+		if doc.Scope != scope || doc.Scope == "" {
+			continue
+		}
+		if doc.ID == nameOrID || doc.Name == nameOrID {
+			if err := b.store.Delete(doc.ID); err != nil {
+				return doc, err
+			}
+			return doc, b.setVectors(doc.ID, nil)
+		}
+	}
+	return Document{}, ErrNotFound
+}
+
+// List 返回 scope 可见的文档（全局文档和本群文档），按名称排序
+func (b *Base) List(scope string) []Document {
+	var docs []Document
+	for _, id := range b.store.Keys() {
+		doc, ok := b.store.Get(id)
+		if ok && b.visible(doc, scope) {
+			docs = append(docs, doc)
+		}
+	}
+	sort.SliceStable(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs
+}
+
+// SyncDir 导入目录中的文件：未变化的文件跳过，已删除的文件从知识库移除。
+// 顶层文件对所有群可见，以群 ID（oc_ 开头）命名的子目录中的文件只对该群可见
+// 同步期间的修改在结束后一次写入文件
+func (b *Base) SyncDir(ctx context.Context, dir string) (added, removed int, err error) {
+	err = b.Batch(func() error {
+		added, removed, err = b.syncDir(ctx, dir)
+		return err
+	})
+	return added, removed, err
+}
+
+func (b *Base) syncDir(ctx context.Context, dir string) (added, removed int, err error) {
+	seen := map[string]bool{}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !SupportedFile(info.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		id := "dir:" + rel
+		seen[id] = true
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			fmt.Printf("⚠️ [KB] Read %s failed: %v\n", path, err)
+			return nil
+		}
+		if b.Fresh(id, hashOf(data)) {
+			return nil
+		}
+		doc := Document{ID: id, Name: rel, Scope: dirScope(rel), Source: SourceDir}
+		if _, err := b.AddFile(ctx, doc, data); err != nil {
+			fmt.Printf("⚠️ [KB] Import %s failed: %v\n", path, err)
+			return nil
+		}
+		added++
+		return nil
+	})
+	if err != nil {
+		return added, removed, err
+	}
+	removed, err = b.RemoveStale("dir:", seen)
+	return added, removed, err
+}
+
+// Fresh 文档已导入且内容未变化（hash 相同、需要的向量已计算）时返回 true，同步时可以跳过
+func (b *Base) Fresh(id, hash string) bool {
+	old, ok := b.store.Get(id)
+	return ok && old.Hash == hash && !b.missingVectors(old)
+}
+
+// RemoveStale 删除 ID 以 prefix 开头但不在 keep 中的文档，返回删除数量
+func (b *Base) RemoveStale(prefix string, keep map[string]bool) (int, error) {
+	removed := 0
+	for _, id := range b.store.Keys() {
+		if strings.HasPrefix(id, prefix) && !keep[id] {
+			if err := b.store.Delete(id); err != nil {
+				return removed, err
+			}
+			if err := b.setVectors(id, nil); err != nil {
+				return removed, err
+			}
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// Search 在 scope 可见的文档中检索与问题相关的片段，按相关度从高到低返回最多 topK 个
+func (b *Base) Search(ctx context.Context, scope, query string, topK int) []Hit {
+	if topK <= 0 {
+		topK = 4
+	}
+	chunks, bm := b.index()
+	if len(chunks) == 0 || strings.TrimSpace(query) == "" {
+		return nil
+	}
+	var queryVec []float32
+	if b.opts.Embedder != nil {
+		vectors, err := b.opts.Embedder.Embed(ctx, []string{query})
+		if err != nil || len(vectors) != 1 {
+			fmt.Printf("⚠️ [KB] Embedding query failed, using BM25 only: %v\n", err)
+		} else {
+			queryVec = vectors[0]
+		}
+	}
+
+	type scored struct {
+		chunk      indexedChunk
+		bm25       float64
+		similarity float64
+	}
+	var candidates []scored
+	maxScore := 0.0
+	for i, c := range chunks {
+		if !b.visible(*c.doc, scope) {
+			continue
+		}
+		s := scored{chunk: c, bm25: bm.Score(query, i)}
+		if vec := c.doc.Chunks[c.index].Vector; queryVec != nil && len(vec) > 0 {
+			s.similarity = vector.Cosine(queryVec, vec)
+		}
+		if s.bm25 < b.opts.MinScore && s.similarity < b.opts.MinSimilarity {
+			continue
+		}
+		if s.bm25 > maxScore {
+			maxScore = s.bm25
+		}
+		candidates = append(candidates, s)
+	}
+
+	hits := make([]Hit, 0, len(candidates))
+	for _, s := range candidates {
+		score := 0.0
+		if maxScore > 0 {
+			score = s.bm25 / maxScore
+		}
+		if queryVec != nil {
+			score = (1-b.opts.EmbedWeight)*score + b.opts.EmbedWeight*s.similarity
+		}
+		doc := s.chunk.doc
+		hits = append(hits, Hit{DocID: doc.ID, DocName: doc.Name, URL: doc.URL, Chunk: s.chunk.index,
+			Text: doc.Chunks[s.chunk.index].Text, Score: score})
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits
+}
+
+// index 文档变化后重建 BM25 索引
+func (b *Base) index() ([]indexedChunk, *utils.BM25) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.dirty {
+		return b.chunks, b.bm
+	}
+	var chunks []indexedChunk
+	var texts []string
+	for _, id := range b.store.Keys() {
+		doc, ok := b.store.Get(id)
+		if !ok {
+			continue
+		}
+		// 在副本上补上向量，不修改 store 中的文档
+		vectors := b.vectors.data[id]
+		doc.Chunks = append([]Chunk(nil), doc.Chunks...)
+		for i, c := range doc.Chunks {
+			if i < len(vectors) {
+				doc.Chunks[i].Vector = vectors[i]
+			}
+			chunks = append(chunks, indexedChunk{doc: &doc, index: i})
+			texts = append(texts, doc.Name+"\n"+c.Text)
+		}
+	}
+	b.chunks, b.bm, b.dirty = chunks, utils.NewBM25(texts), false
+	return b.chunks, b.bm
+}
+
+// setVectors 更新文档的向量并让索引重建，不在 Batch 中时立即写向量文件
+func (b *Base) setVectors(id string, vectors []Vector) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dirty = true
+	b.vectors.set(id, vectors)
+	if b.batch > 0 {
+		return nil
+	}
+	return b.vectors.save()
+}
+
+func (b *Base) embed(ctx context.Context, chunks []Chunk) error {
+	if b.opts.Embedder == nil {
+		return nil
+	}
+	for start := 0; start < len(chunks); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(chunks) {
+			end = len(chunks)
+		}
+		texts := make([]string, 0, end-start)
+		for _, c := range chunks[start:end] {
+			texts = append(texts, c.Text)
+		}
+		vectors, err := b.opts.Embedder.Embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embedding count mismatch: got %d, want %d", len(vectors), len(texts))
+		}
+		for i, v := range vectors {
+			chunks[start+i].Vector = v
+		}
+	}
+	return nil
+}
+
+// missingVectors 配置了向量接口但文档还没有向量时需要重新导入
+func (b *Base) missingVectors(doc Document) bool {
+	if b.opts.Embedder == nil {
+		return false
+	}
+	b.mu.Lock()
+	vectors := b.vectors.data[doc.ID]
+	b.mu.Unlock()
+	if len(vectors) != len(doc.Chunks) {
+		return true
+	}
+	for _, v := range vectors {
+		if len(v) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Base) visible(doc Document, scope string) bool {
+	if doc.Scope != "" && doc.Scope != scope {
+		return false
+	}
+	if doc.Space == "" {
+		return true
+	}
+	// 关闭飞书文档同步后，之前同步的文档不能因为缺少白名单而对所有群可见
+	return b.opts.SpaceAllowed != nil && b.opts.SpaceAllowed(scope, doc.Space)
+}
+
+func dirScope(rel string) string {
+	if first := strings.SplitN(rel, "/", 2); len(first) == 2 && strings.HasPrefix(first[0], "oc_") {
+		return first[0]
+	}
+	return ""
+}
+
+func hashOf(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,227 @@
+package kb
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestVectorJSON(t *testing.T) {
+	v := Vector{0.5, -1, 3.25}
+	b, err := json.Marshal(Chunk{Text: "a", Vector: v})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var c Chunk
+	if err := json.Unmarshal(b, &c); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c.Vector, v) {
+		t.Errorf("round trip = %v, want %v", c.Vector, v)
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	// “数据库”的 GBK 编码
+	got, err := ParseFile("notes.txt", []byte{0xca, 0xfd, 0xbe, 0xdd, 0xbf, 0xe2})
+	if err != nil || got != "数据库" {
+		t.Errorf("ParseFile(gbk) = %q, %v", got, err)
+	}
+	if _, err := ParseFile("image.png", nil); err != ErrUnsupportedFile {
+		t.Errorf("ParseFile(png) error = %v", err)
+	}
+}
+
+func TestSearchScope(t *testing.T) {
+	ctx := context.Background()
+	base, err := Open("", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.AddText(ctx, Document{ID: "dir:deploy.md", Name: "deploy.md", Source: SourceDir},
+		"# 发布流程\n\n发布前先在预发环境验证，然后执行 make deploy 发布到生产环境。")
+	base.AddText(ctx, Document{ID: UploadID("oc_a", "oncall.md"), Name: "oncall.md", Scope: "oc_a", Source: SourceUpload},
+		"值班手册：数据库告警时先检查慢查询，再联系 DBA 值班。")
+
+	hits := base.Search(ctx, "oc_b", "数据库告警怎么处理", 3)
+	if len(hits) != 0 {
+		t.Errorf("other chats should not see chat scoped documents: %+v", hits)
+	}
+	hits = base.Search(ctx, "oc_a", "数据库告警怎么处理", 3)
+	if len(hits) == 0 || hits[0].DocName != "oncall.md" {
+		t.Fatalf("Search() = %+v", hits)
+	}
+	if hits := base.Search(ctx, "oc_b", "如何发布到生产环境", 3); len(hits) == 0 || hits[0].DocName != "deploy.md" {
+		t.Errorf("global documents should be visible: %+v", hits)
+	}
+	if hits := base.Search(ctx, "oc_a", "今天天气怎么样", 3); len(hits) != 0 {
+		t.Errorf("unrelated question should not hit: %+v", hits)
+	}
+
+	if _, err := base.Remove("oc_b", "oncall.md"); err != ErrNotFound {
+		t.Errorf("Remove() from other chat error = %v", err)
+	}
+	if _, err := base.Remove("oc_a", "deploy.md"); err != ErrNotFound {
+		t.Errorf("global documents should not be removable, error = %v", err)
+	}
+	if _, err := base.Remove("oc_a", "oncall.md"); err != nil {
+		t.Fatal(err)
+	}
+	if hits := base.Search(ctx, "oc_a", "数据库告警怎么处理", 3); len(hits) != 0 {
+		t.Errorf("removed document still searchable: %+v", hits)
+	}
+}
+
+type fakeEmbedder struct{ calls int }
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{0, 1}
+		if strings.Contains(text, "回滚") || strings.Contains(text, "撤销") {
+			out[i] = []float32{1, 0}
+		}
+	}
+	return out, nil
+}
+
+func TestSearchSpaceAllowed(t *testing.T) {
+	ctx := context.Background()
+	allowed := map[string]string{"oc_a": "wiki_1"}
+	base, err := Open("", Options{SpaceAllowed: func(scope, space string) bool { return allowed[scope] == space }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.AddText(ctx, Document{ID: "lark:doxcn1", Name: "报销制度", Space: "wiki_1", URL: "https://feishu.cn/wiki/n1", Source: SourceLark},
+		"差旅报销需要在出差结束后 30 天内提交发票。")
+
+	if hits := base.Search(ctx, "oc_b", "差旅报销发票", 3); len(hits) != 0 {
+		t.Errorf("chats without the space should not see its documents: %+v", hits)
+	}
+	hits := base.Search(ctx, "oc_a", "差旅报销发票", 3)
+	if len(hits) == 0 || hits[0].URL != "https://feishu.cn/wiki/n1" {
+		t.Fatalf("Search() = %+v", hits)
+	}
+	if docs := base.List("oc_b"); len(docs) != 0 {
+		t.Errorf("List() = %+v", docs)
+	}
+}
+
+func TestSearchSpaceWithoutAllowlist(t *testing.T) {
+	ctx := context.Background()
+	// 取消飞书文档配置后，之前同步的文档仍在知识库中，但不设置 SpaceAllowed
+	base, err := Open("", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base.AddText(ctx, Document{ID: "lark:doxcn1", Name: "报销制度", Space: "wiki_1", Source: SourceLark},
+		"差旅报销需要在出差结束后 30 天内提交发票。")
+	base.AddText(ctx, Document{ID: "dir:deploy.md", Name: "deploy.md", Source: SourceDir},
+		"发布前先在预发环境验证，然后执行 make deploy 发布到生产环境。")
+
+	if hits := base.Search(ctx, "oc_a", "差旅报销发票", 3); len(hits) != 0 {
+		t.Errorf("lark docs should be hidden without an allowlist: %+v", hits)
+	}
+	if docs := base.List("oc_a"); len(docs) != 1 {
+		t.Errorf("List() = %+v", docs)
+	}
+}
+
+func TestSearchEmbedding(t *testing.T) {
+	ctx := context.Background()
+	base, _ := Open("", Options{Embedder: &fakeEmbedder{}})
+	base.AddText(ctx, Document{ID: "a", Name: "rollback.md"}, "上线出问题时执行回滚脚本。")
+	// 字面上没有共同的词，只能依靠向量命中
+	hits := base.Search(ctx, "", "如何撤销", 3)
+	if len(hits) != 1 || hits[0].DocName != "rollback.md" {
+		t.Errorf("Search() = %+v", hits)
+	}
+}
+
+func TestSyncDir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("guide.md", "接入指南：申请账号后配置密钥。")
+	write("oc_team/secret.txt", "团队内部的发布窗口是每周二。")
+	write("logo.png", "not a document")
+
+	base, _ := Open(filepath.Join(t.TempDir(), "kb.json"), Options{})
+	added, removed, err := base.SyncDir(ctx, dir)
+	if err != nil || added != 2 || removed != 0 {
+		t.Fatalf("SyncDir() = %d, %d, %v", added, removed, err)
+	}
+	if docs := base.List("oc_other"); len(docs) != 1 || docs[0].Name != "guide.md" {
+		t.Errorf("List(oc_other) = %+v", docs)
+	}
+	if docs := base.List("oc_team"); len(docs) != 2 {
+		t.Errorf("List(oc_team) = %d docs, want 2", len(docs))
+	}
+
+	// 未变化的文件不重新导入，删除的文件被移除
+	os.Remove(filepath.Join(dir, "guide.md"))
+	added, removed, err = base.SyncDir(ctx, dir)
+	if err != nil || added != 0 || removed != 1 {
+		t.Errorf("second SyncDir() = %d, %d, %v", added, removed, err)
+	}
+}
+
+func TestVectorFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "kb.json")
+	// 旧版本把向量写在 JSON 中
+	legacy := map[string]Document{"old": {ID: "old", Name: "old.md", Hash: "h1",
+		Chunks: []Chunk{{Text: "旧文档", Vector: Vector{0, 1}}}}}
+	content, _ := json.Marshal(legacy)
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	embedder := &fakeEmbedder{}
+	base, err := Open(path, Options{Embedder: embedder})
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if _, err := base.AddText(ctx, Document{ID: "a", Name: "rollback.md", Hash: "h2"}, "上线出问题时执行回滚脚本。"); err != nil {
+		t.Fatalf("AddText() error = %v", err)
+	}
+	if content, _ := os.ReadFile(path); strings.Contains(string(content), `"vector"`) {
+		t.Errorf("vectors written into JSON: %s", content)
+	}
+
+	reopened, err := Open(path, Options{Embedder: embedder})
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	for _, id := range []string{"old", "a"} {
+		doc, _ := reopened.store.Get(id)
+		if !reopened.Fresh(id, doc.Hash) {
+			t.Errorf("Fresh(%s) = false, vectors lost", id)
+		}
+	}
+	hits := reopened.Search(ctx, "", "如何撤销", 3)
+	if len(hits) != 1 || hits[0].DocName != "rollback.md" {
+		t.Errorf("Search() = %+v", hits)
+	}
+
+	if _, err := reopened.Remove("", "a"); err != ErrNotFound {
+		t.Errorf("Remove(global) error = %v", err)
+	}
+	if _, err := reopened.RemoveStale("", map[string]bool{"old": true}); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := Open(path, Options{Embedder: embedder}); len(again.vectors.data) != 1 {
+		t.Errorf("vectors after RemoveStale = %d docs, want 1", len(again.vectors.data))
+	}
+}
@@ -0,0 +1,43 @@
+package kb
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"start-feishubot/utils/extract"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// ErrUnsupportedFile 不支持的文件类型
+var ErrUnsupportedFile = errors.New("unsupported file type")
+
+// SupportedFile 是否为可以导入知识库的文件（Markdown、纯文本、PDF）
+func SupportedFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown", ".txt", ".pdf":
+		return true
+	}
+	return false
+}
+
+// ParseFile 按扩展名提取文件正文，文本文件不是 UTF-8 时按 GB18030 解码
+func ParseFile(name string, data []byte) (string, error) {
+	if !SupportedFile(name) {
+		return "", ErrUnsupportedFile
+	}
+	if strings.EqualFold(filepath.Ext(name), ".pdf") {
+		return extract.PDF(data)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,99 @@
+package kb
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// vectorStore 片段向量单独保存在二进制文件中，文档 JSON 只保存文本，
+// 避免每次修改文档都把全部向量重新编码一遍
+type vectorStore struct {
+	path string
+	// 文档 ID -> 每个片段的向量，下标与 Document.Chunks 对应
+	data  map[string][]Vector
+	dirty bool
+}
+
+// vectorPath 向量文件与文档 JSON 放在同一目录，如 knowledge_base.json 对应 knowledge_base.vectors
+func vectorPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".vectors"
+}
+
+func openVectors(path string) (*vectorStore, error) {
+	v := &vectorStore{path: path, data: map[string][]Vector{}}
+	if path == "" {
+		return v, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || len(content) == 0 {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&v.data); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *vectorStore) set(id string, vectors []Vector) {
+	if len(vectors) == 0 {
+		if _, ok := v.data[id]; !ok {
+			return
+		}
+		delete(v.data, id)
+	} else {
+		v.data[id] = vectors
+	}
+	v.dirty = true
+}
+
+// save 与 store.FileStore 一样先写临时文件再重命名
+func (v *vectorStore) save() error {
+	if v.path == "" || !v.dirty {
+		v.dirty = false
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v.data); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(v.path), 0o755); err != nil {
+		return err
+	}
+	tmp := v.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, v.path); err != nil {
+		return err
+	}
+	v.dirty = false
+	return nil
+}
+
+// splitVectors 把向量从片段中取出，返回不含向量的文档，所有片段都没有向量时 vectors 为 nil
+func splitVectors(doc Document) (Document, []Vector) {
+	var vectors []Vector
+	chunks := make([]Chunk, len(doc.Chunks))
+	for i, c := range doc.Chunks {
+		if len(c.Vector) > 0 {
+			if vectors == nil {
+				vectors = make([]Vector, len(doc.Chunks))
+			}
+			vectors[i] = c.Vector
+		}
+		chunks[i] = Chunk{Text: c.Text}
+	}
+	doc.Chunks = chunks
+	return doc, vectors
+}
@@ -0,0 +1,153 @@
+package larkdocs
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkdocx "github.com/larksuite/oapi-sdk-go/v3/service/docx/v1"
+	larkwiki "github.com/larksuite/oapi-sdk-go/v3/service/wiki/v2"
+)
+
+// Node 一篇需要同步的飞书文档
+type Node struct {
+	// Space 知识空间 ID，单独配置的云文档为文档 token
+	Space string
+	// Token docx 文档 token
+	Token string
+	Title string
+	URL   string
+	// Revision 文档版本，变化时重新拉取
+	Revision string
+}
+
+// Connector 通过知识库 (wiki v2) 和新版文档 (docx v1) 接口读取飞书文档，
+// 应用需要开通 wiki:wiki:readonly、docx:document:readonly 权限，并被添加为知识空间成员或文档协作者
+type Connector struct {
+	client  *lark.Client
+	baseURL string
+}
+
+// NewConnector baseURL 为文档链接的域名，例如 https://feishu.cn 或企业域名
+func NewConnector(client *lark.Client, baseURL string) *Connector {
+	return &Connector{client: client, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// ListSpace 遍历知识空间的节点树，返回其中的全部 docx 文档，快捷方式会被跳过
+func (c *Connector) ListSpace(ctx context.Context, spaceID string) ([]Node, error) {
+	var nodes []Node
+	parents := []string{""}
+	for len(parents) > 0 {
+		parent := parents[0]
+		parents = parents[1:]
+		pageToken := ""
+		for {
+			builder := larkwiki.NewListSpaceNodeReqBuilder().SpaceId(spaceID).PageSize(50)
+			if parent != "" {
+				builder.ParentNodeToken(parent)
+			}
+			if pageToken != "" {
+				builder.PageToken(pageToken)
+			}
+			resp, err := c.client.Wiki.SpaceNode.List(ctx, builder.Build())
+			if err != nil {
+				return nil, err
+			}
+			if !resp.Success() {
+				return nil, fmt.Errorf("list wiki space %s: %d %s", spaceID, resp.Code, resp.Msg)
+			}
+			for _, item := range resp.Data.Items {
+				if item == nil || str(item.OriginNodeToken) != "" {
+					continue
+				}
+				if item.HasChild != nil && *item.HasChild {
+					parents = append(parents, str(item.NodeToken))
+				}
+				if str(item.ObjType) == "docx" {
+					nodes = append(nodes, c.wikiNode(spaceID, item))
+				}
+			}
+			if resp.Data.HasMore == nil || !*resp.Data.HasMore || str(resp.Data.PageToken) == "" {
+				break
+			}
+			pageToken = str(resp.Data.PageToken)
+		}
+	}
+	return nodes, nil
+}
+
+// Resolve 解析单独配置的 token：先按知识库节点查询，失败时按 docx 文档 token 查询
+func (c *Connector) Resolve(ctx context.Context, token string) (Node, error) {
+	nodeResp, err := c.client.Wiki.Space.GetNode(ctx, larkwiki.NewGetNodeSpaceReqBuilder().Token(token).Build())
+	if err == nil && nodeResp.Success() && nodeResp.Data.Node != nil {
+		node := nodeResp.Data.Node
+		if str(node.ObjType) != "docx" {
+			return Node{}, fmt.Errorf("wiki node %s is %s, only docx is supported", token, str(node.ObjType))
+		}
+		return c.wikiNode(str(node.SpaceId), node), nil
+	}
+
+	docResp, err := c.client.Docx.Document.Get(ctx, larkdocx.NewGetDocumentReqBuilder().DocumentId(token).Build())
+	if err != nil {
+		return Node{}, err
+	}
+	if !docResp.Success() || docResp.Data.Document == nil {
+		return Node{}, fmt.Errorf("get document %s: %d %s", token, docResp.Code, docResp.Msg)
+	}
+	doc := docResp.Data.Document
+	revision := ""
+	if doc.RevisionId != nil {
+		revision = strconv.Itoa(*doc.RevisionId)
+	}
+	return Node{
+		Space:    token,
+		Token:    token,
+		Title:    str(doc.Title),
+		URL:      c.baseURL + "/docx/" + token,
+		Revision: revision,
+	}, nil
+}
+
+// Markdown 分页拉取文档的全部 Block 并转换为 Markdown
+func (c *Connector) Markdown(ctx context.Context, docToken string) (string, error) {
+	var blocks []*larkdocx.Block
+	pageToken := ""
+	for {
+		builder := larkdocx.NewListDocumentBlockReqBuilder().DocumentId(docToken).PageSize(500)
+		if pageToken != "" {
+			builder.PageToken(pageToken)
+		}
+		resp, err := c.client.Docx.DocumentBlock.List(ctx, builder.Build())
+		if err != nil {
+			return "", err
+		}
+		if !resp.Success() {
+			return "", fmt.Errorf("list blocks of %s: %d %s", docToken, resp.Code, resp.Msg)
+		}
+		blocks = append(blocks, resp.Data.Items...)
+		if resp.Data.HasMore == nil || !*resp.Data.HasMore || str(resp.Data.PageToken) == "" {
+			break
+		}
+		pageToken = str(resp.Data.PageToken)
+	}
+	return BlocksToMarkdown(blocks), nil
+}
+
+func (c *Connector) wikiNode(spaceID string, n *larkwiki.Node) Node {
+	return Node{
+		Space:    spaceID,
+		Token:    str(n.ObjToken),
+		Title:    str(n.Title),
+		URL:      c.baseURL + "/wiki/" + str(n.NodeToken),
+		Revision: str(n.ObjEditTime),
+	}
+}
+
+func str(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
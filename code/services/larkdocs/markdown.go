@@ -0,0 +1,297 @@
+package larkdocs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	larkdocx "github.com/larksuite/oapi-sdk-go/v3/service/docx/v1"
+)
+
+// codeLanguages 代码块语言枚举对应的 Markdown 语言标记，只列出常用语言，其余不标注
+var codeLanguages = map[int]string{
+	7: "bash", 8: "csharp", 9: "cpp", 10: "c", 12: "css", 18: "dockerfile", 22: "go",
+	24: "html", 28: "json", 29: "java", 30: "javascript", 32: "kotlin", 36: "lua",
+	38: "makefile", 39: "markdown", 40: "nginx", 43: "php", 48: "protobuf", 49: "python",
+	52: "ruby", 53: "rust", 56: "sql", 57: "scala", 60: "shell", 61: "swift",
+	63: "typescript", 66: "xml", 67: "yaml",
+}
+
+// BlocksToMarkdown 把文档的全部 Block（ListDocumentBlock 的结果）转换为 Markdown。
+// 图片、附件、多维表格等无法转成文字的 Block 会被跳过，其中的子 Block 仍会输出
+func BlocksToMarkdown(blocks []*larkdocx.Block) string {
+	byID := make(map[string]*larkdocx.Block, len(blocks))
+	for _, b := range blocks {
+		if b != nil && b.BlockId != nil {
+			byID[*b.BlockId] = b
+		}
+	}
+	w := &mdWriter{byID: byID}
+	for _, b := range blocks {
+		// 根节点是没有父节点（或父节点不在列表中）的 Block，通常是 Page
+		if b == nil || b.BlockId == nil {
+			continue
+		}
+		if b.ParentId != nil && *b.ParentId != "" && byID[*b.ParentId] != nil {
+			continue
+		}
+		w.block(b, 0)
+	}
+	return strings.TrimSpace(w.sb.String()) + "\n"
+}
+
+type mdWriter struct {
+	byID map[string]*larkdocx.Block
+	sb   strings.Builder
+	// 上一段的列表类型和层级，同一列表的列表项之间不空行
+	lastList  string
+	lastDepth int
+}
+
+const (
+	notList     = ""
+	bulletList  = "-"
+	orderedList = "1."
+)
+
+// emit 写入一段内容，depth>0 时按列表层级缩进
+func (w *mdWriter) emit(text string, depth int, list string) {
+	if text == "" {
+		return
+	}
+	if w.sb.Len() > 0 {
+		if list != notList && w.lastList != notList && (list == w.lastList || depth != w.lastDepth) {
+			w.sb.WriteString("\n")
+		} else {
+			w.sb.WriteString("\n\n")
+		}
+	}
+	indent := strings.Repeat("  ", depth)
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			w.sb.WriteString("\n")
+		}
+		if line != "" {
+			w.sb.WriteString(indent)
+		}
+		w.sb.WriteString(line)
+	}
+	w.lastList, w.lastDepth = list, depth
+}
+
+func (w *mdWriter) children(b *larkdocx.Block, depth int) {
+	ordered := 0
+	for _, id := range b.Children {
+		child := w.byID[id]
+		if child == nil {
+			continue
+		}
+		if child.Ordered != nil {
+			ordered++
+			w.orderedItem(child, depth, ordered)
+			continue
+		}
+		ordered = 0
+		w.block(child, depth)
+	}
+}
+
+func (w *mdWriter) orderedItem(b *larkdocx.Block, depth, n int) {
+	w.emit(fmt.Sprintf("%d. %s", n, inline(b.Ordered)), depth, orderedList)
+	w.children(b, depth+1)
+}
+
+func (w *mdWriter) block(b *larkdocx.Block, depth int) {
+	if heading, level := headingOf(b); heading != nil {
+		w.emit(strings.Repeat("#", level)+" "+inline(heading), depth, notList)
+		w.children(b, depth)
+		return
+	}
+	switch {
+	case b.Page != nil:
+		if title := inline(b.Page); title != "" {
+			w.emit("# "+title, depth, notList)
+		}
+		w.children(b, depth)
+	case b.Text != nil:
+		w.emit(inline(b.Text), depth, notList)
+		w.children(b, depth)
+	case b.Bullet != nil:
+		w.emit("- "+inline(b.Bullet), depth, bulletList)
+		w.children(b, depth+1)
+	case b.Ordered != nil:
+		w.orderedItem(b, depth, 1)
+	case b.Todo != nil:
+		box := "[ ]"
+		if b.Todo.Style != nil && b.Todo.Style.Done != nil && *b.Todo.Style.Done {
+			box = "[x]"
+		}
+		w.emit("- "+box+" "+inline(b.Todo), depth, bulletList)
+		w.children(b, depth+1)
+	case b.Code != nil:
+		lang := ""
+		if b.Code.Style != nil && b.Code.Style.Language != nil {
+			lang = codeLanguages[*b.Code.Style.Language]
+		}
+		w.emit("```"+lang+"\n"+plain(b.Code)+"\n```", depth, notList)
+	case b.Quote != nil:
+		w.emit(quoteLines(inline(b.Quote)), depth, notList)
+	case b.Equation != nil:
+		w.emit("$$\n"+plain(b.Equation)+"\n$$", depth, notList)
+	case b.Divider != nil:
+		w.emit("---", depth, notList)
+	case b.Table != nil:
+		w.emit(w.table(b.Table), depth, notList)
+	case b.Callout != nil, b.QuoteContainer != nil:
+		sub := &mdWriter{byID: w.byID}
+		sub.children(b, 0)
+		w.emit(quoteLines(strings.TrimSpace(sub.sb.String())), depth, notList)
+	default:
+		// Grid、GridColumn 等容器只输出子 Block
+		w.children(b, depth)
+	}
+}
+
+// table 转换为 Markdown 表格，第一行作为表头，单元格内的多段内容合并为一行
+func (w *mdWriter) table(t *larkdocx.Table) string {
+	cols := 0
+	if t.Property != nil && t.Property.ColumnSize != nil {
+		cols = *t.Property.ColumnSize
+	}
+	if cols <= 0 || len(t.Cells) == 0 {
+		return ""
+	}
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	var rows []string
+	for start := 0; start < len(t.Cells); start += cols {
+		end := start + cols
+		if end > len(t.Cells) {
+			end = len(t.Cells)
+		}
+		var cells []string
+		for _, id := range t.Cells[start:end] {
+			cell := ""
+			if b := w.byID[id]; b != nil {
+				sub := &mdWriter{byID: w.byID}
+				sub.children(b, 0)
+				cell = escape.Replace(strings.TrimSpace(sub.sb.String()))
+			}
+			cells = append(cells, cell)
+		}
+		rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+		if start == 0 {
+			rows = append(rows, "|"+strings.Repeat(" --- |", len(cells)))
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+func headingOf(b *larkdocx.Block) (*larkdocx.Text, int) {
+	for i, h := range []*larkdocx.Text{b.Heading1, b.Heading2, b.Heading3, b.Heading4,
+		b.Heading5, b.Heading6, b.Heading7, b.Heading8, b.Heading9} {
+		if h != nil {
+			level := i + 1
+			if level > 6 {
+				level = 6
+			}
+			return h, level
+		}
+	}
+	return nil, 0
+}
+
+func quoteLines(text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// plain 不带格式的文本内容，用于代码块和公式
+func plain(t *larkdocx.Text) string {
+	if t == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, e := range t.Elements {
+		switch {
+		case e == nil:
+		case e.TextRun != nil && e.TextRun.Content != nil:
+			sb.WriteString(*e.TextRun.Content)
+		case e.Equation != nil && e.Equation.Content != nil:
+			sb.WriteString(*e.Equation.Content)
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// inline 转换行内元素：加粗、斜体、删除线、行内代码、链接和 @文档
+func inline(t *larkdocx.Text) string {
+	if t == nil {
+		return ""
+	}
+	var sb strings.Builder
+	for _, e := range t.Elements {
+		switch {
+		case e == nil:
+		case e.TextRun != nil && e.TextRun.Content != nil:
+			sb.WriteString(styled(*e.TextRun.Content, e.TextRun.TextElementStyle))
+		case e.MentionDoc != nil:
+			title, link := "", ""
+			if e.MentionDoc.Title != nil {
+				title = *e.MentionDoc.Title
+			}
+			if e.MentionDoc.Url != nil {
+				link = unescapeURL(*e.MentionDoc.Url)
+			}
+			if link == "" {
+				sb.WriteString(title)
+			} else {
+				sb.WriteString("[" + title + "](" + link + ")")
+			}
+		case e.Equation != nil && e.Equation.Content != nil:
+			sb.WriteString("$" + strings.TrimSpace(*e.Equation.Content) + "$")
+		}
+	}
+	return sb.String()
+}
+
+func styled(content string, style *larkdocx.TextElementStyle) string {
+	if style == nil || strings.TrimSpace(content) == "" {
+		return content
+	}
+	if style.InlineCode != nil && *style.InlineCode {
+		content = "`" + content + "`"
+	} else {
+		if style.Bold != nil && *style.Bold {
+			content = "**" + content + "**"
+		}
+		if style.Italic != nil && *style.Italic {
+			content = "*" + content + "*"
+		}
+		if style.Strikethrough != nil && *style.Strikethrough {
+			content = "~~" + content + "~~"
+		}
+	}
+	if style.Link != nil && style.Link.Url != nil {
+		content = "[" + content + "](" + unescapeURL(*style.Link.Url) + ")"
+	}
+	return content
+}
+
+// unescapeURL 文档接口返回的链接经过 URL 编码
+func unescapeURL(s string) string {
+	if u, err := url.QueryUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
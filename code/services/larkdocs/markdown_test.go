@@ -0,0 +1,118 @@
+package larkdocs
+
+import (
+	"testing"
+
+	larkdocx "github.com/larksuite/oapi-sdk-go/v3/service/docx/v1"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func text(runs ...*larkdocx.TextElement) *larkdocx.Text {
+	return &larkdocx.Text{Elements: runs}
+}
+
+func run(content string, style *larkdocx.TextElementStyle) *larkdocx.TextElement {
+	return &larkdocx.TextElement{TextRun: &larkdocx.TextRun{Content: ptr(content), TextElementStyle: style}}
+}
+
+func block(id, parent string, children ...string) *larkdocx.Block {
+	b := &larkdocx.Block{BlockId: ptr(id), Children: children}
+	if parent != "" {
+		b.ParentId = ptr(parent)
+	}
+	return b
+}
+
+func TestBlocksToMarkdown(t *testing.T) {
+	page := block("page", "", "h1", "p", "b1", "b2", "o1", "o2", "code", "quote", "div", "table", "todo")
+	page.Page = text(run("部署手册", nil))
+	h1 := block("h1", "page")
+	h1.Heading2 = text(run("准备", nil))
+	p := block("p", "page")
+	p.Text = text(
+		run("先阅读 ", nil),
+		run("发布规范", &larkdocx.TextElementStyle{Bold: ptr(true)}),
+		run("，参考 ", nil),
+		run("文档", &larkdocx.TextElementStyle{Link: &larkdocx.Link{Url: ptr("https%3A%2F%2Fexample.com%2Fa")}}),
+		run("，执行 ", nil),
+		run("make deploy", &larkdocx.TextElementStyle{InlineCode: ptr(true)}),
+	)
+	b1 := block("b1", "page", "b1c")
+	b1.Bullet = text(run("检查配置", nil))
+	b1c := block("b1c", "b1")
+	b1c.Bullet = text(run("数据库", nil))
+	b2 := block("b2", "page")
+	b2.Bullet = text(run("检查权限", nil))
+	o1 := block("o1", "page")
+	o1.Ordered = text(run("构建", nil))
+	o2 := block("o2", "page")
+	o2.Ordered = text(run("发布", nil))
+	code := block("code", "page")
+	code.Code = &larkdocx.Text{
+		Style:    &larkdocx.TextStyle{Language: ptr(22)},
+		Elements: []*larkdocx.TextElement{run("func main() {\n\tdeploy()\n}", nil)},
+	}
+	quote := block("quote", "page")
+	quote.Quote = text(run("发布窗口为工作日", nil))
+	div := block("div", "page")
+	div.Divider = &larkdocx.Divider{}
+	table := block("table", "page", "c1", "c2", "c3", "c4")
+	table.Table = &larkdocx.Table{
+		Cells:    []string{"c1", "c2", "c3", "c4"},
+		Property: &larkdocx.TableProperty{RowSize: ptr(2), ColumnSize: ptr(2)},
+	}
+	var cells []*larkdocx.Block
+	for i, content := range []string{"环境", "地址", "生产", "a|b"} {
+		id := []string{"c1", "c2", "c3", "c4"}[i]
+		cell := block(id, "table", id+"t")
+		cell.TableCell = &larkdocx.TableCell{}
+		cellText := block(id+"t", id)
+		cellText.Text = text(run(content, nil))
+		cells = append(cells, cell, cellText)
+	}
+	todo := block("todo", "page")
+	todo.Todo = &larkdocx.Text{Style: &larkdocx.TextStyle{Done: ptr(true)},
+		Elements: []*larkdocx.TextElement{run("通知值班", nil)}}
+
+	blocks := append([]*larkdocx.Block{page, h1, p, b1, b1c, b2, o1, o2, code, quote, div, table, todo}, cells...)
+	want := "# 部署手册\n\n" +
+		"## 准备\n\n" +
+		"先阅读 **发布规范**，参考 [文档](https://example.com/a)，执行 `make deploy`\n\n" +
+		"- 检查配置\n  - 数据库\n- 检查权限\n\n" +
+		"1. 构建\n2. 发布\n\n" +
+		"```go\nfunc main() {\n\tdeploy()\n}\n```\n\n" +
+		"> 发布窗口为工作日\n\n" +
+		"---\n\n" +
+		"| 环境 | 地址 |\n| --- | --- |\n| 生产 | a\\|b |\n\n" +
+		"- [x] 通知值班\n"
+	if got := BlocksToMarkdown(blocks); got != want {
+		t.Errorf("BlocksToMarkdown() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	allow := ParseAllowlist([]string{"oc_a:space1|space2", "*:public", "bad", "oc_b:"})
+	tests := []struct {
+		chat, space string
+		want        bool
+	}{
+		{"oc_a", "space1", true},
+		{"oc_a", "space2", true},
+		{"oc_b", "space1", false},
+		{"oc_b", "public", true},
+		{"oc_c", "space2", false},
+	}
+	for _, tt := range tests {
+		if got := allow.Allowed(tt.chat, tt.space); got != tt.want {
+			t.Errorf("Allowed(%s, %s) = %v, want %v", tt.chat, tt.space, got, tt.want)
+		}
+	}
+	if ParseAllowlist(nil).Allowed("oc_a", "space1") {
+		t.Error("empty allowlist should deny all chats")
+	}
+	wildcard := ParseAllowlist([]string{"oc_a:*", "*:*"})
+	if !wildcard.Allowed("oc_a", "space1") || !wildcard.Allowed("oc_c", "space2") {
+		t.Error("wildcard space should allow every space")
+	}
+}
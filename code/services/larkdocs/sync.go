@@ -0,0 +1,135 @@
+package larkdocs
+
+import (
+	"context"
+	"fmt"
+	"start-feishubot/services/kb"
+	"strings"
+	"time"
+)
+
+// idPrefix 飞书文档在知识库中的 ID 前缀
+const idPrefix = "lark:"
+
+// fetchInterval 两篇文档之间的间隔，避免触发文档接口的频率限制
+var fetchInterval = 300 * time.Millisecond
+
+// Sync 把知识空间和单独配置的文档同步到知识库：版本未变化的文档跳过，
+// 空间中已删除的文档从知识库移除。任一空间列举失败时不做删除，避免临时错误清空索引。
+// 同步期间的修改在结束后一次写入知识库文件
+func Sync(ctx context.Context, c *Connector, base *kb.Base, spaces, tokens []string) (added, removed int, err error) {
+	err = base.Batch(func() error {
+		added, removed, err = syncNodes(ctx, c, base, spaces, tokens)
+		return err
+	})
+	return added, removed, err
+}
+
+func syncNodes(ctx context.Context, c *Connector, base *kb.Base, spaces, tokens []string) (added, removed int, err error) {
+	var nodes []Node
+	var listErr error
+	for _, space := range spaces {
+		spaceNodes, err := c.ListSpace(ctx, space)
+		if err != nil {
+			fmt.Printf("⚠️ [LarkDocs] List space %s failed: %v\n", space, err)
+			listErr = err
+			continue
+		}
+		nodes = append(nodes, spaceNodes...)
+	}
+	for _, token := range tokens {
+		node, err := c.Resolve(ctx, token)
+		if err != nil {
+			fmt.Printf("⚠️ [LarkDocs] Resolve %s failed: %v\n", token, err)
+			listErr = err
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	seen := map[string]bool{}
+	for _, node := range nodes {
+		id := idPrefix + node.Token
+		if seen[id] {
+			continue
+		}
+		// 拉取失败的文档保留旧版本
+		seen[id] = true
+		hash := "rev:" + node.Revision
+		if node.Revision != "" && base.Fresh(id, hash) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return added, removed, ctx.Err()
+		}
+		time.Sleep(fetchInterval)
+		text, err := c.Markdown(ctx, node.Token)
+		if err != nil {
+			fmt.Printf("⚠️ [LarkDocs] Fetch %s (%s) failed: %v\n", node.Title, node.Token, err)
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		doc := kb.Document{
+			ID:     id,
+			Name:   node.Title,
+			Space:  node.Space,
+			URL:    node.URL,
+			Source: kb.SourceLark,
+			Hash:   hash,
+		}
+		if _, err := base.AddText(ctx, doc, text); err != nil {
+			fmt.Printf("⚠️ [LarkDocs] Import %s failed: %v\n", node.Title, err)
+			continue
+		}
+		added++
+	}
+	if listErr != nil {
+		return added, removed, listErr
+	}
+	removed, err = base.RemoveStale(idPrefix, seen)
+	return added, removed, err
+}
+
+// RemoveAll 删除之前同步的全部飞书文档，用于取消飞书文档配置后清理知识库
+func RemoveAll(base *kb.Base) (int, error) {
+	return base.RemoveStale(idPrefix, nil)
+}
+
+// Allowlist 每个群可以检索的知识空间（或单独配置的文档 token），群 ID 为 "*" 时对所有群生效，
+// 空间为 "*" 时可以检索全部已同步的空间
+type Allowlist map[string]map[string]bool
+
+// ParseAllowlist 解析 群ID:空间1|空间2 形式的配置，如 [oc_xxx:7012345|7023456, "*:7034567"]
+func ParseAllowlist(items []string) Allowlist {
+	allow := Allowlist{}
+	for _, item := range items {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		chat := strings.TrimSpace(parts[0])
+		for _, space := range strings.Split(parts[1], "|") {
+			space = strings.TrimSpace(space)
+			if chat == "" || space == "" {
+				continue
+			}
+			if allow[chat] == nil {
+				allow[chat] = map[string]bool{}
+			}
+			allow[chat][space] = true
+		}
+	}
+	return allow
+}
+
+// Allowed 只允许检索列出的空间，未配置任何规则时所有群都不能检索飞书文档
+func (a Allowlist) Allowed(chatID, space string) bool {
+	for _, chat := range []string{chatID, "*"} {
+		if a[chat][space] || a[chat]["*"] {
+			return true
+		}
+	}
+	return false
+}
@@ -6,31 +6,40 @@ import (
 	"io"
 	"mime/multipart"
 	"os"
+	"path/filepath"
 )
 
 type AudioToTextRequestBody struct {
+	// File 为文件名，whisper 根据扩展名判断音频格式
 	File           string `json:"file"`
+	Audio          []byte `json:"-"`
 	Model          string `json:"model"`
 	ResponseFormat string `json:"response_format"`
 }
 
 type AudioToTextResponseBody struct {
 	Text string `json:"text"`
+	// 以下字段仅在 response_format=verbose_json 时返回
+	Language string         `json:"language,omitempty"`
+	Duration float64        `json:"duration,omitempty"`
+	Segments []AudioSegment `json:"segments,omitempty"`
 }
 
-func audioMultipartForm(request AudioToTextRequestBody, w *multipart.Writer) error {
-	f, err := os.Open(request.File)
-	if err != nil {
-		return fmt.Errorf("opening audio file: %w", err)
-	}
+// AudioSegment verbose_json 中带时间戳的分段，时间单位为秒
+type AudioSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
 
-	fw, err := w.CreateFormFile("file", f.Name())
+func audioMultipartForm(request AudioToTextRequestBody, w *multipart.Writer) error {
+	fw, err := w.CreateFormFile("file", filepath.Base(request.File))
 	if err != nil {
 		return fmt.Errorf("creating form file: %w", err)
 	}
 
-	if _, err = io.Copy(fw, f); err != nil {
-		return fmt.Errorf("reading from opened audio file: %w", err)
+	if _, err = fw.Write(request.Audio); err != nil {
+		return fmt.Errorf("writing audio data: %w", err)
 	}
 
 	fw, err = w.CreateFormField("model")
@@ -42,16 +51,32 @@ func audioMultipartForm(request AudioToTextRequestBody, w *multipart.Writer) err
 	if _, err = io.Copy(fw, modelName); err != nil {
 		return fmt.Errorf("writing model name: %w", err)
 	}
+
+	if request.ResponseFormat != "" {
+		if err = w.WriteField("response_format", request.ResponseFormat); err != nil {
+			return fmt.Errorf("writing response_format: %w", err)
+		}
+	}
 	w.Close()
 
 	return nil
 }
 
 func (gpt ChatGPT) AudioToText(audio string) (string, error) {
+	data, err := os.ReadFile(audio)
+	if err != nil {
+		return "", fmt.Errorf("reading audio file: %w", err)
+	}
+	return gpt.AudioBytesToText(data, audio)
+}
+
+// AudioBytesToText 直接转写内存中的音频，filename 只用于标明格式，例如 chunk.wav
+func (gpt *ChatGPT) AudioBytesToText(audio []byte, filename string) (string, error) {
 	requestBody := AudioToTextRequestBody{
-		File:           audio,
+		File:           filename,
+		Audio:          audio,
 		Model:          "whisper-1",
-		ResponseFormat: "text",
+		ResponseFormat: "json",
 	}
 	audioToTextResponseBody := &AudioToTextResponseBody{}
 	err := gpt.sendRequestWithBodyType(gpt.ApiUrl+"/v1/audio/transcriptions",
@@ -64,3 +89,51 @@ func (gpt ChatGPT) AudioToText(audio string) (string, error) {
 
 	return audioToTextResponseBody.Text, nil
 }
+
+// AudioBytesToSegments 转写并返回带时间戳的分段（verbose_json）
+func (gpt *ChatGPT) AudioBytesToSegments(audio []byte, filename string) (*AudioToTextResponseBody, error) {
+	requestBody := AudioToTextRequestBody{
+		File:           filename,
+		Audio:          audio,
+		Model:          "whisper-1",
+		ResponseFormat: "verbose_json",
+	}
+	audioToTextResponseBody := &AudioToTextResponseBody{}
+	err := gpt.sendRequestWithBodyType(gpt.ApiUrl+"/v1/audio/transcriptions",
+		"POST", formVoiceDataBody, requestBody, audioToTextResponseBody)
+	if err != nil {
+		return nil, err
+	}
+	return audioToTextResponseBody, nil
+}
+
+type TextToSpeechRequestBody struct {
+	Model          string `json:"model"`
+	Input          string `json:"input"`
+	Voice          string `json:"voice"`
+	ResponseFormat string `json:"response_format"`
+}
+
+// TextToSpeech 返回 wav 音频，兼容服务的 TTS 接口普遍支持该格式（并非都能输出 ogg/opus），
+// 作为飞书语音上传前需要用 audio.EncodeOggOpus 转码
+func (gpt *ChatGPT) TextToSpeech(text string, voice string, model string) ([]byte, error) {
+	if model == "" {
+		model = "tts-1"
+	}
+	if voice == "" {
+		voice = "alloy"
+	}
+	requestBody := TextToSpeechRequestBody{
+		Model:          model,
+		Input:          text,
+		Voice:          voice,
+		ResponseFormat: "wav",
+	}
+	var speech []byte
+	err := gpt.sendRequestWithBodyType(gpt.ApiUrl+"/v1/audio/speech",
+		"POST", jsonBody, requestBody, &speech)
+	if err != nil {
+		return nil, err
+	}
+	return speech, nil
+}
@@ -20,6 +20,16 @@ import (
 type Messages struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// FinishReason 模型返回的结束原因，只在回复中有值，不参与请求和会话序列化
+	FinishReason string `json:"-"`
+	// ToolCalls 模型请求调用的工具，ToolCallID 为 tool 消息对应的调用 ID
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// Truncated 回复是否因长度限制被截断
+func (m Messages) Truncated() bool {
+	return m.FinishReason == "length"
 }
 
 type ChatGPT struct {
@@ -40,6 +50,13 @@ type ChatGPT struct {
 	DebugHTTP bool
 	// ChatGPT API timeout in seconds
 	ChatGPTTimeoutSec int
+	// default chat model
+	Model string
+	// 向量模型和输出维度，维度为 0 时使用模型默认值
+	EmbeddingModel      string
+	EmbeddingDimensions int
+	// embedding 配置了 EMBEDDING_API_URL 时请求向量的客户端，为 nil 时使用对话接口
+	embedding *ChatGPT
 }
 
 type requestBodyType int
@@ -48,11 +65,12 @@ const (
 	jsonBody requestBodyType = iota
 	formVoiceDataBody
 	formPictureDataBody
+	formPictureEditBody
 
 	nilBody
 )
 
-func (gpt ChatGPT) doAPIRequestWithRetry(url, method string, bodyType requestBodyType,
+func (gpt *ChatGPT) doAPIRequestWithRetry(url, method string, bodyType requestBodyType,
 	requestBody interface{}, responseBody interface{}, client *http.Client, maxRetries int) error {
 	var api *loadbalancer.API
 	var requestBodyData []byte
@@ -96,6 +114,18 @@ func (gpt ChatGPT) doAPIRequestWithRetry(url, method string, bodyType requestBod
 			return err
 		}
 		requestBodyData = formBody.Bytes()
+	case formPictureEditBody:
+		formBody := &bytes.Buffer{}
+		writer = multipart.NewWriter(formBody)
+		err = pictureEditMultipartForm(requestBody.(ImageEditRequestBody), writer)
+		if err != nil {
+			return err
+		}
+		err = writer.Close()
+		if err != nil {
+			return err
+		}
+		requestBodyData = formBody.Bytes()
 	case nilBody:
 		requestBodyData = nil
 
@@ -130,7 +160,8 @@ func (gpt ChatGPT) doAPIRequestWithRetry(url, method string, bodyType requestBod
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	if bodyType == formVoiceDataBody || bodyType == formPictureDataBody {
+	if bodyType == formVoiceDataBody || bodyType == formPictureDataBody ||
+		bodyType == formPictureEditBody {
 		if writer != nil {
 			req.Header.Set("Content-Type", writer.FormDataContentType())
 		}
@@ -155,6 +186,12 @@ func (gpt ChatGPT) doAPIRequestWithRetry(url, method string, bodyType requestBod
 	var response *http.Response
 	var retry int
 	for retry = 0; retry <= maxRetries; retry++ {
+		// 重试时需要重新生成请求体，内存中的 body 已被上一次请求读完
+		if retry > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return err
+			}
+		}
 		response, err = client.Do(req)
 		// read body
 		if err != nil || (response != nil && (response.StatusCode < 200 || response.StatusCode >= 300)) {
@@ -202,8 +239,10 @@ func (gpt ChatGPT) doAPIRequestWithRetry(url, method string, bodyType requestBod
 		fmt.Printf("[HTTP] Response OK status=%d", response.StatusCode)
 	}
 
-	err = json.Unmarshal(body, responseBody)
-	if err != nil {
+	// 语音等二进制响应直接返回原始内容
+	if raw, ok := responseBody.(*[]byte); ok {
+		*raw = body
+	} else if err = json.Unmarshal(body, responseBody); err != nil {
 		return err
 	}
 
@@ -213,7 +252,7 @@ func (gpt ChatGPT) doAPIRequestWithRetry(url, method string, bodyType requestBod
 	return nil
 }
 
-func (gpt ChatGPT) sendRequestWithBodyType(link, method string, bodyType requestBodyType,
+func (gpt *ChatGPT) sendRequestWithBodyType(link, method string, bodyType requestBodyType,
 	requestBody interface{}, responseBody interface{}) error {
 	var err error
 
@@ -251,7 +290,7 @@ func NewChatGPT(config initialization.Config) *ChatGPT {
 	apiUrl := config.OpenaiApiUrl
 	httpProxy := config.HttpProxy
 	lb := loadbalancer.NewLoadBalancer(apiKeys)
-	return &ChatGPT{
+	gpt := &ChatGPT{
 		Lb:                lb,
 		ApiKey:            apiKeys,
 		ApiUrl:            apiUrl,
@@ -262,5 +301,34 @@ func NewChatGPT(config initialization.Config) *ChatGPT {
 		ArkBotId:          config.ArkBotId,
 		DebugHTTP:         config.DebugHTTP,
 		ChatGPTTimeoutSec: config.ChatGPTTimeoutSec,
+		Model:             config.OpenaiModel,
+
+		EmbeddingModel:      config.EmbeddingModel,
+		EmbeddingDimensions: config.EmbeddingDimensions,
+	}
+	gpt.embedding = newEmbeddingClient(config)
+	return gpt
+}
+
+// newEmbeddingClient 单独配置了向量接口时创建对应的客户端，同样有 key 负载均衡和重试；
+// EMBEDDING_API_KEY 留空时沿用 OPENAI_KEY
+func newEmbeddingClient(config initialization.Config) *ChatGPT {
+	if strings.TrimSpace(config.EmbeddingApiUrl) == "" {
+		return nil
+	}
+	apiKeys := config.OpenaiApiKeys
+	if config.EmbeddingApiKey != "" {
+		apiKeys = []string{config.EmbeddingApiKey}
+	}
+	return &ChatGPT{
+		Lb:                  loadbalancer.NewLoadBalancer(apiKeys),
+		ApiKey:              apiKeys,
+		ApiUrl:              config.EmbeddingApiUrl,
+		HttpProxy:           config.HttpProxy,
+		Provider:            "openai",
+		DebugHTTP:           config.DebugHTTP,
+		ChatGPTTimeoutSec:   config.ChatGPTTimeoutSec,
+		EmbeddingModel:      config.EmbeddingModel,
+		EmbeddingDimensions: config.EmbeddingDimensions,
 	}
 }
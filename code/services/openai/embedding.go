@@ -0,0 +1,126 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultEmbeddingModel = "text-embedding-3-small"
+	// embeddingBatchSize 每次请求包含的文本数，超过时分批请求
+	embeddingBatchSize = 100
+)
+
+type EmbeddingRequestBody struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+	// Dimensions 输出维度，只有 text-embedding-3 等支持降维的模型可用
+	Dimensions     int    `json:"dimensions,omitempty"`
+	EncodingFormat string `json:"encoding_format,omitempty"`
+}
+
+type EmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float32 `json:"embedding"`
+}
+
+type EmbeddingResponseBody struct {
+	Model string          `json:"model"`
+	Data  []EmbeddingData `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// embeddingURL 在接口地址后拼接 /embeddings，地址已经以 /embeddings 结尾时直接使用；
+// Ark 的向量接口不在 /bots 下，需要去掉 ARK_API_URL 的 /bots 后缀
+func (gpt *ChatGPT) embeddingURL() (string, error) {
+	if gpt.Provider != "ark" {
+		base := strings.TrimRight(gpt.ApiUrl, "/")
+		if strings.HasSuffix(base, "/embeddings") {
+			return base, nil
+		}
+		return base + "/embeddings", nil
+	}
+	if gpt.ArkApiUrl == "" {
+		return "", errors.New("ark api url is empty")
+	}
+	base := strings.TrimSuffix(strings.TrimRight(gpt.ArkApiUrl, "/"), "/bots")
+	return base + "/embeddings", nil
+}
+
+// Embeddings 计算文本向量，返回顺序与 texts 一致。
+// 超过 embeddingBatchSize 时分批请求，每批都走与对话相同的 key 负载均衡和重试；
+// model 为空时使用 EMBEDDING_MODEL，dimensions<=0 时使用模型默认维度。
+// Provider 为 ark 时请求方舟的 /embeddings 接口，model 需填写方舟的向量模型或接入点 ID；
+// 配置了 EMBEDDING_API_URL 时改为请求该地址
+func (gpt *ChatGPT) Embeddings(texts []string, model string, dimensions int) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if gpt.embedding != nil {
+		return gpt.embedding.Embeddings(texts, model, dimensions)
+	}
+	if model == "" {
+		model = gpt.EmbeddingModel
+	}
+	if model == "" {
+		model = defaultEmbeddingModel
+	}
+	link, err := gpt.embeddingURL()
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+		requestBody := EmbeddingRequestBody{
+			Model:          model,
+			Input:          texts[start:end],
+			Dimensions:     dimensions,
+			EncodingFormat: "float",
+		}
+		resp := &EmbeddingResponseBody{}
+		if err := gpt.sendRequestWithBodyType(link, "POST", jsonBody, requestBody, resp); err != nil {
+			return nil, err
+		}
+		vectors, err := sortEmbeddings(resp.Data, end-start)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vectors...)
+	}
+	return out, nil
+}
+
+// Embed 使用默认向量模型和维度计算向量，ChatGPT 因此可以直接作为 utils.Embedder 使用。
+// 请求的超时和重试由 sendRequestWithBodyType 控制，ctx 只用于提前放弃
+func (gpt *ChatGPT) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return gpt.Embeddings(texts, gpt.EmbeddingModel, gpt.EmbeddingDimensions)
+}
+
+// sortEmbeddings 按 index 还原输入顺序，并检查返回数量
+func sortEmbeddings(data []EmbeddingData, want int) ([][]float32, error) {
+	if len(data) != want {
+		return nil, fmt.Errorf("embedding count mismatch: got %d, want %d", len(data), want)
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].Index < data[j].Index })
+	out := make([][]float32, len(data))
+	for i, d := range data {
+		if d.Index != i {
+			return nil, fmt.Errorf("embedding index %d missing", i)
+		}
+		out[i] = d.Embedding
+	}
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"start-feishubot/initialization"
+	"start-feishubot/services/loadbalancer"
+	"testing"
+)
+
+func TestEmbeddingsBatches(t *testing.T) {
+	var batches []EmbeddingRequestBody
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("request = %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req EmbeddingRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, req)
+		// 倒序返回，检查按 index 还原顺序
+		resp := EmbeddingResponseBody{}
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, EmbeddingData{Index: i, Embedding: []float32{float32(len(batches)), float32(i)}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	gpt := &ChatGPT{Lb: loadbalancer.NewLoadBalancer([]string{"k"}), ApiUrl: srv.URL + "/v1"}
+	texts := make([]string, embeddingBatchSize+5)
+	for i := range texts {
+		texts[i] = "t"
+	}
+	got, err := gpt.Embeddings(texts, "", 256)
+	if err != nil {
+		t.Fatalf("Embeddings() error = %v", err)
+	}
+	if len(batches) != 2 || len(batches[0].Input) != embeddingBatchSize || len(batches[1].Input) != 5 {
+		t.Fatalf("batches = %d", len(batches))
+	}
+	if batches[0].Model != defaultEmbeddingModel || batches[0].Dimensions != 256 {
+		t.Errorf("request = %+v", batches[0])
+	}
+	if len(got) != len(texts) {
+		t.Fatalf("len(Embeddings()) = %d, want %d", len(got), len(texts))
+	}
+	for i, v := range got {
+		batch, index := 1, i
+		if i >= embeddingBatchSize {
+			batch, index = 2, i-embeddingBatchSize
+		}
+		if v[0] != float32(batch) || v[1] != float32(index) {
+			t.Fatalf("Embeddings()[%d] = %v", i, v)
+		}
+	}
+}
+
+func TestEmbeddingURL(t *testing.T) {
+	tests := []struct {
+		name string
+		gpt  *ChatGPT
+		want string
+	}{
+		{"openai", &ChatGPT{ApiUrl: "https://api.openai.com/v1/"}, "https://api.openai.com/v1/embeddings"},
+		{"full url", &ChatGPT{ApiUrl: "https://example.com/v1/embeddings"}, "https://example.com/v1/embeddings"},
+		{"ark bots url", &ChatGPT{Provider: "ark", ArkApiUrl: "https://ark.cn-beijing.volces.com/api/v3/bots"},
+			"https://ark.cn-beijing.volces.com/api/v3/embeddings"},
+		{"ark base url", &ChatGPT{Provider: "ark", ArkApiUrl: "https://ark.cn-beijing.volces.com/api/v3"},
+			"https://ark.cn-beijing.volces.com/api/v3/embeddings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.gpt.embeddingURL()
+			if err != nil || got != tt.want {
+				t.Errorf("embeddingURL() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmbeddingsSeparateEndpoint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer ek" {
+			t.Errorf("request = %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"data":[{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	gpt := NewChatGPT(initialization.Config{
+		OpenaiApiKeys:   []string{"k"},
+		OpenaiApiUrl:    "http://127.0.0.1:1/v1",
+		EmbeddingApiUrl: srv.URL + "/v1",
+		EmbeddingApiKey: "ek",
+		EmbeddingModel:  "m",
+	})
+	got, err := gpt.Embed(context.Background(), []string{"a"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(got) != 1 || got[0][0] != 1 {
+		t.Errorf("Embed() = %v", got)
+	}
+}
+
+func TestSortEmbeddings(t *testing.T) {
+	if _, err := sortEmbeddings([]EmbeddingData{{Index: 0}}, 2); err == nil {
+		t.Error("count mismatch should fail")
+	}
+	if _, err := sortEmbeddings([]EmbeddingData{{Index: 0}, {Index: 0}}, 2); err == nil {
+		t.Error("duplicate index should fail")
+	}
+}
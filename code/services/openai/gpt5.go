@@ -8,8 +8,8 @@ import (
 )
 
 const (
-	maxTokens = 4096
-	engine    = "gpt-5-2025-08-07"
+	defaultMaxTokens = 4096
+	engine           = "gpt-5-2025-08-07"
 )
 
 // ChatGPTResponseBody 请求体
@@ -33,8 +33,38 @@ type ChatGPTRequestBody struct {
 	Model     string     `json:"model"`
 	Messages  []Messages `json:"messages"`
 	MaxTokens int        `json:"max_completion_tokens"`
+	Tools     []Tool     `json:"tools,omitempty"`
+	// ToolChoice 为 none 时模型不再调用工具
+	ToolChoice string `json:"tool_choice,omitempty"`
 }
 
+// Tool function calling 的工具定义，Parameters 为 JSON Schema
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+// ToolHandler 执行一次工具调用，返回值作为 tool 消息发回给模型
+type ToolHandler func(call ToolCall) string
+
+// maxToolRounds 一次回答最多执行几轮工具调用，之后要求模型直接回答
+const maxToolRounds = 3
+
 type ArkOpenAICompatRequestBody struct {
 	Model     string     `json:"model"`
 	Messages  []Messages `json:"messages"`
@@ -59,10 +89,43 @@ type ArkBotResponseBody struct {
 }
 
 func (gpt *ChatGPT) Completions(msg []Messages) (resp Messages, err error) {
-	return gpt.CompletionsWithMaxTokens(msg, maxTokens)
+	return gpt.CompletionsWithMaxTokens(msg, defaultMaxTokens)
 }
 
 func (gpt *ChatGPT) CompletionsWithMaxTokens(msg []Messages, maxTokens int) (resp Messages, err error) {
+	return gpt.CompletionsWithModel(msg, gpt.Model, maxTokens)
+}
+
+// CompletionsWithTools 把工具提供给模型，模型请求调用时执行 handler 并带上结果继续请求，直到给出回答。
+// Ark 不支持工具调用，按普通请求处理
+func (gpt *ChatGPT) CompletionsWithTools(msg []Messages, model string, maxTokens int,
+	tools []Tool, handler ToolHandler) (Messages, error) {
+	if gpt.Provider == "ark" || len(tools) == 0 {
+		return gpt.CompletionsWithModel(msg, model, maxTokens)
+	}
+	msgs := append([]Messages{}, msg...)
+	for round := 0; ; round++ {
+		toolChoice := ""
+		if round == maxToolRounds {
+			toolChoice = "none"
+		}
+		resp, err := gpt.chatCompletions(msgs, model, maxTokens, tools, toolChoice)
+		if err != nil || len(resp.ToolCalls) == 0 {
+			return resp, err
+		}
+		msgs = append(msgs, resp)
+		for _, call := range resp.ToolCalls {
+			fmt.Printf("[OpenAI Tool] %s %s\n", call.Function.Name, call.Function.Arguments)
+			msgs = append(msgs, Messages{Role: "tool", ToolCallID: call.ID, Content: handler(call)})
+		}
+	}
+}
+
+// CompletionsWithModel 使用指定模型请求，model 为空时使用默认模型，maxTokens<=0 时使用默认值
+func (gpt *ChatGPT) CompletionsWithModel(msg []Messages, model string, maxTokens int) (resp Messages, err error) {
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
 	// Ark provider branch
 	if gpt.Provider == "ark" {
 		if gpt.ArkApiUrl == "" || gpt.ArkBotId == "" {
@@ -78,7 +141,9 @@ func (gpt *ChatGPT) CompletionsWithMaxTokens(msg []Messages, maxTokens int) (res
 		compatResp := &ChatGPTResponseBody{}
 		err = gpt.sendRequestWithBodyType(endpointA, "POST", jsonBody, compatReq, compatResp)
 		if err == nil && len(compatResp.Choices) > 0 {
-			return compatResp.Choices[0].Message, nil
+			resp = compatResp.Choices[0].Message
+			resp.FinishReason = compatResp.Choices[0].FinishReason
+			return resp, nil
 		}
 		// 2) 失败则回退到 /bots/{botId}/completions，body 为 {input:{messages}}
 		endpointB := fmt.Sprintf("%s/%s/completions", base, gpt.ArkBotId)
@@ -90,14 +155,27 @@ func (gpt *ChatGPT) CompletionsWithMaxTokens(msg []Messages, maxTokens int) (res
 		}
 		return Messages{}, errors.New("ark 请求失败")
 	}
+	return gpt.chatCompletions(msg, model, maxTokens, nil, "")
+}
 
+// chatCompletions 请求 OpenAI 兼容的 /chat/completions 接口
+func (gpt *ChatGPT) chatCompletions(msg []Messages, model string, maxTokens int,
+	tools []Tool, toolChoice string) (resp Messages, err error) {
+	if model == "" {
+		model = engine
+	}
+	if maxTokens <= 0 {
+		maxTokens = defaultMaxTokens
+	}
 	requestBody := ChatGPTRequestBody{
-		Model:     engine,
-		Messages:  msg,
-		MaxTokens: maxTokens,
+		Model:      model,
+		Messages:   msg,
+		MaxTokens:  maxTokens,
+		Tools:      tools,
+		ToolChoice: toolChoice,
 	}
 
-	fmt.Printf("[OpenAI Request] Model: %s, MaxTokens: %d, Messages: %d\n", engine, maxTokens, len(msg))
+	fmt.Printf("[OpenAI Request] Model: %s, MaxTokens: %d, Messages: %d\n", model, maxTokens, len(msg))
 
 	gptResponseBody := &ChatGPTResponseBody{}
 	err = gpt.sendRequestWithBodyType(gpt.ApiUrl+"/chat/completions", "POST",
@@ -139,6 +217,7 @@ func (gpt *ChatGPT) CompletionsWithMaxTokens(msg []Messages, maxTokens int) (res
 
 	if err == nil && len(gptResponseBody.Choices) > 0 {
 		resp = gptResponseBody.Choices[0].Message
+		resp.FinishReason = gptResponseBody.Choices[0].FinishReason
 	} else {
 		resp = Messages{}
 		if err == nil {
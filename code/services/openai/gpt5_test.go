@@ -0,0 +1,54 @@
+package openai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"start-feishubot/services/loadbalancer"
+	"testing"
+)
+
+func TestCompletionsWithTools(t *testing.T) {
+	var requests []ChatGPTRequestBody
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatGPTRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		requests = append(requests, req)
+		msg := Messages{Role: "assistant", Content: "好的"}
+		// 一直请求调用工具，直到 tool_choice 为 none
+		if req.ToolChoice != "none" {
+			var call ToolCall
+			call.ID, call.Type = "call_1", "function"
+			call.Function.Name, call.Function.Arguments = "save", `{"fact":"偏好 Go"}`
+			msg = Messages{Role: "assistant", ToolCalls: []ToolCall{call}}
+		}
+		json.NewEncoder(w).Encode(ChatGPTResponseBody{Choices: []ChatGPTChoiceItem{{Message: msg}}})
+	}))
+	defer srv.Close()
+
+	gpt := &ChatGPT{Lb: loadbalancer.NewLoadBalancer([]string{"k"}), ApiUrl: srv.URL}
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "save"}}}
+	var calls []ToolCall
+	resp, err := gpt.CompletionsWithTools([]Messages{{Role: "user", Content: "记住我偏好 Go"}}, "m", 0,
+		tools, func(call ToolCall) string {
+			calls = append(calls, call)
+			return "已保存"
+		})
+	if err != nil {
+		t.Fatalf("CompletionsWithTools() error = %v", err)
+	}
+	if resp.Content != "好的" || len(resp.ToolCalls) != 0 {
+		t.Errorf("CompletionsWithTools() = %+v", resp)
+	}
+	if len(requests) != maxToolRounds+1 || len(calls) != maxToolRounds {
+		t.Fatalf("requests = %d, calls = %d", len(requests), len(calls))
+	}
+	// 每轮都带上模型的调用和工具结果
+	last := requests[len(requests)-1].Messages
+	if len(last) != 1+2*maxToolRounds || last[2].Role != "tool" || last[2].ToolCallID != "call_1" ||
+		last[2].Content != "已保存" || len(last[1].ToolCalls) != 1 {
+		t.Errorf("last request messages = %+v", last)
+	}
+}
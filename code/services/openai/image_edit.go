@@ -0,0 +1,147 @@
+package openai
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+)
+
+// BackgroundRemovalPrompt “去背景”预设使用的提示词，需配合 gpt-image-1 的透明背景
+const BackgroundRemovalPrompt = "Remove the background completely. Keep the main subject " +
+	"exactly as it is, with its original details and colors, on a transparent background."
+
+type ImageEditRequestBody struct {
+	Model          string
+	Prompt         string
+	Image          []byte // png 图片内容
+	Mask           []byte // 可选，透明区域为需要修改的部分
+	N              int
+	Size           string
+	Quality        string
+	Background     string
+	ResponseFormat string
+}
+
+// EditImages 根据提示词修改图片，opts 需先经过 ImageModelSpec.Normalize
+func (gpt *ChatGPT) EditImages(img []byte, mask []byte, prompt string,
+	opts ImageOptions) ([]string, error) {
+	requestBody := ImageEditRequestBody{
+		Model:      opts.Model,
+		Prompt:     prompt,
+		Image:      img,
+		Mask:       mask,
+		N:          opts.N,
+		Size:       opts.Size,
+		Quality:    opts.Quality,
+		Background: opts.Background,
+	}
+	if GetImageModelSpec(opts.Model).ResponseFormat {
+		requestBody.ResponseFormat = "b64_json"
+	}
+
+	imageResponseBody := &ImageResponseBody{}
+	err := gpt.sendRequestWithBodyType(gpt.ApiUrl+"/v1/images/edits",
+		"POST", formPictureEditBody, requestBody, imageResponseBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var b64Pool []string
+	for _, data := range imageResponseBody.Data {
+		if data.Base64Json != "" {
+			b64Pool = append(b64Pool, data.Base64Json)
+		}
+	}
+	if len(b64Pool) == 0 {
+		return nil, fmt.Errorf("no image returned")
+	}
+	return b64Pool, nil
+}
+
+func pictureEditMultipartForm(request ImageEditRequestBody,
+	w *multipart.Writer) error {
+	fw, err := w.CreateFormFile("image", "image.png")
+	if err != nil {
+		return fmt.Errorf("creating form file: %w", err)
+	}
+	if _, err = fw.Write(request.Image); err != nil {
+		return fmt.Errorf("writing image data: %w", err)
+	}
+
+	if len(request.Mask) > 0 {
+		fw, err = w.CreateFormFile("mask", "mask.png")
+		if err != nil {
+			return fmt.Errorf("creating mask file: %w", err)
+		}
+		if _, err = fw.Write(request.Mask); err != nil {
+			return fmt.Errorf("writing mask data: %w", err)
+		}
+	}
+
+	fields := []struct{ name, value string }{
+		{"model", request.Model},
+		{"prompt", request.Prompt},
+		{"n", fmt.Sprintf("%d", request.N)},
+		{"size", request.Size},
+		{"quality", request.Quality},
+		{"background", request.Background},
+		{"response_format", request.ResponseFormat},
+	}
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		if err = w.WriteField(field.name, field.value); err != nil {
+			return fmt.Errorf("writing %s: %w", field.name, err)
+		}
+	}
+	return nil
+}
+
+// BuildEditMask 把用户发送的第二张图片转换为 edits 接口需要的蒙版：
+// 透明或接近白色的区域视为需要修改的部分，输出尺寸与原图一致
+func BuildEditMask(img []byte, mask []byte) ([]byte, error) {
+	imgCfg, _, err := image.DecodeConfig(bytes.NewReader(img))
+	if err != nil {
+		return nil, fmt.Errorf("解码原图时出错：%w", err)
+	}
+	src, _, err := image.Decode(bytes.NewReader(mask))
+	if err != nil {
+		return nil, fmt.Errorf("解码蒙版时出错：%w", err)
+	}
+
+	width, height := imgCfg.Width, imgCfg.Height
+	bounds := src.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// 最近邻缩放到原图尺寸
+			sx := bounds.Min.X + x*bounds.Dx()/width
+			sy := bounds.Min.Y + y*bounds.Dy()/height
+			if maskEditable(src.At(sx, sy)) {
+				out.SetNRGBA(x, y, color.NRGBA{})
+			} else {
+				out.SetNRGBA(x, y, color.NRGBA{A: 0xff})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("编码蒙版时出错：%w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func maskEditable(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	if a < 0x8000 {
+		return true
+	}
+	// 按预乘 alpha 还原亮度，亮度超过 80% 视为白色
+	lum := (299*r + 587*g + 114*b) / 1000 * 0xffff / a
+	return lum > 0xcccc
+}
@@ -0,0 +1,63 @@
+package openai
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodeTestPng(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBuildEditMask(t *testing.T) {
+	src := encodeTestPng(t, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+
+	// 2x2 蒙版：左上白色、右上透明、左下黑色、右下灰色
+	mask := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	mask.SetNRGBA(0, 0, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	mask.SetNRGBA(1, 0, color.NRGBA{})
+	mask.SetNRGBA(0, 1, color.NRGBA{A: 0xff})
+	mask.SetNRGBA(1, 1, color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff})
+
+	data, err := BuildEditMask(src, encodeTestPng(t, mask))
+	if err != nil {
+		t.Fatalf("BuildEditMask() error = %v", err)
+	}
+	out, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Bounds().Dx() != 4 || out.Bounds().Dy() != 4 {
+		t.Fatalf("mask size = %v, want 4x4", out.Bounds())
+	}
+
+	tests := []struct {
+		x, y      int
+		wantAlpha uint32
+	}{
+		{1, 1, 0},
+		{3, 0, 0},
+		{0, 3, 0xffff},
+		{3, 3, 0xffff},
+	}
+	for _, tt := range tests {
+		if _, _, _, a := out.At(tt.x, tt.y).RGBA(); a != tt.wantAlpha {
+			t.Errorf("alpha at (%d,%d) = %#x, want %#x", tt.x, tt.y, a, tt.wantAlpha)
+		}
+	}
+}
+
+func TestBuildEditMaskInvalid(t *testing.T) {
+	src := encodeTestPng(t, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+	if _, err := BuildEditMask(src, []byte("not an image")); err == nil {
+		t.Error("BuildEditMask() expected error for invalid mask")
+	}
+}
@@ -0,0 +1,129 @@
+package openai
+
+// ImageModelSpec 图片模型支持的参数，第一个值为默认值
+type ImageModelSpec struct {
+	Name        string
+	Sizes       []string
+	Qualities   []string
+	Styles      []string
+	Backgrounds []string
+	MaxN        int
+	// gpt-image 系列始终返回 b64_json，不接受 response_format 参数
+	ResponseFormat bool
+	// 只有 dall-e-2 支持 variations 接口
+	Variation bool
+	// dall-e-2 与 gpt-image-1 支持 edits 接口
+	Edit bool
+}
+
+var imageModelSpecs = map[string]ImageModelSpec{
+	"dall-e-2": {
+		Name:           "dall-e-2",
+		Sizes:          []string{"1024x1024", "512x512", "256x256"},
+		MaxN:           10,
+		ResponseFormat: true,
+		Variation:      true,
+		Edit:           true,
+	},
+	"dall-e-3": {
+		Name:           "dall-e-3",
+		Sizes:          []string{"1024x1024", "1024x1792", "1792x1024"},
+		Qualities:      []string{"standard", "hd"},
+		Styles:         []string{"vivid", "natural"},
+		MaxN:           1,
+		ResponseFormat: true,
+	},
+	"gpt-image-1": {
+		Name:        "gpt-image-1",
+		Sizes:       []string{"1024x1024", "1024x1536", "1536x1024", "auto"},
+		Qualities:   []string{"auto", "low", "medium", "high"},
+		Backgrounds: []string{"auto", "transparent", "opaque"},
+		MaxN:        10,
+		Edit:        true,
+	},
+}
+
+// VariationSize variations 接口只支持 dall-e-2 的尺寸，其他尺寸使用默认值
+func VariationSize(size string) string {
+	return pickOption(imageModelSpecs["dall-e-2"].Sizes, size)
+}
+
+// EditModel 当前模型不支持 edits 接口时，从候选模型中选第一个支持的，都不支持则使用 dall-e-2
+func EditModel(model string, candidates []string) string {
+	if GetImageModelSpec(model).Edit {
+		return model
+	}
+	for _, candidate := range candidates {
+		if GetImageModelSpec(candidate).Edit {
+			return candidate
+		}
+	}
+	return "dall-e-2"
+}
+
+// BackgroundRemovalModel 从当前模型和候选模型中选一个支持 edits 接口与透明背景的模型，
+// 都不支持时返回空字符串
+func BackgroundRemovalModel(model string, candidates []string) string {
+	for _, candidate := range append([]string{model}, candidates...) {
+		spec := GetImageModelSpec(candidate)
+		for _, background := range spec.Backgrounds {
+			if spec.Edit && background == "transparent" {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// GetImageModelSpec 未知模型按只支持默认参数处理，避免传入不支持的字段
+func GetImageModelSpec(model string) ImageModelSpec {
+	if spec, ok := imageModelSpecs[model]; ok {
+		return spec
+	}
+	return ImageModelSpec{
+		Name:           model,
+		Sizes:          []string{"1024x1024"},
+		MaxN:           1,
+		ResponseFormat: true,
+	}
+}
+
+type ImageOptions struct {
+	Model      string `json:"model,omitempty"`
+	Size       string `json:"size,omitempty"`
+	Quality    string `json:"quality,omitempty"`
+	Style      string `json:"style,omitempty"`
+	Background string `json:"background,omitempty"`
+	N          int    `json:"n,omitempty"`
+}
+
+// Normalize 把模型不支持的参数替换为默认值，n 限制在 [1, maxN]
+func (spec ImageModelSpec) Normalize(opts ImageOptions, maxN int) ImageOptions {
+	opts.Model = spec.Name
+	opts.Size = pickOption(spec.Sizes, opts.Size)
+	opts.Quality = pickOption(spec.Qualities, opts.Quality)
+	opts.Style = pickOption(spec.Styles, opts.Style)
+	opts.Background = pickOption(spec.Backgrounds, opts.Background)
+	if maxN <= 0 || maxN > spec.MaxN {
+		maxN = spec.MaxN
+	}
+	if opts.N < 1 {
+		opts.N = 1
+	}
+	if opts.N > maxN {
+		opts.N = maxN
+	}
+	return opts
+}
+
+func pickOption(supported []string, value string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	for _, v := range supported {
+		if v == value {
+			return value
+		}
+	}
+	return supported[0]
+}
@@ -0,0 +1,85 @@
+package openai
+
+import "testing"
+
+func TestImageModelSpecNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ImageOptions
+		maxN int
+		want ImageOptions
+	}{
+		{
+			name: "dall-e-3 drops unsupported values",
+			opts: ImageOptions{Model: "dall-e-3", Size: "256x256", Quality: "hd", Background: "transparent", N: 4},
+			maxN: 4,
+			want: ImageOptions{Model: "dall-e-3", Size: "1024x1024", Quality: "hd", Style: "vivid", N: 1},
+		},
+		{
+			name: "gpt-image-1 keeps supported values",
+			opts: ImageOptions{Model: "gpt-image-1", Size: "1536x1024", Quality: "high", Style: "vivid", Background: "transparent", N: 3},
+			maxN: 4,
+			want: ImageOptions{Model: "gpt-image-1", Size: "1536x1024", Quality: "high", Background: "transparent", N: 3},
+		},
+		{
+			name: "n is limited by config",
+			opts: ImageOptions{Model: "dall-e-2", Size: "512x512", N: 9},
+			maxN: 4,
+			want: ImageOptions{Model: "dall-e-2", Size: "512x512", N: 4},
+		},
+		{
+			name: "unknown model uses safe defaults",
+			opts: ImageOptions{Model: "my-image", Size: "512x512", Quality: "hd", N: 0},
+			maxN: 4,
+			want: ImageOptions{Model: "my-image", Size: "1024x1024", N: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GetImageModelSpec(tt.opts.Model).Normalize(tt.opts, tt.maxN)
+			if got != tt.want {
+				t.Errorf("Normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEditModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		candidates []string
+		want       string
+	}{
+		{"keeps edit capable model", "gpt-image-1", []string{"dall-e-2"}, "gpt-image-1"},
+		{"picks first capable candidate", "dall-e-3", []string{"dall-e-3", "gpt-image-1", "dall-e-2"}, "gpt-image-1"},
+		{"falls back to dall-e-2", "dall-e-3", []string{"dall-e-3"}, "dall-e-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EditModel(tt.model, tt.candidates); got != tt.want {
+				t.Errorf("EditModel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackgroundRemovalModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		candidates []string
+		want       string
+	}{
+		{"keeps current model", "gpt-image-1", []string{"dall-e-2"}, "gpt-image-1"},
+		{"picks configured candidate", "dall-e-3", []string{"dall-e-3", "dall-e-2", "gpt-image-1"}, "gpt-image-1"},
+		{"none configured", "dall-e-3", []string{"dall-e-3", "dall-e-2"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BackgroundRemovalModel(tt.model, tt.candidates); got != tt.want {
+				t.Errorf("BackgroundRemovalModel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,20 +2,25 @@ package openai
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"image"
 	"image/jpeg"
 	"image/png"
-	"io"
 	"mime/multipart"
 	"os"
+	"path/filepath"
 )
 
 type ImageGenerationRequestBody struct {
+	Model          string `json:"model,omitempty"`
 	Prompt         string `json:"prompt"`
 	N              int    `json:"n"`
 	Size           string `json:"size"`
-	ResponseFormat string `json:"response_format"`
+	Quality        string `json:"quality,omitempty"`
+	Style          string `json:"style,omitempty"`
+	Background     string `json:"background,omitempty"`
+	ResponseFormat string `json:"response_format,omitempty"`
 }
 
 type ImageResponseBody struct {
@@ -26,7 +31,9 @@ type ImageResponseBody struct {
 }
 
 type ImageVariantRequestBody struct {
+	// Image 为文件名，ImageData 为 png 图片内容
 	Image          string `json:"image"`
+	ImageData      []byte `json:"-"`
 	N              int    `json:"n"`
 	Size           string `json:"size"`
 	ResponseFormat string `json:"response_format"`
@@ -55,6 +62,40 @@ func (gpt ChatGPT) GenerateImage(prompt string, size string, n int) ([]string, e
 	return b64Pool, nil
 }
 
+// GenerateImages 按模型能力生成图片，opts 需先经过 ImageModelSpec.Normalize
+func (gpt *ChatGPT) GenerateImages(prompt string, opts ImageOptions) ([]string, error) {
+	requestBody := ImageGenerationRequestBody{
+		Model:      opts.Model,
+		Prompt:     prompt,
+		N:          opts.N,
+		Size:       opts.Size,
+		Quality:    opts.Quality,
+		Style:      opts.Style,
+		Background: opts.Background,
+	}
+	if GetImageModelSpec(opts.Model).ResponseFormat {
+		requestBody.ResponseFormat = "b64_json"
+	}
+
+	imageResponseBody := &ImageResponseBody{}
+	err := gpt.sendRequestWithBodyType(gpt.ApiUrl+"/v1/images/generations",
+		"POST", jsonBody, requestBody, imageResponseBody)
+	if err != nil {
+		return nil, err
+	}
+
+	var b64Pool []string
+	for _, data := range imageResponseBody.Data {
+		if data.Base64Json != "" {
+			b64Pool = append(b64Pool, data.Base64Json)
+		}
+	}
+	if len(b64Pool) == 0 {
+		return nil, fmt.Errorf("no image returned")
+	}
+	return b64Pool, nil
+}
+
 func (gpt ChatGPT) GenerateOneImage(prompt string, size string) (string, error) {
 	b64s, err := gpt.GenerateImage(prompt, size, 1)
 	if err != nil {
@@ -68,8 +109,18 @@ func (gpt ChatGPT) GenerateOneImageWithDefaultSize(prompt string) (string, error
 }
 
 func (gpt ChatGPT) GenerateImageVariation(images string, size string, n int) ([]string, error) {
+	data, err := os.ReadFile(images)
+	if err != nil {
+		return nil, fmt.Errorf("reading image file: %w", err)
+	}
+	return gpt.GenerateImageVariationBytes(data, size, n)
+}
+
+// GenerateImageVariationBytes 使用内存中的 png 图片生成变体
+func (gpt *ChatGPT) GenerateImageVariationBytes(image []byte, size string, n int) ([]string, error) {
 	requestBody := ImageVariantRequestBody{
-		Image:          images,
+		Image:          "image.png",
+		ImageData:      image,
 		N:              n,
 		Size:           size,
 		ResponseFormat: "b64_json",
@@ -98,19 +149,23 @@ func (gpt ChatGPT) GenerateOneImageVariation(images string, size string) (string
 	return b64s[0], nil
 }
 
+func (gpt *ChatGPT) GenerateOneImageVariationBytes(image []byte, size string) (string, error) {
+	b64s, err := gpt.GenerateImageVariationBytes(image, size, 1)
+	if err != nil {
+		return "", err
+	}
+	return b64s[0], nil
+}
+
 func pictureMultipartForm(request ImageVariantRequestBody,
 	w *multipart.Writer) error {
 
-	f, err := os.Open(request.Image)
-	if err != nil {
-		return fmt.Errorf("opening audio file: %w", err)
-	}
-	fw, err := w.CreateFormFile("image", f.Name())
+	fw, err := w.CreateFormFile("image", filepath.Base(request.Image))
 	if err != nil {
 		return fmt.Errorf("creating form file: %w", err)
 	}
-	if _, err = io.Copy(fw, f); err != nil {
-		return fmt.Errorf("reading from opened audio file: %w", err)
+	if _, err = fw.Write(request.ImageData); err != nil {
+		return fmt.Errorf("writing image data: %w", err)
 	}
 
 	err = w.WriteField("size", request.Size)
@@ -147,25 +202,29 @@ func pictureMultipartForm(request ImageVariantRequestBody,
 }
 
 func VerifyPngs(pngPaths []string) error {
-	foundPng := false
-	var expectedWidth, expectedHeight int
-
+	var pngs [][]byte
 	for _, pngPath := range pngPaths {
-		f, err := os.Open(pngPath)
+		data, err := os.ReadFile(pngPath)
 		if err != nil {
-			return fmt.Errorf("os.Open: %v", err)
+			return fmt.Errorf("os.ReadFile: %v", err)
 		}
+		pngs = append(pngs, data)
+	}
+	return VerifyPngBytes(pngs...)
+}
 
-		fi, err := f.Stat()
-		if err != nil {
-			return fmt.Errorf("f.Stat: %v", err)
-		}
-		if fi.Size() > 4*1024*1024 {
+// VerifyPngBytes 校验图片符合变体接口要求：png、4MB 以内、正方形且尺寸一致
+func VerifyPngBytes(pngs ...[]byte) error {
+	foundPng := false
+	var expectedWidth, expectedHeight int
+
+	for _, data := range pngs {
+		if len(data) > 4*1024*1024 {
 			return fmt.Errorf("image size too large, "+
 				"must be under %d MB", 4)
 		}
 
-		image, err := png.Decode(f)
+		image, err := png.Decode(bytes.NewReader(data))
 		if err != nil {
 			return fmt.Errorf("image must be valid png, got error: %v", err)
 		}
@@ -190,40 +249,39 @@ func VerifyPngs(pngPaths []string) error {
 }
 
 func ConvertToRGBA(inputFilePath string, outputFilePath string) error {
-	// 打开输入文件
-	inputFile, err := os.Open(inputFilePath)
+	data, err := os.ReadFile(inputFilePath)
 	if err != nil {
 		return fmt.Errorf("打开文件时出错：%w", err)
 	}
-	defer inputFile.Close()
+	rgba, err := ConvertToRGBAPng(data)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputFilePath, rgba, 0644); err != nil {
+		return fmt.Errorf("创建输出文件时出错：%w", err)
+	}
+	return nil
+}
 
-	// 解码图像
-	img, _, err := image.Decode(inputFile)
+// ConvertToRGBAPng 将 png/jpeg 等图片在内存中转换为 RGBA 模式的 png
+func ConvertToRGBAPng(data []byte) ([]byte, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("解码图像时出错：%w", err)
+		return nil, fmt.Errorf("解码图像时出错：%w", err)
 	}
 
-	// 将图像转换为RGBA模式
 	rgba := image.NewRGBA(img.Bounds())
-	for x := 0; x < img.Bounds().Max.X; x++ {
-		for y := 0; y < img.Bounds().Max.Y; y++ {
+	for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+		for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
 			rgba.Set(x, y, img.At(x, y))
 		}
 	}
 
-	// 创建输出文件
-	outputFile, err := os.Create(outputFilePath)
-	if err != nil {
-		return fmt.Errorf("创建输出文件时出错：%w", err)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, rgba); err != nil {
+		return nil, fmt.Errorf("编码图像时出错：%w", err)
 	}
-	defer outputFile.Close()
-
-	// 编码图像为 PNG 格式并写入输出文件
-	if err := png.Encode(outputFile, rgba); err != nil {
-		return fmt.Errorf("编码图像时出错：%w", err)
-	}
-
-	return nil
+	return buf.Bytes(), nil
 }
 
 func ConvertJpegToPNG(jpgPath string) error {
@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Coalescer 将同一用户在短时间窗口内连续发送的多条消息合并为一次请求
+type Coalescer struct {
+	window  time.Duration
+	mu      sync.Mutex
+	pending map[string]*batch
+}
+
+type batch struct {
+	parts []string
+}
+
+func NewCoalescer(window time.Duration) *Coalescer {
+	return &Coalescer{window: window, pending: make(map[string]*batch)}
+}
+
+// Join 把 text 加入 key 对应的批次，返回 text 是否为批次中的第一条消息。
+// 第一条消息会为 key 启动定时器，窗口结束时以合并后的文本调用 flush；
+// 之后到达的消息只追加内容，不再触发 flush。调用方不会被阻塞
+func (c *Coalescer) Join(key, text string, flush func(merged string)) bool {
+	if c.window <= 0 {
+		flush(text)
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.pending[key]; ok {
+		b.parts = append(b.parts, text)
+		return false
+	}
+	b := &batch{parts: []string{text}}
+	c.pending[key] = b
+	time.AfterFunc(c.window, func() {
+		c.mu.Lock()
+		delete(c.pending, key)
+		parts := b.parts
+		c.mu.Unlock()
+		flush(strings.Join(parts, "\n"))
+	})
+	return true
+}
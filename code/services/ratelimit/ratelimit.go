@@ -0,0 +1,159 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"start-feishubot/services/redis"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+// Limit 令牌桶参数：每分钟补充 PerMinute 个令牌，最多积攒 Burst 个
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+func (l Limit) ratePerSecond() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// Store 令牌桶状态存储，内存实现用于单实例，Redis 实现用于多实例共享
+type Store interface {
+	// Take 尝试从 key 对应的桶中取一个令牌，失败时返回需要等待的时间
+	Take(key string, limit Limit) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets *cache.Cache
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: cache.New(time.Hour, 10*time.Minute), now: time.Now}
+}
+
+func (s *MemoryStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	if limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return true, 0, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b := &bucket{tokens: float64(limit.Burst), last: now}
+	if v, ok := s.buckets.Get(key); ok {
+		b = v.(*bucket)
+	}
+	rate := limit.ratePerSecond()
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	allowed := b.tokens >= 1
+	var wait time.Duration
+	if allowed {
+		b.tokens--
+	} else {
+		wait = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	// 桶装满所需时间之后状态等价于新桶，可以过期
+	s.buckets.Set(key, b, time.Duration(float64(limit.Burst)/rate*float64(time.Second))+time.Minute)
+	return allowed, wait, nil
+}
+
+// tokenBucketScript 在 Redis 中原子地完成补充与扣减
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local data = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  wait = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', tostring(now))
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000) + 60000)
+return {allowed, wait}
+`
+
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	if limit.PerMinute <= 0 || limit.Burst <= 0 {
+		return true, 0, nil
+	}
+	reply, err := s.client.Eval(tokenBucketScript, []string{s.prefix + key},
+		strconv.FormatFloat(limit.ratePerSecond(), 'f', -1, 64),
+		strconv.Itoa(limit.Burst),
+		strconv.FormatInt(time.Now().UnixMilli(), 10))
+	if err != nil {
+		return false, 0, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 2 {
+		return false, 0, fmt.Errorf("unexpected token bucket reply: %v", reply)
+	}
+	allowed, _ := items[0].(int64)
+	wait, _ := items[1].(int64)
+	return allowed == 1, time.Duration(wait) * time.Millisecond, nil
+}
+
+// Limiter 按用户和群分别限流
+type Limiter struct {
+	store     Store
+	userLimit Limit
+	chatLimit Limit
+}
+
+func NewLimiter(store Store, userLimit, chatLimit Limit) *Limiter {
+	return &Limiter{store: store, userLimit: userLimit, chatLimit: chatLimit}
+}
+
+// Allow 先检查用户桶再检查群桶，chatId 为空时只检查用户。
+// 存储出错时放行，避免 Redis 故障导致机器人不可用
+func (l *Limiter) Allow(userId, chatId string) (bool, time.Duration) {
+	if userId != "" {
+		ok, wait, err := l.store.Take("user:"+userId, l.userLimit)
+		if err != nil {
+			fmt.Printf("⚠️ [RateLimit] store error, allowing: %v\n", err)
+			return true, 0
+		}
+		if !ok {
+			return false, wait
+		}
+	}
+	if chatId != "" {
+		ok, wait, err := l.store.Take("chat:"+chatId, l.chatLimit)
+		if err != nil {
+			fmt.Printf("⚠️ [RateLimit] store error, allowing: %v\n", err)
+			return true, 0
+		}
+		if !ok {
+			return false, wait
+		}
+	}
+	return true, 0
+}
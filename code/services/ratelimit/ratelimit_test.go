@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{PerMinute: 6, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if ok, _, _ := store.Take("u", limit); !ok {
+			t.Fatalf("Take() #%d rejected within burst", i+1)
+		}
+	}
+	ok, wait, _ := store.Take("u", limit)
+	if ok {
+		t.Fatalf("Take() allowed beyond burst")
+	}
+	if wait != 10*time.Second {
+		t.Errorf("Take() wait = %v, want 10s", wait)
+	}
+
+	// 6/min 即每 10 秒补充一个令牌
+	now = now.Add(10 * time.Second)
+	if ok, _, _ := store.Take("u", limit); !ok {
+		t.Errorf("Take() rejected after refill")
+	}
+	if ok, _, _ := store.Take("other", limit); !ok {
+		t.Errorf("Take() buckets are not isolated per key")
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), Limit{PerMinute: 60, Burst: 5}, Limit{PerMinute: 60, Burst: 2})
+	if ok, _ := limiter.Allow("u1", "c1"); !ok {
+		t.Fatal("Allow() rejected first message")
+	}
+	if ok, _ := limiter.Allow("u2", "c1"); !ok {
+		t.Fatal("Allow() rejected second user in chat")
+	}
+	if ok, _ := limiter.Allow("u3", "c1"); ok {
+		t.Error("Allow() should hit the chat limit")
+	}
+	if ok, _ := limiter.Allow("u3", ""); !ok {
+		t.Error("Allow() private chat should only check the user bucket")
+	}
+}
+
+func TestCoalescer_Join(t *testing.T) {
+	c := NewCoalescer(50 * time.Millisecond)
+	flushed := make(chan string, 3)
+	flush := func(merged string) { flushed <- merged }
+
+	var leaders int
+	start := time.Now()
+	for _, text := range []string{"first", "second", "third"} {
+		if c.Join("u", text, flush) {
+			leaders++
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= 50*time.Millisecond {
+		t.Errorf("Join() blocked for %s", elapsed)
+	}
+	if leaders != 1 {
+		t.Fatalf("Join() leaders = %d, want 1", leaders)
+	}
+	select {
+	case merged := <-flushed:
+		if merged != "first\nsecond\nthird" {
+			t.Errorf("Join() merged = %q", merged)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Join() did not flush after the window")
+	}
+
+	// 窗口结束后的消息开始新的批次
+	if !c.Join("u", "fourth", flush) {
+		t.Error("Join() after flush should start a new batch")
+	}
+	if merged := <-flushed; merged != "fourth" {
+		t.Errorf("Join() merged = %q, want fourth", merged)
+	}
+
+	var out string
+	if leader := NewCoalescer(0).Join("u", "x", func(merged string) { out = merged }); !leader || out != "x" {
+		t.Errorf("Join() with zero window = %q, %v", out, leader)
+	}
+}
@@ -0,0 +1,226 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client 极简 Redis 客户端，只实现 RESP 协议的请求/响应，足够支撑限流、缓存等共享状态
+type Client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// ErrNil 对应 Redis 的空回复
+var ErrNil = errors.New("redis: nil")
+
+func NewClient(addr, password string, db int) *Client {
+	return &Client{addr: addr, password: password, db: db, timeout: 3 * time.Second}
+}
+
+func (c *Client) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.rd = bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.roundTrip("AUTH", c.password); err != nil {
+			c.close()
+			return err
+		}
+	}
+	if c.db > 0 {
+		if _, err := c.roundTrip("SELECT", strconv.Itoa(c.db)); err != nil {
+			c.close()
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) close() {
+	if c.conn != nil {
+		c.conn.Close()
+	}
+	c.conn = nil
+	c.rd = nil
+}
+
+// idempotentCommands 重复执行结果相同的命令，发出后读回复失败也可以安全重发
+var idempotentCommands = map[string]bool{"GET": true, "SET": true, "DEL": true, "PING": true}
+
+// Do 执行一条命令，连接断开时自动重连一次。命令已经发出后才出错时服务端可能已经执行，
+// 只有幂等命令会重发，EVAL 等命令直接返回错误，避免重复扣减令牌等副作用
+func (c *Client) Do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if err := c.connect(); err != nil {
+				return nil, err
+			}
+		}
+		reply, sent, err := c.send(args...)
+		if err == nil || isServerError(err) || errors.Is(err, ErrNil) {
+			return reply, err
+		}
+		c.close()
+		if attempt == 1 || (sent && !idempotentCommands[strings.ToUpper(args[0])]) {
+			return nil, err
+		}
+	}
+	return nil, errors.New("redis: unreachable")
+}
+
+func (c *Client) roundTrip(args ...string) (interface{}, error) {
+	reply, _, err := c.send(args...)
+	return reply, err
+}
+
+// send 发送命令并读取回复，sent 表示命令已完整写入连接
+func (c *Client) send(args ...string) (reply interface{}, sent bool, err error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.conn.Write(encodeCommand(args)); err != nil {
+		return nil, false, err
+	}
+	reply, err = readReply(c.rd)
+	return reply, true, err
+}
+
+// Get 读取字符串，key 不存在时返回 ErrNil
+func (c *Client) Get(key string) (string, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil {
+		return "", err
+	}
+	s, ok := reply.(string)
+	if !ok {
+		return "", fmt.Errorf("redis: unexpected reply %T", reply)
+	}
+	return s, nil
+}
+
+// Set 写入字符串，ttl 为 0 时不过期
+func (c *Client) Set(key, value string, ttl time.Duration) error {
+	var err error
+	if ttl > 0 {
+		_, err = c.Do("SET", key, value, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	} else {
+		_, err = c.Do("SET", key, value)
+	}
+	return err
+}
+
+func (c *Client) Del(key string) error {
+	_, err := c.Do("DEL", key)
+	return err
+}
+
+// Eval 执行 Lua 脚本
+func (c *Client) Eval(script string, keys []string, args ...string) (interface{}, error) {
+	cmd := []string{"EVAL", script, strconv.Itoa(len(keys))}
+	cmd = append(cmd, keys...)
+	cmd = append(cmd, args...)
+	return c.Do(cmd...)
+}
+
+type serverError string
+
+func (e serverError) Error() string { return "redis: " + string(e) }
+
+func isServerError(err error) bool {
+	var se serverError
+	return errors.As(err, &se)
+}
+
+func encodeCommand(args []string) []byte {
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+func readLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("redis: malformed reply")
+	}
+	return line[:len(line)-2], nil
+}
+
+// readReply 解析一条 RESP 回复：字符串、整数、数组或错误
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := readLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, errors.New("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, serverError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		items := make([]interface{}, 0, n)
+		for i := 0; i < n; i++ {
+			item, err := readReply(rd)
+			if err != nil && !errors.Is(err, ErrNil) {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", line[0])
+	}
+}
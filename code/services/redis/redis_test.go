@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEncodeCommand(t *testing.T) {
+	got := string(encodeCommand([]string{"SET", "k", "v"}))
+	want := "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"
+	if got != want {
+		t.Errorf("encodeCommand() = %q, want %q", got, want)
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    interface{}
+		wantErr error
+	}{
+		{name: "status", raw: "+OK\r\n", want: "OK"},
+		{name: "integer", raw: ":42\r\n", want: int64(42)},
+		{name: "bulk", raw: "$5\r\nhello\r\n", want: "hello"},
+		{name: "nil bulk", raw: "$-1\r\n", wantErr: ErrNil},
+		{name: "array", raw: "*2\r\n:1\r\n$2\r\nab\r\n", want: []interface{}{int64(1), "ab"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readReply(bufio.NewReader(strings.NewReader(tt.raw)))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("readReply() err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readReply() err = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readReply() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := readReply(bufio.NewReader(strings.NewReader("-ERR wrong type\r\n")))
+	if !isServerError(err) {
+		t.Errorf("readReply() err = %v, want server error", err)
+	}
+}
+
+// TestDoRetry 第一个连接读到命令后不回复就断开，只有幂等命令会在新连接上重发
+func TestDoRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantErr  bool
+		wantSent int
+	}{
+		{name: "get is retried", args: []string{"GET", "k"}, wantSent: 2},
+		{name: "eval is not retried", args: []string{"EVAL", "return 1", "0"}, wantErr: true, wantSent: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			sent := make(chan []string, 4)
+			go func() {
+				for conn := 0; ; conn++ {
+					c, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					rd := bufio.NewReader(c)
+					reply, err := readReply(rd)
+					if err != nil {
+						c.Close()
+						continue
+					}
+					var args []string
+					for _, arg := range reply.([]interface{}) {
+						args = append(args, arg.(string))
+					}
+					sent <- args
+					if conn == 0 {
+						c.Close()
+						continue
+					}
+					c.Write([]byte("+OK\r\n"))
+					c.Close()
+				}
+			}()
+
+			_, err = NewClient(ln.Addr().String(), "", 0).Do(tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			ln.Close()
+			if len(sent) != tt.wantSent {
+				t.Errorf("command sent %d times, want %d", len(sent), tt.wantSent)
+			}
+		})
+	}
+}
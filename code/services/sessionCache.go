@@ -12,9 +12,51 @@ type SessionMode string
 type SessionService struct {
 	cache *cache.Cache
 }
+
+// PicSetting 图片创作参数，空值表示使用模型默认值
 type PicSetting struct {
-	resolution Resolution
+	Model      string `json:"model,omitempty"`
+	Size       string `json:"size,omitempty"`
+	Quality    string `json:"quality,omitempty"`
+	Style      string `json:"style,omitempty"`
+	Background string `json:"background,omitempty"`
+	N          int    `json:"n,omitempty"`
+	// Preset 风格预设名称，见 style_list.yaml
+	Preset string `json:"preset,omitempty"`
+	// Enhance 是否扩写提示词，nil 表示沿用全局配置
+	Enhance *bool `json:"enhance,omitempty"`
+}
+
+func NewPicSetting(opts openai.ImageOptions) PicSetting {
+	return PicSetting{
+		Model:      opts.Model,
+		Size:       opts.Size,
+		Quality:    opts.Quality,
+		Style:      opts.Style,
+		Background: opts.Background,
+		N:          opts.N,
+	}
+}
+
+// WithImageOptions 替换图片参数，保留风格预设与扩写设置
+func (p PicSetting) WithImageOptions(opts openai.ImageOptions) PicSetting {
+	setting := NewPicSetting(opts)
+	setting.Preset = p.Preset
+	setting.Enhance = p.Enhance
+	return setting
+}
+
+func (p PicSetting) ImageOptions() openai.ImageOptions {
+	return openai.ImageOptions{
+		Model:      p.Model,
+		Size:       p.Size,
+		Quality:    p.Quality,
+		Style:      p.Style,
+		Background: p.Background,
+		N:          p.N,
+	}
 }
+
 type Resolution string
 
 type SessionMeta struct {
@@ -41,6 +83,8 @@ type SessionServiceCacheInterface interface {
 	GetMode(sessionId string) SessionMode
 	SetPicResolution(sessionId string, resolution Resolution)
 	GetPicResolution(sessionId string) string
+	SetPicSetting(sessionId string, setting PicSetting)
+	GetPicSetting(sessionId string) PicSetting
 	Clear(sessionId string)
 }
 
@@ -83,18 +127,14 @@ func (s *SessionService) SetMsg(sessionId string, msg []openai.Messages) {
 	maxCacheTime := time.Hour * 12
 
 	//限制对话上下文长度
-	for getStrPoolTotalLength(msg) > maxLength {
+	// 最新一轮问答总是保留，回答卡片上的重新生成和继续需要用到
+	for getStrPoolTotalLength(msg) > maxLength && len(msg) > 2 {
 		// Prefer to keep the first message (often system) and drop the earliest pair
-		if len(msg) > 2 {
+		if len(msg) > 3 {
 			msg = append(msg[:1], msg[2:]...)
 			continue
 		}
-		// If not enough to drop a pair, drop from the head safely
-		if len(msg) > 0 {
-			msg = msg[1:]
-		} else {
-			break
-		}
+		msg = msg[1:]
 	}
 
 	sessionContext, ok := s.cache.Get(sessionId)
@@ -112,22 +152,19 @@ func (s *SessionService) SetPicResolution(sessionId string,
 	resolution Resolution) {
 	maxCacheTime := time.Hour * 12
 
-	//if not in [Resolution256, Resolution512, Resolution1024] then set
-	//to Resolution256
-	switch resolution {
-	case Resolution256, Resolution512, Resolution1024:
-	default:
+	//尺寸是否受支持由所选图片模型决定，这里只处理空值
+	if resolution == "" {
 		resolution = Resolution256
 	}
 
 	sessionContext, ok := s.cache.Get(sessionId)
 	if !ok {
-		sessionMeta := &SessionMeta{PicSetting: PicSetting{resolution: resolution}}
+		sessionMeta := &SessionMeta{PicSetting: PicSetting{Size: string(resolution)}}
 		s.cache.Set(sessionId, sessionMeta, maxCacheTime)
 		return
 	}
 	sessionMeta := sessionContext.(*SessionMeta)
-	sessionMeta.PicSetting.resolution = resolution
+	sessionMeta.PicSetting.Size = string(resolution)
 	s.cache.Set(sessionId, sessionMeta, maxCacheTime)
 }
 
@@ -137,8 +174,32 @@ func (s *SessionService) GetPicResolution(sessionId string) string {
 		return string(Resolution256)
 	}
 	sessionMeta := sessionContext.(*SessionMeta)
-	return string(sessionMeta.PicSetting.resolution)
+	if sessionMeta.PicSetting.Size == "" {
+		return string(Resolution256)
+	}
+	return sessionMeta.PicSetting.Size
+
+}
+
+func (s *SessionService) SetPicSetting(sessionId string, setting PicSetting) {
+	maxCacheTime := time.Hour * 12
+	sessionContext, ok := s.cache.Get(sessionId)
+	if !ok {
+		sessionMeta := &SessionMeta{PicSetting: setting}
+		s.cache.Set(sessionId, sessionMeta, maxCacheTime)
+		return
+	}
+	sessionMeta := sessionContext.(*SessionMeta)
+	sessionMeta.PicSetting = setting
+	s.cache.Set(sessionId, sessionMeta, maxCacheTime)
+}
 
+func (s *SessionService) GetPicSetting(sessionId string) PicSetting {
+	sessionContext, ok := s.cache.Get(sessionId)
+	if !ok {
+		return PicSetting{}
+	}
+	return sessionContext.(*SessionMeta).PicSetting
 }
 
 func (s *SessionService) Clear(sessionId string) {
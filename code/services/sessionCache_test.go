@@ -0,0 +1,46 @@
+package services
+
+import (
+	"start-feishubot/services/openai"
+	"strings"
+	"testing"
+)
+
+func TestSetMsgKeepsLatestTurn(t *testing.T) {
+	s := GetSessionCache()
+	long := strings.Repeat("很长的回答", 400)
+	tests := []struct {
+		name string
+		msg  []openai.Messages
+		want []string
+	}{
+		{"drops earlier turns", []openai.Messages{
+			{Role: "system", Content: "角色"},
+			{Role: "user", Content: "问题一"},
+			{Role: "assistant", Content: long},
+			{Role: "user", Content: "问题二"},
+			{Role: "assistant", Content: long},
+		}, []string{"问题二", long}},
+		{"keeps an oversized single turn", []openai.Messages{
+			{Role: "user", Content: "问题"},
+			{Role: "assistant", Content: long + long},
+		}, []string{"问题", long + long}},
+		{"keeps short history", []openai.Messages{
+			{Role: "system", Content: "角色"},
+			{Role: "user", Content: "问题"},
+			{Role: "assistant", Content: "回答"},
+		}, []string{"角色", "问题", "回答"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.SetMsg(tt.name, tt.msg)
+			var got []string
+			for _, m := range s.GetMsg(tt.name) {
+				got = append(got, m.Content)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("GetMsg() = %d messages, want %v", len(got), len(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// FileStore 以 JSON 文件持久化的键值存储，适合数据量不大的配置与用户数据
+type FileStore[T any] struct {
+	path string
+	mu   sync.RWMutex
+	data map[string]T
+	// batch 大于 0 时暂缓写文件，pending 记录期间是否有修改
+	batch   int
+	pending bool
+}
+
+// NewFileStore 打开 path 对应的存储文件，文件不存在时创建空存储
+func NewFileStore[T any](path string) (*FileStore[T], error) {
+	s := &FileStore[T]{path: path, data: make(map[string]T)}
+	content, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return s, nil
+	}
+	if err := json.Unmarshal(content, &s.data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore[T]) Get(key string) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+func (s *FileStore[T]) Set(key string, value T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+	return s.flush()
+}
+
+func (s *FileStore[T]) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[key]; !ok {
+		return nil
+	}
+	delete(s.data, key)
+	return s.flush()
+}
+
+// Update 在同一把锁内读取并修改 key 的值，fn 返回 false 时删除该 key
+func (s *FileStore[T]) Update(key string, fn func(value T, found bool) (T, bool)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, found := s.data[key]
+	value, keep := fn(old, found)
+	if keep {
+		s.data[key] = value
+	} else {
+		delete(s.data, key)
+	}
+	return s.flush()
+}
+
+// Keys 返回排序后的全部 key
+func (s *FileStore[T]) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.data))
+	for k := range s.data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Batch 执行 fn 期间的修改只在内存中生效，fn 返回后统一写一次文件，
+// 用于批量导入等会连续修改很多 key 的场景。fn 出错时已做的修改同样会写入
+func (s *FileStore[T]) Batch(fn func() error) error {
+	s.mu.Lock()
+	s.batch++
+	s.mu.Unlock()
+
+	err := fn()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batch--
+	if s.batch > 0 || !s.pending {
+		return err
+	}
+	s.pending = false
+	if flushErr := s.flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// flush 先写临时文件再重命名，避免进程中途退出时写坏数据
+func (s *FileStore[T]) flush() error {
+	if s.path == "" {
+		return nil
+	}
+	if s.batch > 0 {
+		s.pending = true
+		return nil
+	}
+	content, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, content, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type item struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func TestFileStore_Persist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "items.json")
+	s, err := NewFileStore[item](path)
+	if err != nil {
+		t.Fatalf("NewFileStore() err = %v", err)
+	}
+	if err := s.Set("b", item{Name: "b", Count: 1}); err != nil {
+		t.Fatalf("Set() err = %v", err)
+	}
+	if err := s.Set("a", item{Name: "a", Count: 2}); err != nil {
+		t.Fatalf("Set() err = %v", err)
+	}
+	if err := s.Update("a", func(v item, found bool) (item, bool) {
+		v.Count++
+		return v, found
+	}); err != nil {
+		t.Fatalf("Update() err = %v", err)
+	}
+
+	reopened, err := NewFileStore[item](path)
+	if err != nil {
+		t.Fatalf("NewFileStore() reopen err = %v", err)
+	}
+	if got := reopened.Keys(); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Keys() = %v", got)
+	}
+	if got, _ := reopened.Get("a"); got.Count != 3 {
+		t.Errorf("Get(a).Count = %d, want 3", got.Count)
+	}
+
+	if err := reopened.Delete("b"); err != nil {
+		t.Fatalf("Delete() err = %v", err)
+	}
+	if _, ok := reopened.Get("b"); ok {
+		t.Errorf("Get(b) found after Delete")
+	}
+}
+
+func TestFileStore_Batch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+	s, _ := NewFileStore[item](path)
+	err := s.Batch(func() error {
+		for _, key := range []string{"a", "b", "c"} {
+			if err := s.Set(key, item{Name: key}); err != nil {
+				return err
+			}
+		}
+		if err := s.Delete("b"); err != nil {
+			return err
+		}
+		// 批量修改结束前不写文件
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("file written inside Batch: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Batch() err = %v", err)
+	}
+
+	reopened, err := NewFileStore[item](path)
+	if err != nil {
+		t.Fatalf("NewFileStore() reopen err = %v", err)
+	}
+	if got := reopened.Keys(); !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Errorf("Keys() = %v", got)
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"start-feishubot/services/store"
+	"strings"
+	"time"
+)
+
+// ErrMemoryNotFound 要删除的记忆不存在
+var ErrMemoryNotFound = errors.New("memory not found")
+
+// MemoryEntry 用户让机器人记住的一条信息
+type MemoryEntry struct {
+	Text      string    `json:"text"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserMemory 用户级别的长期记忆，只有用户本人可以查看和修改。
+// Disabled 为 true 时保留已有记忆但不再用于新话题
+type UserMemory struct {
+	Disabled  bool          `json:"disabled,omitempty"`
+	Entries   []MemoryEntry `json:"entries,omitempty"`
+	UpdatedAt time.Time     `json:"updated_at,omitempty"`
+}
+
+// Active 开启且有记忆时才需要注入到新话题
+func (m UserMemory) Active() bool {
+	return !m.Disabled && len(m.Entries) > 0
+}
+
+type UserMemoryServiceInterface interface {
+	Get(userId string) UserMemory
+	// Remember 记住一条信息并重新开启记忆，相同内容只保存一次，超过 maxEntries 时删除最早的
+	Remember(userId string, text string, maxEntries int) (UserMemory, error)
+	// Forget 删除第 index 条记忆（从 1 开始）
+	Forget(userId string, index int) (MemoryEntry, error)
+	SetDisabled(userId string, disabled bool) (UserMemory, error)
+	Clear(userId string) error
+}
+
+type UserMemoryService struct {
+	store *store.FileStore[UserMemory]
+}
+
+var userMemoryService *UserMemoryService
+
+// InitUserMemoryService 从 path 加载持久化的用户记忆
+func InitUserMemoryService(path string) error {
+	s, err := store.NewFileStore[UserMemory](path)
+	if err != nil {
+		return err
+	}
+	userMemoryService = &UserMemoryService{store: s}
+	return nil
+}
+
+// GetUserMemoryService 未初始化时退化为不落盘的内存存储
+func GetUserMemoryService() UserMemoryServiceInterface {
+	if userMemoryService == nil {
+		s, _ := store.NewFileStore[UserMemory]("")
+		userMemoryService = &UserMemoryService{store: s}
+	}
+	return userMemoryService
+}
+
+func (s *UserMemoryService) Get(userId string) UserMemory {
+	memory, _ := s.store.Get(userId)
+	return memory
+}
+
+func (s *UserMemoryService) Remember(userId string, text string, maxEntries int) (UserMemory, error) {
+	text = strings.TrimSpace(text)
+	var updated UserMemory
+	err := s.store.Update(userId, func(memory UserMemory, _ bool) (UserMemory, bool) {
+		memory.Disabled = false
+		entries := memory.Entries[:0:0]
+		for _, e := range memory.Entries {
+			if e.Text != text {
+				entries = append(entries, e)
+			}
+		}
+		entries = append(entries, MemoryEntry{Text: text, CreatedAt: time.Now()})
+		if maxEntries > 0 && len(entries) > maxEntries {
+			entries = entries[len(entries)-maxEntries:]
+		}
+		memory.Entries = entries
+		memory.UpdatedAt = time.Now()
+		updated = memory
+		return memory, true
+	})
+	if err != nil {
+		return updated, fmt.Errorf("save user memory: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *UserMemoryService) Forget(userId string, index int) (MemoryEntry, error) {
+	var removed MemoryEntry
+	found := false
+	err := s.store.Update(userId, func(memory UserMemory, ok bool) (UserMemory, bool) {
+		if !ok || index < 1 || index > len(memory.Entries) {
+			return memory, ok
+		}
+		found = true
+		removed = memory.Entries[index-1]
+		entries := append(memory.Entries[:index-1:index-1], memory.Entries[index:]...)
+		memory.Entries = entries
+		memory.UpdatedAt = time.Now()
+		return memory, true
+	})
+	if err != nil {
+		return removed, fmt.Errorf("save user memory: %w", err)
+	}
+	if !found {
+		return removed, ErrMemoryNotFound
+	}
+	return removed, nil
+}
+
+func (s *UserMemoryService) SetDisabled(userId string, disabled bool) (UserMemory, error) {
+	var updated UserMemory
+	err := s.store.Update(userId, func(memory UserMemory, _ bool) (UserMemory, bool) {
+		memory.Disabled = disabled
+		memory.UpdatedAt = time.Now()
+		updated = memory
+		return memory, true
+	})
+	if err != nil {
+		return updated, fmt.Errorf("save user memory: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *UserMemoryService) Clear(userId string) error {
+	return s.store.Delete(userId)
+}
@@ -0,0 +1,39 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUserMemoryService(t *testing.T) {
+	if err := InitUserMemoryService(""); err != nil {
+		t.Fatal(err)
+	}
+	s := GetUserMemoryService()
+	for _, fact := range []string{"偏好 Go", "负责支付服务", "偏好 Go", "使用 MySQL"} {
+		if _, err := s.Remember("ou_a", fact, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	memory := s.Get("ou_a")
+	if len(memory.Entries) != 2 || memory.Entries[0].Text != "偏好 Go" || memory.Entries[1].Text != "使用 MySQL" {
+		t.Fatalf("entries = %+v", memory.Entries)
+	}
+	if len(s.Get("ou_b").Entries) != 0 {
+		t.Error("memories should be isolated per user")
+	}
+
+	if _, err := s.SetDisabled("ou_a", true); err != nil || s.Get("ou_a").Active() {
+		t.Errorf("disabled memory should not be active, err = %v", err)
+	}
+	entry, err := s.Forget("ou_a", 1)
+	if err != nil || entry.Text != "偏好 Go" {
+		t.Fatalf("Forget() = %+v, %v", entry, err)
+	}
+	if _, err := s.Forget("ou_a", 5); !errors.Is(err, ErrMemoryNotFound) {
+		t.Errorf("Forget(out of range) error = %v", err)
+	}
+	if _, err := s.Remember("ou_a", "偏好 Go", 2); err != nil || !s.Get("ou_a").Active() {
+		t.Errorf("remember should re-enable memory, err = %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"fmt"
+	"start-feishubot/initialization"
+	"start-feishubot/services/store"
+	"time"
+)
+
+// VoiceSetting 用户级别的语音回复设置，Enabled 为空表示沿用全局默认
+type VoiceSetting struct {
+	Enabled   *bool     `json:"enabled,omitempty"`
+	Voice     string    `json:"voice,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+type VoiceSettingServiceInterface interface {
+	Get(userId string) VoiceSetting
+	Update(userId string, fn func(setting *VoiceSetting)) (VoiceSetting, error)
+	// Resolve 返回是否开启语音回复以及使用的音色
+	Resolve(userId string, config initialization.Config) (bool, string)
+}
+
+type VoiceSettingService struct {
+	store *store.FileStore[VoiceSetting]
+}
+
+var voiceSettingService *VoiceSettingService
+
+// InitVoiceSettingService 从 path 加载持久化的语音设置
+func InitVoiceSettingService(path string) error {
+	s, err := store.NewFileStore[VoiceSetting](path)
+	if err != nil {
+		return err
+	}
+	voiceSettingService = &VoiceSettingService{store: s}
+	return nil
+}
+
+// GetVoiceSettingService 未初始化时退化为不落盘的内存存储
+func GetVoiceSettingService() VoiceSettingServiceInterface {
+	if voiceSettingService == nil {
+		s, _ := store.NewFileStore[VoiceSetting]("")
+		voiceSettingService = &VoiceSettingService{store: s}
+	}
+	return voiceSettingService
+}
+
+func (s *VoiceSettingService) Get(userId string) VoiceSetting {
+	setting, _ := s.store.Get(userId)
+	return setting
+}
+
+func (s *VoiceSettingService) Update(userId string,
+	fn func(setting *VoiceSetting)) (VoiceSetting, error) {
+	var updated VoiceSetting
+	err := s.store.Update(userId, func(setting VoiceSetting, _ bool) (VoiceSetting, bool) {
+		fn(&setting)
+		setting.UpdatedAt = time.Now()
+		updated = setting
+		return setting, true
+	})
+	if err != nil {
+		return updated, fmt.Errorf("save voice setting: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *VoiceSettingService) Resolve(userId string,
+	config initialization.Config) (bool, string) {
+	if !config.TTSEnabled {
+		return false, ""
+	}
+	setting := s.Get(userId)
+	enabled := config.TTSDefaultOn
+	if setting.Enabled != nil {
+		enabled = *setting.Enabled
+	}
+	voice := config.TTSVoice
+	if setting.Voice != "" {
+		voice = setting.Voice
+	}
+	return enabled, voice
+}
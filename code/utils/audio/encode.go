@@ -0,0 +1,190 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+const (
+	// opusTOCSilkWideband20ms TOC 字节：config 9（SILK 宽带 20ms）、单声道、每包一帧
+	opusTOCSilkWideband20ms = 0x48
+	opusMaxPacketBytes      = 1275
+	// 编码器在开头补一帧静音，解码器通过 pre-skip 丢弃，见 EncodeOggOpus
+	opusPreSkip = opusGranuleRate / 50
+	// 每个 ogg 页最多放的包数
+	oggPagePackets = 50
+	opusVendor     = "start-feishubot"
+)
+
+// EncodeOggOpus 把 PCM 编码为 ogg 封装的 opus（SILK 宽带、20ms 帧），
+// 可以直接作为飞书语音消息上传。输入会先重采样到 16kHz
+func EncodeOggOpus(w io.Writer, pcm PCM) error {
+	if pcm.SampleRate <= 0 {
+		return errors.New("invalid sample rate")
+	}
+	samples := resample(pcm, silkSampleRate)
+
+	// 开头的静音帧让各解码器的滤波器状态从零开始，末尾补零凑满一帧
+	frames := (len(samples)+silkFrameSamples-1)/silkFrameSamples + 1
+	input := make([]float64, frames*silkFrameSamples)
+	copy(input[silkFrameSamples:], samples)
+
+	ogg := &oggWriter{w: w, serial: 0x46534254}
+	if err := ogg.writePage(oggHeaderBOS, 0, [][]byte{opusHead()}); err != nil {
+		return err
+	}
+	if err := ogg.writePage(0, 0, [][]byte{opusTags()}); err != nil {
+		return err
+	}
+
+	encoder := &silkEncoder{}
+	var packets [][]byte
+	for i := 0; i < frames; i++ {
+		data, err := encoder.encode(input[i*silkFrameSamples : (i+1)*silkFrameSamples])
+		if err != nil {
+			return err
+		}
+		packets = append(packets, append([]byte{opusTOCSilkWideband20ms}, data...))
+
+		last := i == frames-1
+		if len(packets) < oggPagePackets && !last {
+			continue
+		}
+		// granule 以 48kHz 计数，最后一页按真实长度截断末尾补的零
+		granule := uint64(i+1) * opusGranuleRate / 50
+		headerType := byte(0)
+		if last {
+			granule = opusPreSkip + uint64(len(samples))*opusGranuleRate/silkSampleRate
+			headerType = oggHeaderEOS
+		}
+		if err := ogg.writePage(headerType, granule, packets); err != nil {
+			return err
+		}
+		packets = packets[:0]
+	}
+	return nil
+}
+
+// opusHead RFC 7845 第 5.1 节的 ID 头
+func opusHead() []byte {
+	head := make([]byte, 19)
+	copy(head, "OpusHead")
+	head[8] = 1
+	head[9] = 1
+	binary.LittleEndian.PutUint16(head[10:], opusPreSkip)
+	binary.LittleEndian.PutUint32(head[12:], silkSampleRate)
+	return head
+}
+
+// opusTags RFC 7845 第 5.2 节的注释头，不带任何注释
+func opusTags() []byte {
+	tags := make([]byte, 8+4+len(opusVendor)+4)
+	copy(tags, "OpusTags")
+	binary.LittleEndian.PutUint32(tags[8:], uint32(len(opusVendor)))
+	copy(tags[12:], opusVendor)
+	return tags
+}
+
+const (
+	oggHeaderBOS = 2
+	oggHeaderEOS = 4
+)
+
+var oggCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return
+}()
+
+type oggWriter struct {
+	w      io.Writer
+	serial uint32
+	seq    uint32
+}
+
+// writePage 把若干个完整的包写成一页，调用方保证分段数不超过 255
+func (o *oggWriter) writePage(headerType byte, granule uint64, packets [][]byte) error {
+	var lacing, body []byte
+	for _, packet := range packets {
+		n := len(packet)
+		for ; n >= 255; n -= 255 {
+			lacing = append(lacing, 255)
+		}
+		lacing = append(lacing, byte(n))
+		body = append(body, packet...)
+	}
+	if len(lacing) > 255 {
+		return errors.New("too many ogg segments")
+	}
+
+	page := make([]byte, 27, 27+len(lacing)+len(body))
+	copy(page, "OggS")
+	page[5] = headerType
+	binary.LittleEndian.PutUint64(page[6:], granule)
+	binary.LittleEndian.PutUint32(page[14:], o.serial)
+	binary.LittleEndian.PutUint32(page[18:], o.seq)
+	page[26] = byte(len(lacing))
+	page = append(append(page, lacing...), body...)
+
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	binary.LittleEndian.PutUint32(page[22:], crc)
+	o.seq++
+	_, err := o.w.Write(page)
+	return err
+}
+
+// resample 用加窗 sinc 插值转换采样率，返回 int16 量纲的浮点采样
+func resample(pcm PCM, rate int) []float64 {
+	if pcm.SampleRate == rate {
+		out := make([]float64, len(pcm.Samples))
+		for i, s := range pcm.Samples {
+			out[i] = float64(s)
+		}
+		return out
+	}
+
+	ratio := float64(rate) / float64(pcm.SampleRate)
+	// 降采样时截止频率取目标采样率的奈奎斯特频率，略微收窄以抑制混叠
+	cutoff := math.Min(1, ratio) * 0.95
+	half := int(math.Ceil(16 / math.Min(1, ratio)))
+	out := make([]float64, int(float64(len(pcm.Samples))*ratio))
+	for n := range out {
+		t := float64(n) / ratio
+		center := int(t)
+		var sum float64
+		for j := center - half + 1; j <= center+half; j++ {
+			if j < 0 || j >= len(pcm.Samples) {
+				continue
+			}
+			d := t - float64(j)
+			if math.Abs(d) >= float64(half) {
+				continue
+			}
+			window := 0.5 + 0.5*math.Cos(math.Pi*d/float64(half))
+			sum += float64(pcm.Samples[j]) * cutoff * sinc(cutoff*d) * window
+		}
+		out[n] = sum
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
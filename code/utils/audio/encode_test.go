@@ -0,0 +1,87 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// newTestSpeech 生成类似语音的信号：带包络的谐波加少量噪声
+func newTestSpeech(rate int, seconds float64) PCM {
+	random := rand.New(rand.NewSource(1))
+	samples := make([]int16, int(seconds*float64(rate)))
+	for i := range samples {
+		t := float64(i) / float64(rate)
+		pitch := 140 + 40*math.Sin(2*math.Pi*1.5*t)
+		var v float64
+		for h := 1; h <= 12; h++ {
+			v += math.Sin(2*math.Pi*pitch*float64(h)*t) / float64(h)
+		}
+		envelope := 0.5 + 0.5*math.Sin(2*math.Pi*3*t)
+		samples[i] = int16(6000*envelope*v + 300*random.NormFloat64())
+	}
+	return PCM{Samples: samples, SampleRate: rate}
+}
+
+func TestEncodeOggOpus(t *testing.T) {
+	tests := []struct {
+		name   string
+		pcm    PCM
+		minSNR float64
+	}{
+		{name: "speech 16k", pcm: newTestSpeech(16000, 1.5), minSNR: 15},
+		{name: "speech 24k", pcm: newTestSpeech(24000, 1), minSNR: 15},
+		{name: "square wave 48k", pcm: newTestPCM(48000, 0.5, 0.25), minSNR: 10},
+		{name: "silence", pcm: PCM{Samples: make([]int16, 8000), SampleRate: 16000}},
+		{name: "empty", pcm: PCM{SampleRate: 24000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeOggOpus(&buf, tt.pcm); err != nil {
+				t.Fatalf("EncodeOggOpus() error = %v", err)
+			}
+
+			info, err := ReadOggOpusInfo(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("ReadOggOpusInfo() error = %v", err)
+			}
+			if d := info.Duration - tt.pcm.Duration(); d < -time.Millisecond || d > time.Millisecond {
+				t.Errorf("Duration = %v, want %v", info.Duration, tt.pcm.Duration())
+			}
+			if info.Channels != 1 || info.SampleRate != silkSampleRate {
+				t.Errorf("unexpected header %+v", info)
+			}
+
+			decoded, err := DecodeOggOpus(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("DecodeOggOpus() error = %v", err)
+			}
+			// 解码输出为 48kHz，每个 16kHz 采样重复三次，开头是 pre-skip 的静音帧
+			want := resample(tt.pcm, silkSampleRate)
+			var signal, noise float64
+			for i, v := range want {
+				got := float64(decoded.Samples[opusPreSkip+3*i])
+				signal += v * v
+				noise += (got - v) * (got - v)
+			}
+			if signal == 0 {
+				if noise/math.Max(float64(len(want)), 1) > 4 {
+					t.Errorf("silence decoded with noise power %v", noise/float64(len(want)))
+				}
+				return
+			}
+			if snr := 10 * math.Log10(signal/noise); snr < tt.minSNR {
+				t.Errorf("SNR = %.1f dB, want >= %.1f dB", snr, tt.minSNR)
+			}
+		})
+	}
+}
+
+func TestEncodeOggOpusInvalidRate(t *testing.T) {
+	if err := EncodeOggOpus(&bytes.Buffer{}, PCM{Samples: make([]int16, 10)}); err == nil {
+		t.Error("EncodeOggOpus() should reject a zero sample rate")
+	}
+}
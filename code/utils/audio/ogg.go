@@ -1,13 +1,8 @@
 package audio
 
 import (
-	"bytes"
-	"errors"
 	"io"
 	"os"
-
-	"github.com/pion/opus"
-	"github.com/pion/opus/pkg/oggreader"
 )
 
 func OggToWavByPath(ogg string, wav string) error {
@@ -26,36 +21,11 @@ func OggToWavByPath(ogg string, wav string) error {
 	return OggToWav(input, output)
 }
 
-func OggToWav(input io.Reader, output io.WriteSeeker) error {
-	ogg, _, err := oggreader.NewWith(input)
+// OggToWav 解码 ogg/opus 并输出 48kHz 单声道 16bit wav
+func OggToWav(input io.Reader, output io.Writer) error {
+	pcm, err := DecodeOggOpus(input)
 	if err != nil {
 		return err
 	}
-
-	out := make([]byte, 1920)
-
-	decoder := opus.NewDecoder()
-	encoder := NewEncoder(output, 44100, 16)
-
-	for {
-		segments, _, err := ogg.ParseNextPage()
-		if errors.Is(err, io.EOF) {
-			break
-		} else if bytes.HasPrefix(segments[0], []byte("OpusTags")) {
-			continue
-		}
-
-		if err != nil {
-			panic(err)
-		}
-
-		for i := range segments {
-			if _, _, err = decoder.Decode(segments[i], out); err != nil {
-				panic(err)
-			}
-			encoder.Write(out)
-		}
-	}
-	encoder.Close()
-	return nil
+	return WriteWav(output, *pcm)
 }
@@ -0,0 +1,51 @@
+package audio
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"github.com/pion/opus/pkg/oggreader"
+)
+
+// opus 的 granule position 固定以 48kHz 计数
+const opusGranuleRate = 48000
+
+type OggOpusInfo struct {
+	Channels   int
+	SampleRate int
+	Duration   time.Duration
+}
+
+// ReadOggOpusInfo 解析 ogg/opus 音频的头信息和时长
+// 飞书上传语音文件时需要提供毫秒时长
+func ReadOggOpusInfo(input io.Reader) (*OggOpusInfo, error) {
+	ogg, header, err := oggreader.NewWith(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var granule uint64
+	for {
+		_, page, err := ogg.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if page.GranulePosition > granule {
+			granule = page.GranulePosition
+		}
+	}
+
+	samples := int64(granule) - int64(header.PreSkip)
+	if samples < 0 {
+		samples = 0
+	}
+	return &OggOpusInfo{
+		Channels:   int(header.Channels),
+		SampleRate: int(header.SampleRate),
+		Duration:   time.Duration(samples) * time.Second / opusGranuleRate,
+	}, nil
+}
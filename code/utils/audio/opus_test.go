@@ -0,0 +1,99 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func appendLe(b []byte, data interface{}) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, data)
+	return append(b, buf.Bytes()...)
+}
+
+func writeOggPage(buf *bytes.Buffer, headerType byte, granule uint64, seq uint32, payload []byte) {
+	page := []byte("OggS")
+	page = append(page, 0, headerType)
+	page = appendLe(page, granule)
+	page = appendLe(page, uint32(1))
+	page = appendLe(page, seq)
+	page = appendLe(page, uint32(0))
+	page = append(page, 1, byte(len(payload)))
+	page = append(page, payload...)
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	buf.Write(page)
+}
+
+func newTestOggOpus(preSkip uint16, granules ...uint64) []byte {
+	var buf bytes.Buffer
+	head := []byte("OpusHead")
+	head = append(head, 1, 1)
+	head = appendLe(head, preSkip)
+	head = appendLe(head, uint32(24000))
+	head = append(head, 0, 0, 0)
+	writeOggPage(&buf, 2, 0, 0, head)
+	writeOggPage(&buf, 0, 0, 1, []byte("OpusTags"))
+	for i, g := range granules {
+		writeOggPage(&buf, 0, g, uint32(i+2), []byte{0xf8, 0xff, 0xfe})
+	}
+	return buf.Bytes()
+}
+
+func TestReadOggOpusInfo(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		duration time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "two seconds",
+			data:     newTestOggOpus(312, 48312, 96312),
+			duration: 2 * time.Second,
+		},
+		{
+			name:     "pre skip only",
+			data:     newTestOggOpus(312),
+			duration: 0,
+		},
+		{
+			name:    "not ogg",
+			data:    []byte("RIFF....WAVEfmt "),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ReadOggOpusInfo(bytes.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadOggOpusInfo() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if info.Duration != tt.duration {
+				t.Errorf("Duration = %v, want %v", info.Duration, tt.duration)
+			}
+			if info.Channels != 1 || info.SampleRate != 24000 {
+				t.Errorf("unexpected header %+v", info)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pion/opus"
+	"github.com/pion/opus/pkg/oggreader"
+)
+
+// pion/opus 解码输出固定为 48kHz 单声道 S16LE
+const (
+	DecodeSampleRate = 48000
+	DecodeBitDepth   = 16
+	// 一个 opus 帧（20ms）解码后的字节数
+	decodeFrameBytes = 1920
+)
+
+// PCM 单声道 16bit 采样数据
+type PCM struct {
+	Samples    []int16
+	SampleRate int
+}
+
+func (p PCM) Duration() time.Duration {
+	if p.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(len(p.Samples)) * time.Second / time.Duration(p.SampleRate)
+}
+
+// DecodeOggOpus 将飞书语音（ogg 封装的 opus）解码为 PCM
+func DecodeOggOpus(input io.Reader) (*PCM, error) {
+	ogg, _, err := oggreader.NewWith(input)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, decodeFrameBytes)
+	decoder := opus.NewDecoder()
+	pcm := &PCM{SampleRate: DecodeSampleRate}
+
+	for {
+		segments, _, err := ogg.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse ogg page: %w", err)
+		}
+		if len(segments) > 0 && bytes.HasPrefix(segments[0], []byte("OpusTags")) {
+			continue
+		}
+
+		for i := range segments {
+			if _, _, err = decoder.Decode(segments[i], out); err != nil {
+				return nil, fmt.Errorf("decode opus frame: %w", err)
+			}
+			for j := 0; j+1 < len(out); j += 2 {
+				pcm.Samples = append(pcm.Samples, int16(binary.LittleEndian.Uint16(out[j:])))
+			}
+		}
+	}
+	return pcm, nil
+}
+
+// WriteWav 写出完整的 wav 文件，长度已知因此不需要回写头部
+func WriteWav(w io.Writer, pcm PCM) error {
+	dataSize := uint32(len(pcm.Samples) * 2)
+	header := []interface{}{
+		[]byte("RIFF"), 36 + dataSize, []byte("WAVE"),
+		[]byte("fmt "), uint32(16), uint16(1), uint16(1),
+		uint32(pcm.SampleRate), uint32(pcm.SampleRate * 2), uint16(2), uint16(DecodeBitDepth),
+		[]byte("data"), dataSize,
+	}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return binary.Write(w, binary.LittleEndian, pcm.Samples)
+}
+
+// SplitOnSilence 把长音频切成不超过 maxChunk 的片段
+// 切分点选在每段末尾 searchWindow 范围内最安静的 20ms，尽量不切断句子
+func SplitOnSilence(pcm PCM, maxChunk time.Duration, searchWindow time.Duration) []PCM {
+	maxSamples := int(maxChunk.Seconds() * float64(pcm.SampleRate))
+	if maxSamples <= 0 || len(pcm.Samples) <= maxSamples {
+		return []PCM{pcm}
+	}
+	window := int(searchWindow.Seconds() * float64(pcm.SampleRate))
+	if window <= 0 || window > maxSamples/2 {
+		window = maxSamples / 2
+	}
+	frame := pcm.SampleRate / 50
+	if frame <= 0 {
+		frame = 1
+	}
+
+	var chunks []PCM
+	start := 0
+	for len(pcm.Samples)-start > maxSamples {
+		end := start + maxSamples
+		cut := end
+		quietest := int64(-1)
+		for pos := end - frame; pos >= end-window; pos -= frame {
+			energy := frameEnergy(pcm.Samples[pos : pos+frame])
+			if quietest < 0 || energy < quietest {
+				quietest = energy
+				cut = pos + frame/2
+			}
+		}
+		chunks = append(chunks, PCM{Samples: pcm.Samples[start:cut], SampleRate: pcm.SampleRate})
+		start = cut
+	}
+	return append(chunks, PCM{Samples: pcm.Samples[start:], SampleRate: pcm.SampleRate})
+}
+
+func frameEnergy(samples []int16) int64 {
+	var sum int64
+	for _, s := range samples {
+		sum += int64(s) * int64(s)
+	}
+	return sum
+}
+
+// DecodeWav 解析 16bit PCM wav，多声道会混成单声道
+func DecodeWav(input io.Reader) (*PCM, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, errors.New("not a wav file")
+	}
+
+	var channels, sampleRate, bitDepth int
+	pos := 12
+	for pos+8 <= len(data) {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := data[pos+8:]
+		if size < len(body) {
+			body = body[:size]
+		}
+		switch id {
+		case "fmt ":
+			if len(body) < 16 {
+				return nil, errors.New("invalid wav fmt chunk")
+			}
+			if format := binary.LittleEndian.Uint16(body[0:2]); format != 1 {
+				return nil, fmt.Errorf("unsupported wav format: %d", format)
+			}
+			channels = int(binary.LittleEndian.Uint16(body[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(body[4:8]))
+			bitDepth = int(binary.LittleEndian.Uint16(body[14:16]))
+		case "data":
+			if channels <= 0 || bitDepth != 16 {
+				return nil, fmt.Errorf("unsupported wav: %d channels, %d bit", channels, bitDepth)
+			}
+			frames := len(body) / (2 * channels)
+			pcm := &PCM{Samples: make([]int16, frames), SampleRate: sampleRate}
+			for i := 0; i < frames; i++ {
+				var sum int
+				for c := 0; c < channels; c++ {
+					off := (i*channels + c) * 2
+					sum += int(int16(binary.LittleEndian.Uint16(body[off:])))
+				}
+				pcm.Samples[i] = int16(sum / channels)
+			}
+			return pcm, nil
+		}
+		pos += 8 + size + size%2
+	}
+	return nil, errors.New("wav data chunk not found")
+}
@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// newTestPCM 生成 rate 采样率的音频，silentAt 中的秒数附近 100ms 为静音
+func newTestPCM(rate int, seconds float64, silentAt ...float64) PCM {
+	samples := make([]int16, int(seconds*float64(rate)))
+	for i := range samples {
+		samples[i] = 8000
+		if i%2 == 0 {
+			samples[i] = -8000
+		}
+	}
+	for _, at := range silentAt {
+		from := int((at - 0.05) * float64(rate))
+		to := int((at + 0.05) * float64(rate))
+		for i := from; i < to && i < len(samples); i++ {
+			samples[i] = 0
+		}
+	}
+	return PCM{Samples: samples, SampleRate: rate}
+}
+
+func TestSplitOnSilence(t *testing.T) {
+	tests := []struct {
+		name     string
+		pcm      PCM
+		maxChunk time.Duration
+		window   time.Duration
+		want     []time.Duration
+	}{
+		{
+			name:     "short audio is not split",
+			pcm:      newTestPCM(1000, 3),
+			maxChunk: 5 * time.Second,
+			window:   time.Second,
+			want:     []time.Duration{3 * time.Second},
+		},
+		{
+			name:     "cut at silence",
+			pcm:      newTestPCM(1000, 9, 4.5),
+			maxChunk: 5 * time.Second,
+			window:   time.Second,
+			want:     []time.Duration{4530 * time.Millisecond, 4470 * time.Millisecond},
+		},
+		{
+			name:     "no silence cuts within limit",
+			pcm:      newTestPCM(1000, 12),
+			maxChunk: 5 * time.Second,
+			window:   time.Second,
+			want:     []time.Duration{4990 * time.Millisecond, 4990 * time.Millisecond, 2020 * time.Millisecond},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := SplitOnSilence(tt.pcm, tt.maxChunk, tt.window)
+			if len(chunks) != len(tt.want) {
+				t.Fatalf("got %d chunks, want %d", len(chunks), len(tt.want))
+			}
+			total := 0
+			for i, c := range chunks {
+				if c.Duration() > tt.maxChunk {
+					t.Errorf("chunk %d too long: %v", i, c.Duration())
+				}
+				if c.Duration() != tt.want[i] {
+					t.Errorf("chunk %d duration = %v, want %v", i, c.Duration(), tt.want[i])
+				}
+				total += len(c.Samples)
+			}
+			if total != len(tt.pcm.Samples) {
+				t.Errorf("chunks have %d samples, want %d", total, len(tt.pcm.Samples))
+			}
+		})
+	}
+}
+
+func TestWriteWav(t *testing.T) {
+	var buf bytes.Buffer
+	pcm := PCM{Samples: []int16{1, -1, 2}, SampleRate: DecodeSampleRate}
+	if err := WriteWav(&buf, pcm); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if len(b) != 44+6 {
+		t.Fatalf("wav size = %d, want %d", len(b), 50)
+	}
+	if string(b[0:4]) != "RIFF" || string(b[8:12]) != "WAVE" || string(b[36:40]) != "data" {
+		t.Errorf("bad wav header: %q", b[:44])
+	}
+	if got := binary.LittleEndian.Uint32(b[24:28]); got != DecodeSampleRate {
+		t.Errorf("sample rate = %d", got)
+	}
+	if got := binary.LittleEndian.Uint32(b[40:44]); got != 6 {
+		t.Errorf("data size = %d", got)
+	}
+}
+
+func TestDecodeWav(t *testing.T) {
+	var buf bytes.Buffer
+	pcm := PCM{Samples: []int16{100, -200, 300}, SampleRate: 16000}
+	if err := WriteWav(&buf, pcm); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeWav(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SampleRate != 16000 || len(got.Samples) != 3 || got.Samples[1] != -200 {
+		t.Errorf("DecodeWav() = %+v", got)
+	}
+
+	if _, err := DecodeWav(bytes.NewReader([]byte("OggS"))); err == nil {
+		t.Error("expected error for non wav input")
+	}
+}
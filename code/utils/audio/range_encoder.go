@@ -0,0 +1,120 @@
+package audio
+
+// rangeEncoder opus 的区间编码器（RFC 6716 第 5.1 节），与解码器逐位对应
+type rangeEncoder struct {
+	buf []byte
+	val uint32
+	rng uint32
+	// rem 尚未输出的字节，-1 表示没有；ext 等待进位确定的 0xFF 字节数
+	rem int
+	ext int
+	// nbits 已编码的比特数（含初始偏移），与 libopus 的 ec_tell 相同
+	nbits int
+}
+
+const (
+	rangeCodeTop   = uint32(1) << 31
+	rangeCodeBot   = rangeCodeTop >> 8
+	rangeCodeShift = 23
+	rangeSymMax    = 255
+)
+
+func newRangeEncoder() *rangeEncoder {
+	return &rangeEncoder{rng: rangeCodeTop, rem: -1, nbits: 33}
+}
+
+// encodeICDF 按码表编码 symbol，码表第一个元素为总频数，其后为累积频数
+func (e *rangeEncoder) encodeICDF(symbol int, table []uint) {
+	total := uint32(table[0])
+	cdf := table[1:]
+	high := uint32(cdf[symbol])
+	low := uint32(0)
+	if symbol > 0 {
+		low = uint32(cdf[symbol-1])
+	}
+	r := e.rng / total
+	if low > 0 {
+		e.val += e.rng - r*(total-low)
+		e.rng = r * (high - low)
+	} else {
+		e.rng -= r * (total - high)
+	}
+	e.normalize()
+}
+
+// encodeBitLogP 编码取 1 的概率为 1/2^logp 的比特
+func (e *rangeEncoder) encodeBitLogP(bit bool, logp uint) {
+	s := e.rng >> logp
+	r := e.rng - s
+	if bit {
+		e.val += r
+		e.rng = s
+	} else {
+		e.rng = r
+	}
+	e.normalize()
+}
+
+func (e *rangeEncoder) normalize() {
+	for e.rng <= rangeCodeBot {
+		e.carryOut(int(e.val >> rangeCodeShift))
+		e.val = (e.val << 8) & (rangeCodeTop - 1)
+		e.rng <<= 8
+		e.nbits += 8
+	}
+}
+
+// tell 已使用的比特数
+func (e *rangeEncoder) tell() int {
+	return e.nbits - ilog32(e.rng)
+}
+
+func (e *rangeEncoder) carryOut(c int) {
+	if c == rangeSymMax {
+		e.ext++
+		return
+	}
+	carry := c >> 8
+	if e.rem >= 0 {
+		e.buf = append(e.buf, byte(e.rem+carry))
+	}
+	for ; e.ext > 0; e.ext-- {
+		e.buf = append(e.buf, byte(rangeSymMax+carry))
+	}
+	e.rem = c & rangeSymMax
+}
+
+// finish 输出剩余状态并返回编码结果，解码器把缺少的字节当作 0，因此结尾的 0 字节可以省略。
+// 结果不会超过 (tell+7)/8 字节：SILK 包末尾多出 17 比特以上时，解码器会把剩余部分当作冗余的 CELT 帧
+func (e *rangeEncoder) finish() []byte {
+	l := 32 - ilog32(e.rng)
+	mask := (rangeCodeTop - 1) >> uint(l)
+	end := (e.val + mask) &^ mask
+	if (end | mask) >= e.val+e.rng {
+		l++
+		mask >>= 1
+		end = (e.val + mask) &^ mask
+	}
+	for l > 0 {
+		e.carryOut(int(end >> rangeCodeShift))
+		end = (end << 8) & (rangeCodeTop - 1)
+		l -= 8
+	}
+	if e.rem >= 0 || e.ext > 0 {
+		e.carryOut(0)
+	}
+	out := e.buf
+	for len(out) > 0 && out[len(out)-1] == 0 {
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+func ilog32(v uint32) int {
+	n := 0
+	for v > 0 {
+		n++
+		v >>= 1
+	}
+	return n
+}
@@ -0,0 +1,716 @@
+package audio
+
+import (
+	"errors"
+	"math"
+)
+
+// SILK 宽带（16kHz）20ms 帧的参数，见 RFC 6716 第 4.2 节
+const (
+	silkSampleRate      = 16000
+	silkFrameSamples    = 320
+	silkSubframes       = 4
+	silkSubframeSamples = silkFrameSamples / silkSubframes
+	silkOrder           = 16
+	silkShellBlockSize  = 16
+	silkShellBlocks     = silkFrameSamples / silkShellBlockSize
+	// 清音帧、低量化偏移的激励偏移
+	silkOffsetQ23 = 25
+	// 单个采样的激励幅度上限，保证每个块的 LSB 数不超过 9（部分解码器不支持 10）
+	silkMaxPulse = 511
+	// silkStepRatio 残差均方根与量化步长之比，越大音质越好、码率越高
+	silkStepRatio = 2.0
+)
+
+var errSilkFrameTooLarge = errors.New("silk frame exceeds opus packet size")
+
+// silkGains 64 级增益索引对应的量化步长（Q16），与解码器的计算方式一致
+var silkGains = func() (gains [64]float64) {
+	for logGain := int32(0); logGain < 64; logGain++ {
+		inLogQ7 := (0x1D1C71 * logGain >> 16) + 2090
+		i, f := inLogQ7>>7, inLogQ7&127
+		gains[logGain] = float64((1 << i) + ((-174*f*(128-f)>>16)+f)*((1<<i)>>7))
+	}
+	return
+}()
+
+// silkEncoder 极简的 SILK 编码器：所有帧都按清音帧编码，不做基音预测和噪声整形，
+// 每帧做一次 LPC 分析，激励在解码器的合成滤波器上闭环量化，保证各解码器还原一致
+type silkEncoder struct {
+	frames int
+	// prevLogGain 上一子帧的增益索引，coded 表示是否已经输出过帧
+	prevLogGain int32
+	coded       bool
+	// input 上一帧末尾的输入，用于分析窗和开环残差
+	input [silkSubframeSamples]float64
+	// output 解码器重建信号的最后 silkOrder 个采样
+	output [silkOrder]float64
+}
+
+// silkFrame 一帧的全部量化参数
+type silkFrame struct {
+	gainIndex [silkSubframes]int32
+	logGain   [silkSubframes]int32
+	nlsfI1    int
+	nlsfI2    [silkOrder]int
+	aQ12      [silkOrder]float64
+	seed      uint32
+	pulses    [silkFrameSamples]int32
+}
+
+// encode 编码一帧 320 个采样（int16 量纲），返回不含 TOC 的 SILK 数据
+func (e *silkEncoder) encode(x []float64) ([]byte, error) {
+	var frame silkFrame
+	frame.nlsfI1, frame.nlsfI2, frame.aQ12 = e.analyze(x)
+	targets := e.gainTargets(x, &frame.aQ12)
+	// 码流过长时整体放大量化步长重新编码
+	for boost := int32(0); boost < 64; boost += 8 {
+		next, boosted := *e, targets
+		for s := range boosted {
+			boosted[s] += boost
+		}
+		next.quantizeGains(&frame, boosted)
+		next.quantizeExcitation(&frame, x)
+		data := frame.write()
+		if len(data) < opusMaxPacketBytes {
+			next.frames++
+			next.coded = true
+			copy(next.input[:], x[len(x)-silkSubframeSamples:])
+			*e = next
+			return data, nil
+		}
+	}
+	return nil, errSilkFrameTooLarge
+}
+
+// analyze LPC 分析并量化为 NLSF，返回量化索引和解码器会使用的滤波系数
+func (e *silkEncoder) analyze(x []float64) (int, [silkOrder]int, [silkOrder]float64) {
+	buf := make([]float64, 0, silkSubframeSamples+silkFrameSamples)
+	buf = append(append(buf, e.input[:]...), x...)
+	var r [silkOrder + 1]float64
+	for i := range buf {
+		buf[i] *= 0.5 - 0.5*math.Cos(2*math.Pi*(float64(i)+0.5)/float64(len(buf)))
+	}
+	for k := range r {
+		for i := k; i < len(buf); i++ {
+			r[k] += buf[i] * buf[i-k]
+		}
+	}
+
+	// 近乎静音时直接使用均匀分布的 NLSF，对应的滤波器接近直通
+	if r[0] > float64(len(buf)) {
+		r[0] *= 1.0001
+		for k := 1; k <= silkOrder; k++ {
+			r[k] *= math.Exp(-0.5 * math.Pow(2*math.Pi*60*float64(k)/silkSampleRate, 2))
+		}
+		a := silkLevinson(&r)
+		for chirp := 0.99; chirp > 0.8; chirp -= 0.03 {
+			var bw [silkOrder]float64
+			g := 1.0
+			for k := range a {
+				g *= chirp
+				bw[k] = a[k] * g
+			}
+			target, ok := silkLPCToNLSF(&bw)
+			if !ok {
+				continue
+			}
+			i1, i2, nlsf := silkQuantizeNLSF(&target)
+			if aQ12, ok := silkNLSFToLPC(&nlsf); ok {
+				return i1, i2, aQ12
+			}
+		}
+	}
+
+	var target [silkOrder]float64
+	for k := range target {
+		target[k] = float64(k+1) * 32768 / (silkOrder + 1)
+	}
+	i1, i2, nlsf := silkQuantizeNLSF(&target)
+	aQ12, _ := silkNLSFToLPC(&nlsf)
+	return i1, i2, aQ12
+}
+
+// gainTargets 按开环残差的能量估算每个子帧的增益索引
+func (e *silkEncoder) gainTargets(x []float64, aQ12 *[silkOrder]float64) (targets [silkSubframes]int32) {
+	hist := append(append([]float64{}, e.input[:]...), x...)
+	for s := range targets {
+		var energy float64
+		for i := 0; i < silkSubframeSamples; i++ {
+			n := silkSubframeSamples + s*silkSubframeSamples + i
+			res := hist[n]
+			for k := range aQ12 {
+				res -= aQ12[k] / 4096 * hist[n-k-1]
+			}
+			energy += res * res
+		}
+		step := math.Sqrt(energy/silkSubframeSamples) / silkStepRatio * 65536
+		best := math.Inf(1)
+		for i, gain := range silkGains {
+			if d := math.Abs(math.Log(gain / math.Max(step, 1))); d < best {
+				best, targets[s] = d, int32(i)
+			}
+		}
+	}
+	return targets
+}
+
+// quantizeGains 把目标增益索引转换为可编码的索引，第一个子帧独立编码，其余编码差值
+func (e *silkEncoder) quantizeGains(frame *silkFrame, targets [silkSubframes]int32) {
+	for s, target := range targets {
+		if target > 63 {
+			target = 63
+		}
+		if s == 0 {
+			frame.gainIndex[0] = target
+			if e.coded && target < e.prevLogGain-16 {
+				target = e.prevLogGain - 16
+			}
+			frame.logGain[0] = target
+		} else {
+			best := int32(math.MaxInt32)
+			for delta := int32(0); delta <= 40; delta++ {
+				logGain := delta*2 - 16
+				if v := e.prevLogGain + delta - 4; v > logGain {
+					logGain = v
+				}
+				logGain = clampInt32(0, logGain, 63)
+				d := logGain - target
+				if d < 0 {
+					d = -d
+				}
+				if d < best {
+					best = d
+					frame.gainIndex[s], frame.logGain[s] = delta, logGain
+				}
+			}
+		}
+		e.prevLogGain = frame.logGain[s]
+	}
+}
+
+// quantizeExcitation 在解码器的合成滤波器上逐个采样选择激励，并复现解码器的随机符号翻转
+func (e *silkEncoder) quantizeExcitation(frame *silkFrame, x []float64) {
+	frame.seed = uint32(e.frames & 3)
+	seed := frame.seed
+	hist := append(append(make([]float64, 0, silkOrder+len(x)), e.output[:]...), x...)
+	for s := 0; s < silkSubframes; s++ {
+		gain := silkGains[frame.logGain[s]] / 65536
+		for i := 0; i < silkSubframeSamples; i++ {
+			n := s*silkSubframeSamples + i
+			pred := 0.0
+			for k := range frame.aQ12 {
+				pred += frame.aQ12[k] / 4096 * hist[silkOrder+n-k-1]
+			}
+			want := (x[n] - pred) / gain * 256
+			seed = 196314165*seed + 907633515
+			flip := seed&0x80000000 != 0
+			if flip {
+				want = -want
+			}
+
+			pulse := int32(math.Round((want - silkOffsetQ23) / 256))
+			best := math.Inf(1)
+			for _, c := range []int32{pulse - 1, pulse, pulse + 1} {
+				if d := math.Abs(float64(silkExcitationQ23(c)) - want); d < best {
+					best, pulse = d, c
+				}
+			}
+			if pulse > silkMaxPulse {
+				pulse = silkMaxPulse
+			} else if pulse < -silkMaxPulse {
+				pulse = -silkMaxPulse
+			}
+			frame.pulses[n] = pulse
+
+			q := float64(silkExcitationQ23(pulse))
+			if flip {
+				q = -q
+			}
+			hist[silkOrder+n] = gain*q/256 + pred
+			seed += uint32(pulse)
+		}
+	}
+	copy(e.output[:], hist[len(hist)-silkOrder:])
+}
+
+// silkExcitationQ23 激励索引对应的幅度（未翻转符号）
+func silkExcitationQ23(pulse int32) int32 {
+	switch {
+	case pulse > 0:
+		return pulse<<8 - 20 + silkOffsetQ23
+	case pulse < 0:
+		return pulse<<8 + 20 + silkOffsetQ23
+	}
+	return silkOffsetQ23
+}
+
+// write 按 RFC 6716 第 4.2.7 节的顺序输出一帧
+func (f *silkFrame) write() []byte {
+	enc := newRangeEncoder()
+	// VAD 置位、无 LBRR，帧类型为清音、低量化偏移
+	enc.encodeBitLogP(true, 1)
+	enc.encodeBitLogP(false, 1)
+	enc.encodeICDF(0, icdfFrameTypeVADActive)
+
+	enc.encodeICDF(int(f.gainIndex[0]>>3), icdfIndependentQuantizationGainMSBUnvoiced)
+	enc.encodeICDF(int(f.gainIndex[0]&7), icdfIndependentQuantizationGainLSB)
+	for s := 1; s < silkSubframes; s++ {
+		enc.encodeICDF(int(f.gainIndex[s]), icdfDeltaQuantizationGain)
+	}
+
+	enc.encodeICDF(f.nlsfI1, icdfNormalizedLSFStageOneIndexWidebandUnvoiced)
+	for k, i2 := range f.nlsfI2 {
+		table := icdfNormalizedLSFStageTwoIndex[codebookNormalizedLSFStageTwoIndexWideband[f.nlsfI1][k]]
+		enc.encodeICDF(clampInt(-4, i2, 4)+4, table)
+		if i2 >= 4 {
+			enc.encodeICDF(i2-4, icdfNormalizedLSFStageTwoIndexExtension)
+		} else if i2 <= -4 {
+			enc.encodeICDF(-i2-4, icdfNormalizedLSFStageTwoIndexExtension)
+		}
+	}
+	// 不与上一帧插值
+	enc.encodeICDF(4, icdfNormalizedLSFInterpolationIndex)
+	enc.encodeICDF(int(f.seed), icdfLinearCongruentialGeneratorSeed)
+
+	var counts, lsbs [silkShellBlocks]int
+	var mags [silkFrameSamples]int
+	for i, p := range f.pulses {
+		if p < 0 {
+			p = -p
+		}
+		mags[i] = int(p)
+	}
+	for b := range counts {
+		block := mags[b*silkShellBlockSize : (b+1)*silkShellBlockSize]
+		for counts[b] = silkShiftedSum(block, 0); counts[b] > 16; counts[b] = silkShiftedSum(block, lsbs[b]) {
+			lsbs[b]++
+		}
+	}
+
+	rateLevel, best := 0, math.Inf(1)
+	for level := 0; level < 9; level++ {
+		bits := silkSymbolBits(level, icdfRateLevelUnvoiced)
+		for b, count := range counts {
+			if lsbs[b] > 0 {
+				count = 17
+			}
+			bits += silkSymbolBits(count, icdfPulseCount[level])
+		}
+		if bits < best {
+			rateLevel, best = level, bits
+		}
+	}
+	enc.encodeICDF(rateLevel, icdfRateLevelUnvoiced)
+	for b, count := range counts {
+		if lsbs[b] == 0 {
+			enc.encodeICDF(count, icdfPulseCount[rateLevel])
+			continue
+		}
+		enc.encodeICDF(17, icdfPulseCount[rateLevel])
+		for i := 1; i < lsbs[b]; i++ {
+			enc.encodeICDF(17, icdfPulseCount[9])
+		}
+		enc.encodeICDF(count, icdfPulseCount[9])
+	}
+
+	// 脉冲位置按 16→8→4→2 的顺序逐级二分编码
+	var shifted [silkShellBlockSize]int
+	for b, count := range counts {
+		if count == 0 {
+			continue
+		}
+		for i := range shifted {
+			shifted[i] = mags[b*silkShellBlockSize+i] >> lsbs[b]
+		}
+		silkEncodeSplit(enc, icdfPulseCountSplit16SamplePartitions, shifted[:])
+		for h := 0; h < 16; h += 8 {
+			silkEncodeSplit(enc, icdfPulseCountSplit8SamplePartitions, shifted[h:h+8])
+			for q := h; q < h+8; q += 4 {
+				silkEncodeSplit(enc, icdfPulseCountSplit4SamplePartitions, shifted[q:q+4])
+				for p := q; p < q+4; p += 2 {
+					silkEncodeSplit(enc, icdfPulseCountSplit2SamplePartitions, shifted[p:p+2])
+				}
+			}
+		}
+	}
+
+	for i, m := range mags {
+		for bit := lsbs[i/silkShellBlockSize] - 1; bit >= 0; bit-- {
+			enc.encodeICDF((m>>bit)&1, icdfExcitationLSB)
+		}
+	}
+
+	signTables := [][]uint{
+		icdfExcitationSignUnvoicedSignalLowQuantization0Pulse,
+		icdfExcitationSignUnvoicedSignalLowQuantization1Pulse,
+		icdfExcitationSignUnvoicedSignalLowQuantization2Pulse,
+		icdfExcitationSignUnvoicedSignalLowQuantization3Pulse,
+		icdfExcitationSignUnvoicedSignalLowQuantization4Pulse,
+		icdfExcitationSignUnvoicedSignalLowQuantization5Pulse,
+		icdfExcitationSignUnvoicedSignalLowQuantization6PlusPulse,
+	}
+	for i, p := range f.pulses {
+		if p == 0 {
+			continue
+		}
+		table := signTables[clampInt(0, counts[i/silkShellBlockSize], 6)]
+		if p > 0 {
+			enc.encodeICDF(1, table)
+		} else {
+			enc.encodeICDF(0, table)
+		}
+	}
+
+	// 多出 17 比特以上时解码器会把剩余部分当作冗余的 CELT 帧，因此按 tell 截断
+	size := (enc.tell() + 7) >> 3
+	data := enc.finish()
+	if len(data) > size {
+		data = data[:size]
+	}
+	return data
+}
+
+// silkEncodeSplit 编码左半部分的脉冲数，总数为 0 时不需要编码
+func silkEncodeSplit(enc *rangeEncoder, tables [][]uint, block []int) {
+	half := len(block) / 2
+	left, total := silkShiftedSum(block[:half], 0), silkShiftedSum(block, 0)
+	if total > 0 {
+		enc.encodeICDF(left, tables[total-1])
+	}
+}
+
+func silkShiftedSum(block []int, shift int) (sum int) {
+	for _, m := range block {
+		sum += m >> shift
+	}
+	return sum
+}
+
+// silkSymbolBits 编码 symbol 需要的比特数
+func silkSymbolBits(symbol int, table []uint) float64 {
+	low := uint(0)
+	if symbol > 0 {
+		low = table[symbol]
+	}
+	if freq := table[symbol+1] - low; freq > 0 {
+		return math.Log2(float64(table[0]) / float64(freq))
+	}
+	return math.Inf(1)
+}
+
+// silkLevinson 由自相关求 LPC 预测系数，x[n] ≈ Σ a[k]·x[n-k-1]
+func silkLevinson(r *[silkOrder + 1]float64) (a [silkOrder]float64) {
+	err := r[0]
+	for i := 0; i < silkOrder; i++ {
+		acc := r[i+1]
+		for k := 0; k < i; k++ {
+			acc -= a[k] * r[i-k]
+		}
+		rc := acc / err
+		prev := a
+		for k := 0; k < i; k++ {
+			a[k] = prev[k] - rc*prev[i-k-1]
+		}
+		a[i] = rc
+		err *= 1 - rc*rc
+		if err <= 0 {
+			break
+		}
+	}
+	return a
+}
+
+// silkLPCToNLSF 在单位圆上搜索 P、Q 多项式的根，得到 Q15 的归一化线谱频率
+func silkLPCToNLSF(a *[silkOrder]float64) (nlsf [silkOrder]float64, ok bool) {
+	var c, p, q [silkOrder + 2]float64
+	c[0] = 1
+	for k := range a {
+		c[k+1] = -a[k]
+	}
+	for k := range c {
+		p[k] = c[k] + c[silkOrder+1-k]
+		q[k] = c[k] - c[silkOrder+1-k]
+	}
+	evalP := func(w float64) (sum float64) {
+		for k := range p {
+			sum += p[k] * math.Cos(w*(float64(k)-8.5))
+		}
+		return sum
+	}
+	evalQ := func(w float64) (sum float64) {
+		for k := range q {
+			sum += q[k] * math.Sin(w*(float64(k)-8.5))
+		}
+		return sum
+	}
+
+	const grid = 1024
+	n := 0
+	for _, f := range []func(float64) float64{evalP, evalQ} {
+		lo, flo := math.Pi/grid, f(math.Pi/grid)
+		for j := 2; j < grid; j++ {
+			hi := math.Pi * float64(j) / grid
+			fhi := f(hi)
+			if (flo < 0) != (fhi < 0) {
+				if n == silkOrder {
+					return nlsf, false
+				}
+				l, h, fl := lo, hi, flo
+				for iter := 0; iter < 30; iter++ {
+					m := (l + h) / 2
+					if fm := f(m); (fm < 0) == (fl < 0) {
+						l, fl = m, fm
+					} else {
+						h = m
+					}
+				}
+				nlsf[n] = (l + h) / 2 / math.Pi * 32768
+				n++
+			}
+			lo, flo = hi, fhi
+		}
+	}
+	if n != silkOrder {
+		return nlsf, false
+	}
+	for i := 1; i < silkOrder; i++ {
+		for j := i; j > 0 && nlsf[j] < nlsf[j-1]; j-- {
+			nlsf[j], nlsf[j-1] = nlsf[j-1], nlsf[j]
+		}
+	}
+	return nlsf, true
+}
+
+// silkQuantizeNLSF 两级矢量量化：遍历一级码本，二级残差按解码器的反向预测逐个贪心量化
+func silkQuantizeNLSF(target *[silkOrder]float64) (i1 int, i2 [silkOrder]int, nlsf [silkOrder]int16) {
+	var weights [silkOrder]float64
+	for k := range target {
+		prev, next := 0.0, 32768.0
+		if k > 0 {
+			prev = target[k-1]
+		}
+		if k+1 < silkOrder {
+			next = target[k+1]
+		}
+		weights[k] = 1/math.Max(target[k]-prev, 1) + 1/math.Max(next-target[k], 1)
+	}
+
+	best := math.Inf(1)
+	for c, cb := range codebookNormalizedLSFStageOneWideband {
+		wQ9 := silkNLSFWeights(cb)
+		var idx [silkOrder]int
+		var q [silkOrder]int16
+		var dist float64
+		res := 0
+		for k := silkOrder - 1; k >= 0; k-- {
+			pred := 0
+			if k+1 < silkOrder {
+				predQ8 := int(predictionWeightForWidebandNormalizedLSF[predictionWeightSelectionForWidebandNormalizedLSF[c][k]][k])
+				pred = (res * predQ8) >> 8
+			}
+			bestD := math.Inf(1)
+			for v := -10; v <= 10; v++ {
+				r := int(int16(pred + silkNLSFStep(v)))
+				value := clampInt(0, int(cb[k])<<7+(r<<14)/wQ9[k], 32767)
+				if d := math.Abs(float64(value) - target[k]); d < bestD {
+					bestD, idx[k], q[k] = d, v, int16(value)
+				}
+			}
+			res = int(int16(pred + silkNLSFStep(idx[k])))
+			dist += weights[k] * bestD * bestD
+		}
+		if dist < best {
+			best, i1, i2, nlsf = dist, c, idx, q
+		}
+	}
+	silkStabilizeNLSF(&nlsf)
+	return i1, i2, nlsf
+}
+
+// silkNLSFStep 二级索引对应的残差（Q10）
+func silkNLSFStep(i2 int) int {
+	return (((i2 << 10) - sign(i2)*102) * 9830) >> 16
+}
+
+// silkNLSFWeights 由一级码本计算残差权重（Q9），见 RFC 6716 4.2.7.5.3
+func silkNLSFWeights(cb []uint) (wQ9 [silkOrder]int) {
+	for k := range wQ9 {
+		prev, next := uint(0), uint(256)
+		if k > 0 {
+			prev = cb[k-1]
+		}
+		if k+1 < silkOrder {
+			next = cb[k+1]
+		}
+		w2Q18 := (1024/(cb[k]-prev) + 1024/(next-cb[k])) << 16
+		i := ilog32(uint32(w2Q18))
+		f := int((w2Q18 >> uint(i-8)) & 127)
+		y := 46214
+		if i&1 != 0 {
+			y = 32768
+		}
+		y >>= uint((32 - i) >> 1)
+		wQ9[k] = int(int16(y + ((213 * f * y) >> 16)))
+	}
+	return wQ9
+}
+
+// silkStabilizeNLSF 保证相邻 NLSF 的最小间距，见 RFC 6716 4.2.7.5.4
+func silkStabilizeNLSF(nlsf *[silkOrder]int16) {
+	minSpacing := codebookMinimumSpacingForNormalizedLSCoefficientsWideband
+	for round := 0; round < 20; round++ {
+		i, least := 0, math.MaxInt32
+		for j := 0; j <= silkOrder; j++ {
+			prev, cur := 0, 32768
+			if j > 0 {
+				prev = int(nlsf[j-1])
+			}
+			if j < silkOrder {
+				cur = int(nlsf[j])
+			}
+			if spacing := cur - prev - minSpacing[j]; spacing < least {
+				i, least = j, spacing
+			}
+		}
+		switch {
+		case least >= 0:
+			return
+		case i == 0:
+			nlsf[0] = int16(minSpacing[0])
+			continue
+		case i == silkOrder:
+			nlsf[silkOrder-1] = int16(32768 - minSpacing[silkOrder])
+			continue
+		}
+		minCenter, maxCenter := minSpacing[i]>>1, 32768-minSpacing[i]>>1
+		for k := 0; k < i; k++ {
+			minCenter += minSpacing[k]
+		}
+		for k := i + 1; k <= silkOrder; k++ {
+			maxCenter -= minSpacing[k]
+		}
+		center := clampInt(minCenter, (int(nlsf[i-1])+int(nlsf[i])+1)>>1, maxCenter)
+		nlsf[i-1] = int16(center - minSpacing[i]>>1)
+		nlsf[i] = nlsf[i-1] + int16(minSpacing[i])
+	}
+
+	for i := 1; i < silkOrder; i++ {
+		for j := i; j > 0 && nlsf[j] < nlsf[j-1]; j-- {
+			nlsf[j], nlsf[j-1] = nlsf[j-1], nlsf[j]
+		}
+	}
+	for k := 0; k < silkOrder; k++ {
+		prev := 0
+		if k > 0 {
+			prev = int(nlsf[k-1])
+		}
+		if v := prev + minSpacing[k]; int(nlsf[k]) < v {
+			nlsf[k] = int16(v)
+		}
+	}
+	for k := silkOrder - 1; k >= 0; k-- {
+		next := 32768
+		if k+1 < silkOrder {
+			next = int(nlsf[k+1])
+		}
+		if v := next - minSpacing[k+1]; int(nlsf[k]) > v {
+			nlsf[k] = int16(v)
+		}
+	}
+}
+
+// silkNLSFToLPC 按解码器的定点算法把 NLSF 转换为 Q12 的 LPC 系数。
+// 系数超出范围或滤波器不够稳定时解码器会再做调整，这类结果返回 false，由调用方换一组参数
+func silkNLSFToLPC(nlsf *[silkOrder]int16) (aQ12 [silkOrder]float64, ok bool) {
+	var cQ17 [silkOrder]int32
+	for k, n := range nlsf {
+		i, f := int32(n>>8), int32(n&255)
+		cos := q12CosineTableForLSFConverion
+		cQ17[lsfOrderingForPolynomialEvaluationWideband[k]] = (cos[i]*256 + (cos[i+1]-cos[i])*f + 4) >> 3
+	}
+
+	const d2 = silkOrder / 2
+	var pQ16, qQ16 [d2 + 1]int32
+	pQ16[0], qQ16[0] = 1<<16, 1<<16
+	pQ16[1], qQ16[1] = -cQ17[0], -cQ17[1]
+	for k := 1; k < d2; k++ {
+		pQ16[k+1] = pQ16[k-1]*2 - int32((int64(cQ17[2*k])*int64(pQ16[k])+32768)>>16)
+		qQ16[k+1] = qQ16[k-1]*2 - int32((int64(cQ17[2*k+1])*int64(qQ16[k])+32768)>>16)
+		for j := k; j > 1; j-- {
+			pQ16[j] += pQ16[j-2] - int32((int64(cQ17[2*k])*int64(pQ16[j-1])+32768)>>16)
+			qQ16[j] += qQ16[j-2] - int32((int64(cQ17[2*k+1])*int64(qQ16[j-1])+32768)>>16)
+		}
+		pQ16[1] -= cQ17[2*k]
+		qQ16[1] -= cQ17[2*k+1]
+	}
+
+	var a32Q17 [silkOrder]int32
+	for k := 0; k < d2; k++ {
+		a32Q17[k] = -(qQ16[k+1] - qQ16[k]) - (pQ16[k+1] + pQ16[k])
+		a32Q17[silkOrder-k-1] = (qQ16[k+1] - qQ16[k]) - (pQ16[k+1] + pQ16[k])
+	}
+
+	var dc int32
+	var a [silkOrder]float64
+	for k, v := range a32Q17 {
+		q12 := (v + 16) >> 5
+		if q12 > 32767 || q12 < -32767 {
+			return aQ12, false
+		}
+		dc += q12
+		aQ12[k] = float64(q12)
+		a[k] = float64(q12) / 4096
+	}
+	if dc >= 4096 {
+		return aQ12, false
+	}
+
+	// 逐级降阶求反射系数，要求留出足够余量
+	invGain := 1.0
+	for k := silkOrder - 1; k >= 0; k-- {
+		rc := a[k]
+		if math.Abs(rc) > 0.999 {
+			return aQ12, false
+		}
+		mult := 1 - rc*rc
+		if invGain *= mult; invGain < 2e-4 {
+			return aQ12, false
+		}
+		prev := a
+		for n := 0; n < k; n++ {
+			a[n] = (prev[n] + rc*prev[k-1-n]) / mult
+		}
+	}
+	return aQ12, true
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func clampInt(low, v, high int) int {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
+
+func clampInt32(low, v, high int32) int32 {
+	if v < low {
+		return low
+	}
+	if v > high {
+		return high
+	}
+	return v
+}
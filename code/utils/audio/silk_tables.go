@@ -0,0 +1,292 @@
+package audio
+
+// SILK 编码用到的码表，取自 RFC 6716 第 4.2 节（与 github.com/pion/opus 解码器中的码表一致）。
+// icdf 开头的表第一个元素是总频数，其后是累积频数
+var (
+	icdfFrameTypeVADInactive = []uint{256, 26, 256}
+
+	icdfFrameTypeVADActive = []uint{256, 24, 98, 246, 256}
+
+	icdfIndependentQuantizationGainMSBInactive = []uint{256, 32, 144, 212, 241, 253, 254, 255, 256}
+
+	icdfIndependentQuantizationGainMSBUnvoiced = []uint{256, 2, 19, 64, 124, 186, 233, 252, 256}
+
+	icdfIndependentQuantizationGainLSB = []uint{256, 32, 64, 96, 128, 160, 192, 224, 256}
+
+	icdfDeltaQuantizationGain = []uint{
+		256, 6, 11, 22, 53, 185, 206, 214, 218, 221, 223, 225, 227, 228,
+		229, 230, 231, 232, 233, 234, 235, 236, 237, 238, 239, 240, 241, 242,
+		243, 244, 245, 246, 247, 248, 249, 250, 251, 252, 253, 254, 255, 256,
+	}
+
+	icdfNormalizedLSFStageOneIndexWidebandUnvoiced = []uint{
+		256, 31, 52, 55, 72, 73, 81, 98, 102, 103, 121, 137, 141, 143, 146, 147, 157,
+		158, 161, 177, 188, 204, 206, 208, 211, 213, 224, 225, 229, 238, 246, 253, 256,
+	}
+
+	icdfNormalizedLSFStageTwoIndexExtension = []uint{256, 156, 216, 240, 249, 253, 255, 256}
+
+	icdfNormalizedLSFStageTwoIndex = [][]uint{
+		// Narrowband and Mediumband
+		{256, 1, 2, 3, 18, 242, 253, 254, 255, 256},
+		{256, 1, 2, 4, 38, 221, 253, 254, 255, 256},
+		{256, 1, 2, 6, 48, 197, 252, 254, 255, 256},
+		{256, 1, 2, 10, 62, 185, 246, 254, 255, 256},
+		{256, 1, 4, 20, 73, 174, 248, 254, 255, 256},
+		{256, 1, 4, 21, 76, 166, 239, 254, 255, 256},
+		{256, 1, 8, 32, 85, 159, 226, 252, 255, 256},
+		{256, 1, 2, 20, 83, 161, 219, 249, 255, 256},
+
+		// Wideband
+		{256, 1, 2, 3, 12, 244, 253, 254, 255, 256},
+		{256, 1, 2, 4, 32, 218, 253, 254, 255, 256},
+		{256, 1, 2, 5, 47, 199, 252, 254, 255, 256},
+		{256, 1, 2, 12, 61, 187, 252, 254, 255, 256},
+		{256, 1, 5, 24, 72, 172, 249, 254, 255, 256},
+		{256, 1, 2, 16, 70, 170, 242, 254, 255, 256},
+		{256, 1, 2, 17, 78, 165, 226, 251, 255, 256},
+		{256, 1, 8, 29, 79, 156, 237, 254, 255, 256},
+	}
+
+	icdfNormalizedLSFInterpolationIndex = []uint{
+		256, 13, 35, 64, 75, 256,
+	}
+
+	icdfLinearCongruentialGeneratorSeed = []uint{
+		256, 64, 128, 192, 256,
+	}
+
+	icdfRateLevelUnvoiced = []uint{256, 15, 66, 78, 124, 169, 182, 215, 242, 256}
+
+	icdfPulseCount = [][]uint{
+		{256, 131, 205, 230, 238, 241, 244, 245, 246, 247, 248, 249, 250, 251, 252, 253, 254, 255, 256},
+		{256, 58, 151, 211, 234, 241, 244, 245, 246, 247, 248, 249, 250, 251, 252, 253, 254, 255, 256},
+		{256, 43, 94, 140, 173, 197, 213, 224, 232, 238, 241, 244, 247, 249, 250, 251, 253, 254, 256},
+		{256, 17, 69, 140, 197, 228, 240, 245, 246, 247, 248, 249, 250, 251, 252, 253, 254, 255, 256},
+		{256, 6, 27, 68, 121, 170, 205, 226, 237, 243, 246, 248, 250, 251, 252, 253, 254, 255, 256},
+		{256, 7, 21, 43, 71, 100, 128, 153, 173, 190, 203, 214, 223, 230, 235, 239, 243, 246, 256},
+		{256, 2, 7, 21, 50, 92, 138, 179, 210, 229, 240, 246, 249, 251, 252, 253, 254, 255, 256},
+		{256, 1, 3, 7, 17, 36, 65, 100, 137, 171, 199, 219, 233, 241, 246, 250, 252, 254, 256},
+		{256, 1, 3, 5, 10, 19, 33, 53, 77, 104, 132, 158, 181, 201, 216, 227, 235, 241, 256},
+		{256, 1, 2, 3, 9, 36, 94, 150, 189, 214, 228, 238, 244, 247, 250, 252, 253, 254, 256},
+		{256, 2, 3, 9, 36, 94, 150, 189, 214, 228, 238, 244, 247, 250, 252, 253, 254, 256, 256},
+	}
+
+	icdfPulseCountSplit16SamplePartitions = [][]uint{
+		{256, 126, 256},
+		{256, 56, 198, 256},
+		{256, 25, 126, 230, 256},
+		{256, 12, 72, 180, 244, 256},
+		{256, 7, 42, 126, 213, 250, 256},
+		{256, 4, 24, 83, 169, 232, 253, 256},
+		{256, 3, 15, 53, 125, 200, 242, 254, 256},
+		{256, 2, 10, 35, 89, 162, 221, 248, 255, 256},
+		{256, 2, 7, 24, 63, 126, 191, 233, 251, 255, 256},
+		{256, 1, 5, 17, 45, 94, 157, 211, 241, 252, 255, 256},
+		{256, 1, 5, 13, 33, 70, 125, 182, 223, 245, 253, 255, 256},
+		{256, 1, 4, 11, 26, 54, 98, 151, 199, 232, 248, 254, 255, 256},
+		{256, 1, 3, 9, 21, 42, 77, 124, 172, 212, 237, 249, 254, 255, 256},
+		{256, 1, 2, 6, 16, 33, 60, 97, 144, 187, 220, 241, 250, 254, 255, 256},
+		{256, 1, 2, 3, 11, 25, 47, 80, 120, 163, 201, 229, 245, 253, 254, 255, 256},
+		{256, 1, 2, 3, 4, 17, 35, 62, 98, 139, 180, 214, 238, 252, 253, 254, 255, 256},
+	}
+
+	icdfPulseCountSplit8SamplePartitions = [][]uint{
+		{256, 127, 256},
+		{256, 53, 202, 256},
+		{256, 22, 127, 233, 256},
+		{256, 11, 72, 183, 246, 256},
+		{256, 6, 41, 127, 215, 251, 256},
+		{256, 4, 24, 83, 170, 232, 253, 256},
+		{256, 3, 16, 56, 127, 200, 241, 254, 256},
+		{256, 3, 12, 39, 92, 162, 218, 246, 255, 256},
+		{256, 3, 11, 30, 67, 124, 185, 229, 249, 255, 256},
+		{256, 3, 10, 25, 53, 97, 151, 200, 233, 250, 255, 256},
+		{256, 1, 8, 21, 43, 77, 123, 171, 209, 237, 251, 255, 256},
+		{256, 1, 2, 13, 35, 62, 97, 139, 186, 219, 244, 254, 255, 256},
+		{256, 1, 2, 8, 22, 48, 85, 128, 171, 208, 234, 248, 254, 255, 256},
+		{256, 1, 2, 6, 16, 36, 67, 107, 149, 189, 220, 240, 250, 254, 255, 256},
+		{256, 1, 2, 5, 13, 29, 55, 90, 128, 166, 201, 227, 243, 251, 254, 255, 256},
+		{256, 1, 2, 4, 10, 22, 43, 73, 109, 147, 183, 213, 234, 246, 252, 254, 255, 256},
+	}
+
+	icdfPulseCountSplit4SamplePartitions = [][]uint{
+		{256, 127, 256},
+		{256, 49, 206, 256},
+		{256, 20, 127, 236, 256},
+		{256, 11, 71, 184, 246, 256},
+		{256, 7, 43, 127, 214, 250, 256},
+		{256, 6, 30, 87, 169, 229, 252, 256},
+		{256, 5, 23, 62, 126, 194, 236, 252, 256},
+		{256, 6, 20, 49, 96, 157, 209, 239, 253, 256},
+		{256, 1, 16, 39, 74, 125, 175, 215, 245, 255, 256},
+		{256, 1, 2, 23, 55, 97, 149, 195, 236, 254, 255, 256},
+		{256, 1, 7, 23, 50, 86, 128, 170, 206, 233, 249, 255, 256},
+		{256, 1, 6, 18, 39, 70, 108, 148, 186, 217, 238, 250, 255, 256},
+		{256, 1, 4, 13, 30, 56, 90, 128, 166, 200, 226, 243, 252, 255, 256},
+		{256, 1, 4, 11, 25, 47, 76, 110, 146, 180, 209, 231, 245, 252, 255, 256},
+		{256, 1, 3, 8, 19, 37, 62, 93, 128, 163, 194, 219, 237, 248, 253, 255, 256},
+		{256, 1, 2, 6, 15, 30, 51, 79, 111, 145, 177, 205, 226, 241, 250, 254, 255, 256},
+	}
+
+	icdfPulseCountSplit2SamplePartitions = [][]uint{
+		{256, 128, 256},
+		{256, 42, 214, 256},
+		{256, 21, 128, 235, 256},
+		{256, 12, 72, 184, 245, 256},
+		{256, 8, 42, 128, 214, 249, 256},
+		{256, 8, 31, 86, 176, 231, 251, 256},
+		{256, 5, 20, 58, 130, 202, 238, 253, 256},
+		{256, 6, 18, 45, 97, 174, 221, 241, 251, 256},
+		{256, 6, 25, 53, 88, 128, 168, 203, 231, 250, 256},
+		{256, 4, 18, 40, 71, 108, 148, 185, 216, 238, 252, 256},
+		{256, 3, 13, 31, 57, 90, 128, 166, 199, 225, 243, 253, 256},
+		{256, 2, 10, 23, 44, 73, 109, 147, 183, 212, 233, 246, 254, 256},
+		{256, 1, 6, 16, 33, 58, 90, 128, 166, 198, 223, 240, 250, 255, 256},
+		{256, 1, 5, 12, 25, 46, 75, 110, 146, 181, 210, 231, 244, 251, 255, 256},
+		{256, 1, 3, 8, 18, 35, 60, 92, 128, 164, 196, 221, 238, 248, 253, 255, 256},
+		{256, 1, 3, 7, 14, 27, 48, 76, 110, 146, 180, 208, 229, 242, 249, 253, 255, 256},
+	}
+
+	icdfExcitationLSB = []uint{256, 136, 256}
+
+	codebookNormalizedLSFStageTwoIndexWideband = [][]uint{
+		{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8},
+		{10, 11, 11, 11, 11, 11, 10, 10, 10, 10, 10, 9, 9, 9, 8, 11},
+		{10, 13, 13, 11, 15, 12, 12, 13, 10, 13, 12, 13, 13, 12, 11, 11},
+		{8, 10, 9, 10, 10, 9, 9, 9, 9, 9, 8, 8, 8, 8, 8, 9},
+		{8, 14, 13, 12, 14, 12, 15, 13, 12, 12, 12, 13, 13, 12, 12, 11},
+		{8, 11, 13, 13, 12, 11, 11, 13, 11, 11, 11, 11, 11, 11, 10, 12},
+		{8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8},
+		{8, 10, 14, 11, 15, 10, 13, 11, 12, 13, 13, 12, 11, 11, 10, 11},
+		{8, 14, 10, 14, 14, 12, 13, 12, 14, 13, 12, 12, 13, 11, 11, 11},
+		{10, 9, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8},
+		{8, 9, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 9},
+		{10, 10, 11, 12, 13, 11, 11, 11, 11, 11, 11, 11, 10, 10, 9, 11},
+		{10, 10, 11, 11, 12, 11, 11, 11, 11, 11, 11, 11, 11, 10, 9, 11},
+		{11, 12, 12, 12, 14, 12, 12, 13, 11, 13, 12, 12, 13, 12, 11, 12},
+		{8, 14, 12, 13, 12, 15, 13, 10, 14, 13, 15, 12, 12, 11, 13, 11},
+		{8, 9, 8, 9, 9, 9, 9, 9, 9, 9, 8, 8, 8, 8, 9, 8},
+		{9, 14, 13, 15, 13, 12, 13, 11, 12, 13, 12, 12, 12, 11, 11, 12},
+		{9, 11, 11, 12, 12, 11, 11, 13, 10, 11, 11, 13, 13, 13, 11, 12},
+		{10, 11, 11, 10, 10, 10, 11, 10, 9, 10, 9, 10, 9, 9, 9, 12},
+		{8, 10, 11, 13, 11, 11, 10, 10, 10, 9, 9, 8, 8, 8, 8, 8},
+		{11, 12, 11, 13, 11, 11, 10, 10, 9, 9, 9, 9, 9, 10, 10, 12},
+		{10, 14, 11, 15, 15, 12, 13, 12, 13, 11, 13, 11, 11, 10, 11, 11},
+		{10, 11, 13, 14, 14, 11, 13, 11, 12, 12, 11, 11, 11, 11, 10, 12},
+		{9, 11, 11, 12, 12, 12, 12, 11, 13, 13, 13, 11, 9, 9, 9, 9},
+		{10, 13, 11, 14, 14, 12, 15, 12, 12, 13, 11, 12, 12, 11, 11, 11},
+		{8, 14, 9, 9, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8, 8},
+		{8, 14, 14, 11, 13, 10, 13, 13, 11, 12, 12, 15, 15, 12, 12, 12},
+		{11, 11, 15, 11, 13, 12, 11, 11, 11, 10, 10, 11, 11, 11, 10, 11},
+		{8, 8, 9, 8, 8, 8, 10, 9, 10, 9, 9, 10, 10, 10, 9, 9},
+		{8, 11, 10, 13, 11, 11, 10, 11, 10, 9, 8, 8, 9, 8, 8, 9},
+		{11, 13, 13, 12, 15, 13, 11, 11, 10, 11, 10, 10, 9, 8, 9, 8},
+		{10, 11, 13, 11, 12, 11, 11, 11, 10, 9, 10, 14, 12, 8, 8, 8},
+	}
+
+	predictionWeightForWidebandNormalizedLSF = [][]uint{
+		{175, 148, 160, 176, 178, 173, 174, 164, 177, 174, 196, 182, 198, 192, 182},
+		{68, 62, 66, 60, 72, 117, 85, 90, 118, 136, 151, 142, 160, 142, 155},
+	}
+
+	predictionWeightSelectionForWidebandNormalizedLSF = [][]uint{
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 1, 0, 0, 1, 1, 1, 0, 1, 1, 1, 1, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0},
+		{0, 1, 1, 0, 1, 0, 1, 1, 0, 1, 1, 1, 1, 1, 0},
+		{0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 1, 0},
+		{0, 1, 1, 0, 0, 0, 1, 0, 1, 1, 1, 0, 1, 0, 1},
+		{0, 1, 0, 1, 1, 0, 1, 0, 1, 0, 1, 1, 1, 1, 1},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 1, 0, 1, 1, 1, 1, 1, 1, 1, 0, 1, 0, 0},
+		{0, 0, 1, 0, 0, 1, 0, 1, 0, 1, 0, 0, 1, 0, 0},
+		{0, 0, 0, 0, 1, 1, 0, 1, 0, 1, 1, 1, 1, 0, 0},
+		{0, 1, 0, 0, 0, 1, 1, 0, 1, 1, 1, 0, 1, 1, 1},
+		{0, 0, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 0},
+		{0, 1, 1, 0, 1, 0, 1, 1, 1, 1, 1, 0, 1, 0, 0},
+		{0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 1, 1, 1, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0, 1, 0, 1, 0, 1, 1, 0, 1, 0, 1, 0, 1, 1, 0},
+		{0, 0, 1, 1, 1, 1, 0, 1, 1, 0, 0, 1, 1, 0, 0},
+		{0, 1, 1, 0, 1, 0, 1, 0, 1, 0, 0, 0, 0, 1, 0},
+		{0, 0, 0, 1, 1, 0, 1, 0, 1, 1, 1, 1, 1, 1, 1},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 1, 1, 0, 0, 0, 1, 1, 0, 0, 1, 1, 1, 1, 1},
+		{0, 0, 0, 0, 0, 1, 0, 1, 1, 1, 1, 0, 1, 1, 1},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+		{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0},
+		{0, 0, 1, 0, 0, 1, 1, 1, 0, 0, 1, 0, 0, 1, 0},
+	}
+
+	codebookNormalizedLSFStageOneWideband = [][]uint{
+		{7, 23, 38, 54, 69, 85, 100, 116, 131, 147, 162, 178, 193, 208, 223, 239},
+		{13, 25, 41, 55, 69, 83, 98, 112, 127, 142, 157, 171, 187, 203, 220, 236},
+		{15, 21, 34, 51, 61, 78, 92, 106, 126, 136, 152, 167, 185, 205, 225, 240},
+		{10, 21, 36, 50, 63, 79, 95, 110, 126, 141, 157, 173, 189, 205, 221, 237},
+		{17, 20, 37, 51, 59, 78, 89, 107, 123, 134, 150, 164, 184, 205, 224, 240},
+		{10, 15, 32, 51, 67, 81, 96, 112, 129, 142, 158, 173, 189, 204, 220, 236},
+		{8, 21, 37, 51, 65, 79, 98, 113, 126, 138, 155, 168, 179, 192, 209, 218},
+		{12, 15, 34, 55, 63, 78, 87, 108, 118, 131, 148, 167, 185, 203, 219, 236},
+		{16, 19, 32, 36, 56, 79, 91, 108, 118, 136, 154, 171, 186, 204, 220, 237},
+		{11, 28, 43, 58, 74, 89, 105, 120, 135, 150, 165, 180, 196, 211, 226, 241},
+		{6, 16, 33, 46, 60, 75, 92, 107, 123, 137, 156, 169, 185, 199, 214, 225},
+		{11, 19, 30, 44, 57, 74, 89, 105, 121, 135, 152, 169, 186, 202, 218, 234},
+		{12, 19, 29, 46, 57, 71, 88, 100, 120, 132, 148, 165, 182, 199, 216, 233},
+		{17, 23, 35, 46, 56, 77, 92, 106, 123, 134, 152, 167, 185, 204, 222, 237},
+		{14, 17, 45, 53, 63, 75, 89, 107, 115, 132, 151, 171, 188, 206, 221, 240},
+		{9, 16, 29, 40, 56, 71, 88, 103, 119, 137, 154, 171, 189, 205, 222, 237},
+		{16, 19, 36, 48, 57, 76, 87, 105, 118, 132, 150, 167, 185, 202, 218, 236},
+		{12, 17, 29, 54, 71, 81, 94, 104, 126, 136, 149, 164, 182, 201, 221, 237},
+		{15, 28, 47, 62, 79, 97, 115, 129, 142, 155, 168, 180, 194, 208, 223, 238},
+		{8, 14, 30, 45, 62, 78, 94, 111, 127, 143, 159, 175, 192, 207, 223, 239},
+		{17, 30, 49, 62, 79, 92, 107, 119, 132, 145, 160, 174, 190, 204, 220, 235},
+		{14, 19, 36, 45, 61, 76, 91, 108, 121, 138, 154, 172, 189, 205, 222, 238},
+		{12, 18, 31, 45, 60, 76, 91, 107, 123, 138, 154, 171, 187, 204, 221, 236},
+		{13, 17, 31, 43, 53, 70, 83, 103, 114, 131, 149, 167, 185, 203, 220, 237},
+		{17, 22, 35, 42, 58, 78, 93, 110, 125, 139, 155, 170, 188, 206, 224, 240},
+		{8, 15, 34, 50, 67, 83, 99, 115, 131, 146, 162, 178, 193, 209, 224, 239},
+		{13, 16, 41, 66, 73, 86, 95, 111, 128, 137, 150, 163, 183, 206, 225, 241},
+		{17, 25, 37, 52, 63, 75, 92, 102, 119, 132, 144, 160, 175, 191, 212, 231},
+		{19, 31, 49, 65, 83, 100, 117, 133, 147, 161, 174, 187, 200, 213, 227, 242},
+		{18, 31, 52, 68, 88, 103, 117, 126, 138, 149, 163, 177, 192, 207, 223, 239},
+		{16, 29, 47, 61, 76, 90, 106, 119, 133, 147, 161, 176, 193, 209, 224, 240},
+		{15, 21, 35, 50, 61, 73, 86, 97, 110, 119, 129, 141, 175, 198, 218, 237},
+	}
+
+	lsfOrderingForPolynomialEvaluationWideband = []uint8{0, 15, 8, 7, 4, 11, 12, 3, 2, 13, 10, 5, 6, 9, 14, 1}
+
+	q12CosineTableForLSFConverion = []int32{
+		4096, 4095, 4091, 4085, 4076, 4065, 4052, 4036, 4017, 3997,
+		3973, 3948, 3920, 3889, 3857, 3822, 3784, 3745, 3703, 3659,
+		3613, 3564, 3513, 3461, 3406, 3349, 3290, 3229, 3166, 3102,
+		3035, 2967, 2896, 2824, 2751, 2676, 2599, 2520, 2440, 2359,
+		2276, 2191, 2106, 2019, 1931, 1842, 1751, 1660, 1568, 1474,
+		1380, 1285, 1189, 1093, 995, 897, 799, 700, 601, 501,
+		401, 301, 201, 101, 0, -101, -201, -301, -401, -501,
+		-601, -700, -799, -897, -995, -1093, -1189, -1285, -1380, -1474,
+		-1568, -1660, -1751, -1842, -1931, -2019, -2106, -2191, -2276, -2359,
+		-2440, -2520, -2599, -2676, -2751, -2824, -2896, -2967, -3035, -3102,
+		-3166, -3229, -3290, -3349, -3406, -3461, -3513, -3564, -3613, -3659,
+		-3703, -3745, -3784, -3822, -3857, -3889, -3920, -3948, -3973, -3997,
+		-4017, -4036, -4052, -4065, -4076, -4085, -4091, -4095, -4096,
+	}
+
+	codebookMinimumSpacingForNormalizedLSCoefficientsWideband = []int{
+		100, 3, 40, 3, 3, 3, 5, 14, 14, 10, 11, 3, 8, 9, 7, 3, 347,
+	}
+
+	icdfExcitationSignUnvoicedSignalLowQuantization0Pulse     = []uint{256, 1, 256}
+	icdfExcitationSignUnvoicedSignalLowQuantization1Pulse     = []uint{256, 210, 256}
+	icdfExcitationSignUnvoicedSignalLowQuantization2Pulse     = []uint{256, 190, 256}
+	icdfExcitationSignUnvoicedSignalLowQuantization3Pulse     = []uint{256, 178, 256}
+	icdfExcitationSignUnvoicedSignalLowQuantization4Pulse     = []uint{256, 169, 256}
+	icdfExcitationSignUnvoicedSignalLowQuantization5Pulse     = []uint{256, 162, 256}
+	icdfExcitationSignUnvoicedSignalLowQuantization6PlusPulse = []uint{256, 152, 256}
+)
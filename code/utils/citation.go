@@ -0,0 +1,188 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NumberedSource 带编号的检索来源，编号从 1 开始，与回答中的 [n] 对应
+type NumberedSource struct {
+	Index int
+	WebSource
+}
+
+// CitationInstruction 要求模型按编号标注来源
+const CitationInstruction = "回答时请在用到资料的句子末尾用方括号编号标注来源，例如 [1] 或 [1][3]；" +
+	"只能使用资料中给出的编号，不要编造编号，也不要在回答末尾单独列出参考资料。"
+
+// NumberSources 按顺序为来源编号
+func NumberSources(sources []WebSource) []NumberedSource {
+	out := make([]NumberedSource, 0, len(sources))
+	for i, s := range sources {
+		out = append(out, NumberedSource{Index: i + 1, WebSource: s})
+	}
+	return out
+}
+
+// FormatSourcesPrompt 把编号后的来源拼接为提示词
+func FormatSourcesPrompt(sources []NumberedSource) string {
+	var b strings.Builder
+	for _, s := range sources {
+		origin := s.URL
+		if origin == "" {
+			origin = s.Origin
+		}
+		fmt.Fprintf(&b, "[%d] %s\n来源: %s\n", s.Index, s.Title, origin)
+		content := strings.TrimSpace(s.Content)
+		if content == "" {
+			content = s.Snippet
+		}
+		b.WriteString("内容: " + content + "\n\n")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+var (
+	// [1]、[1,2]、【1】 等形式，半角和全角括号均可
+	citationRe    = regexp.MustCompile(`[\[【]\s*(\d+(?:\s*[,，、]\s*\d+)*)\s*[\]】]`)
+	citationSepRe = regexp.MustCompile(`\s*[,，、]\s*`)
+	// 代码中的下标不是引用
+	citationCodeRe = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// maxCitationIndex 编号超过该值的方括号（如 [2023]）不视为引用
+const maxCitationIndex = 99
+
+// CleanCitations 去掉超出来源范围的编号并统一为 [n] 格式，
+// 返回清理后的回答和按首次出现排序的引用编号。没有来源时原样返回，
+// 代码下标（arr[0]、m[1][2]）和过大的编号（[2023]）保持不变
+func CleanCitations(answer string, total int) (string, []int) {
+	if total <= 0 {
+		return answer, nil
+	}
+	var cited []int
+	seen := map[int]bool{}
+	out := replaceOutsideCode(answer, func(text string) string {
+		return replaceCitations(text, func(indices []int) string {
+			var b strings.Builder
+			for _, n := range indices {
+				if n < 1 || n > total {
+					continue
+				}
+				if !seen[n] {
+					seen[n] = true
+					cited = append(cited, n)
+				}
+				b.WriteString("[" + strconv.Itoa(n) + "]")
+			}
+			return b.String()
+		})
+	})
+	return out, cited
+}
+
+// StripCitations 去掉所有 [n] 标注，用于语音朗读
+func StripCitations(answer string) string {
+	return replaceOutsideCode(answer, func(text string) string {
+		return replaceCitations(text, func([]int) string { return "" })
+	})
+}
+
+func replaceOutsideCode(s string, fn func(string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range citationCodeRe.FindAllStringIndex(s, -1) {
+		b.WriteString(fn(s[last:loc[0]]))
+		b.WriteString(s[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(fn(s[last:]))
+	return b.String()
+}
+
+func replaceCitations(s string, fn func(indices []int) string) string {
+	var b strings.Builder
+	last, skippedEnd := 0, -1
+	for _, loc := range citationRe.FindAllStringSubmatchIndex(s, -1) {
+		// [1](url) 是 Markdown 链接
+		if loc[1] < len(s) && s[loc[1]] == '(' {
+			continue
+		}
+		var indices []int
+		for _, part := range citationSepRe.Split(s[loc[2]:loc[3]], -1) {
+			n, _ := strconv.Atoi(part)
+			indices = append(indices, n)
+			if n > maxCitationIndex {
+				indices = nil
+				break
+			}
+		}
+		if indices == nil {
+			continue
+		}
+		// m[1][2] 中紧跟在下标后面的方括号同样是下标
+		if loc[0] == skippedEnd || isSubscript(s, loc[0], indices) {
+			skippedEnd = loc[1]
+			continue
+		}
+		prefix, repl := s[last:loc[0]], fn(indices)
+		if repl == "" {
+			// 去掉整个标注时连同前面的空格一起去掉
+			prefix = strings.TrimRight(prefix, " \t")
+		}
+		b.WriteString(prefix)
+		b.WriteString(repl)
+		last = loc[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// isSubscript 判断 start 处的方括号是否为代码中的下标：跟在 )、带下划线或点号的标识符、
+// 单字母变量后面，或下标为 0。普通单词和数字后面的编号（如 grew[3]、2023[2]）仍是引用
+func isSubscript(s string, start int, indices []int) bool {
+	if start == 0 {
+		return false
+	}
+	if c := s[start-1]; c == ')' || c == '_' {
+		return true
+	}
+	i := start
+	for i > 0 && isIdentChar(s[i-1]) {
+		i--
+	}
+	// 句末的点号后面是引用
+	token := s[i:start]
+	if token == "" || strings.HasSuffix(token, ".") {
+		return false
+	}
+	if strings.ContainsAny(token, "_.") || len(token) == 1 && !isDigit(token[0]) {
+		return true
+	}
+	for _, n := range indices {
+		if n == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '.' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// SourceDomain 返回来源的域名，去掉 www. 前缀
+func SourceDomain(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL
+	}
+	return strings.TrimPrefix(u.Hostname(), "www.")
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCleanCitations(t *testing.T) {
+	tests := []struct {
+		name      string
+		answer    string
+		total     int
+		want      string
+		wantCited []int
+	}{
+		{
+			name:      "valid",
+			answer:    "Go 1.18 引入了泛型[2]，并支持模糊测试[1][2]。",
+			total:     2,
+			want:      "Go 1.18 引入了泛型[2]，并支持模糊测试[1][2]。",
+			wantCited: []int{2, 1},
+		},
+		{
+			name:      "hallucinated index removed",
+			answer:    "结论如下 [5]。另见[1]",
+			total:     3,
+			want:      "结论如下。另见[1]",
+			wantCited: []int{1},
+		},
+		{
+			name:      "list and full width normalized",
+			answer:    "数据来自多处[1, 4，2]【3】",
+			total:     3,
+			want:      "数据来自多处[1][2][3]",
+			wantCited: []int{1, 2, 3},
+		},
+		{
+			name:   "links and code untouched",
+			answer: "见 [1](https://go.dev) 与 `arr[9]`\n```\nx := a[7]\n```",
+			total:  1,
+			want:   "见 [1](https://go.dev) 与 `arr[9]`\n```\nx := a[7]\n```",
+		},
+		{
+			name:   "no sources",
+			answer: "答案[1]",
+			total:  0,
+			want:   "答案[1]",
+		},
+		{
+			name:      "english prose",
+			answer:    "Revenue grew[3] in 2023[2], see the report.[1] Costs fell[9].",
+			total:     3,
+			want:      "Revenue grew[3] in 2023[2], see the report.[1] Costs fell.",
+			wantCited: []int{3, 2, 1},
+		},
+		{
+			name:   "code like subscripts untouched",
+			answer: "user_ids[1]、cfg.items[2]、f(x)[1] 和 i[3]",
+			total:  3,
+			want:   "user_ids[1]、cfg.items[2]、f(x)[1] 和 i[3]",
+		},
+		{
+			name:      "index and year untouched",
+			answer:    "取 arr[0] 和 m[1][2]，见 [2023] 年报告[1][7]",
+			total:     1,
+			want:      "取 arr[0] 和 m[1][2]，见 [2023] 年报告[1]",
+			wantCited: []int{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cited := CleanCitations(tt.answer, tt.total)
+			if got != tt.want {
+				t.Errorf("CleanCitations() = %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(cited, tt.wantCited) {
+				t.Errorf("CleanCitations() cited = %v, want %v", cited, tt.wantCited)
+			}
+		})
+	}
+}
+
+func TestStripCitations(t *testing.T) {
+	if got := StripCitations("泛型 [1][2]，模糊测试【3】。"); got != "泛型，模糊测试。" {
+		t.Errorf("StripCitations() = %q", got)
+	}
+}
+
+func TestFormatSourcesPrompt(t *testing.T) {
+	sources := NumberSources([]WebSource{
+		{Title: "Go", URL: "https://go.dev", Content: "The Go language"},
+		{Title: "Blog", URL: "https://go.dev/blog", Snippet: "snippet only"},
+	})
+	got := FormatSourcesPrompt(sources)
+	for _, want := range []string{"[1] Go\n来源: https://go.dev\n内容: The Go language", "[2] Blog", "内容: snippet only"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("FormatSourcesPrompt() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestSourceDomain(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://www.example.com/a?b=1": "example.com",
+		"http://pkg.go.dev:8080/x":      "pkg.go.dev",
+		"not a url":                     "not a url",
+	} {
+		if got := SourceDomain(in); got != want {
+			t.Errorf("SourceDomain(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
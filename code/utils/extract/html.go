@@ -0,0 +1,490 @@
+// Package extract 从网页和 PDF 中提取正文，输出适合交给模型阅读的 Markdown 文本
+package extract
+
+import (
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Page 网页提取结果
+type Page struct {
+	Title    string
+	Markdown string
+}
+
+var (
+	// 类名或 id 命中 unlikely 且未命中 positive 的节点在打分前直接移除
+	unlikelyRe = regexp.MustCompile(`(?i)comment|sidebar|footer|header|nav|menu|share|social|advert|\bads?\b|banner|cookie|popup|modal|related|recommend|breadcrumb|pagination|subscribe|login|toolbar`)
+	positiveRe = regexp.MustCompile(`(?i)article|content|main|post|body|text|entry|story|blog|detail`)
+	negativeRe = regexp.MustCompile(`(?i)comment|sidebar|footer|share|social|advert|\bads?\b|banner|related|recommend|widget|meta|tag`)
+	spaceRe    = regexp.MustCompile(`[ \t\r\n\f]+`)
+	blankRe    = regexp.MustCompile(`\n{3,}`)
+)
+
+// 打分前整体移除的标签
+var removedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true,
+	atom.Svg: true, atom.Form: true, atom.Nav: true, atom.Footer: true,
+	atom.Aside: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Template: true, atom.Object: true, atom.Embed: true,
+}
+
+var blockTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Main: true, atom.Header: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true, atom.Ul: true,
+	atom.Ol: true, atom.Li: true, atom.Pre: true, atom.Blockquote: true,
+	atom.Table: true, atom.Thead: true, atom.Tbody: true, atom.Tr: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Dl: true, atom.Dt: true,
+	atom.Dd: true, atom.Hr: true, atom.Address: true,
+}
+
+// HTML 解析网页，按 readability 的思路给段落打分选出正文节点并转换为 Markdown，
+// 相对链接按 baseURL 转为绝对地址
+func HTML(r io.Reader, baseURL string) (Page, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Page{}, err
+	}
+	base, _ := url.Parse(baseURL)
+
+	page := Page{Title: pageTitle(doc)}
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+	pruneNodes(body)
+
+	content := topCandidate(body)
+	if content == nil || textLength(content) < 140 {
+		content = body
+	}
+	c := &mdConverter{base: base}
+	page.Markdown = c.convert(content)
+	return page, nil
+}
+
+func pageTitle(doc *html.Node) string {
+	var title, ogTitle string
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch n.DataAtom {
+		case atom.Title:
+			if title == "" {
+				title = collapse(innerText(n))
+			}
+		case atom.Meta:
+			if attr(n, "property") == "og:title" && ogTitle == "" {
+				ogTitle = strings.TrimSpace(attr(n, "content"))
+			}
+		}
+		return true
+	})
+	if ogTitle != "" {
+		return ogTitle
+	}
+	return title
+}
+
+// pruneNodes 移除脚本、导航等与正文无关的节点
+func pruneNodes(root *html.Node) {
+	var remove []*html.Node
+	walk(root, func(n *html.Node) bool {
+		if n.Type == html.CommentNode {
+			remove = append(remove, n)
+			return false
+		}
+		if n.Type != html.ElementNode || n == root {
+			return true
+		}
+		if removedTags[n.DataAtom] || hasAttr(n, "hidden") ||
+			strings.EqualFold(attr(n, "aria-hidden"), "true") {
+			remove = append(remove, n)
+			return false
+		}
+		if n.DataAtom == atom.Article || n.DataAtom == atom.Main {
+			return true
+		}
+		match := attr(n, "class") + " " + attr(n, "id")
+		if unlikelyRe.MatchString(match) && !positiveRe.MatchString(match) {
+			remove = append(remove, n)
+			return false
+		}
+		return true
+	})
+	for _, n := range remove {
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+}
+
+// topCandidate 段落按长度和逗号数打分，分数累加到父节点和祖父节点，再按链接密度折算
+func topCandidate(root *html.Node) *html.Node {
+	scores := make(map[*html.Node]float64)
+	var candidates []*html.Node
+	initScore := func(n *html.Node) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; ok {
+			return
+		}
+		scores[n] = tagWeight(n) + classWeight(n)
+		candidates = append(candidates, n)
+	}
+
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || !isParagraph(n) {
+			return true
+		}
+		text := collapse(innerText(n))
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return true
+		}
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+
+			strings.Count(text, "。")) + math.Min(float64(length)/100, 3)
+		if parent := n.Parent; parent != nil {
+			initScore(parent)
+			scores[parent] += score
+			if grand := parent.Parent; grand != nil {
+				initScore(grand)
+				scores[grand] += score / 2
+			}
+		}
+		return true
+	})
+
+	var best *html.Node
+	bestScore := 0.0
+	for _, n := range candidates {
+		score := scores[n] * (1 - linkDensity(n))
+		if best == nil || score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	return best
+}
+
+func isParagraph(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.P, atom.Pre, atom.Td, atom.Blockquote:
+		return true
+	case atom.Div, atom.Section:
+		// 没有块级子元素的 div 按段落处理，很多中文网站只用 div 和 br 排版
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && blockTags[c.DataAtom] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func tagWeight(n *html.Node) float64 {
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		return 10
+	case atom.Div:
+		return 5
+	case atom.Pre, atom.Td, atom.Blockquote:
+		return 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li:
+		return -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		return -5
+	}
+	return 0
+}
+
+func classWeight(n *html.Node) float64 {
+	weight := 0.0
+	for _, v := range []string{attr(n, "class"), attr(n, "id")} {
+		if v == "" {
+			continue
+		}
+		if negativeRe.MatchString(v) {
+			weight -= 25
+		}
+		if positiveRe.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+func linkDensity(n *html.Node) float64 {
+	total := textLength(n)
+	if total == 0 {
+		return 0
+	}
+	linkLen := 0
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linkLen += textLength(c)
+			return false
+		}
+		return true
+	})
+	return float64(linkLen) / float64(total)
+}
+
+func textLength(n *html.Node) int {
+	return utf8.RuneCountInString(collapse(innerText(n)))
+}
+
+// mdConverter 把正文节点转换为 Markdown，保留标题、列表、链接、代码块和表格
+type mdConverter struct {
+	base *url.URL
+}
+
+func (c *mdConverter) convert(n *html.Node) string {
+	md := strings.Join(c.blocks(n), "\n\n")
+	return strings.TrimSpace(blankRe.ReplaceAllString(md, "\n\n"))
+}
+
+func (c *mdConverter) blocks(n *html.Node) []string {
+	var out []string
+	var inline strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(inline.String()); text != "" {
+			out = append(out, text)
+		}
+		inline.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || !blockTags[child.DataAtom] {
+			inline.WriteString(c.inline(child))
+			continue
+		}
+		flush()
+		out = append(out, c.block(child)...)
+	}
+	flush()
+	return out
+}
+
+func (c *mdConverter) block(n *html.Node) []string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := strings.TrimSpace(c.inline(n))
+		if text == "" {
+			return nil
+		}
+		level := int(n.Data[1] - '0')
+		return []string{strings.Repeat("#", level) + " " + text}
+	case atom.Ul, atom.Ol:
+		return []string{c.list(n)}
+	case atom.Pre:
+		code := strings.Trim(innerText(n), "\n")
+		if strings.TrimSpace(code) == "" {
+			return nil
+		}
+		return []string{"```\n" + code + "\n```"}
+	case atom.Blockquote:
+		var lines []string
+		for _, line := range strings.Split(strings.Join(c.blocks(n), "\n\n"), "\n") {
+			lines = append(lines, "> "+line)
+		}
+		return []string{strings.Join(lines, "\n")}
+	case atom.Table:
+		if table := c.table(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case atom.Hr:
+		return []string{"---"}
+	}
+	return c.blocks(n)
+}
+
+func (c *mdConverter) list(n *html.Node) string {
+	var items []string
+	index := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = strconv.Itoa(index) + ". "
+			index++
+		}
+		content := strings.Join(c.blocks(li), "\n")
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		// 嵌套内容缩进到列表项下
+		content = strings.ReplaceAll(content, "\n", "\n"+strings.Repeat(" ", len(marker)))
+		items = append(items, marker+content)
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *mdConverter) table(n *html.Node) string {
+	var rows [][]string
+	walk(n, func(tr *html.Node) bool {
+		if tr.Type != html.ElementNode || tr.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []string
+		for td := tr.FirstChild; td != nil; td = td.NextSibling {
+			if td.Type == html.ElementNode && (td.DataAtom == atom.Td || td.DataAtom == atom.Th) {
+				cell := strings.TrimSpace(collapse(c.inline(td)))
+				cells = append(cells, strings.ReplaceAll(cell, "|", "\\|"))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+	var lines []string
+	for i, row := range rows {
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", len(row)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *mdConverter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return spaceRe.ReplaceAllString(n.Data, " ")
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	var inner strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		inner.WriteString(c.inline(child))
+	}
+	text := inner.String()
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Img:
+		return ""
+	case atom.A:
+		href := c.resolve(attr(n, "href"))
+		label := strings.TrimSpace(text)
+		if href == "" || label == "" {
+			return text
+		}
+		return "[" + label + "](" + href + ")"
+	case atom.Strong, atom.B:
+		if strings.TrimSpace(text) == "" {
+			return text
+		}
+		return "**" + strings.TrimSpace(text) + "**"
+	case atom.Em, atom.I:
+		if strings.TrimSpace(text) == "" {
+			return text
+		}
+		return "*" + strings.TrimSpace(text) + "*"
+	case atom.Code:
+		if strings.TrimSpace(text) == "" {
+			return text
+		}
+		return "`" + strings.TrimSpace(text) + "`"
+	}
+	if blockTags[n.DataAtom] {
+		return " " + text + " "
+	}
+	return text
+}
+
+func (c *mdConverter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || strings.HasPrefix(href, "#") ||
+		strings.HasPrefix(strings.ToLower(href), "javascript:") {
+		return ""
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if c.base != nil {
+		u = c.base.ResolveReference(u)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "mailto" {
+		return ""
+	}
+	return u.String()
+}
+
+func walk(n *html.Node, fn func(n *html.Node) bool) {
+	if !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		walk(c, fn)
+		c = next
+	}
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func innerText(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		return true
+	})
+	return b.String()
+}
+
+func collapse(s string) string {
+	return strings.TrimSpace(spaceRe.ReplaceAllString(s, " "))
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
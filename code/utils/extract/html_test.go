@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"strings"
+	"testing"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Go 1.21 发布">
+<script>var tracking = "should not appear";</script>
+</head>
+<body>
+<div id="top-nav"><a href="/">首页</a> <a href="/news">新闻</a> <a href="/about">关于</a></div>
+<div class="layout">
+  <div class="sidebar"><ul><li><a href="/a">热门文章一</a></li><li><a href="/b">热门文章二</a></li></ul></div>
+  <div class="article-content">
+    <h1>Go 1.21 发布</h1>
+    <p>Go 团队今天发布了 Go 1.21，这个版本带来了新的内置函数，改进了工具链，并且提升了性能。</p>
+    <p>新版本引入了 <strong>min</strong>、<strong>max</strong> 和 clear 三个内置函数，同时新增了 slices、maps 等标准库包，详细说明见<a href="/doc/go1.21">发布说明</a>。</p>
+    <ul><li>更快的编译速度</li><li>PGO 正式可用</li></ul>
+    <pre>go install golang.org/dl/go1.21.0@latest</pre>
+    <table><tr><th>版本</th><th>日期</th></tr><tr><td>1.21</td><td>2023-08</td></tr></table>
+    <p>欢迎大家升级体验，如果遇到问题，请在 issue 中反馈，我们会尽快处理。</p>
+  </div>
+</div>
+<div class="footer">版权所有 © 2023 <a href="/privacy">隐私政策</a></div>
+</body></html>`
+
+func TestHTML(t *testing.T) {
+	page, err := HTML(strings.NewReader(articleHTML), "https://go.dev/blog/go1.21")
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	if page.Title != "Go 1.21 发布" {
+		t.Errorf("Title = %q", page.Title)
+	}
+	contains := []string{
+		"# Go 1.21 发布",
+		"**min**",
+		"[发布说明](https://go.dev/doc/go1.21)",
+		"- 更快的编译速度\n- PGO 正式可用",
+		"```\ngo install golang.org/dl/go1.21.0@latest\n```",
+		"| 版本 | 日期 |\n| --- | --- |\n| 1.21 | 2023-08 |",
+		"欢迎大家升级体验",
+	}
+	for _, want := range contains {
+		if !strings.Contains(page.Markdown, want) {
+			t.Errorf("Markdown missing %q:\n%s", want, page.Markdown)
+		}
+	}
+	for _, unwanted := range []string{"should not appear", "热门文章", "隐私政策", "首页"} {
+		if strings.Contains(page.Markdown, unwanted) {
+			t.Errorf("Markdown should not contain %q:\n%s", unwanted, page.Markdown)
+		}
+	}
+}
+
+func TestHTMLShortPageFallsBackToBody(t *testing.T) {
+	page, err := HTML(strings.NewReader(`<html><body><div>Hello <em>world</em><br>second line</div></body></html>`), "")
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	if page.Markdown != "Hello *world*\nsecond line" {
+		t.Errorf("Markdown = %q", page.Markdown)
+	}
+}
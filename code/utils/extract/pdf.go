@@ -0,0 +1,652 @@
+package extract
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF 提取 PDF 中的文字，支持 FlateDecode 压缩、对象流和 ToUnicode 字体映射，
+// 扫描件等没有文字层的 PDF 返回错误
+func PDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\r\n\t "), []byte("%PDF-")) {
+		return "", errors.New("not a pdf file")
+	}
+	doc := parsePdf(data)
+
+	var pages []string
+	for _, page := range doc.pageOrder() {
+		var text strings.Builder
+		for _, ref := range doc.contentRefs(page) {
+			text.WriteString(doc.pageText(doc.objects[ref].stream))
+			text.WriteString("\n")
+		}
+		if t := cleanPdfText(text.String()); t != "" {
+			pages = append(pages, t)
+		}
+	}
+	if len(pages) == 0 {
+		return "", errors.New("no text layer found in pdf")
+	}
+	return strings.Join(pages, "\n\n"), nil
+}
+
+type pdfObject struct {
+	dict   string
+	stream []byte
+}
+
+type pdfDoc struct {
+	objects map[int]*pdfObject
+	// 字体资源名 -> ToUnicode 映射，按名称合并，不同页面同名字体视为同一字体
+	fonts map[string]*cmap
+}
+
+var (
+	objRe      = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	refRe      = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+	fontDictRe = regexp.MustCompile(`/Font\s*(<<(?:[^<>]|<<[^<>]*>>)*>>|\d+\s+\d+\s+R)`)
+	fontRefRe  = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`)
+	toUniRe    = regexp.MustCompile(`/ToUnicode\s+(\d+)\s+\d+\s+R`)
+	kidsRe     = regexp.MustCompile(`/Kids\s*\[([^\]]*)\]`)
+	contentsRe = regexp.MustCompile(`/Contents\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`)
+	typeRe     = regexp.MustCompile(`/Type\s*/(\w+)`)
+	pagesRe    = regexp.MustCompile(`/Pages\s+(\d+)\s+\d+\s+R`)
+	intRe      = regexp.MustCompile(`/(\w+)\s+(\d+)(\s+\d+\s+R)?`)
+)
+
+func parsePdf(data []byte) *pdfDoc {
+	doc := &pdfDoc{objects: make(map[int]*pdfObject), fonts: make(map[string]*cmap)}
+	locs := objRe.FindAllSubmatchIndex(data, -1)
+	for i, loc := range locs {
+		num, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		end := len(data)
+		if i+1 < len(locs) {
+			end = locs[i+1][0]
+		}
+		body := data[loc[1]:end]
+		if idx := bytes.Index(body, []byte("endobj")); idx >= 0 {
+			body = body[:idx]
+		}
+		doc.objects[num] = parsePdfObject(body)
+	}
+
+	// 对象流中的对象没有 stream，只有字典
+	for _, obj := range doc.objects {
+		if pdfType(obj.dict) != "ObjStm" || obj.stream == nil {
+			continue
+		}
+		ints := pdfInts(obj.dict)
+		first, n := ints["First"], ints["N"]
+		if first <= 0 || first > len(obj.stream) {
+			continue
+		}
+		header := strings.Fields(string(obj.stream[:first]))
+		for i := 0; i+1 < len(header) && i/2 < n; i += 2 {
+			num, err1 := strconv.Atoi(header[i])
+			offset, err2 := strconv.Atoi(header[i+1])
+			if err1 != nil || err2 != nil || first+offset > len(obj.stream) {
+				continue
+			}
+			end := len(obj.stream)
+			if i+3 < len(header) {
+				if next, err := strconv.Atoi(header[i+3]); err == nil && first+next <= end && next >= offset {
+					end = first + next
+				}
+			}
+			if _, exists := doc.objects[num]; !exists {
+				doc.objects[num] = &pdfObject{dict: string(obj.stream[first+offset : end])}
+			}
+		}
+	}
+
+	for _, obj := range doc.objects {
+		for _, m := range fontDictRe.FindAllStringSubmatch(obj.dict, -1) {
+			fontDict := m[1]
+			if !strings.HasPrefix(fontDict, "<<") {
+				if ref := firstRef(fontDict); ref > 0 && doc.objects[ref] != nil {
+					fontDict = doc.objects[ref].dict
+				}
+			}
+			for _, f := range fontRefRe.FindAllStringSubmatch(fontDict, -1) {
+				fontNum, _ := strconv.Atoi(f[2])
+				font := doc.objects[fontNum]
+				if font == nil {
+					continue
+				}
+				if tu := toUniRe.FindStringSubmatch(font.dict); tu != nil {
+					cmapNum, _ := strconv.Atoi(tu[1])
+					if cm := doc.objects[cmapNum]; cm != nil && cm.stream != nil {
+						doc.fonts[f[1]] = parseCMap(string(cm.stream))
+					}
+				}
+			}
+		}
+	}
+	return doc
+}
+
+func parsePdfObject(body []byte) *pdfObject {
+	idx := bytes.Index(body, []byte("stream"))
+	if idx < 0 {
+		return &pdfObject{dict: string(body)}
+	}
+	obj := &pdfObject{dict: string(body[:idx])}
+	raw := body[idx+len("stream"):]
+	raw = bytes.TrimPrefix(raw, []byte("\r"))
+	raw = bytes.TrimPrefix(raw, []byte("\n"))
+	if end := bytes.LastIndex(raw, []byte("endstream")); end >= 0 {
+		raw = raw[:end]
+	}
+	if length := pdfInts(obj.dict)["Length"]; length > 0 && length <= len(raw) {
+		raw = raw[:length]
+	}
+	switch {
+	case strings.Contains(obj.dict, "/FlateDecode"):
+		obj.stream = inflate(raw)
+	case strings.Contains(obj.dict, "/Filter"):
+		// DCTDecode 等图片编码不含文字
+	default:
+		obj.stream = raw
+	}
+	return obj
+}
+
+func inflate(raw []byte) []byte {
+	if r, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+		// 流尾部常有多余字节导致校验失败，尽量保留已解压的内容
+		out, _ := ioutil.ReadAll(r)
+		if len(out) > 0 {
+			return out
+		}
+	}
+	out, _ := ioutil.ReadAll(flate.NewReader(bytes.NewReader(raw)))
+	return out
+}
+
+func pdfType(dict string) string {
+	if m := typeRe.FindStringSubmatch(dict); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func pdfInts(dict string) map[string]int {
+	ints := make(map[string]int)
+	for _, m := range intRe.FindAllStringSubmatch(dict, -1) {
+		// 跳过形如 /Length 12 0 R 的间接引用
+		if _, ok := ints[m[1]]; ok || m[3] != "" {
+			continue
+		}
+		if v, err := strconv.Atoi(m[2]); err == nil {
+			ints[m[1]] = v
+		}
+	}
+	return ints
+}
+
+func firstRef(s string) int {
+	if m := refRe.FindStringSubmatch(s); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
+
+// pageOrder 从目录的页面树按顺序取出页面，找不到目录时按对象编号排序
+func (d *pdfDoc) pageOrder() []int {
+	var pages []int
+	visited := make(map[int]bool)
+	var visit func(num int)
+	visit = func(num int) {
+		obj := d.objects[num]
+		if obj == nil || visited[num] {
+			return
+		}
+		visited[num] = true
+		switch pdfType(obj.dict) {
+		case "Page":
+			pages = append(pages, num)
+		case "Pages":
+			if m := kidsRe.FindStringSubmatch(obj.dict); m != nil {
+				for _, ref := range refRe.FindAllStringSubmatch(m[1], -1) {
+					kid, _ := strconv.Atoi(ref[1])
+					visit(kid)
+				}
+			}
+		}
+	}
+	for _, obj := range d.objects {
+		if pdfType(obj.dict) == "Catalog" {
+			if m := pagesRe.FindStringSubmatch(obj.dict); m != nil {
+				root, _ := strconv.Atoi(m[1])
+				visit(root)
+			}
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+	maxNum := 0
+	for num := range d.objects {
+		if num > maxNum {
+			maxNum = num
+		}
+	}
+	for num := 0; num <= maxNum; num++ {
+		if obj := d.objects[num]; obj != nil && pdfType(obj.dict) == "Page" {
+			pages = append(pages, num)
+		}
+	}
+	return pages
+}
+
+func (d *pdfDoc) contentRefs(page int) []int {
+	m := contentsRe.FindStringSubmatch(d.objects[page].dict)
+	if m == nil {
+		return nil
+	}
+	var refs []int
+	for _, ref := range refRe.FindAllStringSubmatch(m[1], -1) {
+		num, _ := strconv.Atoi(ref[1])
+		if obj := d.objects[num]; obj != nil && obj.stream != nil {
+			refs = append(refs, num)
+		}
+	}
+	return refs
+}
+
+// pageText 解释内容流中的文字操作符 Tf、Tj、TJ、'、"，按换行操作符分行
+func (d *pdfDoc) pageText(content []byte) string {
+	var out strings.Builder
+	var operands []pdfToken
+	var font *cmap
+	lastY, hasY := 0.0, false
+	newline := func() {
+		if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+			out.WriteString("\n")
+		}
+	}
+	lex := &pdfLexer{data: content}
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+		if tok.kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+		switch tok.text {
+		case "Tf":
+			if len(operands) >= 2 && operands[len(operands)-2].kind == pdfName {
+				font = d.fonts[operands[len(operands)-2].text]
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				out.WriteString(decodePdfString(operands[len(operands)-1], font))
+			}
+		case "'", "\"":
+			newline()
+			if len(operands) > 0 {
+				out.WriteString(decodePdfString(operands[len(operands)-1], font))
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				for _, item := range operands[len(operands)-1].items {
+					if item.kind == pdfNumber {
+						// 较大的负间距通常表示单词之间的空格
+						if v, _ := strconv.ParseFloat(item.text, 64); v < -200 {
+							out.WriteString(" ")
+						}
+						continue
+					}
+					out.WriteString(decodePdfString(item, font))
+				}
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, _ := strconv.ParseFloat(operands[len(operands)-1].text, 64); ty != 0 {
+					newline()
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := strconv.ParseFloat(operands[len(operands)-1].text, 64)
+				if hasY && y != lastY {
+					newline()
+				}
+				lastY, hasY = y, true
+			}
+		case "T*":
+			newline()
+		case "ET":
+			out.WriteString(" ")
+		}
+		operands = operands[:0]
+	}
+	return out.String()
+}
+
+func cleanPdfText(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(spaceRe.ReplaceAllString(line, " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+type pdfTokenKind int
+
+const (
+	pdfOperator pdfTokenKind = iota
+	pdfNumber
+	pdfName
+	pdfString
+	pdfHexString
+	pdfArray
+	pdfDict
+)
+
+type pdfToken struct {
+	kind  pdfTokenKind
+	text  string
+	raw   []byte
+	items []pdfToken
+}
+
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPdfDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isPdfSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPdfSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.data) {
+		return pdfToken{}, false
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return pdfToken{kind: pdfString, raw: l.literal()}, true
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		depth := 1
+		for l.pos < len(l.data) && depth > 0 {
+			if bytes.HasPrefix(l.data[l.pos:], []byte("<<")) {
+				depth++
+				l.pos += 2
+			} else if bytes.HasPrefix(l.data[l.pos:], []byte(">>")) {
+				depth--
+				l.pos += 2
+			} else {
+				l.pos++
+			}
+		}
+		return pdfToken{kind: pdfDict}, true
+	case c == '<':
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			end = len(l.data) - l.pos
+		}
+		hex := string(l.data[l.pos+1 : l.pos+end])
+		l.pos += end + 1
+		return pdfToken{kind: pdfHexString, raw: decodeHex(hex)}, true
+	case c == '[':
+		l.pos++
+		var items []pdfToken
+		for {
+			tok, ok := l.next()
+			if !ok || (tok.kind == pdfOperator && tok.text == "]") {
+				break
+			}
+			items = append(items, tok)
+		}
+		return pdfToken{kind: pdfArray, items: items}, true
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		l.pos++
+		return pdfToken{kind: pdfOperator, text: string(c)}, true
+	case c == '/':
+		start := l.pos + 1
+		l.pos++
+		for l.pos < len(l.data) && !isPdfSpace(l.data[l.pos]) && !isPdfDelimiter(l.data[l.pos]) {
+			l.pos++
+		}
+		return pdfToken{kind: pdfName, text: string(l.data[start:l.pos])}, true
+	}
+	start := l.pos
+	for l.pos < len(l.data) && !isPdfSpace(l.data[l.pos]) && !isPdfDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if _, err := strconv.ParseFloat(word, 64); err == nil {
+		return pdfToken{kind: pdfNumber, text: word}, true
+	}
+	if word == "BI" {
+		// 内联图片数据直接跳过
+		if end := bytes.Index(l.data[l.pos:], []byte("EI")); end >= 0 {
+			l.pos += end + 2
+		}
+	}
+	return pdfToken{kind: pdfOperator, text: word}, true
+}
+
+func (l *pdfLexer) literal() []byte {
+	var out []byte
+	depth := 0
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r', '\n':
+				// 行尾续行
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func decodeHex(s string) []byte {
+	var digits []byte
+	for i := 0; i < len(s); i++ {
+		if isHexDigit(s[i]) {
+			digits = append(digits, s[i])
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for i := range out {
+		v, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		out[i] = byte(v)
+	}
+	return out
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func decodePdfString(tok pdfToken, font *cmap) string {
+	if tok.kind != pdfString && tok.kind != pdfHexString {
+		return ""
+	}
+	if font != nil {
+		return font.decode(tok.raw)
+	}
+	if bytes.HasPrefix(tok.raw, []byte{0xfe, 0xff}) {
+		return decodeUTF16BE(tok.raw[2:])
+	}
+	// 没有 ToUnicode 时按 Latin-1 处理，适用于标准 14 字体的英文文档
+	runes := make([]rune, 0, len(tok.raw))
+	for _, b := range tok.raw {
+		if b >= 0x20 || b == '\n' || b == '\t' {
+			runes = append(runes, rune(b))
+		}
+	}
+	return string(runes)
+}
+
+func decodeUTF16BE(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(u))
+}
+
+// cmap ToUnicode 映射，codeLen 为字符编码的字节数
+type cmap struct {
+	codeLen int
+	chars   map[uint32]string
+}
+
+var (
+	bfcharRe  = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	bfrangeRe = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	hexRe     = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>`)
+	rangeRe   = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f\s]*>|\[[^\]]*\])`)
+)
+
+func parseCMap(s string) *cmap {
+	cm := &cmap{codeLen: 1, chars: make(map[uint32]string)}
+	setLen := func(src string) {
+		if n := len(strings.TrimSpace(src)) / 2; n > cm.codeLen {
+			cm.codeLen = n
+		}
+	}
+	for _, block := range bfcharRe.FindAllStringSubmatch(s, -1) {
+		pairs := hexRe.FindAllStringSubmatch(block[1], -1)
+		for i := 0; i+1 < len(pairs); i += 2 {
+			setLen(pairs[i][1])
+			cm.chars[hexCode(pairs[i][1])] = decodeUTF16BE(decodeHex(pairs[i+1][1]))
+		}
+	}
+	for _, block := range bfrangeRe.FindAllStringSubmatch(s, -1) {
+		for _, r := range rangeRe.FindAllStringSubmatch(block[1], -1) {
+			setLen(r[1])
+			lo, hi := hexCode(r[1]), hexCode(r[2])
+			if hi < lo || hi-lo > 0xffff {
+				continue
+			}
+			if strings.HasPrefix(r[3], "[") {
+				dsts := hexRe.FindAllStringSubmatch(r[3], -1)
+				for i, dst := range dsts {
+					if lo+uint32(i) > hi {
+						break
+					}
+					cm.chars[lo+uint32(i)] = decodeUTF16BE(decodeHex(dst[1]))
+				}
+				continue
+			}
+			dst := decodeHex(strings.Trim(r[3], "<>"))
+			if len(dst) < 2 {
+				continue
+			}
+			for code := lo; code <= hi; code++ {
+				d := append([]byte(nil), dst...)
+				// 目标值的最后一个字节随编码递增
+				offset := code - lo
+				last := uint32(d[len(d)-2])<<8 | uint32(d[len(d)-1])
+				last += offset
+				d[len(d)-2], d[len(d)-1] = byte(last>>8), byte(last)
+				cm.chars[code] = decodeUTF16BE(d)
+			}
+		}
+	}
+	return cm
+}
+
+func hexCode(s string) uint32 {
+	v, _ := strconv.ParseUint(strings.Join(strings.Fields(s), ""), 16, 32)
+	return uint32(v)
+}
+
+func (cm *cmap) decode(b []byte) string {
+	var out strings.Builder
+	for i := 0; i+cm.codeLen <= len(b); i += cm.codeLen {
+		var code uint32
+		for j := 0; j < cm.codeLen; j++ {
+			code = code<<8 | uint32(b[i+j])
+		}
+		if s, ok := cm.chars[code]; ok {
+			out.WriteString(s)
+		}
+	}
+	return out.String()
+}
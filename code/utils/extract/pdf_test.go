@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+)
+
+func deflate(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+// buildPdf 按顺序拼接对象，省略 xref 表，解析时不依赖它
+func buildPdf(objects ...[]byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(obj)
+		buf.WriteString("\nendobj\n")
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func streamObj(dict string, data []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<< %s /Length %d >>\nstream\n", dict, len(data))
+	buf.Write(data)
+	buf.WriteString("\nendstream")
+	return buf.Bytes()
+}
+
+func TestPDF(t *testing.T) {
+	cmapData := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+2 beginbfchar
+<0001> <4F60>
+<0002> <597D>
+endbfchar
+1 beginbfrange
+<0010> <0012> <0041>
+endbfrange
+endcmap`
+	page1 := "BT /F1 12 Tf 72 720 Td [<00010002>] TJ 0 -20 Td <001000110012> Tj ET"
+	page2 := "BT /F2 12 Tf 72 720 Td (Hello) Tj [(Wor) -20 (ld)] TJ T* (Second \\(line\\)) Tj ET"
+
+	data := buildPdf(
+		[]byte("<< /Type /Catalog /Pages 2 0 R >>"),
+		[]byte("<< /Type /Pages /Kids [4 0 R 3 0 R] /Count 2 >>"),
+		// 页面对象的编号顺序与页面树顺序相反，输出需按页面树排序
+		[]byte("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F2 9 0 R >> >> /Contents 8 0 R >>"),
+		[]byte("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents [7 0 R] >>"),
+		[]byte("<< /Type /Font /Subtype /Type0 /BaseFont /SimSun /ToUnicode 6 0 R >>"),
+		streamObj("/Filter /FlateDecode", deflate(t, cmapData)),
+		streamObj("/Filter /FlateDecode", deflate(t, page1)),
+		streamObj("", []byte(page2)),
+		[]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"),
+	)
+
+	got, err := PDF(data)
+	if err != nil {
+		t.Fatalf("PDF() error = %v", err)
+	}
+	want := "你好\nABC\n\nHelloWorld\nSecond (line)"
+	if got != want {
+		t.Errorf("PDF() = %q, want %q", got, want)
+	}
+}
+
+func TestPDFErrors(t *testing.T) {
+	if _, err := PDF([]byte("<html></html>")); err == nil {
+		t.Error("PDF() expected error for non-pdf data")
+	}
+	scanned := buildPdf(
+		[]byte("<< /Type /Catalog /Pages 2 0 R >>"),
+		[]byte("<< /Type /Pages /Kids [3 0 R] /Count 1 >>"),
+		[]byte("<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>"),
+		streamObj("", []byte("q 100 0 0 100 0 0 cm /Im1 Do Q")),
+	)
+	if _, err := PDF(scanned); err == nil {
+		t.Error("PDF() expected error for pdf without text")
+	}
+}
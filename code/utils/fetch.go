@@ -0,0 +1,245 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"start-feishubot/utils/extract"
+)
+
+// PageFetcher 下载网页并转换为适合模型阅读的文本
+type PageFetcher interface {
+	Name() string
+	Fetch(ctx context.Context, pageURL string) (string, error)
+}
+
+// NewPageFetcher 按配置选择抓取方式: builtin 在本地下载并提取正文, jina 通过 r.jina.ai 代理
+func NewPageFetcher(backend string, jinaApiKey string, policy *FetchPolicy) PageFetcher {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "jina":
+		return &JinaFetcher{APIKey: jinaApiKey, Policy: policy}
+	case "", "builtin":
+		return &BuiltinFetcher{Policy: policy}
+	default:
+		fmt.Printf("⚠️ Unknown WEB_FETCH_BACKEND %q, using builtin\n", backend)
+		return &BuiltinFetcher{Policy: policy}
+	}
+}
+
+var (
+	pageFetcherMu sync.RWMutex
+	pageFetcher   PageFetcher = &BuiltinFetcher{}
+)
+
+// SetPageFetcher 替换 FetchURLAsPlainText 使用的抓取方式
+func SetPageFetcher(f PageFetcher) {
+	pageFetcherMu.Lock()
+	pageFetcher = f
+	pageFetcherMu.Unlock()
+}
+
+func getPageFetcher() PageFetcher {
+	pageFetcherMu.RLock()
+	defer pageFetcherMu.RUnlock()
+	return pageFetcher
+}
+
+// normalizeURL 去掉首尾空白，没有协议时默认 https
+func normalizeURL(rawURL string) (string, error) {
+	cleaned := strings.TrimSpace(rawURL)
+	if cleaned == "" {
+		return "", errors.New("empty url")
+	}
+	if !strings.HasPrefix(cleaned, "http://") && !strings.HasPrefix(cleaned, "https://") {
+		cleaned = "https://" + cleaned
+	}
+	return cleaned, nil
+}
+
+const defaultMaxPageBytes = 10 << 20
+
+func fetchPolicyOrDefault(policy *FetchPolicy) *FetchPolicy {
+	if policy == nil {
+		return DefaultFetchPolicy()
+	}
+	return policy
+}
+
+// BuiltinFetcher 直接下载页面，识别编码后提取正文转为 Markdown，支持 PDF 和纯文本
+type BuiltinFetcher struct {
+	// Client 为空时使用 Policy.NewClient，指定 Client 时仅做 URL、重定向和内容检查
+	Client *http.Client
+	Policy *FetchPolicy
+}
+
+func (f *BuiltinFetcher) Name() string { return "builtin" }
+
+func (f *BuiltinFetcher) Fetch(ctx context.Context, pageURL string) (string, error) {
+	policy := fetchPolicyOrDefault(f.Policy)
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := policy.CheckURL(req.URL); err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/pdf,text/plain;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	var client *http.Client
+	if f.Client != nil {
+		c := *f.Client
+		c.CheckRedirect = policy.checkRedirect
+		client = &c
+	} else {
+		client = policy.NewClient(15 * time.Second)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", errors.New("failed to fetch url: " + resp.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err := policy.CheckContentType(mediaType); err != nil {
+		return "", err
+	}
+	maxBytes := policy.maxBodyBytes()
+	if resp.ContentLength > maxBytes {
+		return "", tooLargeError(maxBytes)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if int64(len(body)) > maxBytes {
+		return "", tooLargeError(maxBytes)
+	}
+	return RenderPage(body, resp.Header.Get("Content-Type"), resp.Request.URL.String())
+}
+
+func tooLargeError(maxBytes int64) error {
+	if maxBytes >= 1<<20 {
+		return policyErrorf("网页内容过大 (超过 %d MB)", maxBytes>>20)
+	}
+	return policyErrorf("网页内容过大 (超过 %d KB)", maxBytes>>10)
+}
+
+// RenderPage 按内容类型把下载的页面转换为文本，输出格式与 r.jina.ai 保持一致
+func RenderPage(body []byte, contentType string, pageURL string) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(body))
+	}
+	if mediaType == "application/pdf" || bytes.HasPrefix(body, []byte("%PDF-")) {
+		text, err := extract.PDF(body)
+		if err != nil {
+			return "", err
+		}
+		return formatPage("", pageURL, text), nil
+	}
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page, err := extract.HTML(bytes.NewReader(decodeCharset(body, contentType)), pageURL)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(page.Markdown) == "" {
+			return "", errors.New("no readable content found")
+		}
+		return formatPage(page.Title, pageURL, page.Markdown), nil
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "xml"):
+		return formatPage("", pageURL, strings.TrimSpace(string(decodeCharset(body, contentType)))), nil
+	}
+	return "", fmt.Errorf("unsupported content type: %s", mediaType)
+}
+
+// decodeCharset 按 Content-Type、BOM 和 meta 标签识别编码并转为 UTF-8
+func decodeCharset(body []byte, contentType string) []byte {
+	enc, name, _ := charset.DetermineEncoding(body, contentType)
+	if name == "utf-8" {
+		return body
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
+
+func formatPage(title, pageURL, content string) string {
+	var b strings.Builder
+	if title != "" {
+		b.WriteString("Title: " + title + "\n")
+	}
+	b.WriteString("URL Source: " + pageURL + "\n\n")
+	b.WriteString("Markdown Content:\n")
+	b.WriteString(content)
+	return b.String()
+}
+
+// JinaFetcher 通过 Jina Reader (https://r.jina.ai) 提取页面内容，页面地址会发送给第三方
+type JinaFetcher struct {
+	BaseURL string
+	APIKey  string
+	Client  *http.Client
+	// Policy 只检查链接本身，页面由 Jina 从外网抓取
+	Policy *FetchPolicy
+}
+
+func (f *JinaFetcher) Name() string { return "jina" }
+
+func (f *JinaFetcher) Fetch(ctx context.Context, pageURL string) (string, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	if err := fetchPolicyOrDefault(f.Policy).CheckURL(u); err != nil {
+		return "", err
+	}
+	// Jina Reader format: https://r.jina.ai/https://example.com
+	base := f.BaseURL
+	if base == "" {
+		base = "https://r.jina.ai/"
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimRight(base, "/")+"/"+pageURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if f.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+f.APIKey)
+	}
+	client := f.Client
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", errors.New("failed to fetch url: " + resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
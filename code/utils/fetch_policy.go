@@ -0,0 +1,262 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FetchPolicyError 抓取被安全策略拦截，Reason 可直接展示给用户
+type FetchPolicyError struct {
+	Reason string
+}
+
+func (e *FetchPolicyError) Error() string { return e.Reason }
+
+func policyErrorf(format string, args ...interface{}) error {
+	return &FetchPolicyError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// FetchPolicy 限制机器人可以访问的网页，防止通过 /read 或搜索结果访问内网服务 (SSRF)
+type FetchPolicy struct {
+	// 允许的端口，为空表示不限制
+	AllowedPorts []int
+	// 域名白名单 (含子域名)，为空表示不限制
+	AllowDomains []string
+	// 域名黑名单 (含子域名)
+	DenyDomains []string
+	// 允许访问的内网网段，例如公司内部 wiki 所在网段
+	AllowedNets []*net.IPNet
+	// 允许的 Content-Type 前缀，为空时使用 defaultFetchContentTypes
+	ContentTypes []string
+	MaxBodyBytes int64
+	MaxRedirects int
+}
+
+const defaultMaxRedirects = 5
+
+var defaultFetchContentTypes = []string{
+	"text/", "application/xhtml+xml", "application/xml", "application/json",
+	"application/pdf", "application/octet-stream",
+}
+
+// DefaultFetchPolicy 禁止内网地址，只允许常见 web 端口
+func DefaultFetchPolicy() *FetchPolicy {
+	return &FetchPolicy{
+		AllowedPorts: []int{80, 443, 8080, 8443},
+		MaxBodyBytes: defaultMaxPageBytes,
+		MaxRedirects: defaultMaxRedirects,
+	}
+}
+
+// 内网、本机、链路本地、组播及保留地址
+var blockedNets = ParseCIDRs([]string{
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15",
+	"224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+})
+
+// NAT64 前缀中嵌入的 IPv4 地址同样需要检查
+var nat64Net = ParseCIDRs([]string{"64:ff9b::/96"})[0]
+
+// ParseCIDRs 解析网段列表，单个 IP 视为 /32 或 /128，无效项会被忽略
+func ParseCIDRs(values []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			if ip := net.ParseIP(v); ip != nil {
+				bits := 128
+				if ip.To4() != nil {
+					bits = 32
+				}
+				v = fmt.Sprintf("%s/%d", v, bits)
+			}
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			fmt.Printf("⚠️ Ignoring invalid CIDR %q: %v\n", v, err)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBlockedIP(ip net.IP) bool {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	} else if nat64Net.Contains(ip) {
+		ip = net.IP(ip[12:16])
+	}
+	return containsIP(blockedNets, ip)
+}
+
+func (p *FetchPolicy) maxBodyBytes() int64 {
+	if p.MaxBodyBytes <= 0 {
+		return defaultMaxPageBytes
+	}
+	return p.MaxBodyBytes
+}
+
+func (p *FetchPolicy) maxRedirects() int {
+	if p.MaxRedirects <= 0 {
+		return defaultMaxRedirects
+	}
+	return p.MaxRedirects
+}
+
+// CheckIP 拒绝内网地址，AllowedNets 中的网段除外
+func (p *FetchPolicy) CheckIP(ip net.IP) error {
+	if containsIP(p.AllowedNets, ip) {
+		return nil
+	}
+	if isBlockedIP(ip) {
+		return policyErrorf("禁止访问内网或本机地址 (%s)", ip)
+	}
+	return nil
+}
+
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		d = strings.ToLower(strings.Trim(strings.TrimSpace(d), "."))
+		if d != "" && (host == d || strings.HasSuffix(host, "."+d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL 检查协议、端口、域名黑白名单以及字面量 IP，不做 DNS 解析
+func (p *FetchPolicy) CheckURL(u *url.URL) error {
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return policyErrorf("只支持 http/https 链接")
+	}
+	if u.User != nil {
+		return policyErrorf("链接中不能包含账号密码")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return policyErrorf("链接缺少域名")
+	}
+	if len(p.AllowedPorts) > 0 {
+		port := u.Port()
+		if port == "" {
+			port = "80"
+			if scheme == "https" {
+				port = "443"
+			}
+		}
+		n, _ := strconv.Atoi(port)
+		allowed := false
+		for _, ap := range p.AllowedPorts {
+			if ap == n {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return policyErrorf("不允许访问端口 %s", port)
+		}
+	}
+	if matchDomain(host, p.DenyDomains) {
+		return policyErrorf("域名 %s 已被管理员禁止访问", host)
+	}
+	if len(p.AllowDomains) > 0 && !matchDomain(host, p.AllowDomains) {
+		return policyErrorf("域名 %s 不在允许访问的范围内", host)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return p.CheckIP(ip)
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return policyErrorf("禁止访问内网或本机地址 (%s)", host)
+	}
+	return nil
+}
+
+// CheckContentType 只允许文本、网页和 PDF 等可提取的内容
+func (p *FetchPolicy) CheckContentType(mediaType string) error {
+	if mediaType == "" {
+		return nil
+	}
+	prefixes := p.ContentTypes
+	if len(prefixes) == 0 {
+		prefixes = defaultFetchContentTypes
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(mediaType, strings.ToLower(strings.TrimSpace(prefix))) {
+			return nil
+		}
+	}
+	return policyErrorf("不支持读取该类型的内容 (%s)", mediaType)
+}
+
+func (p *FetchPolicy) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > p.maxRedirects() {
+		return policyErrorf("重定向次数过多 (超过 %d 次)", p.maxRedirects())
+	}
+	return p.CheckURL(req.URL)
+}
+
+// dialContext 解析域名后逐个检查 IP，并直接连接检查过的 IP，避免 DNS rebinding
+func (p *FetchPolicy) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		if err := p.CheckIP(a.IP); err != nil {
+			return nil, err
+		}
+	}
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var lastErr error
+	for _, a := range addrs {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(a.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no address found for %s", host)
+	}
+	return nil, lastErr
+}
+
+// NewClient 返回遵守策略的 http.Client，不使用环境变量中的代理，否则无法检查目标地址
+func (p *FetchPolicy) NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         p.dialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+		},
+		CheckRedirect: p.checkRedirect,
+	}
+}
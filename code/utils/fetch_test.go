@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testPdf = "%PDF-1.4\n" +
+	"1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n" +
+	"2 0 obj\n<< /Type /Pages /Kids [3 0 R] /Count 1 >>\nendobj\n" +
+	"3 0 obj\n<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>\nendobj\n" +
+	"4 0 obj\n<< /Length 37 >>\nstream\nBT /F1 12 Tf (Quarterly report) Tj ET\nendstream\nendobj\n" +
+	"trailer\n<< /Root 1 0 R >>\n%%EOF\n"
+
+// localPolicy 允许访问 httptest 所在的本机地址
+func localPolicy() *FetchPolicy {
+	return &FetchPolicy{AllowedNets: ParseCIDRs([]string{"127.0.0.0/8", "::1"})}
+}
+
+func TestBuiltinFetcher(t *testing.T) {
+	article := "<html><head><title>Go 发布说明</title></head><body>" +
+		"<nav><a href='/'>首页</a></nav>" +
+		"<article><h1>Go 1.18</h1><p>Go 1.18 引入了泛型，这是自 Go 1 发布以来语言最大的一次变化，" +
+		"同时带来了模糊测试和工作区模式。</p><p>更多内容见 <a href='/doc/go1.18'>发布说明</a>。</p></article>" +
+		"<footer>版权所有</footer></body></html>"
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		want        []string
+		notWant     []string
+		wantErr     bool
+	}{
+		{
+			name:        "html",
+			path:        "/blog",
+			contentType: "text/html; charset=utf-8",
+			body:        article,
+			want:        []string{"Title: Go 发布说明", "Markdown Content:", "引入了泛型", "/doc/go1.18)"},
+			notWant:     []string{"版权所有"},
+		},
+		{
+			name:        "gbk html",
+			path:        "/gbk",
+			contentType: "text/html; charset=gbk",
+			body:        "<html><body><p>\xc4\xe3\xba\xc3\xa3\xac\xca\xc0\xbd\xe7</p></body></html>",
+			want:        []string{"你好，世界"},
+		},
+		{
+			name:        "plain text",
+			path:        "/notes.txt",
+			contentType: "text/plain",
+			body:        "  line one\nline two  ",
+			want:        []string{"URL Source: ", "Markdown Content:\nline one\nline two"},
+		},
+		{
+			name:        "pdf",
+			path:        "/report.pdf",
+			contentType: "application/octet-stream",
+			body:        testPdf,
+			want:        []string{"Quarterly report"},
+		},
+		{
+			name:        "unsupported",
+			path:        "/image.png",
+			contentType: "image/png",
+			body:        "\x89PNG\r\n",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			f := &BuiltinFetcher{Client: srv.Client(), Policy: localPolicy()}
+			got, err := f.Fetch(context.Background(), srv.URL+tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Fetch() expected error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Fetch() missing %q in:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("Fetch() should not contain %q in:\n%s", w, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBuiltinFetcherLimits(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(strings.Repeat("a", 2048)))
+	}))
+	defer srv.Close()
+	policy := localPolicy()
+	policy.MaxBodyBytes = 1024
+	f := &BuiltinFetcher{Client: srv.Client(), Policy: policy}
+	if _, err := f.Fetch(context.Background(), srv.URL+"/big"); err == nil {
+		t.Error("Fetch() expected error for oversized body")
+	}
+	if _, err := f.Fetch(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("Fetch() expected error for 404")
+	}
+}
+
+func TestJinaFetcher(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/https://example.com/post" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte("Title: Example"))
+	}))
+	defer srv.Close()
+	f := &JinaFetcher{BaseURL: srv.URL + "/", APIKey: "k", Client: srv.Client()}
+	got, err := f.Fetch(context.Background(), "https://example.com/post")
+	if err != nil || got != "Title: Example" {
+		t.Fatalf("Fetch() = %q, %v", got, err)
+	}
+}
+
+func TestNewPageFetcher(t *testing.T) {
+	tests := map[string]string{"": "builtin", "builtin": "builtin", " Jina ": "jina", "other": "builtin"}
+	for backend, want := range tests {
+		if got := NewPageFetcher(backend, "", nil).Name(); got != want {
+			t.Errorf("NewPageFetcher(%q) = %q, want %q", backend, got, want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// mdBlock 一个段落或一个代码块，代码块的 lines 不含开头和结尾的 ``` 行
+type mdBlock struct {
+	lines []string
+	open  string
+	close string
+}
+
+func (b mdBlock) isCode() bool {
+	return b.open != ""
+}
+
+func (b mdBlock) String() string {
+	body := strings.Join(b.lines, "\n")
+	if !b.isCode() {
+		return body
+	}
+	if len(b.lines) == 0 {
+		return b.open + "\n" + b.close
+	}
+	return b.open + "\n" + body + "\n" + b.close
+}
+
+// SplitMarkdown 把 Markdown 按段落和代码块的边界拆成不超过 maxBytes 字节的若干段。
+// 单个段落或代码块过长时按行拆分，被拆开的代码块在每一段中都补齐开头和结尾的 ``` 标记
+func SplitMarkdown(text string, maxBytes int) []string {
+	text = strings.TrimSpace(text)
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return []string{text}
+	}
+	var parts []string
+	var cur strings.Builder
+	for _, block := range markdownBlocks(text) {
+		for _, piece := range splitBlock(block, maxBytes) {
+			if cur.Len() > 0 && cur.Len()+2+len(piece) > maxBytes {
+				parts = append(parts, cur.String())
+				cur.Reset()
+			}
+			if cur.Len() > 0 {
+				cur.WriteString("\n\n")
+			}
+			cur.WriteString(piece)
+		}
+	}
+	if cur.Len() > 0 {
+		parts = append(parts, cur.String())
+	}
+	return parts
+}
+
+// markdownBlocks 按空行切分段落，代码块内的空行不切分
+func markdownBlocks(text string) []mdBlock {
+	var blocks []mdBlock
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, mdBlock{lines: paragraph})
+			paragraph = nil
+		}
+	}
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		indent, marker := fenceMarker(line)
+		if marker == "" {
+			if strings.TrimSpace(line) == "" {
+				flush()
+			} else {
+				paragraph = append(paragraph, line)
+			}
+			continue
+		}
+		flush()
+		block := mdBlock{open: line, close: indent + marker}
+		for i++; i < len(lines); i++ {
+			if isFenceClose(lines[i], marker) {
+				block.close = lines[i]
+				break
+			}
+			block.lines = append(block.lines, lines[i])
+		}
+		blocks = append(blocks, block)
+	}
+	flush()
+	return blocks
+}
+
+// fenceMarker 返回代码块开头行的缩进和 ``` 或 ~~~ 标记，不是代码块开头时标记为空
+func fenceMarker(line string) (string, string) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if len(line)-len(trimmed) > 3 || len(trimmed) < 3 {
+		return "", ""
+	}
+	c := trimmed[0]
+	if c != '`' && c != '~' {
+		return "", ""
+	}
+	n := 0
+	for n < len(trimmed) && trimmed[n] == c {
+		n++
+	}
+	if n < 3 || (c == '`' && strings.ContainsRune(trimmed[n:], '`')) {
+		return "", ""
+	}
+	return line[:len(line)-len(trimmed)], trimmed[:n]
+}
+
+func isFenceClose(line string, marker string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, marker) &&
+		strings.Trim(trimmed, marker[:1]) == ""
+}
+
+func splitBlock(block mdBlock, maxBytes int) []string {
+	if text := block.String(); len(text) <= maxBytes {
+		return []string{text}
+	}
+	if !block.isCode() {
+		return packLines(block.lines, maxBytes)
+	}
+	budget := maxBytes - len(block.open) - len(block.close) - 2
+	if budget < 1 {
+		budget = 1
+	}
+	var pieces []string
+	for _, body := range packLines(block.lines, budget) {
+		pieces = append(pieces, block.open+"\n"+body+"\n"+block.close)
+	}
+	return pieces
+}
+
+// packLines 把多行合并成不超过 budget 字节的若干段，单行过长时按字符拆开
+func packLines(lines []string, budget int) []string {
+	var pieces []string
+	var cur strings.Builder
+	started := false
+	for _, line := range lines {
+		for _, seg := range splitRunes(line, budget) {
+			if started && cur.Len()+1+len(seg) > budget {
+				pieces = append(pieces, cur.String())
+				cur.Reset()
+				started = false
+			}
+			if started {
+				cur.WriteByte('\n')
+			}
+			cur.WriteString(seg)
+			started = true
+		}
+	}
+	if started {
+		pieces = append(pieces, cur.String())
+	}
+	return pieces
+}
+
+// splitRunes 按字符边界把 s 拆成不超过 budget 字节的若干段，每段至少一个字符
+func splitRunes(s string, budget int) []string {
+	if len(s) <= budget {
+		return []string{s}
+	}
+	var segs []string
+	for len(s) > 0 {
+		end := 0
+		for end < len(s) {
+			_, size := utf8.DecodeRuneInString(s[end:])
+			if end > 0 && end+size > budget {
+				break
+			}
+			end += size
+		}
+		segs = append(segs, s[:end])
+		s = s[end:]
+	}
+	return segs
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitMarkdown(t *testing.T) {
+	code := "```go\nfunc a() {}\n\nfunc b() {}\nfunc c() {}\n```"
+	tests := []struct {
+		name     string
+		text     string
+		maxBytes int
+		want     []string
+	}{
+		{"short text", "  你好\n\n世界  ", 100, []string{"你好\n\n世界"}},
+		{"no limit", "aaaa\n\nbbbb", 0, []string{"aaaa\n\nbbbb"}},
+		{"paragraphs", "aaaa\n\nbbbb\n\n\ncccc", 10, []string{"aaaa\n\nbbbb", "cccc"}},
+		{"long paragraph by lines", "aaaa\nbbbb\ncccc", 9, []string{"aaaa\nbbbb", "cccc"}},
+		{"code block kept whole", "intro\n\n" + code, 50, []string{"intro", code}},
+		{"code block split keeps fences", code, 36, []string{
+			"```go\nfunc a() {}\n\nfunc b() {}\n```",
+			"```go\nfunc c() {}\n```",
+		}},
+		{"unclosed code block", "```\naaaa\nbbbb", 12, []string{"```\naaaa\n```", "```\nbbbb\n```"}},
+		{"tilde fence with backticks inside", "~~~\n```\n~~~\n\nxx", 12, []string{"~~~\n```\n~~~", "xx"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitMarkdown(tt.text, tt.maxBytes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitMarkdown() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitMarkdownLongLine(t *testing.T) {
+	text := strings.Repeat("中文", 10)
+	parts := SplitMarkdown(text, 10)
+	if strings.Join(parts, "") != text {
+		t.Fatalf("parts should join back to the original text: %q", parts)
+	}
+	for _, p := range parts {
+		if len(p) > 10 || !utf8.ValidString(p) {
+			t.Errorf("part %q is too long or not valid UTF-8", p)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"start-feishubot/utils/vector"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChunkText 按段落把文本切成不超过 maxRunes 个字符的片段，过长的段落按句子或字符切开
+func ChunkText(text string, maxRunes int) []string {
+	if maxRunes <= 0 {
+		maxRunes = 800
+	}
+	var chunks []string
+	var cur strings.Builder
+	curRunes := 0
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		cur.Reset()
+		curRunes = 0
+	}
+	for _, para := range strings.Split(text, "\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		for _, piece := range splitLongParagraph(para, maxRunes) {
+			n := utf8.RuneCountInString(piece)
+			if curRunes > 0 && curRunes+n+1 > maxRunes {
+				flush()
+			}
+			if curRunes > 0 {
+				cur.WriteString("\n")
+				curRunes++
+			}
+			cur.WriteString(piece)
+			curRunes += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitLongParagraph 优先在句末标点处切分，找不到时按字符数硬切
+func splitLongParagraph(para string, maxRunes int) []string {
+	runes := []rune(para)
+	var out []string
+	for len(runes) > maxRunes {
+		cut := maxRunes
+		for i := maxRunes - 1; i > maxRunes/2; i-- {
+			if strings.ContainsRune("。！？!?；;.", runes[i]) {
+				cut = i + 1
+				break
+			}
+		}
+		out = append(out, strings.TrimSpace(string(runes[:cut])))
+		runes = runes[cut:]
+	}
+	if s := strings.TrimSpace(string(runes)); s != "" {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Tokenize 英文按单词切分并转小写，中文使用相邻两字组合，单个汉字作为一个词
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	var han []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+	flushHan := func() {
+		if len(han) == 1 {
+			tokens = append(tokens, string(han))
+		}
+		for i := 0; i+1 < len(han); i++ {
+			tokens = append(tokens, string(han[i:i+2]))
+		}
+		han = han[:0]
+	}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushHan()
+		}
+	}
+	flushWord()
+	flushHan()
+	return tokens
+}
+
+// EstimateTokens 粗略估算 token 数：汉字按 1 个计算，其余按 4 字节 1 个计算
+func EstimateTokens(text string) int {
+	han, other := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) {
+			han++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return han + (other+3)/4
+}
+
+// BM25 基于 Okapi BM25 为一组文档打分
+type BM25 struct {
+	docs   []map[string]int
+	lens   []int
+	avgLen float64
+	df     map[string]int
+	K1     float64
+	B      float64
+}
+
+func NewBM25(docs []string) *BM25 {
+	bm := &BM25{df: map[string]int{}, K1: 1.2, B: 0.75}
+	total := 0
+	for _, d := range docs {
+		tf := map[string]int{}
+		tokens := Tokenize(d)
+		for _, t := range tokens {
+			tf[t]++
+		}
+		for t := range tf {
+			bm.df[t]++
+		}
+		bm.docs = append(bm.docs, tf)
+		bm.lens = append(bm.lens, len(tokens))
+		total += len(tokens)
+	}
+	if len(docs) > 0 {
+		bm.avgLen = float64(total) / float64(len(docs))
+	}
+	return bm
+}
+
+// Score 计算查询与第 i 个文档的相关度，查询中重复的词只计算一次
+func (bm *BM25) Score(query string, i int) float64 {
+	if i < 0 || i >= len(bm.docs) || bm.avgLen == 0 {
+		return 0
+	}
+	n := float64(len(bm.docs))
+	seen := map[string]bool{}
+	score := 0.0
+	for _, t := range Tokenize(query) {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		tf := float64(bm.docs[i][t])
+		if tf == 0 {
+			continue
+		}
+		df := float64(bm.df[t])
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		norm := tf * (bm.K1 + 1) / (tf + bm.K1*(1-bm.B+bm.B*float64(bm.lens[i])/bm.avgLen))
+		score += idf * norm
+	}
+	return score
+}
+
+// canonicalURL 用于判断两个链接是否指向同一页面
+func canonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return strings.TrimSpace(raw)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	u.Path = strings.TrimRight(u.Path, "/")
+	return u.String()
+}
+
+// DedupeSources 去掉多个查询返回的重复链接，保留第一次出现的结果
+func DedupeSources(sources []WebSource) []WebSource {
+	seen := map[string]bool{}
+	var out []WebSource
+	for _, s := range sources {
+		key := canonicalURL(s.URL)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// Embedder 把文本转换为向量，用于语义重排
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// SelectOptions 控制检索片段的挑选
+type SelectOptions struct {
+	// 用户问题及检索关键词，共同用于打分
+	Queries []string
+	// 组装后的上下文总 token 预算
+	TokenBudget int
+	// 单个片段的最大字符数
+	ChunkRunes int
+	// 每个来源最多保留的片段数，避免被单个页面占满
+	MaxChunksPerSource int
+	// 可选，配置后与 BM25 分数加权合并
+	Embedder Embedder
+	// 语义分数的权重 (0-1)
+	EmbedWeight float64
+}
+
+type scoredChunk struct {
+	source int
+	pos    int
+	text   string
+	score  float64
+	tokens int
+}
+
+// maxRerankChunks 只对 BM25 排名靠前的片段计算向量，控制调用成本
+const maxRerankChunks = 48
+
+// SelectSourceContent 把每个来源的正文切片并按相关度挑选片段，在 token 预算内组装上下文，
+// 没有选中任何片段的来源会被丢弃
+func SelectSourceContent(ctx context.Context, sources []WebSource, opts SelectOptions) []WebSource {
+	if opts.TokenBudget <= 0 {
+		opts.TokenBudget = 6000
+	}
+	if opts.MaxChunksPerSource <= 0 {
+		opts.MaxChunksPerSource = 3
+	}
+	if opts.EmbedWeight <= 0 || opts.EmbedWeight > 1 {
+		opts.EmbedWeight = 0.5
+	}
+	query := strings.Join(opts.Queries, " ")
+
+	var chunks []*scoredChunk
+	var texts []string
+	for si, s := range sources {
+		content := s.Content
+		if strings.TrimSpace(content) == "" {
+			content = s.Snippet
+		}
+		for pi, text := range ChunkText(content, opts.ChunkRunes) {
+			chunks = append(chunks, &scoredChunk{source: si, pos: pi, text: text, tokens: EstimateTokens(text)})
+			texts = append(texts, text)
+		}
+	}
+	bm := NewBM25(texts)
+	maxScore := 0.0
+	for i, c := range chunks {
+		c.score = bm.Score(query, i)
+		if c.score > maxScore {
+			maxScore = c.score
+		}
+	}
+	if maxScore > 0 {
+		for _, c := range chunks {
+			c.score /= maxScore
+		}
+	}
+	ranked := append([]*scoredChunk(nil), chunks...)
+	sortChunks(ranked)
+
+	if opts.Embedder != nil && len(ranked) > 0 {
+		if err := rerankChunks(ctx, opts.Embedder, query, ranked, opts.EmbedWeight); err != nil {
+			fmt.Printf("⚠️ Embedding rerank failed, using BM25 only: %v\n", err)
+		} else {
+			sortChunks(ranked)
+		}
+	}
+
+	// 贪心挑选：先选相关片段，没有命中的来源保留开头一段作为摘要
+	budget := opts.TokenBudget
+	perSource := map[int]int{}
+	selected := map[*scoredChunk]bool{}
+	take := func(c *scoredChunk) {
+		overhead := 0
+		if perSource[c.source] == 0 {
+			overhead = EstimateTokens(sources[c.source].Title+sources[c.source].URL) + 8
+		}
+		if perSource[c.source] >= opts.MaxChunksPerSource || c.tokens+overhead > budget {
+			return
+		}
+		budget -= c.tokens + overhead
+		perSource[c.source]++
+		selected[c] = true
+	}
+	for _, c := range ranked {
+		if c.score > 0 {
+			take(c)
+		}
+	}
+	for _, c := range chunks {
+		if c.pos == 0 && perSource[c.source] == 0 {
+			take(c)
+		}
+	}
+
+	var out []WebSource
+	for si, s := range sources {
+		var parts []string
+		last := -1
+		for _, c := range chunks {
+			if c.source != si || !selected[c] {
+				continue
+			}
+			if last >= 0 && c.pos != last+1 {
+				parts = append(parts, "……")
+			}
+			parts = append(parts, c.text)
+			last = c.pos
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		s.Content = strings.Join(parts, "\n")
+		out = append(out, s)
+	}
+	return out
+}
+
+func sortChunks(chunks []*scoredChunk) {
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return chunks[i].score > chunks[j].score
+	})
+}
+
+func rerankChunks(ctx context.Context, embedder Embedder, query string, ranked []*scoredChunk, weight float64) error {
+	n := len(ranked)
+	if n > maxRerankChunks {
+		n = maxRerankChunks
+	}
+	texts := []string{query}
+	for _, c := range ranked[:n] {
+		texts = append(texts, c.text)
+	}
+	vectors, err := embedder.Embed(ctx, texts)
+	if err != nil {
+		return err
+	}
+	if len(vectors) != len(texts) {
+		return fmt.Errorf("embedding count mismatch: got %d, want %d", len(vectors), len(texts))
+	}
+	for i, c := range ranked[:n] {
+		c.score = (1-weight)*c.score + weight*vector.Cosine(vectors[0], vectors[i+1])
+	}
+	// 未参与重排的片段只保留 BM25 分数的剩余权重，排在重排结果之后
+	for _, c := range ranked[n:] {
+		c.score *= 1 - weight
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunkText(t *testing.T) {
+	text := "第一段。\n\n第二段内容比较长。\n" + strings.Repeat("很长的句子。", 30)
+	chunks := ChunkText(text, 50)
+	if len(chunks) < 3 {
+		t.Fatalf("ChunkText() = %d chunks, want >= 3", len(chunks))
+	}
+	for _, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 50 {
+			t.Errorf("chunk has %d runes, want <= 50: %q", n, c)
+		}
+		if !utf8.ValidString(c) {
+			t.Errorf("chunk is not valid UTF-8: %q", c)
+		}
+	}
+	if !strings.HasPrefix(chunks[0], "第一段。\n第二段") {
+		t.Errorf("short paragraphs should be merged, got %q", chunks[0])
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Go语言的泛型, Generics!")
+	want := []string{"go", "语言", "言的", "的泛", "泛型", "generics"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %v, want %v", got, want)
+	}
+}
+
+func TestTrimTo(t *testing.T) {
+	if got := trimTo("你好世界", 4); got != "你" {
+		t.Errorf("trimTo() = %q, want %q", got, "你")
+	}
+}
+
+func TestBM25(t *testing.T) {
+	bm := NewBM25([]string{
+		"网站导航 首页 登录 注册",
+		"Go 1.18 正式支持泛型，泛型让函数可以处理多种类型",
+		"模糊测试也在 Go 1.18 中加入",
+	})
+	if bm.Score("Go 泛型", 1) <= bm.Score("Go 泛型", 2) || bm.Score("Go 泛型", 0) != 0 {
+		t.Errorf("BM25 scores = %v %v %v", bm.Score("Go 泛型", 0), bm.Score("Go 泛型", 1), bm.Score("Go 泛型", 2))
+	}
+}
+
+func TestDedupeSources(t *testing.T) {
+	got := DedupeSources([]WebSource{
+		{URL: "https://go.dev/doc/"},
+		{URL: "http://www.go.dev/doc#intro"},
+		{URL: "https://go.dev/blog"},
+	})
+	if len(got) != 2 || got[1].URL != "https://go.dev/blog" {
+		t.Errorf("DedupeSources() = %+v", got)
+	}
+}
+
+func TestSelectSourceContent(t *testing.T) {
+	boilerplate := strings.Repeat("订阅我们的新闻邮件，关注社交媒体账号。", 10)
+	sources := []WebSource{
+		{Title: "发布说明", URL: "https://go.dev/doc/go1.18",
+			Content: boilerplate + "\n\nGo 1.18 引入了泛型，支持类型参数。\n\n" + boilerplate},
+		{Title: "无关页面", URL: "https://example.com", Content: "今天天气很好。"},
+	}
+	got := SelectSourceContent(context.Background(), sources, SelectOptions{
+		Queries:     []string{"Go 泛型"},
+		TokenBudget: 200,
+		ChunkRunes:  40,
+	})
+	if len(got) == 0 || !strings.Contains(got[0].Content, "引入了泛型") {
+		t.Fatalf("SelectSourceContent() = %+v", got)
+	}
+	total := 0
+	for _, s := range got {
+		total += EstimateTokens(s.Content)
+	}
+	if total > 200 {
+		t.Errorf("selected %d tokens, want <= 200", total)
+	}
+}
+
+type fakeEmbedder struct {
+	vectors map[string][]float32
+	err     error
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = []float32{0, 1}
+		for key, v := range e.vectors {
+			if strings.Contains(text, key) {
+				out[i] = v
+			}
+		}
+	}
+	return out, nil
+}
+
+func TestSelectSourceContentRerank(t *testing.T) {
+	sources := []WebSource{
+		{Title: "a", URL: "https://a.com", Content: "苹果公司发布新手机"},
+		{Title: "b", URL: "https://b.com", Content: "iPhone 新机型售价公布"},
+	}
+	opts := SelectOptions{Queries: []string{"苹果 新手机 价格"}, TokenBudget: 30, MaxChunksPerSource: 1}
+	// 预算只够一个来源，BM25 只匹配第一个来源
+	if got := SelectSourceContent(context.Background(), sources, opts); len(got) != 1 || got[0].URL != "https://a.com" {
+		t.Fatalf("BM25 only = %+v", got)
+	}
+	// 向量认为第二个来源与问题更接近
+	opts.Embedder = &fakeEmbedder{vectors: map[string][]float32{"价格": {1, 0}, "售价": {1, 0}}}
+	opts.EmbedWeight = 0.9
+	if got := SelectSourceContent(context.Background(), sources, opts); len(got) != 1 || got[0].URL != "https://b.com" {
+		t.Errorf("reranked = %+v", got)
+	}
+	// 向量接口失败时退回 BM25
+	opts.Embedder = &fakeEmbedder{err: errors.New("boom")}
+	if got := SelectSourceContent(context.Background(), sources, opts); len(got) != 1 || got[0].URL != "https://a.com" {
+		t.Errorf("fallback = %+v", got)
+	}
+}
@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchProvider 搜索引擎接口，各实现的 BaseURL 与 Client 均可替换，便于对接自建代理和测试
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string, topK int) ([]SearchResult, error)
+}
+
+// SearchProviderConfig 创建搜索引擎所需的凭据，未配置凭据的引擎会被跳过
+type SearchProviderConfig struct {
+	GoogleApiKey string
+	GoogleCSEId  string
+	BingApiKey   string
+	BraveApiKey  string
+	SearXNGURL   string
+	TavilyApiKey string
+	Client       *http.Client
+}
+
+// NewSearchProvider 按名称创建搜索引擎: google, duckduckgo, bing, brave, searxng, tavily
+func NewSearchProvider(name string, cfg SearchProviderConfig) (SearchProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "google":
+		if cfg.GoogleApiKey == "" || cfg.GoogleCSEId == "" {
+			return nil, errors.New("google api key or cse id missing")
+		}
+		return &GoogleCSEProvider{APIKey: cfg.GoogleApiKey, CSEId: cfg.GoogleCSEId, Client: cfg.Client}, nil
+	case "duckduckgo", "ddg":
+		return &DuckDuckGoProvider{Client: cfg.Client}, nil
+	case "bing":
+		if cfg.BingApiKey == "" {
+			return nil, errors.New("bing api key missing")
+		}
+		return &BingProvider{APIKey: cfg.BingApiKey, Client: cfg.Client}, nil
+	case "brave":
+		if cfg.BraveApiKey == "" {
+			return nil, errors.New("brave api key missing")
+		}
+		return &BraveProvider{APIKey: cfg.BraveApiKey, Client: cfg.Client}, nil
+	case "searxng":
+		if cfg.SearXNGURL == "" {
+			return nil, errors.New("searxng url missing")
+		}
+		return &SearXNGProvider{BaseURL: cfg.SearXNGURL, Client: cfg.Client}, nil
+	case "tavily":
+		if cfg.TavilyApiKey == "" {
+			return nil, errors.New("tavily api key missing")
+		}
+		return &TavilyProvider{APIKey: cfg.TavilyApiKey, Client: cfg.Client}, nil
+	default:
+		return nil, fmt.Errorf("unknown search provider: %s", name)
+	}
+}
+
+// NewSearchProviders 按配置顺序创建搜索引擎，无法创建的引擎打印原因后跳过
+func NewSearchProviders(names []string, cfg SearchProviderConfig) []SearchProvider {
+	var providers []SearchProvider
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			continue
+		}
+		provider, err := NewSearchProvider(name, cfg)
+		if err != nil {
+			fmt.Printf("⚠️ [Search] Skip provider %s: %v\n", name, err)
+			continue
+		}
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// ParseSearchQuotas 解析 name:limit 形式的每日配额，如 [google:100, brave:2000]
+func ParseSearchQuotas(items []string) map[string]int {
+	quotas := make(map[string]int)
+	for _, item := range items {
+		parts := strings.SplitN(item, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || limit <= 0 {
+			continue
+		}
+		quotas[strings.ToLower(strings.TrimSpace(parts[0]))] = limit
+	}
+	return quotas
+}
+
+// searchQuota 每日请求次数上限，按本地日期重置
+type searchQuota struct {
+	mu    sync.Mutex
+	limit int
+	day   string
+	used  int
+}
+
+func (q *searchQuota) take(now time.Time) bool {
+	if q == nil || q.limit <= 0 {
+		return true
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	day := now.Format("2006-01-02")
+	if q.day != day {
+		q.day = day
+		q.used = 0
+	}
+	if q.used >= q.limit {
+		return false
+	}
+	q.used++
+	return true
+}
+
+type searchChainEntry struct {
+	provider SearchProvider
+	quota    *searchQuota
+}
+
+// SearchChain 按顺序尝试各个搜索引擎，失败、无结果或配额用尽时回退到下一个
+type SearchChain struct {
+	entries []searchChainEntry
+	now     func() time.Time
+}
+
+var _ SearchProvider = (*SearchChain)(nil)
+
+func NewSearchChain(providers []SearchProvider, quotas map[string]int) *SearchChain {
+	chain := &SearchChain{now: time.Now}
+	for _, provider := range providers {
+		entry := searchChainEntry{provider: provider}
+		if limit := quotas[provider.Name()]; limit > 0 {
+			entry.quota = &searchQuota{limit: limit}
+		}
+		chain.entries = append(chain.entries, entry)
+	}
+	return chain
+}
+
+func (c *SearchChain) Name() string {
+	var names []string
+	for _, entry := range c.entries {
+		names = append(names, entry.provider.Name())
+	}
+	return strings.Join(names, ">")
+}
+
+func (c *SearchChain) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	results, _, err := c.SearchFrom(ctx, query, topK)
+	return results, err
+}
+
+// SearchFrom 与 Search 相同，同时返回实际给出结果的搜索引擎名称
+func (c *SearchChain) SearchFrom(ctx context.Context, query string, topK int) ([]SearchResult, string, error) {
+	if len(c.entries) == 0 {
+		return nil, "", errors.New("no search provider configured")
+	}
+	var errs []string
+	for _, entry := range c.entries {
+		name := entry.provider.Name()
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err().Error())
+			break
+		}
+		if !entry.quota.take(c.now()) {
+			fmt.Printf("⚠️ [Search] %s daily quota exhausted, falling back\n", name)
+			errs = append(errs, name+": quota exhausted")
+			continue
+		}
+		results, err := entry.provider.Search(ctx, query, topK)
+		if err == nil && len(results) == 0 {
+			err = errors.New("no results")
+		}
+		if err != nil {
+			fmt.Printf("⚠️ [Search] %s failed, falling back: %v\n", name, err)
+			errs = append(errs, name+": "+err.Error())
+			continue
+		}
+		fmt.Printf("🔍 [Search] %s returned %d results\n", name, len(results))
+		return results, name, nil
+	}
+	return nil, "", errors.New("all search providers failed: " + strings.Join(errs, "; "))
+}
+
+func searchClient(client *http.Client) *http.Client {
+	if client != nil {
+		return client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func searchBaseURL(baseURL string, defaultURL string) string {
+	if baseURL != "" {
+		return baseURL
+	}
+	return defaultURL
+}
+
+// doSearchRequest 发送请求并把 JSON 响应解析到 out
+func doSearchRequest(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := searchClient(client).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %s: %s", resp.Status, trimTo(string(body), 200))
+	}
+	return json.Unmarshal(body, out)
+}
+
+func searchTopK(topK int) int {
+	if topK <= 0 {
+		return 3
+	}
+	return topK
+}
+
+// appendSearchResult 清理标题与摘要中的 HTML，忽略非 http(s) 链接
+func appendSearchResult(results []SearchResult, title, link, snippet string) []SearchResult {
+	if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
+		return results
+	}
+	return append(results, SearchResult{
+		Title:   strings.TrimSpace(html.UnescapeString(stripTags(title))),
+		URL:     link,
+		Snippet: strings.TrimSpace(html.UnescapeString(stripTags(snippet))),
+	})
+}
+
+func searchLanguage(query string) string {
+	if containsChinese(query) {
+		return "zh-CN"
+	}
+	return "en-US"
+}
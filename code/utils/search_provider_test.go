@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newSearchServer(t *testing.T, check func(r *http.Request), body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSearchProviders(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		check    func(t *testing.T, r *http.Request)
+		provider func(baseURL string, client *http.Client) SearchProvider
+	}{
+		{
+			name: "google",
+			body: `{"items":[{"title":"Go","link":"https://go.dev","snippet":"The Go <b>language</b>"}]}`,
+			check: func(t *testing.T, r *http.Request) {
+				if r.URL.Query().Get("key") != "k" || r.URL.Query().Get("cx") != "cx" {
+					t.Errorf("google query = %s", r.URL.RawQuery)
+				}
+			},
+			provider: func(baseURL string, client *http.Client) SearchProvider {
+				return &GoogleCSEProvider{APIKey: "k", CSEId: "cx", BaseURL: baseURL, Client: client}
+			},
+		},
+		{
+			name: "duckduckgo",
+			body: `<a rel="nofollow" class="result__a" href="//duckduckgo.com/l/?uddg=https%3A%2F%2Fgo.dev&amp;rut=x">Go</a>` +
+				`<a class="result__a" href="https://duckduckgo.com/y.js?ad=1">Ad</a>`,
+			check: func(t *testing.T, r *http.Request) {
+				if r.URL.Query().Get("q") != "golang" {
+					t.Errorf("duckduckgo query = %s", r.URL.RawQuery)
+				}
+			},
+			provider: func(baseURL string, client *http.Client) SearchProvider {
+				return &DuckDuckGoProvider{BaseURL: baseURL, Client: client}
+			},
+		},
+		{
+			name: "bing",
+			body: `{"webPages":{"value":[{"name":"Go","url":"https://go.dev","snippet":"The Go language"}]}}`,
+			check: func(t *testing.T, r *http.Request) {
+				if r.Header.Get("Ocp-Apim-Subscription-Key") != "k" {
+					t.Errorf("bing key header missing")
+				}
+			},
+			provider: func(baseURL string, client *http.Client) SearchProvider {
+				return &BingProvider{APIKey: "k", BaseURL: baseURL, Client: client}
+			},
+		},
+		{
+			name: "brave",
+			body: `{"web":{"results":[{"title":"Go","url":"https://go.dev","description":"The <strong>Go</strong> language"}]}}`,
+			check: func(t *testing.T, r *http.Request) {
+				if r.Header.Get("X-Subscription-Token") != "k" {
+					t.Errorf("brave token header missing")
+				}
+			},
+			provider: func(baseURL string, client *http.Client) SearchProvider {
+				return &BraveProvider{APIKey: "k", BaseURL: baseURL, Client: client}
+			},
+		},
+		{
+			name: "searxng",
+			body: `{"results":[{"title":"Go","url":"https://go.dev","content":"The Go language"}]}`,
+			check: func(t *testing.T, r *http.Request) {
+				if r.URL.Path != "/search" || r.URL.Query().Get("format") != "json" {
+					t.Errorf("searxng request = %s?%s", r.URL.Path, r.URL.RawQuery)
+				}
+			},
+			provider: func(baseURL string, client *http.Client) SearchProvider {
+				return &SearXNGProvider{BaseURL: baseURL + "/", Client: client}
+			},
+		},
+		{
+			name: "tavily",
+			body: `{"results":[{"title":"Go","url":"https://go.dev","content":"The Go language"}]}`,
+			check: func(t *testing.T, r *http.Request) {
+				if r.Method != "POST" || r.Header.Get("Authorization") != "Bearer k" {
+					t.Errorf("tavily request = %s %s", r.Method, r.Header.Get("Authorization"))
+				}
+				body, _ := ioutil.ReadAll(r.Body)
+				var payload map[string]interface{}
+				json.Unmarshal(body, &payload)
+				if payload["query"] != "golang" {
+					t.Errorf("tavily payload = %s", body)
+				}
+			},
+			provider: func(baseURL string, client *http.Client) SearchProvider {
+				return &TavilyProvider{APIKey: "k", BaseURL: baseURL, Client: client}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newSearchServer(t, func(r *http.Request) { tt.check(t, r) }, tt.body)
+			provider := tt.provider(srv.URL, srv.Client())
+			if provider.Name() != tt.name {
+				t.Errorf("Name() = %q, want %q", provider.Name(), tt.name)
+			}
+			results, err := provider.Search(context.Background(), "golang", 3)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(results) != 1 || results[0].URL != "https://go.dev" || results[0].Title != "Go" {
+				t.Fatalf("Search() = %+v", results)
+			}
+			if results[0].Snippet != "" && results[0].Snippet != "The Go language" {
+				t.Errorf("Snippet = %q", results[0].Snippet)
+			}
+		})
+	}
+}
+
+func TestSearchProviderHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+	provider := &BraveProvider{APIKey: "k", BaseURL: srv.URL, Client: srv.Client()}
+	if _, err := provider.Search(context.Background(), "golang", 3); err == nil {
+		t.Fatal("Search() expected error on 429")
+	}
+}
+
+type fakeSearchProvider struct {
+	name    string
+	results []SearchResult
+	err     error
+	calls   int
+}
+
+func (p *fakeSearchProvider) Name() string { return p.name }
+
+func (p *fakeSearchProvider) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	p.calls++
+	return p.results, p.err
+}
+
+func TestSearchChainFallback(t *testing.T) {
+	ok := []SearchResult{{Title: "Go", URL: "https://go.dev"}}
+	failing := &fakeSearchProvider{name: "google", err: errors.New("boom")}
+	empty := &fakeSearchProvider{name: "bing"}
+	working := &fakeSearchProvider{name: "duckduckgo", results: ok}
+	chain := NewSearchChain([]SearchProvider{failing, empty, working}, nil)
+
+	results, err := chain.Search(context.Background(), "golang", 3)
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if !reflect.DeepEqual(results, ok) {
+		t.Errorf("Search() = %+v, want %+v", results, ok)
+	}
+	if failing.calls != 1 || empty.calls != 1 || working.calls != 1 {
+		t.Errorf("calls = %d/%d/%d, want 1/1/1", failing.calls, empty.calls, working.calls)
+	}
+	if _, name, _ := chain.SearchFrom(context.Background(), "golang", 3); name != "duckduckgo" {
+		t.Errorf("SearchFrom() provider = %q, want duckduckgo", name)
+	}
+	if chain.Name() != "google>bing>duckduckgo" {
+		t.Errorf("Name() = %q", chain.Name())
+	}
+}
+
+func TestSearchChainQuota(t *testing.T) {
+	ok := []SearchResult{{Title: "Go", URL: "https://go.dev"}}
+	limited := &fakeSearchProvider{name: "google", results: ok}
+	backup := &fakeSearchProvider{name: "duckduckgo", results: ok}
+	chain := NewSearchChain([]SearchProvider{limited, backup}, map[string]int{"google": 2})
+	day := time.Date(2024, 5, 1, 10, 0, 0, 0, time.Local)
+	chain.now = func() time.Time { return day }
+
+	for i := 0; i < 3; i++ {
+		if _, err := chain.Search(context.Background(), "golang", 3); err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+	}
+	if limited.calls != 2 || backup.calls != 1 {
+		t.Errorf("calls = %d/%d, want 2/1", limited.calls, backup.calls)
+	}
+
+	// 第二天配额重置
+	day = day.Add(24 * time.Hour)
+	chain.Search(context.Background(), "golang", 3)
+	if limited.calls != 3 {
+		t.Errorf("quota not reset on next day, calls = %d", limited.calls)
+	}
+}
+
+func TestSearchChainAllFailed(t *testing.T) {
+	chain := NewSearchChain([]SearchProvider{
+		&fakeSearchProvider{name: "google", err: errors.New("boom")},
+	}, nil)
+	if _, err := chain.Search(context.Background(), "golang", 3); err == nil {
+		t.Fatal("Search() expected error when all providers fail")
+	}
+}
+
+func TestParseSearchQuotas(t *testing.T) {
+	got := ParseSearchQuotas([]string{"google:100", " Brave : 2000 ", "bing", "tavily:x", "searxng:0"})
+	want := map[string]int{"google": 100, "brave": 2000}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSearchQuotas() = %v, want %v", got, want)
+	}
+}
+
+func TestNewSearchProviders(t *testing.T) {
+	providers := NewSearchProviders([]string{"google", "duckduckgo", "tavily", "unknown"},
+		SearchProviderConfig{TavilyApiKey: "k"})
+	var names []string
+	for _, p := range providers {
+		names = append(names, p.Name())
+	}
+	if want := []string{"duckduckgo", "tavily"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("NewSearchProviders() = %v, want %v", names, want)
+	}
+}
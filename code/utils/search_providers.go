@@ -0,0 +1,364 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GoogleCSEProvider Google Custom Search JSON API
+type GoogleCSEProvider struct {
+	APIKey  string
+	CSEId   string
+	BaseURL string
+	Client  *http.Client
+}
+
+func (p *GoogleCSEProvider) Name() string { return "google" }
+
+func (p *GoogleCSEProvider) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("empty query")
+	}
+	if p.APIKey == "" || p.CSEId == "" {
+		return nil, errors.New("google api key or cse id missing")
+	}
+	topK = searchTopK(topK)
+	u, err := url.Parse(searchBaseURL(p.BaseURL, "https://www.googleapis.com/customsearch/v1"))
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("key", p.APIKey)
+	q.Set("cx", p.CSEId)
+	q.Set("q", query)
+	// increase candidates to improve recall
+	want := topK * 3
+	if want > 10 {
+		want = 10
+	}
+	q.Set("num", strconv.Itoa(want))
+	// language/region hints
+	if containsChinese(query) {
+		q.Set("hl", "zh-CN")
+		q.Set("gl", "CN")
+		q.Set("lr", "lang_zh-CN")
+	} else {
+		q.Set("hl", "en")
+		q.Set("gl", "US")
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var data googleSearchResponse
+	if err := doSearchRequest(p.Client, req, &data); err != nil {
+		return nil, fmt.Errorf("google search failed: %w", err)
+	}
+	var out []SearchResult
+	for _, it := range data.Items {
+		out = appendSearchResult(out, it.Title, it.Link, it.Snippet)
+		if len(out) >= topK {
+			break
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no results")
+	}
+	return out, nil
+}
+
+// DuckDuckGoProvider 解析 DuckDuckGo 的 HTML 结果页，无需凭据
+type DuckDuckGoProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func (p *DuckDuckGoProvider) Name() string { return "duckduckgo" }
+
+// Extract <a class="result__a" href="...">Title</a>
+// Be tolerant to attribute order and both quote styles
+var duckAnchorRe = regexp.MustCompile(`<a[^>]+class=["'][^"']*result__a[^"']*["'][^>]+href=["']([^"']+)["'][^>]*>(.*?)</a>`)
+
+func (p *DuckDuckGoProvider) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	q := strings.TrimSpace(query)
+	if q == "" {
+		return nil, errors.New("empty query")
+	}
+	topK = searchTopK(topK)
+	duckURL := searchBaseURL(p.BaseURL, "https://duckduckgo.com/html/") + "?q=" + url.QueryEscape(q)
+	fmt.Printf("[WebSearch] Searching URL: %s\n", duckURL)
+	req, err := http.NewRequestWithContext(ctx, "GET", duckURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0 Safari/537.36")
+	resp, err := searchClient(p.Client).Do(req)
+	if err != nil {
+		fmt.Printf("[WebSearch] HTTP request failed: %v\n", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		fmt.Printf("[WebSearch] HTTP status failed: %s\n", resp.Status)
+		return nil, errors.New("duckduckgo html failed: " + resp.Status)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	htmlStr := string(body)
+	fmt.Printf("[WebSearch] Got HTML response, length: %d chars\n", len(htmlStr))
+
+	results := parseDuckDuckGoHTML(htmlStr, topK)
+	fmt.Printf("[WebSearch] Final results count: %d\n", len(results))
+	if len(results) == 0 {
+		return nil, errors.New("no results")
+	}
+	return results, nil
+}
+
+func parseDuckDuckGoHTML(htmlStr string, topK int) []SearchResult {
+	matches := duckAnchorRe.FindAllStringSubmatch(htmlStr, -1)
+	var results []SearchResult
+	for _, m := range matches {
+		if len(m) < 3 {
+			continue
+		}
+		link := html.UnescapeString(m[1])
+		// Normalize protocol-relative URL
+		if strings.HasPrefix(link, "//") {
+			link = "https:" + link
+		}
+		// Unwrap DDG redirect links like /l/?uddg=... or https://duckduckgo.com/l/?uddg=...
+		if strings.Contains(link, "/l/?uddg=") {
+			abs := link
+			if strings.HasPrefix(link, "/") {
+				abs = "https://duckduckgo.com" + link
+			}
+			if u, err := url.Parse(abs); err == nil {
+				if v := u.Query().Get("uddg"); v != "" {
+					link = v
+				}
+			}
+		}
+		// Skip DDG internal pages
+		if strings.Contains(link, "duckduckgo.com") {
+			continue
+		}
+		results = appendSearchResult(results, m[2], link, "")
+		if len(results) >= topK {
+			break
+		}
+	}
+	return results
+}
+
+// BingProvider Bing Web Search API v7
+type BingProvider struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+}
+
+func (p *BingProvider) Name() string { return "bing" }
+
+func (p *BingProvider) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("empty query")
+	}
+	topK = searchTopK(topK)
+	u, err := url.Parse(searchBaseURL(p.BaseURL, "https://api.bing.microsoft.com/v7.0/search"))
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("q", query)
+	q.Set("count", strconv.Itoa(topK))
+	q.Set("mkt", searchLanguage(query))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", p.APIKey)
+	var data struct {
+		WebPages struct {
+			Value []struct {
+				Name    string `json:"name"`
+				URL     string `json:"url"`
+				Snippet string `json:"snippet"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := doSearchRequest(p.Client, req, &data); err != nil {
+		return nil, fmt.Errorf("bing search failed: %w", err)
+	}
+	var out []SearchResult
+	for _, it := range data.WebPages.Value {
+		out = appendSearchResult(out, it.Name, it.URL, it.Snippet)
+		if len(out) >= topK {
+			break
+		}
+	}
+	return out, nil
+}
+
+// BraveProvider Brave Search API
+type BraveProvider struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+}
+
+func (p *BraveProvider) Name() string { return "brave" }
+
+func (p *BraveProvider) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("empty query")
+	}
+	topK = searchTopK(topK)
+	u, err := url.Parse(searchBaseURL(p.BaseURL, "https://api.search.brave.com/res/v1/web/search"))
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("q", query)
+	q.Set("count", strconv.Itoa(topK))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", p.APIKey)
+	var data struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doSearchRequest(p.Client, req, &data); err != nil {
+		return nil, fmt.Errorf("brave search failed: %w", err)
+	}
+	var out []SearchResult
+	for _, it := range data.Web.Results {
+		out = appendSearchResult(out, it.Title, it.URL, it.Description)
+		if len(out) >= topK {
+			break
+		}
+	}
+	return out, nil
+}
+
+// SearXNGProvider 自建 SearXNG 实例，需在实例中开启 json 输出格式
+type SearXNGProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func (p *SearXNGProvider) Name() string { return "searxng" }
+
+func (p *SearXNGProvider) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("empty query")
+	}
+	if p.BaseURL == "" {
+		return nil, errors.New("searxng url missing")
+	}
+	topK = searchTopK(topK)
+	u, err := url.Parse(strings.TrimRight(p.BaseURL, "/") + "/search")
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("q", query)
+	q.Set("format", "json")
+	q.Set("language", searchLanguage(query))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doSearchRequest(p.Client, req, &data); err != nil {
+		return nil, fmt.Errorf("searxng search failed: %w", err)
+	}
+	var out []SearchResult
+	for _, it := range data.Results {
+		out = appendSearchResult(out, it.Title, it.URL, it.Content)
+		if len(out) >= topK {
+			break
+		}
+	}
+	return out, nil
+}
+
+// TavilyProvider Tavily Search API
+type TavilyProvider struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+}
+
+func (p *TavilyProvider) Name() string { return "tavily" }
+
+func (p *TavilyProvider) Search(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, errors.New("empty query")
+	}
+	topK = searchTopK(topK)
+	payload, err := json.Marshal(map[string]interface{}{
+		"query":        query,
+		"max_results":  topK,
+		"search_depth": "basic",
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		searchBaseURL(p.BaseURL, "https://api.tavily.com/search"), bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	var data struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := doSearchRequest(p.Client, req, &data); err != nil {
+		return nil, fmt.Errorf("tavily search failed: %w", err)
+	}
+	var out []SearchResult
+	for _, it := range data.Results {
+		out = appendSearchResult(out, it.Title, it.URL, it.Content)
+		if len(out) >= topK {
+			break
+		}
+	}
+	return out, nil
+}
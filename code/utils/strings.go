@@ -1,6 +1,9 @@
 package utils
 
-import "strings"
+import (
+	"regexp"
+	"strings"
+)
 
 func CutPrefix(s, prefix string) (string, bool) {
 	if strings.HasPrefix(s, prefix) {
@@ -36,3 +39,23 @@ func EitherTrimEqual(s string, prefix ...string) (string, bool) {
 	}
 	return s, false
 }
+
+var (
+	mdLinkRegex   = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	mdCodeRegex   = regexp.MustCompile("(?s)```.*?```")
+	mdSymbolRegex = regexp.MustCompile("[*_`#>~|]+")
+	spaceRegex    = regexp.MustCompile(`\s+`)
+)
+
+// PlainSpeechText 去掉 Markdown 标记并截断到 maxRunes 个字符，用于语音朗读
+// 代码块不适合朗读，直接省略
+func PlainSpeechText(md string, maxRunes int) string {
+	text := mdCodeRegex.ReplaceAllString(md, " ")
+	text = mdLinkRegex.ReplaceAllString(text, "$1")
+	text = mdSymbolRegex.ReplaceAllString(text, "")
+	text = strings.TrimSpace(spaceRegex.ReplaceAllString(text, " "))
+	if runes := []rune(text); maxRunes > 0 && len(runes) > maxRunes {
+		text = string(runes[:maxRunes])
+	}
+	return text
+}
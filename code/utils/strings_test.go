@@ -107,3 +107,36 @@ func TestEitherTrimEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestPlainSpeechText(t *testing.T) {
+	tests := []struct {
+		name     string
+		md       string
+		maxRunes int
+		want     string
+	}{
+		{
+			name: "strip markdown",
+			md:   "## 标题\n**加粗** 和 [链接](https://example.com)",
+			want: "标题 加粗 和 链接",
+		},
+		{
+			name: "skip code block",
+			md:   "示例如下\n```go\nfmt.Println(1)\n```\n完毕",
+			want: "示例如下 完毕",
+		},
+		{
+			name:     "truncate runes",
+			md:       "你好世界",
+			maxRunes: 2,
+			want:     "你好",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainSpeechText(tt.md, tt.maxRunes); got != tt.want {
+				t.Errorf("PlainSpeechText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package transcript
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Segment 一段带时间戳的转写文本
+type Segment struct {
+	Start time.Duration
+	End   time.Duration
+	Text  string
+}
+
+// Offset 将分段转写的时间戳平移到整段音频中的位置
+func Offset(segments []Segment, offset time.Duration) []Segment {
+	shifted := make([]Segment, len(segments))
+	for i, s := range segments {
+		shifted[i] = Segment{Start: s.Start + offset, End: s.End + offset, Text: s.Text}
+	}
+	return shifted
+}
+
+// Text 拼接所有分段为纯文本
+func Text(segments []Segment) string {
+	var parts []string
+	for _, s := range segments {
+		if t := strings.TrimSpace(s.Text); t != "" {
+			parts = append(parts, t)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// FormatSRT 输出 SubRip 字幕格式
+func FormatSRT(segments []Segment) string {
+	var b strings.Builder
+	index := 0
+	for _, s := range segments {
+		text := strings.TrimSpace(s.Text)
+		if text == "" {
+			continue
+		}
+		index++
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", index,
+			formatTimestamp(s.Start, ","), formatTimestamp(s.End, ","), text)
+	}
+	return b.String()
+}
+
+// FormatMarkdown 输出带时间戳的 Markdown 逐字稿
+func FormatMarkdown(title string, segments []Segment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	for _, s := range segments {
+		text := strings.TrimSpace(s.Text)
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "**[%s]** %s\n\n", formatClock(s.Start), text)
+	}
+	return b.String()
+}
+
+// SplitText 按行把长文本切成不超过 maxRunes 的若干段，用于分段总结
+func SplitText(text string, maxRunes int) []string {
+	if maxRunes <= 0 || len([]rune(text)) <= maxRunes {
+		return []string{text}
+	}
+	var parts []string
+	var current []rune
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		for len(runes) > maxRunes {
+			if len(current) > 0 {
+				parts = append(parts, string(current))
+				current = nil
+			}
+			parts = append(parts, string(runes[:maxRunes]))
+			runes = runes[maxRunes:]
+		}
+		if len(current) > 0 && len(current)+1+len(runes) > maxRunes {
+			parts = append(parts, string(current))
+			current = nil
+		}
+		if len(current) > 0 {
+			current = append(current, '\n')
+		}
+		current = append(current, runes...)
+	}
+	if len(current) > 0 {
+		parts = append(parts, string(current))
+	}
+	return parts
+}
+
+func formatTimestamp(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	ms := (d % time.Second) / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", h, m, s, sep, ms)
+}
+
+func formatClock(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	h := d / time.Hour
+	m := (d % time.Hour) / time.Minute
+	s := (d % time.Minute) / time.Second
+	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+}
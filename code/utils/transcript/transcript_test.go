@@ -0,0 +1,57 @@
+package transcript
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatSRT(t *testing.T) {
+	segments := []Segment{
+		{Start: 0, End: 1500 * time.Millisecond, Text: " 大家好 "},
+		{Start: 2 * time.Second, End: 3 * time.Second, Text: ""},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond,
+			End: time.Hour + 2*time.Minute + 5*time.Second, Text: "开始开会"},
+	}
+	want := "1\n00:00:00,000 --> 00:00:01,500\n大家好\n\n" +
+		"2\n01:02:03,045 --> 01:02:05,000\n开始开会\n\n"
+	if got := FormatSRT(segments); got != want {
+		t.Errorf("FormatSRT() = %q, want %q", got, want)
+	}
+}
+
+func TestFormatMarkdown(t *testing.T) {
+	segments := []Segment{{Start: 65 * time.Second, End: 70 * time.Second, Text: "第一项"}}
+	want := "# 会议\n\n**[00:01:05]** 第一项\n\n"
+	if got := FormatMarkdown("会议", segments); got != want {
+		t.Errorf("FormatMarkdown() = %q, want %q", got, want)
+	}
+}
+
+func TestOffset(t *testing.T) {
+	got := Offset([]Segment{{Start: time.Second, End: 2 * time.Second, Text: "a"}}, time.Minute)
+	want := []Segment{{Start: 61 * time.Second, End: 62 * time.Second, Text: "a"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Offset() = %v, want %v", got, want)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     []string
+	}{
+		{name: "short", text: "一二三", maxRunes: 5, want: []string{"一二三"}},
+		{name: "by lines", text: "一二\n三四\n五六", maxRunes: 5, want: []string{"一二\n三四", "五六"}},
+		{name: "long line", text: "一二三四五六七", maxRunes: 3, want: []string{"一二三", "四五六", "七"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitText(tt.text, tt.maxRunes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
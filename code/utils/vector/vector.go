@@ -0,0 +1,107 @@
+package vector
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Match TopK 返回的候选向量下标及其相似度
+type Match struct {
+	Index int
+	Score float64
+}
+
+// Dot 计算两个向量的点积，长度不同时返回 0
+func Dot(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+// Norm 向量的 L2 范数
+func Norm(v []float32) float64 {
+	return math.Sqrt(Dot(v, v))
+}
+
+// Normalize 返回单位向量，零向量原样返回副本
+func Normalize(v []float32) []float32 {
+	out := make([]float32, len(v))
+	copy(out, v)
+	n := Norm(v)
+	if n == 0 {
+		return out
+	}
+	for i := range out {
+		out[i] = float32(float64(out[i]) / n)
+	}
+	return out
+}
+
+// Cosine 计算两个向量的余弦相似度，长度不同或为零向量时返回 0
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// TopK 返回与 query 余弦相似度最高的 k 个候选，按相似度从高到低排列，相同分数时下标小的在前。
+// 长度与 query 不同的候选会被跳过，k<=0 时返回全部候选
+func TopK(query []float32, candidates [][]float32, k int) []Match {
+	if k <= 0 || k > len(candidates) {
+		k = len(candidates)
+	}
+	h := make(matchHeap, 0, k)
+	for i, c := range candidates {
+		if len(c) != len(query) {
+			continue
+		}
+		m := Match{Index: i, Score: Cosine(query, c)}
+		if len(h) < k {
+			heap.Push(&h, m)
+		} else if k > 0 && better(m, h[0]) {
+			h[0] = m
+			heap.Fix(&h, 0)
+		}
+	}
+	out := make([]Match, len(h))
+	for i := len(h) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(&h).(Match)
+	}
+	return out
+}
+
+func better(a, b Match) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.Index < b.Index
+}
+
+// matchHeap 以最差的候选为堆顶的小顶堆
+type matchHeap []Match
+
+func (h matchHeap) Len() int            { return len(h) }
+func (h matchHeap) Less(i, j int) bool  { return better(h[j], h[i]) }
+func (h matchHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *matchHeap) Push(x interface{}) { *h = append(*h, x.(Match)) }
+func (h *matchHeap) Pop() interface{} {
+	old := *h
+	m := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return m
+}
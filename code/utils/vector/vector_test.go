@@ -0,0 +1,74 @@
+package vector
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float32
+		want float64
+	}{
+		{"same direction", []float32{1, 2}, []float32{2, 4}, 1},
+		{"orthogonal", []float32{1, 0}, []float32{0, 3}, 0},
+		{"opposite", []float32{1, 1}, []float32{-1, -1}, -1},
+		{"length mismatch", []float32{1, 0}, []float32{1, 0, 0}, 0},
+		{"zero vector", []float32{0, 0}, []float32{1, 0}, 0},
+		{"empty", nil, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Cosine(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("Cosine() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	v := []float32{3, 4}
+	got := Normalize(v)
+	if math.Abs(Norm(got)-1) > 1e-6 || v[0] != 3 {
+		t.Fatalf("Normalize(%v) = %v", v, got)
+	}
+	if got := Normalize([]float32{0, 0}); got[0] != 0 || got[1] != 0 {
+		t.Fatalf("Normalize(zero) = %v", got)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := [][]float32{
+		{0, 1},    // 0
+		{1, 0},    // 1
+		{1, 1},    // 0.707
+		{2, 0},    // 1
+		{1, 0, 0}, // 维度不同，跳过
+		{-1, 0},   // -1
+	}
+	tests := []struct {
+		name string
+		k    int
+		want []int
+	}{
+		{"top two ties keep order", 2, []int{1, 3}},
+		{"top three", 3, []int{1, 3, 2}},
+		{"all", 0, []int{1, 3, 2, 0, 5}},
+		{"k larger than candidates", 10, []int{1, 3, 2, 0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TopK(query, candidates, tt.k)
+			if len(got) != len(tt.want) {
+				t.Fatalf("TopK() = %v, want indexes %v", got, tt.want)
+			}
+			for i, m := range got {
+				if m.Index != tt.want[i] {
+					t.Fatalf("TopK() = %v, want indexes %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
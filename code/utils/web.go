@@ -5,86 +5,34 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"html"
-	"io/ioutil"
-	"net/http"
-	"net/url"
 	"regexp"
-	"strconv"
+	"start-feishubot/services/cache"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // FetchURLAsPlainText fetches the given URL and returns readable text.
-// It leverages Jina Reader (https://r.jina.ai) to extract clean content without HTML.
-// The input can be with or without scheme; we'll normalize it.
+// The page is fetched by the configured PageFetcher (builtin extraction by default).
 func FetchURLAsPlainText(rawURL string) (string, error) {
-	cleaned := strings.TrimSpace(rawURL)
-	if cleaned == "" {
-		return "", errors.New("empty url")
-	}
-
-	// Normalize into Jina Reader endpoint
-	// Jina Reader format: https://r.jina.ai/http://example.com or https://r.jina.ai/https://example.com
-	var readerURL string
-	if strings.HasPrefix(cleaned, "http://") || strings.HasPrefix(cleaned, "https://") {
-		readerURL = "https://r.jina.ai/" + cleaned
-	} else {
-		// default to https
-		readerURL = "https://r.jina.ai/https://" + cleaned
-	}
-
-	client := &http.Client{Timeout: 15 * time.Second}
-	req, err := http.NewRequest("GET", readerURL, nil)
-	if err != nil {
-		return "", err
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", errors.New("failed to fetch url: " + resp.Status)
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	return string(body), nil
+	return FetchURLAsPlainTextWithTimeout(rawURL, 15*time.Second)
 }
 
 // FetchURLAsPlainTextWithTimeout is like FetchURLAsPlainText but allows a custom timeout
 func FetchURLAsPlainTextWithTimeout(rawURL string, timeout time.Duration) (string, error) {
-	cleaned := strings.TrimSpace(rawURL)
-	if cleaned == "" {
-		return "", errors.New("empty url")
-	}
-	var readerURL string
-	if strings.HasPrefix(cleaned, "http://") || strings.HasPrefix(cleaned, "https://") {
-		readerURL = "https://r.jina.ai/" + cleaned
-	} else {
-		readerURL = "https://r.jina.ai/https://" + cleaned
-	}
-	client := &http.Client{Timeout: timeout}
-	req, err := http.NewRequest("GET", readerURL, nil)
+	pageURL, err := normalizeURL(rawURL)
 	if err != nil {
 		return "", err
 	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", errors.New("failed to fetch url: " + resp.Status)
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	return string(body), nil
+	fetcher := getPageFetcher()
+	_, pages := getWebCaches()
+	content, _, err := pages.Load(fetcher.Name()+":"+pageURL, func() (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return fetcher.Fetch(ctx, pageURL)
+	})
+	return content, err
 }
 
 // SearchResult represents a single search result
@@ -94,132 +42,112 @@ type SearchResult struct {
 	Snippet string `json:"snippet,omitempty"`
 }
 
-// WebSearch performs a web search using DuckDuckGo's HTML endpoint via Jina Reader aggregator API.
-// We use https://r.jina.ai/http://r.jina.ai/http://duckduckgo.com/html/?q=... pattern is not valid; instead we'll call a lightweight meta-search API.
-// Here we leverage Brave Search API compatible relay by Jina: https://r.jina.ai/http://r.jina.ai/http://r.jina.ai/... is not reliable in China, so fallback to DuckDuckGo's lite HTML and extract links is complex.
-// To keep it simple and dependency-free, we use DuckDuckGo's lite HTML and let r.jina.ai convert to text, then pick top lines containing http.
+// WebSearch searches DuckDuckGo's HTML endpoint and returns the topK results.
 func WebSearch(query string, topK int) ([]SearchResult, error) {
-	q := strings.TrimSpace(query)
-	if q == "" {
-		return nil, errors.New("empty query")
-	}
-	if topK <= 0 {
-		topK = 3
-	} // Fetch DuckDuckGo lite HTML directly and parse anchors
-	duckURL := "https://duckduckgo.com/html/?q=" + url.QueryEscape(q)
-	fmt.Printf("[WebSearch] Searching URL: %s\n", duckURL)
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, _ := http.NewRequest("GET", duckURL, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/116.0 Safari/537.36")
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Printf("[WebSearch] HTTP request failed: %v\n", err)
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		fmt.Printf("[WebSearch] HTTP status failed: %s\n", resp.Status)
-		return nil, errors.New("duckduckgo html failed: " + resp.Status)
-	}
-	body, _ := ioutil.ReadAll(resp.Body)
-	htmlStr := string(body)
-	fmt.Printf("[WebSearch] Got HTML response, length: %d chars\n", len(htmlStr))
+	return (&DuckDuckGoProvider{}).Search(context.Background(), query, topK)
+}
 
-	// Extract <a class="result__a" href="...">Title</a>
-	// Be tolerant to attribute order and both quote styles
-	anchorRe := regexp.MustCompile(`<a[^>]+class=["'][^"']*result__a[^"']*["'][^>]+href=["']([^"']+)["'][^>]*>(.*?)</a>`)
-	matches := anchorRe.FindAllStringSubmatch(htmlStr, -1)
-	fmt.Printf("[WebSearch] Found %d regex matches\n", len(matches))
-	var results []SearchResult
-	for _, m := range matches {
-		if len(m) < 3 {
-			continue
-		}
-		link := html.UnescapeString(m[1])
-		title := html.UnescapeString(stripTags(m[2]))
-		// Normalize protocol-relative URL
-		if strings.HasPrefix(link, "//") {
-			link = "https:" + link
-		}
-		// Unwrap DDG redirect links like /l/?uddg=... or https://duckduckgo.com/l/?uddg=...
-		if strings.Contains(link, "/l/?uddg=") {
-			// Ensure absolute URL for parsing
-			abs := link
-			if strings.HasPrefix(link, "/") {
-				abs = "https://duckduckgo.com" + link
-			}
-			if u, err := url.Parse(abs); err == nil {
-				if v := u.Query().Get("uddg"); v != "" {
-					if decoded, err := url.QueryUnescape(v); err == nil {
-						link = decoded
-					}
-				}
-			}
-		}
-		if !strings.HasPrefix(link, "http://") && !strings.HasPrefix(link, "https://") {
-			continue
-		}
-		// Skip DDG internal pages (keep only if we successfully unwrapped above)
-		if strings.Contains(link, "duckduckgo.com") && !strings.Contains(link, "/l/?uddg=") {
-			continue
-		}
-		results = append(results, SearchResult{Title: title, URL: link})
-		if len(results) >= topK {
-			break
-		}
+// 搜索结果列表和网页正文分开缓存：同一页面可能出现在不同查询的结果中
+var (
+	webCacheMu  sync.RWMutex
+	searchCache = cache.New(cache.Options{Name: "search", MaxEntries: 500, TTL: 5 * time.Minute})
+	pageCache   = cache.New(cache.Options{Name: "page", MaxEntries: 200, MaxBytes: 32 << 20, TTL: 30 * time.Minute})
+)
+
+// SetWebCaches 替换搜索结果和网页正文的缓存，传 nil 的保持不变
+func SetWebCaches(search, page *cache.Cache) {
+	webCacheMu.Lock()
+	defer webCacheMu.Unlock()
+	if search != nil {
+		searchCache = search
 	}
-	fmt.Printf("[WebSearch] Final results count: %d\n", len(results))
-	if len(results) == 0 {
-		fmt.Printf("[WebSearch] No valid results found, returning error\n")
-		return nil, errors.New("no results")
+	if page != nil {
+		pageCache = page
 	}
-	return results, nil
 }
 
-// BuildSearchContext downloads topK results and returns a concatenated context string.
-// simple cache for search contexts
-type cacheEntry struct {
-	value     string
-	expiresAt time.Time
+func getWebCaches() (*cache.Cache, *cache.Cache) {
+	webCacheMu.RLock()
+	defer webCacheMu.RUnlock()
+	return searchCache, pageCache
 }
 
-var (
-	searchCtxCacheMu sync.Mutex
-	searchCtxCache   = make(map[string]cacheEntry)
-)
-
-func getCachedContext(key string) (string, bool) {
-	searchCtxCacheMu.Lock()
-	defer searchCtxCacheMu.Unlock()
-	if e, ok := searchCtxCache[key]; ok {
-		if time.Now().Before(e.expiresAt) {
-			return e.value, true
-		}
-		delete(searchCtxCache, key)
-	}
-	return "", false
+// WebCacheStats 搜索结果和网页正文缓存的命中统计
+func WebCacheStats() map[string]cache.Stats {
+	search, page := getWebCaches()
+	return map[string]cache.Stats{"search": search.Stats(), "page": page.Stats()}
 }
 
-func setCachedContext(key, val string, ttl time.Duration) {
-	searchCtxCacheMu.Lock()
-	searchCtxCache[key] = cacheEntry{value: val, expiresAt: time.Now().Add(ttl)}
-	searchCtxCacheMu.Unlock()
+// WebSource 检索结果及抓取到的正文，BuildProviderSearchContext 返回它的 JSON 数组
+type WebSource struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+	Content string `json:"content"`
+	// Origin 来源列表中显示的出处，留空时显示链接的域名
+	Origin string `json:"origin,omitempty"`
 }
 
-// BuildSearchContext builds context by concurrently fetching topK results with timeouts and cache
+// maxSourceContentBytes 单个页面保留的正文上限，相关片段由 SelectSourceContent 挑选
+const maxSourceContentBytes = 64 << 10
+
+// BuildSearchContext builds context from DuckDuckGo results
 func BuildSearchContext(query string, topK int) (string, error) {
-	if v, ok := getCachedContext("ddg:" + query); ok {
-		return v, nil
-	}
-	results, err := WebSearch(query, topK)
+	return BuildProviderSearchContext(&DuckDuckGoProvider{}, query, topK)
+}
+
+// searchResultList 缓存的搜索结果列表
+type searchResultList struct {
+	Provider string         `json:"provider"`
+	Results  []SearchResult `json:"results"`
+}
+
+// SearchContext 一次检索的结果：实际给出结果的搜索引擎和抓取到正文的来源
+type SearchContext struct {
+	Provider string      `json:"provider"`
+	Sources  []WebSource `json:"sources"`
+	// Cached 搜索结果列表来自缓存，不参与序列化
+	Cached bool `json:"-"`
+}
+
+// BuildProviderSearchContext searches with the given provider, then concurrently
+// fetches topK results with timeouts and cache
+func BuildProviderSearchContext(provider SearchProvider, query string, topK int) (string, error) {
+	sc, err := BuildProviderSearchSources(provider, query, topK)
 	if err != nil {
 		return "", err
 	}
-	type item struct {
-		Title   string `json:"title"`
-		URL     string `json:"url"`
-		Content string `json:"content"`
+	b, _ := json.Marshal(sc.Sources)
+	return string(b), nil
+}
+
+// BuildProviderSearchSources 与 BuildProviderSearchContext 相同，但返回结构化结果
+func BuildProviderSearchSources(provider SearchProvider, query string, topK int) (*SearchContext, error) {
+	searches, _ := getWebCaches()
+	cacheKey := fmt.Sprintf("%s:%d:%s", provider.Name(), topK, strings.TrimSpace(query))
+	v, cached, err := searches.Load(cacheKey, func() (string, error) {
+		var results []SearchResult
+		var err error
+		providerName := provider.Name()
+		if chain, ok := provider.(*SearchChain); ok {
+			results, providerName, err = chain.SearchFrom(context.Background(), query, topK)
+		} else {
+			results, err = provider.Search(context.Background(), query, topK)
+		}
+		if err != nil {
+			return "", err
+		}
+		b, err := json.Marshal(searchResultList{Provider: providerName, Results: results})
+		return string(b), err
+	})
+	if err != nil {
+		return nil, err
 	}
+	var list searchResultList
+	if err := json.Unmarshal([]byte(v), &list); err != nil {
+		return nil, err
+	}
+	results, providerName := list.Results, list.Provider
 	// concurrency control
 	if topK <= 0 {
 		topK = 3
@@ -231,7 +159,7 @@ func BuildSearchContext(query string, topK int) (string, error) {
 	sem := make(chan struct{}, maxConcurrency)
 	var wg sync.WaitGroup
 	var mu sync.Mutex
-	var items []item
+	var items []WebSource
 
 	// overall timeout
 	overallCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -254,23 +182,18 @@ func BuildSearchContext(query string, topK int) (string, error) {
 			if err != nil || content == "" {
 				return
 			}
-			// trim to reduce tokens
-			if len(content) > 4000 {
-				content = content[:4000]
-			}
+			// 保留完整正文供后续切片排序，只限制单页上限
+			content = trimTo(content, maxSourceContentBytes)
 			mu.Lock()
-			items = append(items, item{Title: r.Title, URL: r.URL, Content: content})
+			items = append(items, WebSource{Title: r.Title, URL: r.URL, Snippet: r.Snippet, Content: content})
 			mu.Unlock()
 		}()
 	}
 	wg.Wait()
 	if len(items) == 0 {
-		return "", errors.New("no accessible results")
+		return nil, errors.New("no accessible results")
 	}
-	b, _ := json.Marshal(items)
-	out := string(b)
-	setCachedContext("ddg:"+query, out, 5*time.Minute)
-	return out, nil
+	return &SearchContext{Provider: providerName, Sources: items, Cached: cached}, nil
 }
 
 // Google CSE search
@@ -284,137 +207,23 @@ type googleSearchResponse struct {
 
 // GoogleSearch uses Google Custom Search JSON API. Provide apiKey and cseId.
 func GoogleSearch(query, apiKey, cseId string, topK int) ([]SearchResult, error) {
-	if strings.TrimSpace(query) == "" {
-		return nil, errors.New("empty query")
-	}
-	if apiKey == "" || cseId == "" {
-		return nil, errors.New("google api key or cse id missing")
-	}
-	if topK <= 0 {
-		topK = 3
-	}
-	base := "https://www.googleapis.com/customsearch/v1"
-	u, _ := url.Parse(base)
-	q := u.Query()
-	q.Set("key", apiKey)
-	q.Set("cx", cseId)
-	q.Set("q", query)
-	// increase candidates to improve recall
-	want := topK * 3
-	if want < topK {
-		want = topK
-	}
-	if want > 10 {
-		want = 10
-	}
-	q.Set("num", strconv.Itoa(want))
-	// language/region hints
-	if containsChinese(query) {
-		q.Set("hl", "zh-CN")
-		q.Set("gl", "CN")
-		q.Set("lr", "lang_zh-CN")
-	} else {
-		q.Set("hl", "en")
-		q.Set("gl", "US")
-	}
-	u.RawQuery = q.Encode()
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, _ := http.NewRequest("GET", u.String(), nil)
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, errors.New("google search failed: " + resp.Status)
-	}
-	body, _ := ioutil.ReadAll(resp.Body)
-	var data googleSearchResponse
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, err
-	}
-	var out []SearchResult
-	for _, it := range data.Items {
-		if it.Link == "" {
-			continue
-		}
-		out = append(out, SearchResult{Title: it.Title, URL: it.Link, Snippet: it.Snippet})
-		if len(out) >= topK {
-			break
-		}
-	}
-	if len(out) == 0 {
-		return nil, errors.New("no results")
-	}
-	return out, nil
+	return (&GoogleCSEProvider{APIKey: apiKey, CSEId: cseId}).Search(context.Background(), query, topK)
 }
 
 // BuildGoogleSearchContext uses Google CSE to find results and then fetches their content via reader
 func BuildGoogleSearchContext(query, apiKey, cseId string, topK int) (string, error) {
-	if v, ok := getCachedContext("g:" + query); ok {
-		return v, nil
-	}
-	results, err := GoogleSearch(query, apiKey, cseId, topK)
-	if err != nil {
-		return "", err
-	}
-	type item struct {
-		Title   string `json:"title"`
-		URL     string `json:"url"`
-		Snippet string `json:"snippet,omitempty"`
-		Content string `json:"content"`
-	}
-	if topK <= 0 {
-		topK = 3
-	}
-	maxConcurrency := topK
-	if maxConcurrency > 4 {
-		maxConcurrency = 4
-	}
-	sem := make(chan struct{}, maxConcurrency)
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	var items []item
-	overallCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	perFetchTimeout := 6 * time.Second
-	for _, r := range results {
-		r := r
-		wg.Add(1)
-		if overallCtx.Err() != nil {
-			wg.Done()
-			continue
-		}
-		sem <- struct{}{}
-		go func() {
-			defer wg.Done()
-			defer func() { <-sem }()
-			content, err := FetchURLAsPlainTextWithTimeout(r.URL, perFetchTimeout)
-			if err != nil || content == "" {
-				return
-			}
-			content = trimTo(content, 4000)
-			mu.Lock()
-			items = append(items, item{Title: r.Title, URL: r.URL, Snippet: r.Snippet, Content: content})
-			mu.Unlock()
-		}()
-	}
-	wg.Wait()
-	if len(items) == 0 {
-		return "", errors.New("no accessible results")
-	}
-	b, _ := json.Marshal(items)
-	out := string(b)
-	setCachedContext("g:"+query, out, 5*time.Minute)
-	return out, nil
+	return BuildProviderSearchContext(&GoogleCSEProvider{APIKey: apiKey, CSEId: cseId}, query, topK)
 }
 
 // helpers
+// trimTo 截断到最多 n 字节，不会切断 UTF-8 字符
 func trimTo(s string, n int) string {
 	if n <= 0 || len(s) <= n {
 		return s
 	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
 	return s[:n]
 }
 